@@ -146,8 +146,11 @@ func runServer() {
 		r2 = nil // Continue without storage
 	}
 
+	// Shared between HTTP handlers (SSE) and the outbox relay worker
+	eventSvc := service.NewEventService()
+
 	// Setup router
-	handler := router.New(cfg, db, redis, r2)
+	handler := router.New(cfg, db, redis, r2, eventSvc)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -177,6 +180,8 @@ func runServer() {
 	
 	// Repos
 	notifRepo := repository.NewNotificationRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	reportRepo := repository.NewReportRepository(db)
 	
 	// Clients
 	qClient := queue.NewClient(cfg.Redis.Address(), cfg.Redis.Password)
@@ -184,13 +189,39 @@ func runServer() {
 	
 	// Services
 	notifSvc := service.NewNotificationService(notifRepo, osClient, qClient)
+	outboxSvc := service.NewOutboxService(db, outboxRepo, qClient, eventSvc)
+	reportSvc := service.NewReportService(
+		repository.NewTransactionRepository(db), repository.NewCashFlowRepository(db), repository.NewKasbonRepository(db),
+		repository.NewInventoryRepository(db), reportRepo,
+	)
 	// Note: We need TransactionService here if 'NewTransactionTask' needs it.
 	
 	// Register Handlers
 	queueServer.Handle(queue.TypeLowStockAlert, notifSvc.HandleLowStockTask)
 	queueServer.Handle(queue.TypeNewTransaction, notifSvc.HandleNewTransactionTask)
+	queueServer.Handle(queue.TypeOutboxRelay, outboxSvc.HandleRelayTask)
+	queueServer.Handle(queue.TypeOutboxPurge, outboxSvc.HandlePurgeTask)
+	queueServer.Handle(queue.TypeDailyRollup, reportSvc.HandleRollupTask)
 	// queueServer.Handle(queue.TypeNotificationSend, ...) 
 
+	// The outbox is also relayed on a schedule, so events survive a missed kick or a Redis outage
+	scheduler := queue.NewScheduler(cfg.Redis.Address(), cfg.Redis.Password)
+	if err := scheduler.Register("@every 30s", queue.TypeOutboxRelay); err != nil {
+		logger.Fatal("Failed to register outbox relay schedule: %v", err)
+	}
+	// Closed days are rolled up into daily_summaries just after midnight
+	if err := scheduler.Register("5 0 * * *", queue.TypeDailyRollup); err != nil {
+		logger.Fatal("Failed to register daily rollup schedule: %v", err)
+	}
+	// Published outbox events are only kept for a week
+	if err := scheduler.Register("30 0 * * *", queue.TypeOutboxPurge); err != nil {
+		logger.Fatal("Failed to register outbox purge schedule: %v", err)
+	}
+	if err := scheduler.Start(); err != nil {
+		logger.Warn("Failed to start scheduler: %v", err)
+	}
+	defer scheduler.Shutdown()
+
 	go func() {
 		logger.Info("Starting Queue Server...")
 		if err := queueServer.Run(); err != nil {
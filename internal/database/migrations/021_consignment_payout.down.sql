@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_settlements_consignor_period;
+DELETE FROM cash_flow_categories WHERE name = 'Pembayaran Titipan';
@@ -0,0 +1,11 @@
+-- =============================================
+-- Migration: 021_consignment_payout
+-- Description: Cash flow category for consignor payouts
+-- =============================================
+
+INSERT INTO cash_flow_categories (name, type, description)
+SELECT 'Pembayaran Titipan', 'expense', 'Pembayaran hasil penjualan barang titipan'
+WHERE NOT EXISTS (SELECT 1 FROM cash_flow_categories WHERE name = 'Pembayaran Titipan');
+
+CREATE INDEX IF NOT EXISTS idx_settlements_consignor_period
+    ON consignment_settlements(consignor_id, period_end DESC);
@@ -0,0 +1,3 @@
+-- Enum values cannot be dropped; the 'refund' kasbon_type value is left in place
+DROP INDEX IF EXISTS idx_refund_items_transaction_item;
+DELETE FROM cash_flow_categories WHERE name = 'Refund Penjualan';
@@ -0,0 +1,13 @@
+-- =============================================
+-- Migration: 022_refund_workflow
+-- Description: Kasbon refund entries and cash flow category for refunds
+-- =============================================
+
+-- kasbon_records entry that reduces debt because sold goods were returned
+ALTER TYPE kasbon_type ADD VALUE IF NOT EXISTS 'refund';
+
+INSERT INTO cash_flow_categories (name, type, description)
+SELECT 'Refund Penjualan', 'expense', 'Pengembalian uang atas retur penjualan'
+WHERE NOT EXISTS (SELECT 1 FROM cash_flow_categories WHERE name = 'Refund Penjualan');
+
+CREATE INDEX IF NOT EXISTS idx_refund_items_transaction_item ON refund_items(transaction_item_id);
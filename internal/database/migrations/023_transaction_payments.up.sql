@@ -0,0 +1,27 @@
+-- =============================================
+-- Migration: 023_transaction_payments
+-- Description: Per-tender payment lines for split-tender (mixed) checkout
+-- =============================================
+
+CREATE TABLE IF NOT EXISTS transaction_payments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    method payment_method NOT NULL,            -- cash, kasbon, transfer, qris (never 'mixed')
+    amount BIGINT NOT NULL,                    -- porsi total yang dibayar dengan metode ini (setelah kembalian)
+    reference VARCHAR(100),                    -- no. referensi transfer/QRIS
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT positive_payment_amount CHECK (amount > 0),
+    CONSTRAINT single_tender_method CHECK (method <> 'mixed')
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_payments_transaction ON transaction_payments(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_payments_method ON transaction_payments(method);
+
+-- Existing single-method sales become one payment line each
+INSERT INTO transaction_payments (transaction_id, method, amount, created_at)
+SELECT t.id, t.payment_method, t.total_amount, t.created_at
+FROM transactions t
+WHERE t.payment_method <> 'mixed'
+  AND t.total_amount > 0
+  AND NOT EXISTS (SELECT 1 FROM transaction_payments tp WHERE tp.transaction_id = t.id);
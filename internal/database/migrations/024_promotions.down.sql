@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_transaction_items_promotion;
+ALTER TABLE transaction_items DROP COLUMN IF EXISTS promotion_discount;
+ALTER TABLE transaction_items DROP COLUMN IF EXISTS promotion_name;
+ALTER TABLE transaction_items DROP COLUMN IF EXISTS promotion_id;
+
+DROP TABLE IF EXISTS promotions;
+DROP TYPE IF EXISTS promotion_type;
@@ -0,0 +1,68 @@
+-- =============================================
+-- Migration: 024_promotions
+-- Description: Promotion definitions and promo snapshot on transaction items
+-- =============================================
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'promotion_type') THEN
+        CREATE TYPE promotion_type AS ENUM (
+            'percentage',   -- potongan persen
+            'bundle',       -- paket, mis. 5 pcs 14.000
+            'buy_x_get_y'   -- beli X gratis Y
+        );
+    END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS promotions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    type promotion_type NOT NULL,
+
+    -- scope: a single product or every product in a category
+    product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+    category_id UUID REFERENCES categories(id) ON DELETE CASCADE,
+
+    discount_percent NUMERIC(5,2),             -- percentage
+    bundle_quantity INT,                       -- bundle: jumlah per paket
+    bundle_price BIGINT,                       -- bundle: harga per paket
+    buy_quantity INT,                          -- buy_x_get_y: X
+    get_quantity INT,                          -- buy_x_get_y: Y (gratis)
+
+    starts_at TIMESTAMPTZ,                     -- NULL = langsung berlaku
+    ends_at TIMESTAMPTZ,                       -- NULL = tanpa batas
+    days_of_week INT[],                        -- 0 = Minggu ... 6 = Sabtu, NULL/kosong = setiap hari
+    start_time TIME,                           -- jam mulai harian (mis. happy hour)
+    end_time TIME,                             -- jam selesai harian
+
+    priority INT DEFAULT 0,
+    is_active BOOLEAN DEFAULT true,
+    created_by VARCHAR(100),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT promotion_has_scope CHECK (product_id IS NOT NULL OR category_id IS NOT NULL),
+    CONSTRAINT promotion_percentage_valid CHECK (type <> 'percentage' OR (discount_percent > 0 AND discount_percent <= 100)),
+    CONSTRAINT promotion_bundle_valid CHECK (type <> 'bundle' OR (bundle_quantity > 1 AND bundle_price >= 0)),
+    CONSTRAINT promotion_bxgy_valid CHECK (type <> 'buy_x_get_y' OR (buy_quantity > 0 AND get_quantity > 0)),
+    CONSTRAINT promotion_period_valid CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotions_product ON promotions(product_id);
+CREATE INDEX IF NOT EXISTS idx_promotions_category ON promotions(category_id);
+CREATE INDEX IF NOT EXISTS idx_promotions_active ON promotions(is_active, starts_at, ends_at);
+
+DROP TRIGGER IF EXISTS update_promotions_updated_at ON promotions;
+CREATE TRIGGER update_promotions_updated_at
+    BEFORE UPDATE ON promotions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Snapshot of the promotion applied to a sold line
+ALTER TABLE transaction_items ADD COLUMN IF NOT EXISTS promotion_id UUID REFERENCES promotions(id) ON DELETE SET NULL;
+ALTER TABLE transaction_items ADD COLUMN IF NOT EXISTS promotion_name VARCHAR(100);
+ALTER TABLE transaction_items ADD COLUMN IF NOT EXISTS promotion_discount BIGINT DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_transaction_items_promotion ON transaction_items(promotion_id);
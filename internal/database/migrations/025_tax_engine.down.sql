@@ -0,0 +1,3 @@
+ALTER TABLE categories DROP COLUMN IF EXISTS is_tax_exempt;
+
+DELETE FROM app_settings WHERE key IN ('tax_inclusive', 'tax_rounding');
@@ -0,0 +1,7 @@
+-- Server-side tax: pricing mode, rounding and per-category exemptions
+INSERT INTO app_settings (key, value, description) VALUES
+    ('tax_inclusive', 'false', 'Harga jual sudah termasuk pajak (true/false)'),
+    ('tax_rounding', 'nearest', 'Pembulatan pajak: nearest, up, down')
+ON CONFLICT (key) DO NOTHING;
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS is_tax_exempt BOOLEAN NOT NULL DEFAULT false;
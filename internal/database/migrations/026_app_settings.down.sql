@@ -0,0 +1,4 @@
+DROP FUNCTION IF EXISTS generate_purchase_number(TEXT);
+DROP FUNCTION IF EXISTS generate_invoice_number(TEXT);
+
+DELETE FROM app_settings WHERE key = 'payment_instructions';
@@ -0,0 +1,41 @@
+-- Settings read through the cached settings service
+INSERT INTO app_settings (key, value, description) VALUES
+    ('payment_instructions', 'Silakan lakukan pembayaran ke kasir.', 'Instruksi pembayaran pada tagihan kasbon')
+ON CONFLICT (key) DO NOTHING;
+
+-- Number generators taking the prefix from the application
+CREATE OR REPLACE FUNCTION generate_invoice_number(p_prefix TEXT)
+RETURNS TEXT AS $$
+DECLARE
+    today TEXT;
+    seq INTEGER;
+BEGIN
+    today := TO_CHAR(NOW(), 'YYYYMMDD');
+
+    SELECT COALESCE(MAX(
+        CAST(SUBSTRING(invoice_number FROM LENGTH(p_prefix) + 9) AS INTEGER)
+    ), 0) + 1 INTO seq
+    FROM transactions
+    WHERE invoice_number ~ ('^' || p_prefix || today || '[0-9]+$');
+
+    RETURN p_prefix || today || LPAD(seq::TEXT, 4, '0');
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION generate_purchase_number(p_prefix TEXT)
+RETURNS TEXT AS $$
+DECLARE
+    today TEXT;
+    seq INTEGER;
+BEGIN
+    today := TO_CHAR(NOW(), 'YYYYMMDD');
+
+    SELECT COALESCE(MAX(
+        CAST(SUBSTRING(purchase_number FROM LENGTH(p_prefix) + 9) AS INTEGER)
+    ), 0) + 1 INTO seq
+    FROM purchases
+    WHERE purchase_number ~ ('^' || p_prefix || today || '[0-9]+$');
+
+    RETURN p_prefix || today || LPAD(seq::TEXT, 4, '0');
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,3 @@
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS last_printed_at,
+    DROP COLUMN IF EXISTS receipt_print_count;
@@ -0,0 +1,4 @@
+-- Receipt reprint tracking
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS receipt_print_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_printed_at TIMESTAMPTZ;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_customers_updated_at;
+DROP INDEX IF EXISTS idx_categories_updated_at;
+DROP INDEX IF EXISTS idx_pricing_tiers_updated_at;
+DROP INDEX IF EXISTS idx_products_updated_at;
+DROP INDEX IF EXISTS idx_transactions_client_id;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS client_created_at,
+    DROP COLUMN IF EXISTS client_id;
@@ -0,0 +1,11 @@
+-- Offline sync: client-generated transaction IDs and delta pull indexes
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS client_id UUID,
+    ADD COLUMN IF NOT EXISTS client_created_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_client_id ON transactions(client_id) WHERE client_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_products_updated_at ON products(updated_at);
+CREATE INDEX IF NOT EXISTS idx_pricing_tiers_updated_at ON pricing_tiers(updated_at);
+CREATE INDEX IF NOT EXISTS idx_categories_updated_at ON categories(updated_at);
+CREATE INDEX IF NOT EXISTS idx_customers_updated_at ON customers(updated_at);
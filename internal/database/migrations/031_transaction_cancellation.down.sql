@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_container_movements_reference;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS cancelled_by,
+    DROP COLUMN IF EXISTS cancel_reason;
+
+-- Enum values 'reversal' and 'sale_cancel' cannot be dropped and are left in place
@@ -0,0 +1,17 @@
+-- =============================================
+-- Migration: 031_transaction_cancellation
+-- Description: Cancellation audit fields and reversal entry types
+-- =============================================
+
+-- kasbon_records entry that takes back the debt of a cancelled sale
+ALTER TYPE kasbon_type ADD VALUE IF NOT EXISTS 'reversal';
+
+-- container_movements entry that undoes the swap of a cancelled sale
+ALTER TYPE container_movement_type ADD VALUE IF NOT EXISTS 'sale_cancel';
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS cancel_reason TEXT,
+    ADD COLUMN IF NOT EXISTS cancelled_by VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_container_movements_reference ON container_movements(reference_type, reference_id);
@@ -0,0 +1,6 @@
+ALTER TABLE purchase_items
+    DROP COLUMN IF EXISTS expiry_date,
+    DROP COLUMN IF EXISTS batch_number;
+
+DROP TABLE IF EXISTS stock_batch_allocations;
+DROP TABLE IF EXISTS stock_batches;
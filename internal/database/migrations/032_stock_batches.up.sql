@@ -0,0 +1,76 @@
+-- =============================================
+-- Migration: 032_stock_batches
+-- Description: Stock lots with expiry, consumed first-expired-first-out
+-- =============================================
+
+CREATE TABLE IF NOT EXISTS stock_batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    batch_number VARCHAR(50),
+    expiry_date DATE,                          -- NULL = tidak kedaluwarsa / tidak dicatat
+    initial_quantity INTEGER NOT NULL,
+    remaining_quantity INTEGER NOT NULL,
+    cost_per_unit BIGINT,
+    movement_id UUID REFERENCES stock_movements(id) ON DELETE SET NULL, -- movement that brought the lot in
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT stock_batch_initial_positive CHECK (initial_quantity > 0),
+    CONSTRAINT stock_batch_remaining_valid CHECK (remaining_quantity >= 0 AND remaining_quantity <= initial_quantity)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_batches_product ON stock_batches(product_id, expiry_date, created_at)
+    WHERE remaining_quantity > 0;
+CREATE INDEX IF NOT EXISTS idx_stock_batches_expiry ON stock_batches(expiry_date)
+    WHERE remaining_quantity > 0 AND expiry_date IS NOT NULL;
+
+DROP TRIGGER IF EXISTS update_stock_batches_updated_at ON stock_batches;
+CREATE TRIGGER update_stock_batches_updated_at
+    BEFORE UPDATE ON stock_batches
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Which lots a stock movement took from (negative) or put back into (positive)
+CREATE TABLE IF NOT EXISTS stock_batch_allocations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    batch_id UUID NOT NULL REFERENCES stock_batches(id) ON DELETE CASCADE,
+    movement_id UUID NOT NULL REFERENCES stock_movements(id) ON DELETE CASCADE,
+    transaction_id UUID REFERENCES transactions(id) ON DELETE SET NULL, -- sale the units belong to
+    quantity INTEGER NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT stock_batch_allocation_nonzero CHECK (quantity <> 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_batch_allocations_batch ON stock_batch_allocations(batch_id);
+CREATE INDEX IF NOT EXISTS idx_stock_batch_allocations_movement ON stock_batch_allocations(movement_id);
+CREATE INDEX IF NOT EXISTS idx_stock_batch_allocations_transaction ON stock_batch_allocations(transaction_id)
+    WHERE transaction_id IS NOT NULL;
+
+-- Lot details on purchase lines, posted to the lot when the purchase is received
+ALTER TABLE purchase_items
+    ADD COLUMN IF NOT EXISTS batch_number VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS expiry_date DATE;
+
+-- Turn receipts recorded with lot details before this migration into lots.
+-- What is left of each is unknown, so current stock is assigned to the newest receipts first.
+INSERT INTO stock_batches (product_id, batch_number, expiry_date, initial_quantity, remaining_quantity, cost_per_unit, movement_id, created_at)
+SELECT product_id, batch_number, expiry_date, quantity,
+       GREATEST(0, LEAST(quantity, current_stock - newer_quantity)),
+       cost_per_unit, id, created_at
+FROM (
+    SELECT sm.id, sm.product_id, sm.batch_number, sm.expiry_date, sm.quantity, sm.cost_per_unit, sm.created_at,
+           p.current_stock,
+           COALESCE(SUM(sm.quantity) OVER (
+               PARTITION BY sm.product_id
+               ORDER BY sm.created_at DESC, sm.id
+               ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+           ), 0) AS newer_quantity
+    FROM stock_movements sm
+    JOIN products p ON p.id = sm.product_id
+    WHERE sm.type IN ('purchase', 'initial')
+        AND sm.quantity > 0
+        AND p.is_stock_active = true
+        AND (sm.expiry_date IS NOT NULL OR sm.batch_number IS NOT NULL)
+        AND NOT EXISTS (SELECT 1 FROM stock_batches b WHERE b.movement_id = sm.id)
+) legacy;
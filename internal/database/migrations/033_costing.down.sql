@@ -0,0 +1,6 @@
+ALTER TABLE transaction_items DROP COLUMN IF EXISTS cogs_amount;
+
+DROP TABLE IF EXISTS product_cost_history;
+DROP TABLE IF EXISTS cost_layers;
+
+DELETE FROM app_settings WHERE key = 'costing_method';
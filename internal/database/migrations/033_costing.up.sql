@@ -0,0 +1,62 @@
+-- =============================================
+-- Migration: 033_costing
+-- Description: Weighted-average / FIFO cost of goods sold
+-- =============================================
+
+INSERT INTO app_settings (key, value, description) VALUES
+    ('costing_method', 'average', 'Metode HPP: average (rata-rata tertimbang) atau fifo')
+ON CONFLICT (key) DO NOTHING;
+
+-- Stock received at one unit cost; FIFO uses the oldest layers first
+CREATE TABLE IF NOT EXISTS cost_layers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    movement_id UUID REFERENCES stock_movements(id) ON DELETE SET NULL,
+    unit_cost BIGINT NOT NULL,
+    initial_quantity INTEGER NOT NULL,
+    remaining_quantity INTEGER NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT cost_layer_initial_positive CHECK (initial_quantity > 0),
+    CONSTRAINT cost_layer_remaining_valid CHECK (remaining_quantity >= 0 AND remaining_quantity <= initial_quantity),
+    CONSTRAINT cost_layer_cost_non_negative CHECK (unit_cost >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cost_layers_open ON cost_layers(product_id, created_at)
+    WHERE remaining_quantity > 0;
+
+DROP TRIGGER IF EXISTS update_cost_layers_updated_at ON cost_layers;
+CREATE TRIGGER update_cost_layers_updated_at
+    BEFORE UPDATE ON cost_layers
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- How each receipt or return moved the weighted-average cost
+CREATE TABLE IF NOT EXISTS product_cost_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    movement_id UUID REFERENCES stock_movements(id) ON DELETE SET NULL,
+    movement_type stock_movement_type NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_cost BIGINT NOT NULL,
+    stock_before INTEGER NOT NULL,
+    average_cost_before BIGINT NOT NULL,
+    average_cost_after BIGINT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_cost_history_product ON product_cost_history(product_id, created_at DESC);
+
+-- Actual cost of the units on each sold line; older lines used the cost price snapshot
+ALTER TABLE transaction_items ADD COLUMN IF NOT EXISTS cogs_amount BIGINT;
+UPDATE transaction_items SET cogs_amount = cost_price * quantity WHERE cogs_amount IS NULL;
+ALTER TABLE transaction_items ALTER COLUMN cogs_amount SET DEFAULT 0;
+ALTER TABLE transaction_items ALTER COLUMN cogs_amount SET NOT NULL;
+
+-- Opening layer: stock on hand at the current cost price
+INSERT INTO cost_layers (product_id, unit_cost, initial_quantity, remaining_quantity)
+SELECT id, cost_price, current_stock, current_stock
+FROM products
+WHERE is_stock_active = true AND current_stock > 0
+    AND NOT EXISTS (SELECT 1 FROM cost_layers l WHERE l.product_id = products.id);
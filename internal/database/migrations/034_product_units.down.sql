@@ -0,0 +1,11 @@
+ALTER TABLE purchase_items
+    DROP COLUMN IF EXISTS unit_cost,
+    DROP COLUMN IF EXISTS unit_quantity,
+    DROP COLUMN IF EXISTS unit_name;
+
+ALTER TABLE transaction_items
+    DROP COLUMN IF EXISTS conversion_factor,
+    DROP COLUMN IF EXISTS unit_quantity,
+    DROP COLUMN IF EXISTS product_unit_id;
+
+DROP TABLE IF EXISTS product_units;
@@ -0,0 +1,46 @@
+-- =============================================
+-- Migration: 034_product_units
+-- Description: Alternative units (pack, dus, renteng) with conversion to the base unit
+-- =============================================
+
+-- products.unit stays the base unit; current_stock is always counted in it
+CREATE TABLE IF NOT EXISTS product_units (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,                 -- "pack", "dus", "renteng"
+    conversion_factor INTEGER NOT NULL,        -- base units in one of this unit
+    barcode VARCHAR(50),                       -- carton / pack barcode
+    price BIGINT,                              -- selling price per unit; NULL = base price x factor
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT product_unit_factor_positive CHECK (conversion_factor >= 1),
+    CONSTRAINT product_unit_price_non_negative CHECK (price IS NULL OR price >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_units_product ON product_units(product_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_units_name ON product_units(product_id, lower(name))
+    WHERE is_active = true;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_units_barcode ON product_units(barcode)
+    WHERE is_active = true AND barcode IS NOT NULL;
+
+DROP TRIGGER IF EXISTS update_product_units_updated_at ON product_units;
+CREATE TRIGGER update_product_units_updated_at
+    BEFORE UPDATE ON product_units
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Sale lines keep quantity in base units; the unit sold is recorded alongside
+ALTER TABLE transaction_items
+    ADD COLUMN IF NOT EXISTS product_unit_id UUID REFERENCES product_units(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS unit_quantity INTEGER,
+    ADD COLUMN IF NOT EXISTS conversion_factor INTEGER NOT NULL DEFAULT 1;
+UPDATE transaction_items SET unit_quantity = quantity WHERE unit_quantity IS NULL;
+ALTER TABLE transaction_items ALTER COLUMN unit_quantity SET NOT NULL;
+
+-- Purchase lines bought in a larger unit; quantity and cost_per_unit stay in base units
+ALTER TABLE purchase_items
+    ADD COLUMN IF NOT EXISTS unit_name VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS unit_quantity INTEGER,
+    ADD COLUMN IF NOT EXISTS unit_cost BIGINT;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_products_variant_name;
+DROP INDEX IF EXISTS idx_products_parent;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS product_parent_not_self;
+ALTER TABLE products
+    DROP COLUMN IF EXISTS variant_name,
+    DROP COLUMN IF EXISTS parent_id;
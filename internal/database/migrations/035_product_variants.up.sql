@@ -0,0 +1,17 @@
+-- =============================================
+-- Migration: 035_product_variants
+-- Description: Variants (size, flavor) grouped under a parent product
+-- =============================================
+
+-- A variant is a full product with its own barcode, price, tiers and stock.
+-- Category, consignor and image are shared and kept equal to the parent's.
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES products(id),
+    ADD COLUMN IF NOT EXISTS variant_name VARCHAR(100);
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS product_parent_not_self;
+ALTER TABLE products ADD CONSTRAINT product_parent_not_self CHECK (parent_id IS NULL OR parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_products_parent ON products(parent_id) WHERE parent_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_variant_name ON products(parent_id, lower(variant_name))
+    WHERE parent_id IS NOT NULL AND is_active = true;
@@ -0,0 +1 @@
+ALTER TABLE daily_summaries DROP COLUMN IF EXISTS total_tax;
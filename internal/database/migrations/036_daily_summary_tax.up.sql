@@ -0,0 +1,10 @@
+-- =============================================
+-- Migration: 036_daily_summary_tax
+-- Description: Keep tax out of the daily rollup's sales and profit
+-- =============================================
+
+ALTER TABLE daily_summaries ADD COLUMN IF NOT EXISTS total_tax BIGINT DEFAULT 0;
+
+-- Rows written before this counted tax as sales. Closed days without a row are
+-- recomputed on the next read and by the nightly rollup.
+DELETE FROM daily_summaries;
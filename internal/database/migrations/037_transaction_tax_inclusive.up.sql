@@ -0,0 +1,12 @@
+-- =============================================
+-- Migration: 037_transaction_tax_inclusive
+-- Description: Remember whether a sale's tax was included in its prices
+-- =============================================
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Older sales did not record the mode; with inclusive tax the total is the
+-- discounted subtotal, with exclusive tax the tax is added on top of it.
+UPDATE transactions
+SET tax_inclusive = TRUE
+WHERE tax_amount > 0 AND total_amount = subtotal - discount_amount;
@@ -0,0 +1,2 @@
+-- Lets the nightly purge find old published events without scanning the table
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published ON outbox_events(published_at) WHERE status = 'published';
@@ -0,0 +1,19 @@
+DROP FUNCTION IF EXISTS generate_invoice_number(TEXT, TIMESTAMPTZ);
+
+CREATE OR REPLACE FUNCTION generate_invoice_number(p_prefix TEXT)
+RETURNS TEXT AS $$
+DECLARE
+    today TEXT;
+    seq INTEGER;
+BEGIN
+    today := TO_CHAR(NOW(), 'YYYYMMDD');
+
+    SELECT COALESCE(MAX(
+        CAST(SUBSTRING(invoice_number FROM LENGTH(p_prefix) + 9) AS INTEGER)
+    ), 0) + 1 INTO seq
+    FROM transactions
+    WHERE invoice_number ~ ('^' || p_prefix || today || '[0-9]+$');
+
+    RETURN p_prefix || today || LPAD(seq::TEXT, 4, '0');
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,25 @@
+-- =============================================
+-- Migration: 039_invoice_number_date
+-- Description: Number invoices by the day a sale is booked, so an offline sale
+--              pushed later keeps the date it was made on
+-- =============================================
+
+DROP FUNCTION IF EXISTS generate_invoice_number(TEXT);
+
+CREATE OR REPLACE FUNCTION generate_invoice_number(p_prefix TEXT, p_at TIMESTAMPTZ DEFAULT NOW())
+RETURNS TEXT AS $$
+DECLARE
+    booked TEXT;
+    seq INTEGER;
+BEGIN
+    booked := TO_CHAR(COALESCE(p_at, NOW()), 'YYYYMMDD');
+
+    SELECT COALESCE(MAX(
+        CAST(SUBSTRING(invoice_number FROM LENGTH(p_prefix) + 9) AS INTEGER)
+    ), 0) + 1 INTO seq
+    FROM transactions
+    WHERE invoice_number ~ ('^' || p_prefix || booked || '[0-9]+$');
+
+    RETURN p_prefix || booked || LPAD(seq::TEXT, 4, '0');
+END;
+$$ LANGUAGE plpgsql;
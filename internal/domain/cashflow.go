@@ -111,7 +111,8 @@ type CashFlowFilter struct {
 type ProfitLossReport struct {
 	DateFrom       time.Time           `json:"date_from"`
 	DateTo         time.Time           `json:"date_to"`
-	TotalRevenue   int64               `json:"total_revenue"`      // Total sales
+	TotalRevenue   int64               `json:"total_revenue"`      // Total sales, excluding tax
+	TotalTax       int64               `json:"total_tax"`          // Tax collected, not part of revenue
 	TotalCOGS      int64               `json:"total_cogs"`         // HPP (Harga Pokok Penjualan)
 	GrossProfit    int64               `json:"gross_profit"`       // Revenue - COGS
 	TotalExpenses  int64               `json:"total_expenses"`     // Operational expenses
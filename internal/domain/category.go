@@ -13,6 +13,7 @@ type Category struct {
 	Description *string    `json:"description,omitempty"`
 	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
 	IsActive    bool       `json:"is_active"`
+	IsTaxExempt bool       `json:"is_tax_exempt"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	
@@ -26,6 +27,7 @@ type CategoryCreateInput struct {
 	Name        string     `json:"name"`
 	Description *string    `json:"description,omitempty"`
 	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
+	IsTaxExempt bool       `json:"is_tax_exempt"`
 }
 
 // CategoryUpdateInput is the input for updating a category
@@ -34,6 +36,7 @@ type CategoryUpdateInput struct {
 	Description *string    `json:"description,omitempty"`
 	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
 	IsActive    *bool      `json:"is_active,omitempty"`
+	IsTaxExempt *bool      `json:"is_tax_exempt,omitempty"`
 }
 
 // CategoryResponse is the response for list categories (includes product count)
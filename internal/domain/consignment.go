@@ -82,3 +82,29 @@ type UpdateConsignorInput struct {
 	Notes       *string `json:"notes,omitempty"`
 	IsActive    *bool   `json:"is_active,omitempty"`
 }
+
+// CanTransitionTo checks whether a settlement may move to the given status
+func (s *ConsignmentSettlement) CanTransitionTo(status SettlementStatus) bool {
+	switch status {
+	case SettlementStatusConfirmed:
+		return s.Status == SettlementStatusDraft
+	case SettlementStatusPaid:
+		return s.Status == SettlementStatusConfirmed
+	}
+	return false
+}
+
+type GenerateSettlementInput struct {
+	ConsignorID uuid.UUID
+	PeriodStart *time.Time // inclusive, defaults to the day after the last settlement
+	PeriodEnd   *time.Time // inclusive, defaults to yesterday
+	Notes       *string
+	CreatedBy   string
+}
+
+type SettlementFilter struct {
+	ConsignorID *uuid.UUID        `json:"consignor_id,omitempty"`
+	Status      *SettlementStatus `json:"status,omitempty"`
+	Page        int               `json:"page,omitempty"`
+	PerPage     int               `json:"per_page,omitempty"`
+}
@@ -36,4 +36,7 @@ var (
 	
 	// ErrCustomerInactive is returned when customer is inactive
 	ErrCustomerInactive = errors.New("customer is inactive")
+	
+	// ErrInvalidStatusTransition is returned when a document cannot move to the requested status
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
 )
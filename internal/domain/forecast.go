@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ForecastMethod chooses how daily demand is forecast from a product's sales history
+type ForecastMethod string
+
+const (
+	ForecastMovingAverage        ForecastMethod = "moving_average"        // recent average, scaled by weekday
+	ForecastExponentialSmoothing ForecastMethod = "exponential_smoothing" // simple exponential smoothing, flat across weekdays
+)
+
+// IsValid reports whether the method is known
+func (m ForecastMethod) IsValid() bool {
+	return m == ForecastMovingAverage || m == ForecastExponentialSmoothing
+}
+
+// ForecastOptions controls a demand forecast; the defaults come from the forecast settings
+type ForecastOptions struct {
+	Method       ForecastMethod
+	LeadTimeDays int // days from ordering until the goods are on the shelf
+	CoverDays    int // days an order should last once it arrives
+}
+
+// DemandForecast is a product's expected demand and the reorder figures derived from it.
+// Quantities are in base units.
+type DemandForecast struct {
+	ProductID      uuid.UUID      `json:"product_id"`
+	Method         ForecastMethod `json:"method"`
+	HistoryDays    int            `json:"history_days"` // closed days of sales history used
+	DailyDemand    float64        `json:"daily_demand"` // expected units per day over the lead time and cover days
+	LeadTimeDays   int            `json:"lead_time_days"`
+	LeadTimeDemand float64        `json:"lead_time_demand"` // expected units sold before an order arrives
+	SafetyStock    int            `json:"safety_stock"`     // buffer for demand above the forecast
+	ReorderPoint   int            `json:"reorder_point"`    // order once stock is at or below this level
+	TargetStock    int            `json:"target_stock"`     // stock that lasts the lead time and cover days, with the buffer
+}
+
+// ProductDailySales is one product's units sold on one day, net of refunds
+type ProductDailySales struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Date      time.Time `json:"date"`
+	Quantity  int       `json:"quantity"` // base units
+}
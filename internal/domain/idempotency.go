@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// IdempotencyStatus represents the state of a request stored under an Idempotency-Key
+type IdempotencyStatus string
+
+const (
+	IdempotencyStatusProcessing IdempotencyStatus = "processing"
+	IdempotencyStatusCompleted  IdempotencyStatus = "completed"
+)
+
+// IdempotencyRecord is the stored outcome of a request sent with an Idempotency-Key
+type IdempotencyRecord struct {
+	Scope               string
+	Key                 string
+	RequestHash         string
+	Status              IdempotencyStatus
+	ResponseStatus      *int
+	ResponseContentType *string
+	ResponseBody        []byte
+	CreatedAt           time.Time
+	CompletedAt         *time.Time
+}
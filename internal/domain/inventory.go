@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -31,6 +33,8 @@ type StockMovement struct {
 	ReferenceType *string            `json:"reference_type,omitempty"` // 'transaction', 'purchase', 'adjustment'
 	ReferenceID   *uuid.UUID         `json:"reference_id,omitempty"`
 	CostPerUnit   *int64             `json:"cost_per_unit,omitempty"`
+	BatchNumber   *string            `json:"batch_number,omitempty"`
+	ExpiryDate    *time.Time         `json:"expiry_date,omitempty"`
 	Notes         *string            `json:"notes,omitempty"`
 	CreatedBy     *string            `json:"created_by,omitempty"`
 	CreatedAt     time.Time          `json:"created_at"`
@@ -49,11 +53,13 @@ type StockAdjustmentInput struct {
 
 // RestockInput is the input for restocking products
 type RestockInput struct {
-	ProductID   uuid.UUID `json:"product_id"`
-	Quantity    int       `json:"quantity"`
-	CostPerUnit int64     `json:"cost_per_unit"`
-	Notes       *string   `json:"notes,omitempty"`
-	CreatedBy   *string   `json:"created_by,omitempty"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	UnitID      *uuid.UUID `json:"unit_id,omitempty"` // quantity and cost are per this unit; omitted = base unit
+	Quantity    int        `json:"quantity"`
+	CostPerUnit int64      `json:"cost_per_unit"`
+	LotInput
+	Notes     *string `json:"notes,omitempty"`
+	CreatedBy *string `json:"created_by,omitempty"`
 }
 
 // BulkRestockInput is the input for restocking multiple products
@@ -66,9 +72,45 @@ type BulkRestockInput struct {
 
 // RestockItem is a single item in a bulk restock
 type RestockItem struct {
-	ProductID   uuid.UUID `json:"product_id"`
-	Quantity    int       `json:"quantity"`
-	CostPerUnit int64     `json:"cost_per_unit"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	UnitID      *uuid.UUID `json:"unit_id,omitempty"`
+	Quantity    int        `json:"quantity"`
+	CostPerUnit int64      `json:"cost_per_unit"`
+	LotInput
+}
+
+// LotInput carries the optional lot details of incoming stock
+type LotInput struct {
+	BatchNumber *string `json:"batch_number,omitempty"`
+	ExpiryDate  *string `json:"expiry_date,omitempty"` // YYYY-MM-DD
+}
+
+// Lot parses the lot details
+func (l LotInput) Lot() (StockLot, error) {
+	lot := StockLot{BatchNumber: l.BatchNumber}
+	if l.BatchNumber != nil && strings.TrimSpace(*l.BatchNumber) == "" {
+		lot.BatchNumber = nil
+	}
+	if l.ExpiryDate != nil && *l.ExpiryDate != "" {
+		expiry, err := time.Parse("2006-01-02", *l.ExpiryDate)
+		if err != nil {
+			return lot, fmt.Errorf("%w: expiry_date must be a YYYY-MM-DD date", ErrInvalidInput)
+		}
+		lot.ExpiryDate = &expiry
+	}
+	return lot, nil
+}
+
+// StockLot identifies the lot incoming stock belongs to.
+// Stock received without any lot details is not tracked as a lot.
+type StockLot struct {
+	BatchNumber *string
+	ExpiryDate  *time.Time
+}
+
+// IsZero reports whether no lot details were given
+func (l StockLot) IsZero() bool {
+	return l.BatchNumber == nil && l.ExpiryDate == nil
 }
 
 // StockMovementFilter is the filter for listing stock movements
@@ -96,3 +138,117 @@ type StockReport struct {
 	OutOfStockCount   int               `json:"out_of_stock_count"`
 	LowStockProducts  []LowStockProduct `json:"low_stock_products,omitempty"`
 }
+
+// StockBatch is a lot of a product received together.
+// Sales take from lots first-expired-first-out; stock that is not in any lot
+// (received before lot tracking or found during a count) is used last.
+type StockBatch struct {
+	ID                uuid.UUID  `json:"id"`
+	ProductID         uuid.UUID  `json:"product_id"`
+	BatchNumber       *string    `json:"batch_number,omitempty"`
+	ExpiryDate        *time.Time `json:"expiry_date,omitempty"`
+	InitialQuantity   int        `json:"initial_quantity"`
+	RemainingQuantity int        `json:"remaining_quantity"`
+	CostPerUnit       *int64     `json:"cost_per_unit,omitempty"`
+	MovementID        *uuid.UUID `json:"movement_id,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// Relations
+	Product *Product `json:"product,omitempty"`
+}
+
+// IsExpired reports whether the lot is past its expiry date on the given day
+func (b *StockBatch) IsExpired(now time.Time) bool {
+	if b.ExpiryDate == nil {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return b.ExpiryDate.Before(today)
+}
+
+// BatchWriteOffInput is the input for writing off stock from a lot
+type BatchWriteOffInput struct {
+	Quantity  *int    `json:"quantity,omitempty"` // defaults to everything left in the lot
+	Notes     *string `json:"notes,omitempty"`
+	CreatedBy *string `json:"-"`
+}
+
+// BatchWriteOffResult summarizes written-off lots
+type BatchWriteOffResult struct {
+	Batches   int             `json:"batches"`
+	Quantity  int             `json:"quantity"`
+	Value     int64           `json:"value"` // at the lot cost, or the product cost when the lot has none
+	Movements []StockMovement `json:"movements"`
+}
+
+// RepackInput converts stock of one product into another, e.g. one sack of rice into 25 packs of 1 kg
+type RepackInput struct {
+	SourceProductID uuid.UUID `json:"source_product_id"`
+	SourceQuantity  int       `json:"source_quantity"`
+	TargetProductID uuid.UUID `json:"target_product_id"`
+	TargetQuantity  int       `json:"target_quantity"`
+	Notes           *string   `json:"notes,omitempty"`
+	CreatedBy       *string   `json:"created_by,omitempty"`
+}
+
+// RepackResult is the pair of movements posted by a repack
+type RepackResult struct {
+	RepackID       uuid.UUID     `json:"repack_id"` // reference_id of both movements
+	SourceMovement StockMovement `json:"source_movement"`
+	TargetMovement StockMovement `json:"target_movement"`
+	TotalCost      int64         `json:"total_cost"`       // cost carried from source to target
+	TargetUnitCost int64         `json:"target_unit_cost"` // total_cost / target_quantity, rounded
+}
+
+// CostingMethod decides how the cost of goods sold (HPP) of a sale is measured
+type CostingMethod string
+
+const (
+	CostingAverage CostingMethod = "average" // weighted moving average of what is in stock
+	CostingFIFO    CostingMethod = "fifo"    // oldest cost layers first
+)
+
+// CostLayer is stock received at one unit cost, used up oldest first under FIFO
+type CostLayer struct {
+	ID                uuid.UUID  `json:"id"`
+	ProductID         uuid.UUID  `json:"product_id"`
+	MovementID        *uuid.UUID `json:"movement_id,omitempty"`
+	UnitCost          int64      `json:"unit_cost"`
+	InitialQuantity   int        `json:"initial_quantity"`
+	RemainingQuantity int        `json:"remaining_quantity"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// CostHistoryEntry records how a receipt or return moved a product's average cost
+type CostHistoryEntry struct {
+	ID                uuid.UUID         `json:"id"`
+	ProductID         uuid.UUID         `json:"product_id"`
+	MovementID        *uuid.UUID        `json:"movement_id,omitempty"`
+	MovementType      StockMovementType `json:"movement_type"`
+	Quantity          int               `json:"quantity"`
+	UnitCost          int64             `json:"unit_cost"`
+	StockBefore       int               `json:"stock_before"`
+	AverageCostBefore int64             `json:"average_cost_before"`
+	AverageCostAfter  int64             `json:"average_cost_after"`
+	CreatedAt         time.Time         `json:"created_at"`
+}
+
+// ProductCostReport is the current cost position and cost history of a product
+type ProductCostReport struct {
+	ProductID     uuid.UUID          `json:"product_id"`
+	CostingMethod CostingMethod      `json:"costing_method"`
+	AverageCost   int64              `json:"average_cost"` // products.cost_price
+	LayerValue    int64              `json:"layer_value"`  // stock value at FIFO layer costs
+	Layers        []CostLayer        `json:"layers"`       // open FIFO layers, oldest first
+	History       []CostHistoryEntry `json:"history"`      // newest first
+}
+
+// DivRound divides two non-negative amounts, rounding half up. Unit costs derived from a
+// total use it so they round the same way everywhere.
+func DivRound(a, b int64) int64 {
+	if b == 0 {
+		return 0
+	}
+	return (a + b/2) / b
+}
@@ -10,8 +10,10 @@ import (
 type KasbonType string
 
 const (
-	KasbonTypeDebt    KasbonType = "debt"    // hutang baru
-	KasbonTypePayment KasbonType = "payment" // pembayaran hutang
+	KasbonTypeDebt     KasbonType = "debt"     // hutang baru
+	KasbonTypePayment  KasbonType = "payment"  // pembayaran hutang
+	KasbonTypeRefund   KasbonType = "refund"   // pengurangan hutang karena retur
+	KasbonTypeReversal KasbonType = "reversal" // pembatalan hutang karena transaksi dibatalkan
 )
 
 // KasbonRecord represents a debt or payment record
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxStatus represents the delivery state of an outbox event
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"
+	OutboxStatusPublished OutboxStatus = "published"
+	OutboxStatusFailed    OutboxStatus = "failed" // gave up after the maximum attempts
+)
+
+// OutboxEvent is a side effect recorded in the same database transaction
+// as the change that caused it, and published only after commit
+type OutboxEvent struct {
+	ID             uuid.UUID       `json:"id"`
+	EventType      string          `json:"event_type"` // queue task type
+	IdempotencyKey string          `json:"idempotency_key"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         OutboxStatus    `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastError      *string         `json:"last_error,omitempty"`
+	AvailableAt    time.Time       `json:"available_at"`
+	PublishedAt    *time.Time      `json:"published_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
@@ -73,6 +73,12 @@ const (
 	RefundStatusCompleted RefundStatus = "completed"
 )
 
+const (
+	RefundMethodCash        = "cash"
+	RefundMethodStoreCredit = "store_credit"
+	RefundMethodOriginal    = "original"
+)
+
 type RefundRecord struct {
 	ID                uuid.UUID    `json:"id"`
 	RefundNumber      string       `json:"refund_number"`
@@ -93,6 +99,17 @@ type RefundRecord struct {
 	Transaction *Transaction `json:"transaction,omitempty"`
 }
 
+// CanTransitionTo checks whether a refund may move to the given status
+func (r *RefundRecord) CanTransitionTo(status RefundStatus) bool {
+	switch status {
+	case RefundStatusApproved, RefundStatusRejected:
+		return r.Status == RefundStatusPending
+	case RefundStatusCompleted:
+		return r.Status == RefundStatusApproved
+	}
+	return false
+}
+
 type RefundItem struct {
 	ID                uuid.UUID `json:"id"`
 	RefundID          uuid.UUID `json:"refund_id"`
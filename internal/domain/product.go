@@ -27,6 +27,8 @@ type Product struct {
 	IsRefillable   bool       `json:"is_refillable"`
 	EmptyProductID *uuid.UUID `json:"empty_product_id,omitempty"`
 	FullProductID  *uuid.UUID `json:"full_product_id,omitempty"`
+	ParentID       *uuid.UUID `json:"parent_id,omitempty"`    // set on variants
+	VariantName    *string    `json:"variant_name,omitempty"` // "Goreng", "Soto", "500 ml"
 	IsActive       bool       `json:"is_active"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
@@ -34,6 +36,36 @@ type Product struct {
 	// Relations (populated when needed)
 	Category     *Category      `json:"category,omitempty"`
 	PricingTiers []PricingTier  `json:"pricing_tiers,omitempty"`
+	Units        []ProductUnit  `json:"units,omitempty"`
+	ScannedUnit  *ProductUnit   `json:"scanned_unit,omitempty"` // set when a unit barcode was scanned
+	Variants     []Product      `json:"variants,omitempty"`     // set on parent products
+}
+
+// IsVariant reports whether the product is a variant of another product.
+// A variant shares its parent's category, consignor and image.
+func (p *Product) IsVariant() bool {
+	return p.ParentID != nil
+}
+
+// ProductUnit is a larger unit a product is bought or sold in, such as a pack or dus.
+// Stock is always counted in the product's base unit (Product.Unit).
+type ProductUnit struct {
+	ID               uuid.UUID `json:"id"`
+	ProductID        uuid.UUID `json:"product_id"`
+	Name             string    `json:"name"`              // "pack", "dus", "renteng"
+	ConversionFactor int       `json:"conversion_factor"` // base units in one of this unit
+	Barcode          *string   `json:"barcode,omitempty"`
+	Price            *int64    `json:"price,omitempty"` // NULL = base price (or tier price) x factor
+	IsActive         bool      `json:"is_active"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ToBase converts a quantity and a cost per unit of u into base units.
+// The base cost is rounded to the nearest rupiah.
+func (u *ProductUnit) ToBase(quantity int, costPerUnit int64) (int, int64) {
+	factor := int64(u.ConversionFactor)
+	return quantity * u.ConversionFactor, (costPerUnit + factor/2) / factor
 }
 
 // PricingTier represents a pricing tier for variable pricing
@@ -88,6 +120,30 @@ func (p *Product) CalculatePrice(quantity int) (pricePerUnit int64, tierName str
 	return p.BasePrice, "Harga Dasar", nil
 }
 
+// FindUnit returns the product's unit with the given ID, or nil
+func (p *Product) FindUnit(id uuid.UUID) *ProductUnit {
+	for i := range p.Units {
+		if p.Units[i].ID == id {
+			return &p.Units[i]
+		}
+	}
+	return nil
+}
+
+// CalculateUnitPrice calculates the price of one unit when selling quantity of them.
+// Without a unit it is CalculatePrice. A unit without its own price is charged at the
+// tier price of the base quantity times its conversion factor.
+func (p *Product) CalculateUnitPrice(unit *ProductUnit, quantity int) (pricePerUnit int64, tierName string, tierID *uuid.UUID) {
+	if unit == nil {
+		return p.CalculatePrice(quantity)
+	}
+	if unit.Price != nil {
+		return *unit.Price, unit.Name, nil
+	}
+	pricePerUnit, tierName, tierID = p.CalculatePrice(quantity * unit.ConversionFactor)
+	return pricePerUnit * int64(unit.ConversionFactor), tierName, tierID
+}
+
 // CalculateTotal calculates the total price for a given quantity
 func (p *Product) CalculateTotal(quantity int) (total int64, pricePerUnit int64, tierName string, tierID *uuid.UUID) {
 	pricePerUnit, tierName, tierID = p.CalculatePrice(quantity)
@@ -137,8 +193,11 @@ type ProductCreateInput struct {
 	EmptyProductID *uuid.UUID        `json:"empty_product_id,omitempty"`
 	FullProductID  *uuid.UUID        `json:"full_product_id,omitempty"`
 	PricingTiers   []PricingTierInput `json:"pricing_tiers,omitempty"`
+	Units          []ProductUnitInput `json:"units,omitempty"`
 	ImageURL       *string            `json:"image_url,omitempty"`
 	IsActive       *bool              `json:"is_active,omitempty"`
+	ParentID       *uuid.UUID         `json:"parent_id,omitempty"`    // create as a variant of this product
+	VariantName    *string            `json:"variant_name,omitempty"` // required with parent_id
 }
 
 // ProductUpdateInput is the input for updating a product
@@ -162,6 +221,17 @@ type ProductUpdateInput struct {
 	ImageURL      *string    `json:"image_url,omitempty"`
 }
 
+// ChangesSharedAttributes reports whether the update touches an attribute variants share with their parent
+func (in ProductUpdateInput) ChangesSharedAttributes() bool {
+	return in.CategoryID != nil || in.ConsignorID != nil || in.ImageURL != nil
+}
+
+// ProductParentInput attaches a product to a parent as a variant, or detaches it when ParentID is nil
+type ProductParentInput struct {
+	ParentID    *uuid.UUID `json:"parent_id"`
+	VariantName *string    `json:"variant_name,omitempty"` // required with parent_id
+}
+
 // PricingTierInput is the input for creating/updating a pricing tier
 type PricingTierInput struct {
 	Name        *string `json:"name,omitempty"`
@@ -170,18 +240,27 @@ type PricingTierInput struct {
 	Price       int64   `json:"price"`
 }
 
+// ProductUnitInput is the input for creating/updating a product unit
+type ProductUnitInput struct {
+	Name             string  `json:"name"`
+	ConversionFactor int     `json:"conversion_factor"`
+	Barcode          *string `json:"barcode,omitempty"`
+	Price            *int64  `json:"price,omitempty"`
+}
+
 // ProductFilter is the filter options for listing products
 type ProductFilter struct {
-	Search        *string    `json:"search,omitempty"`
-	CategoryID    *uuid.UUID `json:"category_id,omitempty"`
-	ConsignorID   *uuid.UUID `json:"consignor_id,omitempty"` // Added ConsignorID
-	IsActive      *bool      `json:"is_active,omitempty"`
-	IsStockActive *bool      `json:"is_stock_active,omitempty"`
-	LowStockOnly  bool       `json:"low_stock_only,omitempty"`
-	Page          int        `json:"page,omitempty"`
-	PerPage       int        `json:"per_page,omitempty"`
-	SortBy        string     `json:"sort_by,omitempty"`
-	SortOrder     string     `json:"sort_order,omitempty"` // "asc" or "desc"
+	Search           *string    `json:"search,omitempty"`
+	CategoryID       *uuid.UUID `json:"category_id,omitempty"`
+	ConsignorID      *uuid.UUID `json:"consignor_id,omitempty"` // Added ConsignorID
+	IsActive         *bool      `json:"is_active,omitempty"`
+	IsStockActive    *bool      `json:"is_stock_active,omitempty"`
+	LowStockOnly     bool       `json:"low_stock_only,omitempty"`
+	CollapseVariants bool       `json:"collapse_variants,omitempty"` // list parents only, with their variants nested
+	Page             int        `json:"page,omitempty"`
+	PerPage          int        `json:"per_page,omitempty"`
+	SortBy           string     `json:"sort_by,omitempty"`
+	SortOrder        string     `json:"sort_order,omitempty"` // "asc" or "desc"
 }
 
 // DefaultFilter returns a filter with default values
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ProductImportColumns is the header of the product import/export template, in order.
+// Import matches headers case-insensitively and ignores columns it does not know.
+var ProductImportColumns = []string{
+	"barcode", "sku", "name", "category", "unit", "base_price", "cost_price",
+	"is_stock_active", "current_stock", "min_stock_alert", "max_stock", "is_active", "pricing_tiers",
+}
+
+// ProductImportRowError is a problem with one row of an import file
+type ProductImportRowError struct {
+	Row     int    `json:"row"`              // 1-based, the header is row 1
+	Column  string `json:"column,omitempty"` // empty for problems with the whole row
+	Message string `json:"message"`
+}
+
+// ProductImportResult is the validation report of an import.
+// Nothing is written when the file has errors or in a dry run.
+type ProductImportResult struct {
+	DryRun  bool                    `json:"dry_run"`
+	Applied bool                    `json:"applied"` // true when the rows were written
+	Rows    int                     `json:"rows"`    // data rows, not counting blank ones
+	Created int                     `json:"created"` // new products (to be) created
+	Updated int                     `json:"updated"` // existing products (to be) updated, matched by barcode
+	Errors  []ProductImportRowError `json:"errors"`
+}
+
+// ProductCodeIndex maps the barcodes and SKUs in use to their products, for matching imported rows
+type ProductCodeIndex struct {
+	ByBarcode    map[string]uuid.UUID
+	BySKU        map[string]uuid.UUID
+	UnitBarcodes map[string]bool
+	Variants     map[uuid.UUID]bool // products whose category comes from a parent
+}
+
+// FormatPricingTiers writes active tiers in the template's pricing_tiers format:
+// "[name:]min[-max]=price" entries separated by semicolons, e.g. "Grosir:10-49=2800; 50+=2600"
+func FormatPricingTiers(tiers []PricingTier) string {
+	var parts []string
+	for _, t := range tiers {
+		if !t.IsActive {
+			continue
+		}
+		var b strings.Builder
+		if t.Name != nil && *t.Name != "" {
+			b.WriteString(*t.Name + ":")
+		}
+		if t.MaxQuantity != nil {
+			fmt.Fprintf(&b, "%d-%d", t.MinQuantity, *t.MaxQuantity)
+		} else {
+			fmt.Fprintf(&b, "%d+", t.MinQuantity)
+		}
+		fmt.Fprintf(&b, "=%d", t.Price)
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ParsePricingTiers reads the format written by FormatPricingTiers. A minimum without
+// "-max" or "+" has no maximum.
+func ParsePricingTiers(s string) ([]PricingTierInput, error) {
+	var tiers []PricingTierInput
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var tier PricingTierInput
+		if name, rest, ok := strings.Cut(entry, ":"); ok {
+			name = strings.TrimSpace(name)
+			tier.Name = &name
+			entry = rest
+		}
+		quantities, price, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("tier %q needs a price after '='", entry)
+		}
+
+		var err error
+		if tier.Price, err = strconv.ParseInt(strings.TrimSpace(price), 10, 64); err != nil || tier.Price <= 0 {
+			return nil, fmt.Errorf("tier %q has an invalid price", entry)
+		}
+		minQty, maxQty, hasMax := strings.Cut(strings.TrimSuffix(strings.TrimSpace(quantities), "+"), "-")
+		if tier.MinQuantity, err = strconv.Atoi(strings.TrimSpace(minQty)); err != nil || tier.MinQuantity < 1 {
+			return nil, fmt.Errorf("tier %q has an invalid minimum quantity", entry)
+		}
+		if hasMax {
+			maxQuantity, err := strconv.Atoi(strings.TrimSpace(maxQty))
+			if err != nil || maxQuantity < tier.MinQuantity {
+				return nil, fmt.Errorf("tier %q has an invalid maximum quantity", entry)
+			}
+			tier.MaxQuantity = &maxQuantity
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
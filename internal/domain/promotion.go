@@ -0,0 +1,203 @@
+package domain
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PromotionType represents how a promotion discounts a cart line
+type PromotionType string
+
+const (
+	PromotionTypePercentage PromotionType = "percentage"  // potongan persen
+	PromotionTypeBundle     PromotionType = "bundle"      // paket, mis. 5 pcs 14.000
+	PromotionTypeBuyXGetY   PromotionType = "buy_x_get_y" // beli X gratis Y
+)
+
+// Promotion represents a promotion definition
+type Promotion struct {
+	ID          uuid.UUID     `json:"id"`
+	Name        string        `json:"name"`
+	Description *string       `json:"description,omitempty"`
+	Type        PromotionType `json:"type"`
+
+	// Scope: a single product or every product in a category
+	ProductID  *uuid.UUID `json:"product_id,omitempty"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+
+	DiscountPercent *float64 `json:"discount_percent,omitempty"` // percentage
+	BundleQuantity  *int     `json:"bundle_quantity,omitempty"`  // bundle: units per bundle
+	BundlePrice     *int64   `json:"bundle_price,omitempty"`     // bundle: price per bundle
+	BuyQuantity     *int     `json:"buy_quantity,omitempty"`     // buy_x_get_y: X
+	GetQuantity     *int     `json:"get_quantity,omitempty"`     // buy_x_get_y: Y free
+
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	DaysOfWeek []int      `json:"days_of_week,omitempty"` // 0 = Sunday ... 6 = Saturday, empty = every day
+	StartTime  *string    `json:"start_time,omitempty"`   // HH:MM, daily window start
+	EndTime    *string    `json:"end_time,omitempty"`     // HH:MM, daily window end
+
+	Priority  int       `json:"priority"`
+	IsActive  bool      `json:"is_active"`
+	CreatedBy *string   `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsActiveAt checks if the promotion runs at the given moment:
+// inside its period, on an allowed weekday and inside its daily time window
+func (p *Promotion) IsActiveAt(t time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.StartsAt != nil && t.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !t.Before(*p.EndsAt) {
+		return false
+	}
+
+	if len(p.DaysOfWeek) > 0 {
+		allowed := false
+		for _, d := range p.DaysOfWeek {
+			if d == int(t.Weekday()) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	clock := t.Format("15:04")
+	if p.StartTime != nil && clock < hourMinute(*p.StartTime) {
+		return false
+	}
+	if p.EndTime != nil && clock >= hourMinute(*p.EndTime) {
+		return false
+	}
+	return true
+}
+
+// hourMinute trims a TIME value such as "14:30:00" to "14:30"
+func hourMinute(clock string) string {
+	if len(clock) > 5 {
+		return clock[:5]
+	}
+	return clock
+}
+
+// AppliesTo checks if the product is in the promotion's scope
+func (p *Promotion) AppliesTo(product *Product) bool {
+	if p.ProductID != nil {
+		return *p.ProductID == product.ID
+	}
+	if p.CategoryID != nil {
+		return product.CategoryID != nil && *p.CategoryID == *product.CategoryID
+	}
+	return false
+}
+
+// Discount calculates the discount for a line of quantity units at unitPrice.
+// Bundles and buy-X-get-Y only discount complete groups; leftover units pay full price.
+func (p *Promotion) Discount(quantity int, unitPrice int64) int64 {
+	if quantity <= 0 || unitPrice <= 0 {
+		return 0
+	}
+	subtotal := unitPrice * int64(quantity)
+
+	var discount int64
+	switch p.Type {
+	case PromotionTypePercentage:
+		if p.DiscountPercent != nil {
+			discount = int64(math.Round(float64(subtotal) * *p.DiscountPercent / 100))
+		}
+	case PromotionTypeBundle:
+		if p.BundleQuantity != nil && p.BundlePrice != nil && *p.BundleQuantity > 0 {
+			bundles := quantity / *p.BundleQuantity
+			perBundle := unitPrice*int64(*p.BundleQuantity) - *p.BundlePrice
+			if perBundle > 0 {
+				discount = int64(bundles) * perBundle
+			}
+		}
+	case PromotionTypeBuyXGetY:
+		if p.BuyQuantity != nil && p.GetQuantity != nil {
+			groupSize := *p.BuyQuantity + *p.GetQuantity
+			if groupSize > 0 {
+				discount = int64(quantity/groupSize) * int64(*p.GetQuantity) * unitPrice
+			}
+		}
+	}
+
+	if discount > subtotal {
+		discount = subtotal
+	}
+	return discount
+}
+
+// BestPromotion picks the promotion giving the largest discount on a cart line.
+// Ties go to the higher priority. It returns nil when no promotion applies.
+func BestPromotion(promotions []Promotion, product *Product, quantity int, unitPrice int64) (*Promotion, int64) {
+	var best *Promotion
+	var bestDiscount int64
+	for i := range promotions {
+		promo := &promotions[i]
+		if !promo.AppliesTo(product) {
+			continue
+		}
+		discount := promo.Discount(quantity, unitPrice)
+		if discount <= 0 {
+			continue
+		}
+		if best == nil || discount > bestDiscount || (discount == bestDiscount && promo.Priority > best.Priority) {
+			best = promo
+			bestDiscount = discount
+		}
+	}
+	return best, bestDiscount
+}
+
+// PromotionInput is the input for creating or replacing a promotion
+type PromotionInput struct {
+	Name            string        `json:"name"`
+	Description     *string       `json:"description,omitempty"`
+	Type            PromotionType `json:"type"`
+	ProductID       *uuid.UUID    `json:"product_id,omitempty"`
+	CategoryID      *uuid.UUID    `json:"category_id,omitempty"`
+	DiscountPercent *float64      `json:"discount_percent,omitempty"`
+	BundleQuantity  *int          `json:"bundle_quantity,omitempty"`
+	BundlePrice     *int64        `json:"bundle_price,omitempty"`
+	BuyQuantity     *int          `json:"buy_quantity,omitempty"`
+	GetQuantity     *int          `json:"get_quantity,omitempty"`
+	StartsAt        *time.Time    `json:"starts_at,omitempty"`
+	EndsAt          *time.Time    `json:"ends_at,omitempty"`
+	DaysOfWeek      []int         `json:"days_of_week,omitempty"`
+	StartTime       *string       `json:"start_time,omitempty"`
+	EndTime         *string       `json:"end_time,omitempty"`
+	Priority        int           `json:"priority"`
+	IsActive        *bool         `json:"is_active,omitempty"`
+	CreatedBy       *string       `json:"-"`
+}
+
+// PromotionFilter is the filter options for listing promotions
+type PromotionFilter struct {
+	Search    *string        `json:"search,omitempty"`
+	Type      *PromotionType `json:"type,omitempty"`
+	ProductID *uuid.UUID     `json:"product_id,omitempty"`
+	IsActive  *bool          `json:"is_active,omitempty"`
+	Page      int            `json:"page,omitempty"`
+	PerPage   int            `json:"per_page,omitempty"`
+}
+
+// PromotionUsage summarizes what a promotion cost over a period
+type PromotionUsage struct {
+	PromotionID   *uuid.UUID `json:"promotion_id,omitempty"`
+	PromotionName string     `json:"promotion_name"`
+	Transactions  int        `json:"transactions"`
+	Quantity      int        `json:"quantity"`
+	GrossSales    int64      `json:"gross_sales"`    // before promo discount
+	TotalDiscount int64      `json:"total_discount"` // promo cost
+}
@@ -28,6 +28,7 @@ const (
 	ContainerMovementPurchaseFull    ContainerMovementType = "purchase_full"
 	ContainerMovementReturnEmpty     ContainerMovementType = "return_empty"
 	ContainerMovementAdjustment      ContainerMovementType = "adjustment"
+	ContainerMovementSaleCancel      ContainerMovementType = "sale_cancel" // reverses a sale_exchange
 )
 
 type ContainerMovement struct {
@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DailySummary is one day of sales and kasbon totals.
+// Closed days are read from the nightly rollup in daily_summaries; today is always computed live.
+// Sales count on the day they were made, net of completed refunds, so a back-dated
+// cancellation or refund changes the day it belongs to.
+type DailySummary struct {
+	Date              time.Time  `json:"date"`
+	TotalTransactions int        `json:"total_transactions"` // completed sales
+	TotalSales        int64      `json:"total_sales"`        // net of refunds and tax
+	TotalTax          int64      `json:"total_tax"`
+	TotalCost         int64      `json:"total_cost"` // HPP
+	TotalProfit       int64      `json:"total_profit"`
+	TotalKasbonGiven  int64      `json:"total_kasbon_given"` // new debt, less reversals and refunds of that day's sales
+	TotalKasbonPaid   int64      `json:"total_kasbon_paid"`
+	CashSales         int64      `json:"cash_sales"`
+	KasbonSales       int64      `json:"kasbon_sales"`
+	TransferSales     int64      `json:"transfer_sales"`
+	QRISSales         int64      `json:"qris_sales"`
+	Live              bool       `json:"live"`                   // computed on request instead of read from the rollup
+	RolledUpAt        *time.Time `json:"rolled_up_at,omitempty"` // when the rollup row was last written
+}
+
+// TrendInterval is the bucket size of a sales trend
+type TrendInterval string
+
+const (
+	TrendIntervalDay   TrendInterval = "day"
+	TrendIntervalWeek  TrendInterval = "week" // ISO weeks, starting Monday
+	TrendIntervalMonth TrendInterval = "month"
+)
+
+// IsValid reports whether the interval is one of the supported bucket sizes
+func (i TrendInterval) IsValid() bool {
+	switch i {
+	case TrendIntervalDay, TrendIntervalWeek, TrendIntervalMonth:
+		return true
+	}
+	return false
+}
+
+// TrendBucket is the sales of one day, week or month.
+// Start is the first day of the bucket, which may fall before the report's date_from.
+type TrendBucket struct {
+	Start        time.Time `json:"start"`
+	Sales        int64     `json:"sales"`
+	Transactions int       `json:"transactions"`
+	Cost         int64     `json:"cost"`
+	Profit       int64     `json:"profit"`
+}
+
+// PeriodTotals sums the sales of a period
+type PeriodTotals struct {
+	DateFrom           time.Time `json:"date_from"`
+	DateTo             time.Time `json:"date_to"`
+	Sales              int64     `json:"sales"`
+	Transactions       int       `json:"transactions"`
+	Cost               int64     `json:"cost"`
+	Profit             int64     `json:"profit"`
+	AverageTransaction int64     `json:"average_transaction"`
+}
+
+// PeriodGrowth is the change against a comparison period in percent;
+// a field is null when the comparison period had nothing to compare with
+type PeriodGrowth struct {
+	Sales        *float64 `json:"sales"`
+	Transactions *float64 `json:"transactions"`
+	Profit       *float64 `json:"profit"`
+}
+
+// CategorySales is one category's share of a period's item sales
+type CategorySales struct {
+	CategoryID    *uuid.UUID `json:"category_id,omitempty"` // nil for uncategorized products
+	CategoryName  string     `json:"category_name"`
+	Quantity      int        `json:"quantity"` // base units
+	Sales         int64      `json:"sales"`
+	Cost          int64      `json:"cost"`
+	Profit        int64      `json:"profit"`
+	Share         float64    `json:"share"` // percent of item sales
+	PreviousSales int64      `json:"previous_sales"`
+	Growth        *float64   `json:"growth"` // percent against the previous period
+}
+
+// CashierSales is one cashier's share of a period's sales
+type CashierSales struct {
+	CashierName        string   `json:"cashier_name"`
+	Transactions       int      `json:"transactions"`
+	Sales              int64    `json:"sales"`
+	Profit             int64    `json:"profit"`
+	AverageTransaction int64    `json:"average_transaction"`
+	Share              float64  `json:"share"` // percent of sales
+	PreviousSales      int64    `json:"previous_sales"`
+	Growth             *float64 `json:"growth"` // percent against the previous period
+}
+
+// SalesTrendReport shows sales over a range in buckets and compares the range with the
+// period just before it and the same dates a year earlier
+type SalesTrendReport struct {
+	Interval   TrendInterval   `json:"interval"`
+	Current    PeriodTotals    `json:"current"`
+	Previous   PeriodTotals    `json:"previous"`  // the same number of days right before
+	LastYear   PeriodTotals    `json:"last_year"` // the same dates a year earlier
+	VsPrevious PeriodGrowth    `json:"vs_previous"`
+	VsLastYear PeriodGrowth    `json:"vs_last_year"`
+	Buckets    []TrendBucket   `json:"buckets"`
+	ByCategory []CategorySales `json:"by_category"`
+	ByCashier  []CashierSales  `json:"by_cashier"`
+}
+
+// ProductClass is a product's ABC class by its contribution to revenue
+type ProductClass string
+
+const (
+	ProductClassA ProductClass = "A" // the best sellers making up the first 80% of revenue
+	ProductClassB ProductClass = "B" // the next 15%
+	ProductClassC ProductClass = "C" // the rest, including products that did not sell
+)
+
+// ProductStockFlow is how a product's stock moved over a period, from stock_movements.
+// Sold is net of units returned to stock; adjustments, damage and repacking only show
+// in the opening and closing stock.
+type ProductStockFlow struct {
+	ProductID     uuid.UUID `json:"product_id"`
+	ProductName   string    `json:"product_name"`
+	IsStockActive bool      `json:"is_stock_active"`
+	CostPrice     int64     `json:"cost_price"`
+	OpeningStock  int       `json:"opening_stock"` // at the start of the period
+	Received      int       `json:"received"`      // purchases, initial stock and repacked units in
+	Sold          int       `json:"sold"`
+	ClosingStock  int       `json:"closing_stock"` // at the end of the period
+	CurrentStock  int       `json:"current_stock"`
+}
+
+// ProductPerformance is how one product did over a period
+type ProductPerformance struct {
+	ProductID       uuid.UUID    `json:"product_id"`
+	ProductName     string       `json:"product_name"`
+	Class           ProductClass `json:"class"`
+	QuantitySold    int          `json:"quantity_sold"` // base units, net of refunds
+	Revenue         int64        `json:"revenue"`
+	Cost            int64        `json:"cost"`
+	Profit          int64        `json:"profit"`
+	Margin          *float64     `json:"margin"`           // profit as a percent of revenue, nil without revenue
+	RevenueShare    float64      `json:"revenue_share"`    // percent of the revenue of all products
+	CumulativeShare float64      `json:"cumulative_share"` // running share down the revenue ranking
+
+	// Stock figures are zero, and the ratios nil, for products that do not track stock
+	OpeningStock      int      `json:"opening_stock"`
+	Received          int      `json:"received"`
+	StockSold         int      `json:"stock_sold"` // units that left stock as sales, net of returns
+	ClosingStock      int      `json:"closing_stock"`
+	CurrentStock      int      `json:"current_stock"`
+	StockValue        int64    `json:"stock_value"`         // current stock at cost price
+	SellThrough       *float64 `json:"sell_through"`        // percent of opening stock plus units received that sold
+	AverageDailySales float64  `json:"average_daily_sales"` // stock sold per day of the period
+	DaysOfCover       *float64 `json:"days_of_cover"`       // days current stock lasts at that rate, nil without sales
+}
+
+// ProductClassSummary totals the products in one ABC class
+type ProductClassSummary struct {
+	Class    ProductClass `json:"class"`
+	Products int          `json:"products"`
+	Revenue  int64        `json:"revenue"`
+	Share    float64      `json:"share"` // percent of revenue
+}
+
+// ProductMargin is one place in the margin ranking
+type ProductMargin struct {
+	Rank        int       `json:"rank"`
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Revenue     int64     `json:"revenue"`
+	Profit      int64     `json:"profit"`
+	Margin      float64   `json:"margin"` // profit as a percent of revenue
+}
+
+// DeadStockItem is a product with stock on hand that has not sold for a while
+type DeadStockItem struct {
+	ProductID         uuid.UUID  `json:"product_id"`
+	ProductName       string     `json:"product_name"`
+	CurrentStock      int        `json:"current_stock"`
+	CostPrice         int64      `json:"cost_price"`
+	StockValue        int64      `json:"stock_value"` // capital tied up at cost price
+	LastSoldAt        *time.Time `json:"last_sold_at,omitempty"`
+	DaysSinceLastSale *int       `json:"days_since_last_sale"` // nil when it never sold
+}
+
+// ProductPerformanceReport classifies products by revenue, ranks them by margin and lists
+// the stock that is not selling
+type ProductPerformanceReport struct {
+	DateFrom       time.Time             `json:"date_from"`
+	DateTo         time.Time             `json:"date_to"`
+	Days           int                   `json:"days"` // days of the period up to today, for the daily averages
+	TotalRevenue   int64                 `json:"total_revenue"`
+	Classes        []ProductClassSummary `json:"classes"`
+	Products       []ProductPerformance  `json:"products"`       // by revenue, largest first
+	MarginRanking  []ProductMargin       `json:"margin_ranking"` // products with revenue, highest margin first
+	DeadStockDays  int                   `json:"dead_stock_days"`
+	DeadStockValue int64                 `json:"dead_stock_value"`
+	DeadStock      []DeadStockItem       `json:"dead_stock"` // largest stock value first
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "time"
+
+// App setting keys stored in app_settings
+const (
+	SettingStoreName            = "store_name"
+	SettingStoreAddress         = "store_address"
+	SettingStorePhone           = "store_phone"
+	SettingReceiptFooter        = "receipt_footer"
+	SettingPaymentInstructions  = "payment_instructions" // printed on kasbon billing statements
+	SettingInvoicePrefix        = "invoice_prefix"
+	SettingPurchasePrefix       = "purchase_prefix"
+	SettingTaxRate              = "tax_rate"                // percentage, 0 disables tax
+	SettingTaxInclusive         = "tax_inclusive"           // "true" when selling prices already include tax
+	SettingTaxRounding          = "tax_rounding"            // nearest, up or down
+	SettingCostingMethod        = "costing_method"          // average or fifo
+	SettingForecastMethod       = "forecast_method"         // moving_average or exponential_smoothing
+	SettingForecastLeadTimeDays = "forecast_lead_time_days" // days from ordering until goods arrive
+	SettingForecastCoverDays    = "forecast_cover_days"     // days an order should last once it arrives
+)
+
+// AppSetting represents a single key-value setting
+type AppSetting struct {
+	Key         string    `json:"key"`
+	Value       string    `json:"value"`
+	Description *string   `json:"description,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// StoreSettings is the typed view of app_settings used across the app
+type StoreSettings struct {
+	StoreName            string         `json:"store_name"`
+	StoreAddress         string         `json:"store_address"`
+	StorePhone           string         `json:"store_phone"`
+	ReceiptFooter        string         `json:"receipt_footer"`
+	PaymentInstructions  string         `json:"payment_instructions"`
+	InvoicePrefix        string         `json:"invoice_prefix"`
+	PurchasePrefix       string         `json:"purchase_prefix"`
+	TaxRate              float64        `json:"tax_rate"`
+	TaxInclusive         bool           `json:"tax_inclusive"`
+	TaxRounding          TaxRounding    `json:"tax_rounding"`
+	CostingMethod        CostingMethod  `json:"costing_method"`
+	ForecastMethod       ForecastMethod `json:"forecast_method"`
+	ForecastLeadTimeDays int            `json:"forecast_lead_time_days"`
+	ForecastCoverDays    int            `json:"forecast_cover_days"`
+}
+
+// DefaultStoreSettings returns the values used when a setting is missing
+func DefaultStoreSettings() StoreSettings {
+	return StoreSettings{
+		StoreName:      "Warung Kelontong",
+		ReceiptFooter:  "Terima kasih telah berbelanja!",
+		InvoicePrefix:  "INV",
+		PurchasePrefix: "PO",
+		TaxRounding:    TaxRoundingNearest,
+		CostingMethod:  CostingAverage,
+
+		ForecastMethod:       ForecastMovingAverage,
+		ForecastLeadTimeDays: 2,
+		ForecastCoverDays:    7,
+	}
+}
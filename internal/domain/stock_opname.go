@@ -104,16 +104,17 @@ type FinalizeOpnameInput struct {
 
 // ShoppingListItem represents an item in the auto-generated shopping list
 type ShoppingListItem struct {
-	ID            uuid.UUID `json:"id"`
-	ProductID     uuid.UUID `json:"product_id"`
-	CurrentStock  int       `json:"current_stock"`
-	MinStock      int       `json:"min_stock"`
-	SuggestedQty  int       `json:"suggested_qty"`
-	EstimatedCost *int64    `json:"estimated_cost,omitempty"`
-	IsPurchased   bool      `json:"is_purchased"`
-	Notes         *string   `json:"notes,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID       `json:"id"`
+	ProductID     uuid.UUID       `json:"product_id"`
+	CurrentStock  int             `json:"current_stock"`
+	MinStock      int             `json:"min_stock"`
+	SuggestedQty  int             `json:"suggested_qty"`
+	EstimatedCost *int64          `json:"estimated_cost,omitempty"`
+	IsPurchased   bool            `json:"is_purchased"`
+	Forecast      *DemandForecast `json:"forecast,omitempty"` // set when the product has recent sales; drives SuggestedQty
+	Notes         *string         `json:"notes,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
 
 	// Relations
 	Product *Product `json:"product,omitempty"`
@@ -121,14 +122,19 @@ type ShoppingListItem struct {
 
 // ShoppingList represents the auto-generated restock plan
 type ShoppingList struct {
-	GeneratedAt   time.Time          `json:"generated_at"`
-	TotalItems    int                `json:"total_items"`
-	TotalCost     int64              `json:"total_cost"`
-	Items         []ShoppingListItem `json:"items"`
+	GeneratedAt    time.Time          `json:"generated_at"`
+	ForecastMethod ForecastMethod     `json:"forecast_method"`
+	LeadTimeDays   int                `json:"lead_time_days"`
+	CoverDays      int                `json:"cover_days"`
+	TotalItems     int                `json:"total_items"`
+	TotalCost      int64              `json:"total_cost"`
+	Items          []ShoppingListItem `json:"items"`
 }
 
-// NearExpiryItem represents an item nearing expiry
+// NearExpiryItem represents a stock lot nearing (or past) its expiry.
+// Quantity is what is left in the lot; DaysUntilExpiry is negative once it has expired.
 type NearExpiryItem struct {
+	BatchID      uuid.UUID  `json:"batch_id"`
 	ProductID    uuid.UUID  `json:"product_id"`
 	ProductName  string     `json:"product_name"`
 	Barcode      *string    `json:"barcode,omitempty"`
@@ -136,14 +142,15 @@ type NearExpiryItem struct {
 	ExpiryDate   time.Time  `json:"expiry_date"`
 	DaysUntilExpiry int     `json:"days_until_expiry"`
 	Quantity     int        `json:"quantity"`
-	CostPrice    int64      `json:"cost_price"`
+	CostPrice    int64      `json:"cost_price"` // lot cost, or the product cost when the lot has none
 }
 
 // NearExpiryReport represents items nearing expiry date
 type NearExpiryReport struct {
-	GeneratedAt time.Time        `json:"generated_at"`
-	DaysAhead   int              `json:"days_ahead"` // How many days ahead we're looking
-	TotalItems  int              `json:"total_items"`
-	TotalValue  int64            `json:"total_value"`
-	Items       []NearExpiryItem `json:"items"`
+	GeneratedAt  time.Time        `json:"generated_at"`
+	DaysAhead    int              `json:"days_ahead"` // How many days ahead we're looking
+	TotalItems   int              `json:"total_items"`
+	ExpiredItems int              `json:"expired_items"` // lots already past expiry, to be written off
+	TotalValue   int64            `json:"total_value"`
+	Items        []NearExpiryItem `json:"items"`
 }
@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Supplier represents a vendor that goods are purchased from
+type Supplier struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Phone     *string   `json:"phone,omitempty"`
+	Address   *string   `json:"address,omitempty"`
+	Notes     *string   `json:"notes,omitempty"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SupplierCreateInput is the input for creating a supplier
+type SupplierCreateInput struct {
+	Name    string  `json:"name"`
+	Phone   *string `json:"phone,omitempty"`
+	Address *string `json:"address,omitempty"`
+	Notes   *string `json:"notes,omitempty"`
+}
+
+// SupplierUpdateInput is the input for updating a supplier
+type SupplierUpdateInput struct {
+	Name     *string `json:"name,omitempty"`
+	Phone    *string `json:"phone,omitempty"`
+	Address  *string `json:"address,omitempty"`
+	Notes    *string `json:"notes,omitempty"`
+	IsActive *bool   `json:"is_active,omitempty"`
+}
+
+// SupplierFilter is the filter options for listing suppliers
+type SupplierFilter struct {
+	Search   *string `json:"search,omitempty"`
+	IsActive *bool   `json:"is_active,omitempty"`
+	Page     int     `json:"page,omitempty"`
+	PerPage  int     `json:"per_page,omitempty"`
+}
+
+// PurchaseStatus represents the lifecycle state of a purchase order
+type PurchaseStatus string
+
+const (
+	PurchaseStatusDraft     PurchaseStatus = "draft"
+	PurchaseStatusOrdered   PurchaseStatus = "ordered"
+	PurchaseStatusReceived  PurchaseStatus = "received"
+	PurchaseStatusCancelled PurchaseStatus = "cancelled"
+)
+
+// Purchase represents a purchase order to a supplier
+type Purchase struct {
+	ID             uuid.UUID      `json:"id"`
+	PurchaseNumber string         `json:"purchase_number"`
+	SupplierID     *uuid.UUID     `json:"supplier_id,omitempty"`
+	TotalAmount    int64          `json:"total_amount"`
+	Status         PurchaseStatus `json:"status"`
+	Notes          *string        `json:"notes,omitempty"`
+	ReceivedAt     *time.Time     `json:"received_at,omitempty"`
+	CreatedBy      *string        `json:"created_by,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+
+	// Relations
+	Supplier *Supplier      `json:"supplier,omitempty"`
+	Items    []PurchaseItem `json:"items,omitempty"`
+}
+
+// CanReceive checks if the purchase can still be received into stock
+func (p *Purchase) CanReceive() bool {
+	return p.Status == PurchaseStatusDraft || p.Status == PurchaseStatusOrdered
+}
+
+// PurchaseItem represents a line item in a purchase order
+type PurchaseItem struct {
+	ID          uuid.UUID  `json:"id"`
+	PurchaseID  uuid.UUID  `json:"purchase_id"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	Quantity    int        `json:"quantity"`
+	CostPerUnit int64      `json:"cost_per_unit"`
+	TotalCost   int64      `json:"total_cost"`
+	BatchNumber *string    `json:"batch_number,omitempty"`
+	ExpiryDate  *time.Time `json:"expiry_date,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Set when bought in a product unit; Quantity and CostPerUnit are then converted to base units
+	UnitName     *string `json:"unit_name,omitempty"`
+	UnitQuantity *int    `json:"unit_quantity,omitempty"`
+	UnitCost     *int64  `json:"unit_cost,omitempty"`
+
+	// Relations
+	Product *Product `json:"product,omitempty"`
+}
+
+// PurchaseCreateInput is the input for creating a purchase order
+type PurchaseCreateInput struct {
+	SupplierID *uuid.UUID          `json:"supplier_id,omitempty"`
+	Items      []PurchaseItemInput `json:"items"`
+	Notes      *string             `json:"notes,omitempty"`
+	CreatedBy  string              `json:"-"`
+}
+
+// PurchaseItemInput is a single line in a purchase order
+type PurchaseItemInput struct {
+	ProductID   uuid.UUID  `json:"product_id"`
+	UnitID      *uuid.UUID `json:"unit_id,omitempty"` // quantity and cost are per this unit; omitted = base unit
+	Quantity    int        `json:"quantity"`
+	CostPerUnit int64      `json:"cost_per_unit"`
+	LotInput
+}
+
+// PurchaseFilter is the filter options for listing purchases
+type PurchaseFilter struct {
+	SupplierID *uuid.UUID      `json:"supplier_id,omitempty"`
+	Status     *PurchaseStatus `json:"status,omitempty"`
+	DateFrom   *time.Time      `json:"date_from,omitempty"`
+	DateTo     *time.Time      `json:"date_to,omitempty"`
+	Page       int             `json:"page,omitempty"`
+	PerPage    int             `json:"per_page,omitempty"`
+}
+
+// SupplierPurchaseSummary summarizes what was received from a supplier in a period
+type SupplierPurchaseSummary struct {
+	Supplier      Supplier                 `json:"supplier"`
+	DateFrom      *time.Time               `json:"date_from,omitempty"`
+	DateTo        *time.Time               `json:"date_to,omitempty"`
+	PurchaseCount int                      `json:"purchase_count"`
+	TotalAmount   int64                    `json:"total_amount"`
+	Products      []SupplierProductSummary `json:"products"`
+}
+
+// SupplierProductSummary is a per-product line in a supplier purchase summary
+type SupplierProductSummary struct {
+	ProductID      uuid.UUID `json:"product_id"`
+	ProductName    string    `json:"product_name"`
+	TotalQuantity  int       `json:"total_quantity"`
+	TotalCost      int64     `json:"total_cost"`
+	AvgCostPerUnit int64     `json:"avg_cost_per_unit"`
+}
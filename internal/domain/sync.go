@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SyncPullResult is the set of master data changed since a cursor.
+// Deactivated records are included with is_active=false so clients can drop them.
+type SyncPullResult struct {
+	Cursor     time.Time  `json:"cursor"`   // pass back as since on the next pull
+	Full       bool       `json:"full"`     // true when no cursor was given
+	Products   []Product  `json:"products"` // with their current pricing tiers
+	Categories []Category `json:"categories"`
+	Customers  []Customer `json:"customers"`
+}
+
+// SyncPushInput is a batch of sales recorded while offline, in the order they were made
+type SyncPushInput struct {
+	Transactions []OfflineTransactionInput `json:"transactions"`
+}
+
+// OfflineTransactionInput is one offline sale.
+// ClientID is generated on the device and makes pushing the same sale twice harmless.
+type OfflineTransactionInput struct {
+	ClientID        uuid.UUID              `json:"client_id"`
+	ClientCreatedAt *time.Time             `json:"client_created_at,omitempty"`
+	Transaction     TransactionCreateInput `json:"transaction"`
+}
+
+// SyncItemStatus is the outcome of replaying one offline sale
+type SyncItemStatus string
+
+const (
+	SyncItemCreated      SyncItemStatus = "created"
+	SyncItemDuplicate    SyncItemStatus = "duplicate"     // already synced earlier
+	SyncItemConflict     SyncItemStatus = "conflict"      // rejected, see conflict
+	SyncItemNotProcessed SyncItemStatus = "not_processed" // batch stopped on a server error; push again
+)
+
+// SyncConflictCode identifies why an offline sale could not be replayed
+type SyncConflictCode string
+
+const (
+	SyncConflictInsufficientStock SyncConflictCode = "insufficient_stock"
+	SyncConflictProductInactive   SyncConflictCode = "product_inactive"
+	SyncConflictCreditLimit       SyncConflictCode = "credit_limit_exceeded"
+	SyncConflictCustomerInactive  SyncConflictCode = "customer_inactive"
+	SyncConflictNotFound          SyncConflictCode = "not_found" // product or customer no longer exists
+	SyncConflictInvalid           SyncConflictCode = "invalid"
+)
+
+// SyncConflict explains a rejected offline sale
+type SyncConflict struct {
+	Code    SyncConflictCode `json:"code"`
+	Message string           `json:"message"`
+}
+
+// SyncPushItemResult is the per-sale result of a push
+type SyncPushItemResult struct {
+	ClientID      uuid.UUID      `json:"client_id"`
+	Status        SyncItemStatus `json:"status"`
+	TransactionID *uuid.UUID     `json:"transaction_id,omitempty"`
+	InvoiceNumber *string        `json:"invoice_number,omitempty"`
+	Conflict      *SyncConflict  `json:"conflict,omitempty"`
+}
+
+// SyncPushResult summarizes a push
+type SyncPushResult struct {
+	Created    int                  `json:"created"`
+	Duplicates int                  `json:"duplicates"`
+	Conflicts  int                  `json:"conflicts"`
+	Results    []SyncPushItemResult `json:"results"`
+}
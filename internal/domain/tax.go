@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// TaxRounding controls how fractional Rupiah are rounded
+type TaxRounding string
+
+const (
+	TaxRoundingNearest TaxRounding = "nearest"
+	TaxRoundingUp      TaxRounding = "up"
+	TaxRoundingDown    TaxRounding = "down"
+)
+
+// TaxConfig is the store's tax configuration
+type TaxConfig struct {
+	Rate      float64     `json:"rate"`
+	Inclusive bool        `json:"inclusive"`
+	Rounding  TaxRounding `json:"rounding"`
+
+	// Categories whose products are not taxed
+	ExemptCategoryIDs map[uuid.UUID]bool `json:"-"`
+}
+
+// IsExempt checks if a product is exempt from tax through its category
+func (c *TaxConfig) IsExempt(product *Product) bool {
+	return product.CategoryID != nil && c.ExemptCategoryIDs[*product.CategoryID]
+}
+
+// Calculate returns the tax on a cart.
+// taxable is the part of subtotal from non-exempt lines; the order discount is
+// spread over the lines so only the taxable share of it lowers the tax base.
+// For inclusive pricing the result is the tax already contained in the price.
+func (c *TaxConfig) Calculate(subtotal, taxable, discount int64) int64 {
+	if c.Rate <= 0 || subtotal <= 0 || taxable <= 0 {
+		return 0
+	}
+
+	base := float64(taxable)
+	if discount > 0 {
+		base -= float64(discount) * float64(taxable) / float64(subtotal)
+	}
+	if base <= 0 {
+		return 0
+	}
+
+	var tax float64
+	if c.Inclusive {
+		tax = base * c.Rate / (100 + c.Rate)
+	} else {
+		tax = base * c.Rate / 100
+	}
+
+	switch c.Rounding {
+	case TaxRoundingUp:
+		return int64(math.Ceil(tax))
+	case TaxRoundingDown:
+		return int64(math.Floor(tax))
+	default:
+		return int64(math.Round(tax))
+	}
+}
+
+// Total returns the amount due; inclusive tax is already part of the subtotal
+func (c *TaxConfig) Total(subtotal, discount, tax int64) int64 {
+	if c.Inclusive {
+		return subtotal - discount
+	}
+	return subtotal - discount + tax
+}
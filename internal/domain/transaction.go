@@ -35,6 +35,7 @@ type Transaction struct {
 	Subtotal       int64              `json:"subtotal"`        // total sebelum diskon & pajak
 	DiscountAmount int64              `json:"discount_amount"` // total diskon
 	TaxAmount      int64              `json:"tax_amount"`      // total pajak
+	TaxInclusive   bool               `json:"tax_inclusive"`   // pajak sudah termasuk dalam harga
 	TotalAmount    int64              `json:"total_amount"`    // total akhir
 	PaymentMethod  PaymentMethod      `json:"payment_method"`
 	AmountPaid     int64              `json:"amount_paid"`
@@ -45,9 +46,47 @@ type Transaction struct {
 	CreatedAt      time.Time          `json:"created_at"`
 	UpdatedAt      time.Time          `json:"updated_at"`
 
+	ReceiptPrintCount int        `json:"receipt_print_count"`
+	LastPrintedAt     *time.Time `json:"last_printed_at,omitempty"`
+
+	// Set for sales recorded offline and pushed through /sync
+	ClientID        *uuid.UUID `json:"client_id,omitempty"`
+	ClientCreatedAt *time.Time `json:"client_created_at,omitempty"`
+
+	// Set when the sale is cancelled
+	CancelReason *string    `json:"cancel_reason,omitempty"`
+	CancelledBy  *string    `json:"cancelled_by,omitempty"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+
 	// Relations (populated when needed)
 	Customer *Customer          `json:"customer,omitempty"`
 	Items    []TransactionItem  `json:"items,omitempty"`
+	Payments []TransactionPayment `json:"payments,omitempty"`
+}
+
+// KasbonAmount returns the part of the total that was charged to the customer's kasbon
+func (t *Transaction) KasbonAmount() int64 {
+	if t.PaymentMethod == PaymentMethodKasbon {
+		return t.TotalAmount
+	}
+	var amount int64
+	for _, p := range t.Payments {
+		if p.Method == PaymentMethodKasbon {
+			amount += p.Amount
+		}
+	}
+	return amount
+}
+
+// TransactionPayment is a single tender used to pay a transaction.
+// Amounts are net of change, so the payments of a sale add up to its total.
+type TransactionPayment struct {
+	ID            uuid.UUID     `json:"id"`
+	TransactionID uuid.UUID     `json:"transaction_id"`
+	Method        PaymentMethod `json:"method"`
+	Amount        int64         `json:"amount"`
+	Reference     *string       `json:"reference,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
 }
 
 // TransactionItem represents an item in a transaction
@@ -57,11 +96,12 @@ type TransactionItem struct {
 	ProductID       uuid.UUID  `json:"product_id"`
 	ProductName     string     `json:"product_name"`
 	ProductBarcode  *string    `json:"product_barcode,omitempty"`
-	Quantity        int        `json:"quantity"`
-	Unit            string     `json:"unit"`
-	UnitPrice       int64      `json:"unit_price"`
-	CostPrice       int64      `json:"cost_price"`
-	Subtotal        int64      `json:"subtotal"`        // quantity * unit_price
+	Quantity        int        `json:"quantity"`        // in the product's base unit
+	Unit            string     `json:"unit"`            // unit sold
+	UnitPrice       int64      `json:"unit_price"`      // per unit sold
+	CostPrice       int64      `json:"cost_price"`      // HPP per base unit, COGSAmount / Quantity rounded
+	COGSAmount      int64      `json:"cogs_amount"`     // actual cost of the units sold
+	Subtotal        int64      `json:"subtotal"`        // unit_quantity * unit_price
 	DiscountAmount  int64      `json:"discount_amount"`
 	TotalAmount     int64      `json:"total_amount"`    // subtotal - discount
 	PricingTierID   *uuid.UUID `json:"pricing_tier_id,omitempty"`
@@ -69,13 +109,29 @@ type TransactionItem struct {
 	Notes           *string    `json:"notes,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 
+	// Unit sold; Quantity = UnitQuantity * ConversionFactor
+	ProductUnitID    *uuid.UUID `json:"product_unit_id,omitempty"` // nil when sold in the base unit
+	UnitQuantity     int        `json:"unit_quantity"`
+	ConversionFactor int        `json:"conversion_factor"`
+
+	// Promotion snapshot; PromotionDiscount is included in DiscountAmount
+	PromotionID       *uuid.UUID `json:"promotion_id,omitempty"`
+	PromotionName     *string    `json:"promotion_name,omitempty"`
+	PromotionDiscount int64      `json:"promotion_discount"`
+
 	// Relations (populated when needed)
 	Product *Product `json:"product,omitempty"`
 }
 
+// BaseUnitPrice returns the price of one base unit on this line, rounded
+func (ti *TransactionItem) BaseUnitPrice() int64 {
+	factor := int64(max(ti.ConversionFactor, 1))
+	return (ti.UnitPrice + factor/2) / factor
+}
+
 // Profit calculates the profit for this item
 func (ti *TransactionItem) Profit() int64 {
-	return ti.TotalAmount - (ti.CostPrice * int64(ti.Quantity))
+	return ti.TotalAmount - ti.COGSAmount
 }
 
 // TransactionCreateInput is the input for creating a transaction
@@ -83,49 +139,84 @@ type TransactionCreateInput struct {
 	CustomerID     *uuid.UUID              `json:"customer_id,omitempty"`
 	Items          []TransactionItemInput  `json:"items"`
 	DiscountAmount *int64                  `json:"discount_amount,omitempty"`
-	TaxAmount      *int64                  `json:"tax_amount,omitempty"`
+	TaxAmount      *int64                  `json:"tax_amount,omitempty"` // optional; must match the server-calculated tax
 	PaymentMethod  PaymentMethod           `json:"payment_method"`
 	AmountPaid     int64                   `json:"amount_paid"`
+	Payments       []TenderInput           `json:"payments,omitempty"` // required for mixed
 	Notes          *string                 `json:"notes,omitempty"`
 	CashierName    *string                 `json:"cashier_name,omitempty"`
+
+	// Filled in by the offline sync push, not accepted on /transactions
+	ClientID        *uuid.UUID `json:"-"`
+	ClientCreatedAt *time.Time `json:"-"`
+}
+
+// TenderInput is one tender of a mixed (split) payment.
+// A kasbon tender may only cover the remainder left unpaid by the other tenders.
+type TenderInput struct {
+	Method    PaymentMethod `json:"method"`
+	Amount    int64         `json:"amount"`
+	Reference *string       `json:"reference,omitempty"`
 }
 
 // TransactionItemInput is the input for a transaction item
 type TransactionItemInput struct {
-	ProductID      uuid.UUID `json:"product_id"`
-	Quantity       int       `json:"quantity"`
-	DiscountAmount *int64    `json:"discount_amount,omitempty"`
-	Notes          *string   `json:"notes,omitempty"`
+	ProductID      uuid.UUID  `json:"product_id"`
+	UnitID         *uuid.UUID `json:"unit_id,omitempty"` // sell by a product unit; omitted = base unit
+	Quantity       int        `json:"quantity"`          // in the unit sold
+	DiscountAmount *int64     `json:"discount_amount,omitempty"`
+	Notes          *string    `json:"notes,omitempty"`
+}
+
+// TransactionCancelInput is the input for cancelling a transaction
+type TransactionCancelInput struct {
+	Reason      string `json:"reason"`
+	CancelledBy string `json:"-"` // set from the authenticated user
 }
 
 // CartCalculateInput is the input for calculating cart totals (preview)
 type CartCalculateInput struct {
-	Items []CartItem `json:"items"`
+	Items          []CartItem `json:"items"`
+	DiscountAmount *int64     `json:"discount_amount,omitempty"` // order-level discount
 }
 
 // CartItem represents an item in the cart for calculation
 type CartItem struct {
-	ProductID uuid.UUID `json:"product_id"`
-	Quantity  int       `json:"quantity"`
+	ProductID uuid.UUID  `json:"product_id"`
+	UnitID    *uuid.UUID `json:"unit_id,omitempty"`
+	Quantity  int        `json:"quantity"`
 }
 
 // CartCalculateResult is the result of cart calculation
 type CartCalculateResult struct {
-	Items    []CartItemResult `json:"items"`
-	Subtotal int64            `json:"subtotal"`
+	Items             []CartItemResult `json:"items"`
+	Subtotal          int64            `json:"subtotal"`           // after promotions
+	PromotionDiscount int64            `json:"promotion_discount"` // total promo discount
+	DiscountAmount    int64            `json:"discount_amount"`    // order-level discount
+	TaxRate           float64          `json:"tax_rate"`
+	TaxInclusive      bool             `json:"tax_inclusive"`
+	TaxAmount         int64            `json:"tax_amount"`
+	TotalAmount       int64            `json:"total_amount"` // amount due
 }
 
 // CartItemResult is the result for each item in cart calculation
 type CartItemResult struct {
 	ProductID     uuid.UUID `json:"product_id"`
 	ProductName   string    `json:"product_name"`
-	Quantity      int       `json:"quantity"`
+	Quantity      int       `json:"quantity"` // in the unit sold
 	Unit          string    `json:"unit"`
 	UnitPrice     int64     `json:"unit_price"`
+	BaseQuantity  int       `json:"base_quantity"` // quantity in the product's base unit
 	TierName      string    `json:"tier_name"`
 	Subtotal      int64     `json:"subtotal"`
 	IsAvailable   bool      `json:"is_available"`
 	AvailableQty  *int      `json:"available_qty,omitempty"`
+
+	PromotionID       *uuid.UUID `json:"promotion_id,omitempty"`
+	PromotionName     *string    `json:"promotion_name,omitempty"`
+	PromotionDiscount int64      `json:"promotion_discount"`
+	TotalAmount       int64      `json:"total_amount"` // subtotal - promotion discount
+	TaxExempt         bool       `json:"tax_exempt"`
 }
 
 // TransactionFilter is the filter options for listing transactions
@@ -12,15 +12,17 @@ import (
 	"github.com/eveeze/warung-backend/internal/middleware"
 	"github.com/eveeze/warung-backend/internal/pkg/response"
 	"github.com/eveeze/warung-backend/internal/pkg/validator"
+	"github.com/eveeze/warung-backend/internal/repository"
 	"github.com/eveeze/warung-backend/internal/service"
 )
 
 type CashFlowHandler struct {
 	cashFlowSvc *service.CashFlowService
+	auditRepo   *repository.AuditRepository
 }
 
-func NewCashFlowHandler(cashFlowSvc *service.CashFlowService) *CashFlowHandler {
-	return &CashFlowHandler{cashFlowSvc: cashFlowSvc}
+func NewCashFlowHandler(cashFlowSvc *service.CashFlowService, auditRepo *repository.AuditRepository) *CashFlowHandler {
+	return &CashFlowHandler{cashFlowSvc: cashFlowSvc, auditRepo: auditRepo}
 }
 
 // GetCategories lists all categories
@@ -188,6 +190,10 @@ func (h *CashFlowHandler) ListCashFlows(w http.ResponseWriter, r *http.Request)
 		}
 	}
 	
+	if cid, err := uuid.Parse(r.URL.Query().Get("category_id")); err == nil {
+		filter.CategoryID = &cid
+	}
+
 	typeStr := r.URL.Query().Get("type")
 	if typeStr != "" {
 		t := domain.CashFlowType(typeStr)
@@ -200,6 +206,28 @@ func (h *CashFlowHandler) ListCashFlows(w http.ResponseWriter, r *http.Request)
 			filter.DateFrom = &t
 		}
 	}
+	if t, err := time.Parse(time.RFC3339, r.URL.Query().Get("date_to")); err == nil {
+		filter.DateTo = &t
+	}
+
+	// The xlsx download holds every matching record rather than one page
+	if wantsXLSX(r) {
+		var records []domain.CashFlowRecord
+		filter.PerPage = 100
+		for filter.Page = 1; ; filter.Page++ {
+			page, total, err := h.cashFlowSvc.ListCashFlows(r.Context(), filter)
+			if err != nil {
+				response.InternalServerError(w, err.Error())
+				return
+			}
+			records = append(records, page...)
+			if len(page) == 0 || int64(len(records)) >= total {
+				break
+			}
+		}
+		writeWorkbook(w, r, h.auditRepo, "cash-flows-"+time.Now().Format("20060102"), cashFlowSheets(records), "cash_flow", nil)
+		return
+	}
 
 	records, total, err := h.cashFlowSvc.ListCashFlows(r.Context(), filter)
 	if err != nil {
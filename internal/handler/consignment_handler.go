@@ -2,7 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -88,3 +92,174 @@ func (h *ConsignmentHandler) DeleteConsignor(w http.ResponseWriter, r *http.Requ
 
 	response.OK(w, "Consignor deleted successfully", nil)
 }
+
+// GenerateSettlement computes a draft settlement for a consignor
+// POST /consignors/{id}/settlements
+func (h *ConsignmentHandler) GenerateSettlement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid ID")
+		return
+	}
+
+	var req struct {
+		PeriodStart string  `json:"period_start"` // YYYY-MM-DD, optional
+		PeriodEnd   string  `json:"period_end"`   // YYYY-MM-DD, optional
+		Notes       *string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		response.BadRequest(w, "Invalid body")
+		return
+	}
+
+	input := domain.GenerateSettlementInput{Notes: req.Notes}
+	if req.PeriodStart != "" {
+		t, err := time.Parse("2006-01-02", req.PeriodStart)
+		if err != nil {
+			response.BadRequest(w, "Invalid period_start, use YYYY-MM-DD")
+			return
+		}
+		input.PeriodStart = &t
+	}
+	if req.PeriodEnd != "" {
+		t, err := time.Parse("2006-01-02", req.PeriodEnd)
+		if err != nil {
+			response.BadRequest(w, "Invalid period_end, use YYYY-MM-DD")
+			return
+		}
+		input.PeriodEnd = &t
+	}
+
+	input.ConsignorID = id
+	input.CreatedBy = currentUsername(r)
+
+	settlement, err := h.consignmentSvc.GenerateSettlement(r.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			response.NotFound(w, "Consignor not found")
+		case errors.Is(err, domain.ErrInvalidInput):
+			response.BadRequest(w, err.Error())
+		default:
+			response.InternalServerError(w, "Failed to generate settlement")
+		}
+		return
+	}
+	response.Created(w, "Settlement generated", settlement)
+}
+
+// ListSettlements lists settlements
+// GET /consignment/settlements?consignor_id=&status=
+func (h *ConsignmentHandler) ListSettlements(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.SettlementFilter{Page: 1, PerPage: 20}
+
+	if consignorID := query.Get("consignor_id"); consignorID != "" {
+		if id, err := uuid.Parse(consignorID); err == nil {
+			filter.ConsignorID = &id
+		}
+	}
+	if status := query.Get("status"); status != "" {
+		s := domain.SettlementStatus(status)
+		filter.Status = &s
+	}
+	if page := query.Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if perPage := query.Get("per_page"); perPage != "" {
+		if pp, err := strconv.Atoi(perPage); err == nil {
+			filter.PerPage = pp
+		}
+	}
+
+	list, total, err := h.consignmentSvc.ListSettlements(r.Context(), filter)
+	if err != nil {
+		response.InternalServerError(w, err.Error())
+		return
+	}
+	response.SuccessWithMeta(w, http.StatusOK, "Settlements retrieved", list, response.NewMeta(filter.Page, filter.PerPage, total))
+}
+
+// GetSettlement gets a settlement with items
+// GET /consignment/settlements/{id}
+func (h *ConsignmentHandler) GetSettlement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid ID")
+		return
+	}
+
+	settlement, err := h.consignmentSvc.GetSettlement(r.Context(), id)
+	if err == domain.ErrNotFound {
+		response.NotFound(w, "Settlement not found")
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, err.Error())
+		return
+	}
+	response.OK(w, "Settlement retrieved", settlement)
+}
+
+// ConfirmSettlement confirms a draft settlement
+// POST /consignment/settlements/{id}/confirm
+func (h *ConsignmentHandler) ConfirmSettlement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid ID")
+		return
+	}
+
+	settlement, err := h.consignmentSvc.ConfirmSettlement(r.Context(), id)
+	if err != nil {
+		writeSettlementError(w, err)
+		return
+	}
+	response.OK(w, "Settlement confirmed", settlement)
+}
+
+// PaySettlement marks a confirmed settlement as paid
+// POST /consignment/settlements/{id}/pay
+func (h *ConsignmentHandler) PaySettlement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid ID")
+		return
+	}
+
+	settlement, err := h.consignmentSvc.PaySettlement(r.Context(), id, currentUsername(r))
+	if err != nil {
+		writeSettlementError(w, err)
+		return
+	}
+	response.OK(w, "Settlement paid", settlement)
+}
+
+// DeleteSettlement discards a draft settlement
+// DELETE /consignment/settlements/{id}
+func (h *ConsignmentHandler) DeleteSettlement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid ID")
+		return
+	}
+
+	if err := h.consignmentSvc.DeleteSettlement(r.Context(), id); err != nil {
+		writeSettlementError(w, err)
+		return
+	}
+	response.OK(w, "Settlement deleted", nil)
+}
+
+func writeSettlementError(w http.ResponseWriter, err error) {
+	switch err {
+	case domain.ErrNotFound:
+		response.NotFound(w, "Settlement not found")
+	case domain.ErrInvalidStatusTransition:
+		response.Conflict(w, "Settlement cannot move to the requested status")
+	default:
+		response.InternalServerError(w, err.Error())
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/middleware"
+	"github.com/eveeze/warung-backend/internal/pkg/response"
+	"github.com/eveeze/warung-backend/internal/pkg/spreadsheet"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// currentUsername returns the authenticated username, falling back to "system"
+func currentUsername(r *http.Request) string {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims != nil {
+		return claims.Username
+	}
+	return "system"
+}
+
+// parseDateRange parses YYYY-MM-DD bounds, extending the upper bound to end of day
+func parseDateRange(from, to string) (*time.Time, *time.Time) {
+	var dateFrom, dateTo *time.Time
+	if from != "" {
+		if t, err := time.Parse("2006-01-02", from); err == nil {
+			dateFrom = &t
+		}
+	}
+	if to != "" {
+		if t, err := time.Parse("2006-01-02", to); err == nil {
+			endOfDay := t.Add(24*time.Hour - time.Second)
+			dateTo = &endOfDay
+		}
+	}
+	return dateFrom, dateTo
+}
+
+// wantsXLSX reports whether a report or list was requested as an Excel download (?format=xlsx)
+func wantsXLSX(r *http.Request) bool {
+	return r.URL.Query().Get("format") == string(spreadsheet.FormatXLSX)
+}
+
+// writeWorkbook sends sheets as an XLSX download named <name>.xlsx and records the export in the audit log
+func writeWorkbook(w http.ResponseWriter, r *http.Request, auditRepo *repository.AuditRepository, name string, sheets []spreadsheet.Sheet, entityType string, entityID *uuid.UUID) {
+	var buf bytes.Buffer
+	if err := spreadsheet.WriteWorkbook(&buf, sheets); err != nil {
+		response.InternalServerError(w, "Failed to build export")
+		return
+	}
+
+	middleware.LogAudit(r.Context(), auditRepo, repository.AuditActionExport, entityType, entityID, name+".xlsx")
+
+	w.Header().Set("Content-Type", spreadsheet.FormatXLSX.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", name))
+	w.Write(buf.Bytes())
+}
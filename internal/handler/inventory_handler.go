@@ -2,6 +2,9 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -22,6 +25,9 @@ type InventoryHandler struct {
 	productRepo   *repository.ProductRepository
 	cache         *service.CacheService
 	event         *service.EventService
+	settingsSvc   *service.SettingsService
+	opnameSvc     *service.StockOpnameService
+	auditRepo     *repository.AuditRepository
 }
 
 // NewInventoryHandler creates a new InventoryHandler
@@ -30,12 +36,18 @@ func NewInventoryHandler(
 	productRepo *repository.ProductRepository,
 	cache *service.CacheService,
 	event *service.EventService,
+	settingsSvc *service.SettingsService,
+	opnameSvc *service.StockOpnameService,
+	auditRepo *repository.AuditRepository,
 ) *InventoryHandler {
 	return &InventoryHandler{
 		inventoryRepo: inventoryRepo,
 		productRepo:   productRepo,
 		cache:         cache,
 		event:         event,
+		settingsSvc:   settingsSvc,
+		opnameSvc:     opnameSvc,
+		auditRepo:     auditRepo,
 	}
 }
 
@@ -69,6 +81,10 @@ func (h *InventoryHandler) Restock(w http.ResponseWriter, r *http.Request) {
 
 	movement, err := h.inventoryRepo.Restock(r.Context(), input)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
 		response.InternalServerError(w, "Failed to restock product")
 		return
 	}
@@ -149,6 +165,8 @@ func (h *InventoryHandler) Adjust(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetMovements retrieves stock movements for a product
+// GET /inventory/{productId}/movements?type=&date_from=&date_to=&format=xlsx
+// The xlsx download holds every matching movement rather than one page.
 func (h *InventoryHandler) GetMovements(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("productId")
 	productID, err := uuid.Parse(idStr)
@@ -174,6 +192,12 @@ func (h *InventoryHandler) GetMovements(w http.ResponseWriter, r *http.Request)
 		t := domain.StockMovementType(typeStr)
 		filter.Type = &t
 	}
+	filter.DateFrom, filter.DateTo = parseDateRange(query.Get("date_from"), query.Get("date_to"))
+
+	if wantsXLSX(r) {
+		h.exportMovements(w, r, productID, filter)
+		return
+	}
 
 	movements, total, err := h.inventoryRepo.GetByProduct(r.Context(), productID, filter)
 	if err != nil {
@@ -185,6 +209,243 @@ func (h *InventoryHandler) GetMovements(w http.ResponseWriter, r *http.Request)
 	response.SuccessWithMeta(w, http.StatusOK, "Stock movements retrieved", movements, meta)
 }
 
+func (h *InventoryHandler) exportMovements(w http.ResponseWriter, r *http.Request, productID uuid.UUID, filter domain.StockMovementFilter) {
+	product, err := h.productRepo.GetByID(r.Context(), productID)
+	if err == domain.ErrNotFound {
+		response.NotFound(w, "Product not found")
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "Failed to get product")
+		return
+	}
+
+	var movements []domain.StockMovement
+	filter.PerPage = 100
+	for filter.Page = 1; ; filter.Page++ {
+		page, total, err := h.inventoryRepo.GetByProduct(r.Context(), productID, filter)
+		if err != nil {
+			response.InternalServerError(w, "Failed to get stock movements")
+			return
+		}
+		movements = append(movements, page...)
+		if len(page) == 0 || int64(len(movements)) >= total {
+			break
+		}
+	}
+
+	name := fmt.Sprintf("stock-movements-%s-%s", productID.String()[:8], time.Now().Format("20060102"))
+	writeWorkbook(w, r, h.auditRepo, name, movementSheets(product, movements), "stock_movement", &productID)
+}
+
+// GetBatches retrieves the stock lots of a product
+// GET /inventory/{productId}/batches?include_empty=true
+func (h *InventoryHandler) GetBatches(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(r.PathValue("productId"))
+	if err != nil {
+		response.BadRequest(w, "Invalid product ID")
+		return
+	}
+
+	includeEmpty := r.URL.Query().Get("include_empty") == "true"
+	batches, err := h.inventoryRepo.ListBatches(r.Context(), productID, includeEmpty)
+	if err != nil {
+		response.InternalServerError(w, "Failed to get stock batches")
+		return
+	}
+
+	response.OK(w, "Stock batches retrieved", batches)
+}
+
+// GetCostHistory retrieves a product's cost position: average cost, open FIFO layers and
+// the latest average cost changes
+// GET /inventory/{productId}/cost-history?limit=
+func (h *InventoryHandler) GetCostHistory(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(r.PathValue("productId"))
+	if err != nil {
+		response.BadRequest(w, "Invalid product ID")
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 {
+			limit = min(n, 200)
+		}
+	}
+
+	product, err := h.productRepo.GetByID(r.Context(), productID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			response.NotFound(w, "Product not found")
+			return
+		}
+		response.InternalServerError(w, "Failed to get product")
+		return
+	}
+
+	settings, err := h.settingsSvc.Store(r.Context())
+	if err != nil {
+		response.InternalServerError(w, "Failed to get store settings")
+		return
+	}
+
+	layers, err := h.inventoryRepo.GetCostLayers(r.Context(), productID)
+	if err != nil {
+		response.InternalServerError(w, "Failed to get cost layers")
+		return
+	}
+	history, err := h.inventoryRepo.GetCostHistory(r.Context(), productID, limit)
+	if err != nil {
+		response.InternalServerError(w, "Failed to get cost history")
+		return
+	}
+
+	report := domain.ProductCostReport{
+		ProductID:     productID,
+		CostingMethod: settings.CostingMethod,
+		AverageCost:   product.CostPrice,
+		Layers:        layers,
+		History:       history,
+	}
+	for _, l := range layers {
+		report.LayerValue += l.UnitCost * int64(l.RemainingQuantity)
+	}
+
+	response.OK(w, "Cost history retrieved", report)
+}
+
+// WriteOffBatch writes off stock from a lot as damage
+// POST /inventory/batches/{id}/write-off
+func (h *InventoryHandler) WriteOffBatch(w http.ResponseWriter, r *http.Request) {
+	batchID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid batch ID")
+		return
+	}
+
+	var input domain.BatchWriteOffInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	createdBy := currentUsername(r)
+	input.CreatedBy = &createdBy
+
+	result, err := h.inventoryRepo.WriteOffBatch(r.Context(), batchID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			response.NotFound(w, "Stock batch not found")
+		case errors.Is(err, domain.ErrInvalidInput):
+			response.BadRequest(w, err.Error())
+		default:
+			response.InternalServerError(w, "Failed to write off stock batch")
+		}
+		return
+	}
+
+	h.afterWriteOff(r, result)
+	response.Created(w, "Stock batch written off", result)
+}
+
+// WriteOffExpired writes off everything left in expired lots as damage
+// POST /inventory/batches/write-off-expired
+func (h *InventoryHandler) WriteOffExpired(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Notes *string `json:"notes,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	createdBy := currentUsername(r)
+
+	result, err := h.inventoryRepo.WriteOffExpired(r.Context(), input.Notes, &createdBy)
+	if err != nil {
+		response.InternalServerError(w, "Failed to write off expired stock")
+		return
+	}
+
+	h.afterWriteOff(r, result)
+	response.OK(w, "Expired stock written off", result)
+}
+
+// Repack converts stock of one product into another, carrying its cost to the new units
+// POST /inventory/repack
+func (h *InventoryHandler) Repack(w http.ResponseWriter, r *http.Request) {
+	var input domain.RepackInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	v := validator.New()
+	v.Custom("source_product_id", input.SourceProductID != uuid.Nil, "Source product ID is required")
+	v.Custom("target_product_id", input.TargetProductID != uuid.Nil, "Target product ID is required")
+	v.Custom("target_product_id", input.TargetProductID != input.SourceProductID, "Target product must differ from the source product")
+	v.Min("source_quantity", input.SourceQuantity, 1, "Source quantity must be at least 1")
+	v.Min("target_quantity", input.TargetQuantity, 1, "Target quantity must be at least 1")
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+	createdBy := currentUsername(r)
+	input.CreatedBy = &createdBy
+
+	settings, err := h.settingsSvc.Store(r.Context())
+	if err != nil {
+		response.InternalServerError(w, "Failed to get store settings")
+		return
+	}
+
+	result, err := h.inventoryRepo.Repack(r.Context(), input, settings.CostingMethod)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			response.NotFound(w, "Product not found")
+		case errors.Is(err, domain.ErrInvalidInput), errors.Is(err, domain.ErrInsufficientStock):
+			response.BadRequest(w, err.Error())
+		default:
+			response.InternalServerError(w, "Failed to repack stock")
+		}
+		return
+	}
+
+	h.afterStockChange(r, []domain.StockMovement{result.SourceMovement, result.TargetMovement})
+	response.Created(w, "Stock repacked successfully", result)
+}
+
+// afterWriteOff clears cached stock and announces the new stock of each affected product
+func (h *InventoryHandler) afterWriteOff(r *http.Request, result *domain.BatchWriteOffResult) {
+	if result.Batches == 0 {
+		return
+	}
+	h.afterStockChange(r, result.Movements)
+}
+
+// afterStockChange clears cached stock and announces the new stock of each product moved
+func (h *InventoryHandler) afterStockChange(r *http.Request, movements []domain.StockMovement) {
+	_ = h.cache.InvalidatePattern(r.Context(), "products:list:*")
+	_ = h.cache.InvalidatePattern(r.Context(), "reports:*")
+
+	published := make(map[uuid.UUID]bool)
+	for _, m := range movements {
+		if published[m.ProductID] {
+			continue
+		}
+		published[m.ProductID] = true
+		product, _ := h.productRepo.GetByID(r.Context(), m.ProductID)
+		if product != nil {
+			h.event.Publish(service.EventStockUpdate, map[string]interface{}{
+				"product_id":    product.ID,
+				"current_stock": product.CurrentStock,
+				"is_low_stock":  product.CurrentStock <= product.MinStockAlert,
+			})
+		}
+	}
+}
+
 // GetLowStock retrieves products with low stock
 func (h *InventoryHandler) GetLowStock(w http.ResponseWriter, r *http.Request) {
 	products, err := h.productRepo.GetLowStockProducts(r.Context())
@@ -211,37 +472,50 @@ func (h *InventoryHandler) GetReport(w http.ResponseWriter, r *http.Request) {
 	response.OK(w, "Stock report retrieved", report)
 }
 
-// DownloadRestockPDF generates and downloads PDF for low-stock products
+// DownloadRestockPDF generates and downloads PDF for the shopping list: low-stock products and
+// products at their forecast reorder point
 func (h *InventoryHandler) DownloadRestockPDF(w http.ResponseWriter, r *http.Request) {
-	// Get low stock products
-	lowStockProducts, err := h.productRepo.GetLowStockProducts(r.Context())
+	list, err := h.opnameSvc.GetShoppingList(r.Context())
 	if err != nil {
 		response.InternalServerError(w, "Failed to get low stock products")
 		return
 	}
 
-	if len(lowStockProducts) == 0 {
+	if len(list.Items) == 0 {
 		response.BadRequest(w, "No low stock products found")
 		return
 	}
 
 	// Convert to RestockItems
-	items := make([]pdf.RestockItem, len(lowStockProducts))
-	for i, p := range lowStockProducts {
+	items := make([]pdf.RestockItem, len(list.Items))
+	for i, item := range list.Items {
 		items[i] = pdf.RestockItem{
-			ProductName:  p.Product.Name,
-			CurrentStock: p.Product.CurrentStock,
-			MinStock:     p.Product.MinStockAlert,
-			Deficit:      p.DeficitAmount,
-			Unit:         p.Product.Unit,
-			CostPrice:    p.Product.CostPrice,
+			ProductName:  item.Product.Name,
+			CurrentStock: item.CurrentStock,
+			MinStock:     item.MinStock,
+			Deficit:      item.MinStock - item.CurrentStock,
+			Unit:         item.Product.Unit,
+			CostPrice:    item.Product.CostPrice,
 		}
+		if item.Forecast != nil {
+			items[i].ReorderPoint = item.Forecast.ReorderPoint
+			items[i].Deficit = max(item.MinStock, item.Forecast.ReorderPoint) - item.CurrentStock
+			items[i].SuggestedQty = item.SuggestedQty
+			items[i].Forecasted = true
+		}
+	}
+
+	settings, err := h.settingsSvc.Store(r.Context())
+	if err != nil {
+		response.InternalServerError(w, "Failed to get store settings")
+		return
 	}
 
 	// Generate PDF
 	pdfData := pdf.RestockData{
-		StoreName:    "Warung Kelontong",
-		StoreAddress: "Jl. Contoh No. 123",
+		StoreName:    settings.StoreName,
+		StoreAddress: settings.StoreAddress,
+		StorePhone:   settings.StorePhone,
 		GeneratedAt:  time.Now(),
 		Items:        items,
 	}
@@ -14,17 +14,19 @@ import (
 	"github.com/eveeze/warung-backend/internal/pkg/response"
 	"github.com/eveeze/warung-backend/internal/pkg/validator"
 	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
 )
 
 // KasbonHandler handles kasbon endpoints
 type KasbonHandler struct {
 	kasbonRepo   *repository.KasbonRepository
 	customerRepo *repository.CustomerRepository
+	settingsSvc  *service.SettingsService
 }
 
 // NewKasbonHandler creates a new KasbonHandler
-func NewKasbonHandler(kasbonRepo *repository.KasbonRepository, customerRepo *repository.CustomerRepository) *KasbonHandler {
-	return &KasbonHandler{kasbonRepo: kasbonRepo, customerRepo: customerRepo}
+func NewKasbonHandler(kasbonRepo *repository.KasbonRepository, customerRepo *repository.CustomerRepository, settingsSvc *service.SettingsService) *KasbonHandler {
+	return &KasbonHandler{kasbonRepo: kasbonRepo, customerRepo: customerRepo, settingsSvc: settingsSvc}
 }
 
 // GetHistory retrieves kasbon history for a customer
@@ -246,18 +248,25 @@ func (h *KasbonHandler) DownloadBillingPDF(w http.ResponseWriter, r *http.Reques
 		})
 	}
 
+	settings, err := h.settingsSvc.Store(r.Context())
+	if err != nil {
+		response.InternalServerError(w, "Failed to get store settings")
+		return
+	}
+
 	data := pdf.BillingData{
-		StoreName:      "WARUNG KELONTONG", // Should get from Config
-		StoreAddress:   "Jalan Raya No. 1",
+		StoreName:      settings.StoreName,
+		StoreAddress:   settings.StoreAddress,
+		StorePhone:     settings.StorePhone,
 		CustomerName:   customer.Name,
-		InvoiceNumber:  fmt.Sprintf("INV/%s/%s", now.Format("200601"), customer.Name[:3]), // Pseudo
+		InvoiceNumber:  fmt.Sprintf("%s/%s/%s", settings.InvoicePrefix, now.Format("200601"), customer.ID.String()[:8]), // Statement number
 		Date:           now,
 		PeriodStart:    &dateFrom,
 		PeriodEnd:      &now,
 		OpeningBalance: openingBalance,
 		EndingBalance:  currentDebt,
 		Transactions:   billingTx,
-		PaymentInst:    "Silakan lakukan pembayaran ke Kasir atau Transfer BCA 1234567890 a.n Warung.",
+		PaymentInst:    settings.PaymentInstructions,
 	}
 
 	// 4. Generate
@@ -9,6 +9,7 @@ import (
 	"github.com/eveeze/warung-backend/internal/domain"
 	"github.com/eveeze/warung-backend/internal/middleware"
 	"github.com/eveeze/warung-backend/internal/pkg/response"
+	"github.com/eveeze/warung-backend/internal/pkg/validator"
 	"github.com/eveeze/warung-backend/internal/service"
 )
 
@@ -119,6 +120,20 @@ func (h *POSHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	v := validator.New()
+	v.Required("reason", input.Reason, "Reason is required")
+	v.InSlice("refund_method", input.RefundMethod,
+		[]string{domain.RefundMethodCash, domain.RefundMethodStoreCredit, domain.RefundMethodOriginal},
+		"Refund method must be cash, store_credit or original")
+	v.Custom("items", len(input.Items) > 0, "At least one item is required")
+	for _, item := range input.Items {
+		v.Min("items.quantity", item.Quantity, 1, "Quantity must be at least 1")
+	}
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+
 	claims := middleware.GetUserFromContext(r.Context())
 	username := "system"
 	if claims != nil {
@@ -128,8 +143,90 @@ func (h *POSHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
 
 	refund, err := h.posSvc.CreateRefund(r.Context(), input)
 	if err != nil {
+		if err == domain.ErrNotFound {
+			response.NotFound(w, "Transaction not found")
+			return
+		}
 		response.BadRequest(w, err.Error())
 		return
 	}
 	response.Created(w, "Refund request created", refund)
 }
+
+func (h *POSHandler) GetRefund(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid ID")
+		return
+	}
+
+	refund, err := h.posSvc.GetRefund(r.Context(), id)
+	if err == domain.ErrNotFound {
+		response.NotFound(w, "Refund not found")
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, err.Error())
+		return
+	}
+	response.OK(w, "Refund retrieved", refund)
+}
+
+// ApproveRefund approves a pending refund
+func (h *POSHandler) ApproveRefund(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid ID")
+		return
+	}
+
+	refund, err := h.posSvc.ApproveRefund(r.Context(), id, currentUsername(r))
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+	response.OK(w, "Refund approved", refund)
+}
+
+// RejectRefund rejects a pending refund
+func (h *POSHandler) RejectRefund(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid ID")
+		return
+	}
+
+	refund, err := h.posSvc.RejectRefund(r.Context(), id, currentUsername(r))
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+	response.OK(w, "Refund rejected", refund)
+}
+
+// CompleteRefund restocks, reverses kasbon and pays out an approved refund
+func (h *POSHandler) CompleteRefund(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid ID")
+		return
+	}
+
+	refund, err := h.posSvc.CompleteRefund(r.Context(), id, currentUsername(r))
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+	response.OK(w, "Refund completed", refund)
+}
+
+func writeRefundError(w http.ResponseWriter, err error) {
+	switch err {
+	case domain.ErrNotFound:
+		response.NotFound(w, "Refund not found")
+	case domain.ErrInvalidStatusTransition:
+		response.Conflict(w, "Refund cannot move to the requested status")
+	default:
+		response.BadRequest(w, err.Error())
+	}
+}
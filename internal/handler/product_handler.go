@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/eveeze/warung-backend/internal/domain"
 	"github.com/eveeze/warung-backend/internal/pkg/response"
+	"github.com/eveeze/warung-backend/internal/pkg/spreadsheet"
 	"github.com/eveeze/warung-backend/internal/pkg/validator"
 	"github.com/eveeze/warung-backend/internal/repository"
 	"github.com/eveeze/warung-backend/internal/service"
@@ -21,17 +23,19 @@ import (
 
 // ProductHandler handles product endpoints
 type ProductHandler struct {
-	repo  *repository.ProductRepository
-	r2    *storage.R2Client
-	cache *service.CacheService
+	repo      *repository.ProductRepository
+	r2        *storage.R2Client
+	cache     *service.CacheService
+	importSvc *service.ProductImportService
 }
 
 // NewProductHandler creates a new ProductHandler
-func NewProductHandler(repo *repository.ProductRepository, r2 *storage.R2Client, cache *service.CacheService) *ProductHandler {
+func NewProductHandler(repo *repository.ProductRepository, r2 *storage.R2Client, cache *service.CacheService, importSvc *service.ProductImportService) *ProductHandler {
 	return &ProductHandler{
-		repo:  repo,
-		r2:    r2,
-		cache: cache,
+		repo:      repo,
+		r2:        r2,
+		cache:     cache,
+		importSvc: importSvc,
 	}
 }
 
@@ -116,6 +120,9 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	v.Required("unit", input.Unit, "Unit is required")
 	v.Positive("base_price", input.BasePrice, "Base price must be positive")
 	v.NonNegative("cost_price", input.CostPrice, "Cost price cannot be negative")
+	for _, unit := range input.Units {
+		validateProductUnit(v, unit)
+	}
 
 	if v.HasErrors() {
 		response.ValidationError(w, v.Errors())
@@ -124,7 +131,7 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	product, err := h.repo.Create(r.Context(), input)
 	if err != nil {
-		response.InternalServerError(w, "Failed to create product")
+		h.createError(w, err)
 		return
 	}
 
@@ -134,6 +141,196 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	response.Created(w, "Product created successfully", product)
 }
 
+// createError answers a failed product or variant creation
+func (h *ProductHandler) createError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrAlreadyExists):
+		response.Conflict(w, err.Error())
+	case errors.Is(err, domain.ErrInvalidInput):
+		response.BadRequest(w, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		response.NotFound(w, "Parent product not found")
+	default:
+		response.InternalServerError(w, "Failed to create product")
+	}
+}
+
+// AddVariant creates a variant (size, flavor) under a product. The variant takes the parent's
+// category, consignor and image; name defaults to "<parent name> <variant_name>" and unit to the parent's.
+// POST /products/{id}/variants
+func (h *ProductHandler) AddVariant(w http.ResponseWriter, r *http.Request) {
+	parentID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid product ID")
+		return
+	}
+
+	var input domain.ProductCreateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	input.ParentID = &parentID
+
+	v := validator.New()
+	v.Custom("variant_name", input.VariantName != nil && strings.TrimSpace(*input.VariantName) != "", "Variant name is required")
+	if input.Name != "" {
+		v.MinLength("name", input.Name, 2, "Name must be at least 2 characters")
+	}
+	v.Positive("base_price", input.BasePrice, "Base price must be positive")
+	v.NonNegative("cost_price", input.CostPrice, "Cost price cannot be negative")
+	for _, unit := range input.Units {
+		validateProductUnit(v, unit)
+	}
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+
+	variant, err := h.repo.Create(r.Context(), input)
+	if err != nil {
+		h.createError(w, err)
+		return
+	}
+
+	_ = h.cache.InvalidatePattern(r.Context(), "products:list:*")
+
+	response.Created(w, "Product variant created", variant)
+}
+
+// SetParent attaches an existing product to a parent as a variant, or detaches it with a null parent_id
+// PUT /products/{id}/parent
+func (h *ProductHandler) SetParent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid product ID")
+		return
+	}
+
+	var input domain.ProductParentInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	existing, err := h.repo.GetByID(r.Context(), id)
+	if err == domain.ErrNotFound {
+		response.NotFound(w, "Product not found")
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "Failed to get product")
+		return
+	}
+
+	product, err := h.repo.SetParent(r.Context(), id, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrAlreadyExists):
+			response.Conflict(w, err.Error())
+		case errors.Is(err, domain.ErrInvalidInput):
+			response.BadRequest(w, err.Error())
+		case errors.Is(err, domain.ErrNotFound):
+			response.NotFound(w, "Parent product not found")
+		default:
+			response.InternalServerError(w, "Failed to update product parent")
+		}
+		return
+	}
+
+	// A product joining a parent swaps its own image for the parent's
+	if input.ParentID != nil && !existing.IsVariant() && existing.ImageURL != nil && h.r2 != nil {
+		if product.ImageURL == nil || *product.ImageURL != *existing.ImageURL {
+			if key, err := h.r2.GetKeyFromURL(*existing.ImageURL); err == nil && key != "" {
+				if err := h.r2.DeleteFile(r.Context(), key); err != nil {
+					fmt.Printf("WARNING: Failed to delete old image %s: %v\n", key, err)
+				}
+			}
+		}
+	}
+
+	_ = h.cache.InvalidatePattern(r.Context(), "products:list:*")
+
+	response.OK(w, "Product parent updated", product)
+}
+
+// isVariant reports whether the product is a variant, whose category, consignor and image are set on its parent
+func (h *ProductHandler) isVariant(r *http.Request, id uuid.UUID) bool {
+	product, err := h.repo.GetByID(r.Context(), id)
+	return err == nil && product.IsVariant()
+}
+
+// Import creates and updates products from an XLSX or CSV file in the export template
+// POST /products/import?dry_run=true (multipart field "file")
+func (h *ProductHandler) Import(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		response.BadRequest(w, "File too large or invalid multipart data")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		response.BadRequest(w, "Missing 'file' field containing an XLSX or CSV file")
+		return
+	}
+	defer file.Close()
+
+	format, err := spreadsheet.FormatOf(header.Filename)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	rows, err := spreadsheet.Read(file, format)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	result, err := h.importSvc.Import(r.Context(), rows, dryRun)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		response.InternalServerError(w, "Failed to import products")
+		return
+	}
+
+	message := "Products imported"
+	switch {
+	case dryRun:
+		message = "Import checked, nothing was written"
+	case !result.Applied:
+		message = "Import has errors, nothing was written"
+	default:
+		_ = h.cache.InvalidatePattern(r.Context(), "products:list:*")
+	}
+
+	response.OK(w, message, result)
+}
+
+// Export downloads all products in the import template, with their pricing tiers
+// GET /products/export?format=xlsx|csv
+func (h *ProductHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format, err := spreadsheet.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	rows, err := h.importSvc.Export(r.Context())
+	if err != nil {
+		response.InternalServerError(w, "Failed to export products")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=products-%s.%s", time.Now().Format("20060102"), format))
+	if err := spreadsheet.Write(w, format, "Products", rows); err != nil {
+		fmt.Printf("ERROR: Failed to write product export: %v\n", err)
+	}
+}
+
 // GetByID retrieves a product by ID
 func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -214,6 +411,9 @@ func (h *ProductHandler) List(w http.ResponseWriter, r *http.Request) {
 	if query.Get("low_stock") == "true" {
 		filter.LowStockOnly = true
 	}
+	if query.Get("collapse_variants") == "true" {
+		filter.CollapseVariants = true
+	}
 	if isActive := query.Get("is_active"); isActive != "" {
 		if strings.ToLower(isActive) == "all" {
 			filter.IsActive = nil
@@ -228,7 +428,7 @@ func (h *ProductHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate cache key based on filters
-	cacheKey := fmt.Sprintf("products:list:%s:%s:%s:%d:%d:%s:%s:%v:%v:%v",
+	cacheKey := fmt.Sprintf("products:list:%s:%s:%s:%d:%d:%s:%s:%v:%v:%v:%v",
 		query.Get("search"),
 		query.Get("category_id"),
 		query.Get("consignor_id"), // Added to cache key
@@ -239,6 +439,7 @@ func (h *ProductHandler) List(w http.ResponseWriter, r *http.Request) {
 		filter.LowStockOnly,
 		query.Get("is_active"),
 		query.Get("is_stock_active"),
+		filter.CollapseVariants,
 	)
 
 	// Try cache first
@@ -299,6 +500,12 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		_, hasImage := r.MultipartForm.File["image"]
+		if (hasImage || input.ChangesSharedAttributes()) && h.isVariant(r, id) {
+			response.BadRequest(w, "Category, consignor and image of a variant are set on its parent")
+			return
+		}
+
 		// Handle Image Upload
 		file, header, err := r.FormFile("image")
 		if err == nil {
@@ -369,6 +576,11 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if input.ChangesSharedAttributes() && h.isVariant(r, id) {
+			response.BadRequest(w, "Category, consignor and image of a variant are set on its parent")
+			return
+		}
+
 		// Handle image removal (empty string)
 		if input.ImageURL != nil && *input.ImageURL == "" {
 			// Fetch existing product to delete image later
@@ -394,11 +606,15 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	product, err := h.repo.Update(r.Context(), id, input)
+	product, err := h.repo.Update(r.Context(), nil, id, input)
 	if err == domain.ErrNotFound {
 		response.NotFound(w, "Product not found")
 		return
 	}
+	if errors.Is(err, domain.ErrInvalidInput) {
+		response.BadRequest(w, err.Error())
+		return
+	}
 	if err != nil {
 		response.InternalServerError(w, "Failed to update product")
 		return
@@ -455,9 +671,16 @@ func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete image if exists
+	for _, variant := range product.Variants {
+		if variant.IsActive {
+			response.BadRequest(w, "Product still has active variants; delete them first")
+			return
+		}
+	}
+
+	// Delete image if exists; a variant's image belongs to its parent
 	// We check for nil r2 to avoid panic, though partial delete is better than crash
-	if product.ImageURL != nil && h.r2 != nil {
+	if product.ImageURL != nil && h.r2 != nil && !product.IsVariant() {
 		key, err := h.r2.GetKeyFromURL(*product.ImageURL)
 		if err == nil && key != "" {
 			_ = h.r2.DeleteFile(r.Context(), key)
@@ -556,6 +779,138 @@ func (h *ProductHandler) DeletePricingTier(w http.ResponseWriter, r *http.Reques
 	response.NoContent(w)
 }
 
+// AddUnit adds a unit (pack, dus, ...) to a product
+// POST /products/{id}/units
+func (h *ProductHandler) AddUnit(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid product ID")
+		return
+	}
+
+	var input domain.ProductUnitInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	v := validator.New()
+	validateProductUnit(v, input)
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+
+	if _, err := h.repo.GetByID(r.Context(), productID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			response.NotFound(w, "Product not found")
+			return
+		}
+		response.InternalServerError(w, "Failed to get product")
+		return
+	}
+
+	unit, err := h.repo.CreateUnit(r.Context(), productID, input)
+	if errors.Is(err, domain.ErrAlreadyExists) {
+		response.Conflict(w, err.Error())
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "Failed to create product unit")
+		return
+	}
+
+	_ = h.cache.InvalidatePattern(r.Context(), "products:list:*")
+
+	response.Created(w, "Product unit created", unit)
+}
+
+// UpdateUnit updates a product unit; omitted fields are kept
+// PUT /products/{id}/units/{unitId}
+func (h *ProductHandler) UpdateUnit(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid product ID")
+		return
+	}
+	unitID, err := uuid.Parse(r.PathValue("unitId"))
+	if err != nil {
+		response.BadRequest(w, "Invalid unit ID")
+		return
+	}
+
+	var input domain.ProductUnitInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	v := validator.New()
+	v.MaxLength("name", input.Name, 50, "Name must be at most 50 characters")
+	v.Min("conversion_factor", input.ConversionFactor, 0, "Conversion factor must be at least 1")
+	if input.Price != nil {
+		v.NonNegative("price", *input.Price, "Price cannot be negative")
+	}
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+
+	unit, err := h.repo.UpdateUnit(r.Context(), productID, unitID, input)
+	if errors.Is(err, domain.ErrNotFound) {
+		response.NotFound(w, "Product unit not found")
+		return
+	}
+	if errors.Is(err, domain.ErrAlreadyExists) {
+		response.Conflict(w, err.Error())
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "Failed to update product unit")
+		return
+	}
+
+	_ = h.cache.InvalidatePattern(r.Context(), "products:list:*")
+
+	response.OK(w, "Product unit updated", unit)
+}
+
+// DeleteUnit removes a product unit
+// DELETE /products/{id}/units/{unitId}
+func (h *ProductHandler) DeleteUnit(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid product ID")
+		return
+	}
+	unitID, err := uuid.Parse(r.PathValue("unitId"))
+	if err != nil {
+		response.BadRequest(w, "Invalid unit ID")
+		return
+	}
+
+	if err := h.repo.DeleteUnit(r.Context(), productID, unitID); errors.Is(err, domain.ErrNotFound) {
+		response.NotFound(w, "Product unit not found")
+		return
+	} else if err != nil {
+		response.InternalServerError(w, "Failed to delete product unit")
+		return
+	}
+
+	_ = h.cache.InvalidatePattern(r.Context(), "products:list:*")
+
+	response.NoContent(w)
+}
+
+func validateProductUnit(v *validator.Validator, input domain.ProductUnitInput) {
+	v.Required("units.name", input.Name, "Unit name is required")
+	v.MaxLength("units.name", input.Name, 50, "Unit name must be at most 50 characters")
+	v.Min("units.conversion_factor", input.ConversionFactor, 1, "Conversion factor must be at least 1")
+	if input.Price != nil {
+		v.NonNegative("units.price", *input.Price, "Price cannot be negative")
+	}
+}
+
 // GetLowStock retrieves products with low stock
 func (h *ProductHandler) GetLowStock(w http.ResponseWriter, r *http.Request) {
 	products, err := h.repo.GetLowStockProducts(r.Context())
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/pkg/response"
+	"github.com/eveeze/warung-backend/internal/pkg/validator"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// PromotionHandler handles promotion endpoints
+type PromotionHandler struct {
+	promotionSvc *service.PromotionService
+}
+
+// NewPromotionHandler creates a new PromotionHandler
+func NewPromotionHandler(promotionSvc *service.PromotionService) *PromotionHandler {
+	return &PromotionHandler{promotionSvc: promotionSvc}
+}
+
+// Create creates a promotion
+// POST /promotions
+func (h *PromotionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodePromotionInput(w, r)
+	if !ok {
+		return
+	}
+
+	username := currentUsername(r)
+	input.CreatedBy = &username
+
+	promo, err := h.promotionSvc.CreatePromotion(r.Context(), input)
+	if err != nil {
+		writePromotionError(w, err, "Failed to create promotion")
+		return
+	}
+
+	response.Created(w, "Promotion created successfully", promo)
+}
+
+// GetByID retrieves a promotion
+// GET /promotions/{id}
+func (h *PromotionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid promotion ID")
+		return
+	}
+
+	promo, err := h.promotionSvc.GetPromotion(r.Context(), id)
+	if err != nil {
+		writePromotionError(w, err, "Failed to get promotion")
+		return
+	}
+
+	response.OK(w, "Promotion retrieved", promo)
+}
+
+// List retrieves promotions with filtering
+// GET /promotions?search=&type=&product_id=&is_active=
+func (h *PromotionHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.PromotionFilter{Page: 1, PerPage: 20}
+	if search := query.Get("search"); search != "" {
+		filter.Search = &search
+	}
+	if promoType := query.Get("type"); promoType != "" {
+		t := domain.PromotionType(promoType)
+		filter.Type = &t
+	}
+	if productID := query.Get("product_id"); productID != "" {
+		if id, err := uuid.Parse(productID); err == nil {
+			filter.ProductID = &id
+		}
+	}
+	if isActive := query.Get("is_active"); isActive != "" {
+		active := isActive == "true"
+		filter.IsActive = &active
+	}
+	if page := query.Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if perPage := query.Get("per_page"); perPage != "" {
+		if pp, err := strconv.Atoi(perPage); err == nil {
+			filter.PerPage = pp
+		}
+	}
+
+	promotions, total, err := h.promotionSvc.ListPromotions(r.Context(), filter)
+	if err != nil {
+		response.InternalServerError(w, "Failed to list promotions")
+		return
+	}
+
+	meta := response.NewMeta(filter.Page, filter.PerPage, total)
+	response.SuccessWithMeta(w, http.StatusOK, "Promotions retrieved", promotions, meta)
+}
+
+// Update replaces a promotion's definition
+// PUT /promotions/{id}
+func (h *PromotionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid promotion ID")
+		return
+	}
+
+	input, ok := decodePromotionInput(w, r)
+	if !ok {
+		return
+	}
+
+	promo, err := h.promotionSvc.UpdatePromotion(r.Context(), id, input)
+	if err != nil {
+		writePromotionError(w, err, "Failed to update promotion")
+		return
+	}
+
+	response.OK(w, "Promotion updated", promo)
+}
+
+// Delete deactivates a promotion
+// DELETE /promotions/{id}
+func (h *PromotionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid promotion ID")
+		return
+	}
+
+	if err := h.promotionSvc.DeletePromotion(r.Context(), id); err != nil {
+		writePromotionError(w, err, "Failed to delete promotion")
+		return
+	}
+
+	response.OK(w, "Promotion deleted", nil)
+}
+
+// GetUsage summarizes the promo cost per promotion
+// GET /promotions/usage?date_from=&date_to=
+func (h *PromotionHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dateFrom, dateTo := parseDateRange(query.Get("date_from"), query.Get("date_to"))
+
+	usage, err := h.promotionSvc.GetUsage(r.Context(), dateFrom, dateTo)
+	if err != nil {
+		response.InternalServerError(w, "Failed to get promotion usage")
+		return
+	}
+
+	response.OK(w, "Promotion usage retrieved", usage)
+}
+
+func decodePromotionInput(w http.ResponseWriter, r *http.Request) (domain.PromotionInput, bool) {
+	var input domain.PromotionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return input, false
+	}
+
+	v := validator.New()
+	v.Required("name", input.Name, "Name is required")
+	v.InSlice("type", string(input.Type), []string{
+		string(domain.PromotionTypePercentage),
+		string(domain.PromotionTypeBundle),
+		string(domain.PromotionTypeBuyXGetY),
+	}, "Type must be percentage, bundle or buy_x_get_y")
+	v.Custom("scope", input.ProductID != nil || input.CategoryID != nil, "Product or category is required")
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return input, false
+	}
+	return input, true
+}
+
+func writePromotionError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case err == domain.ErrNotFound:
+		response.NotFound(w, "Promotion not found")
+	case errors.Is(err, domain.ErrInvalidInput):
+		response.BadRequest(w, err.Error())
+	default:
+		response.InternalServerError(w, fallback)
+	}
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/pkg/response"
+	"github.com/eveeze/warung-backend/internal/pkg/validator"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// PurchaseHandler handles purchase order endpoints
+type PurchaseHandler struct {
+	purchaseSvc *service.PurchaseService
+	cache       *service.CacheService
+	event       *service.EventService
+}
+
+// NewPurchaseHandler creates a new PurchaseHandler
+func NewPurchaseHandler(
+	purchaseSvc *service.PurchaseService,
+	cache *service.CacheService,
+	event *service.EventService,
+) *PurchaseHandler {
+	return &PurchaseHandler{
+		purchaseSvc: purchaseSvc,
+		cache:       cache,
+		event:       event,
+	}
+}
+
+// Create creates a draft purchase order
+// POST /purchases
+func (h *PurchaseHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input domain.PurchaseCreateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	v := validator.New()
+	v.Custom("items", len(input.Items) > 0, "At least one item is required")
+	for _, item := range input.Items {
+		v.Custom("items.product_id", item.ProductID != uuid.Nil, "Product ID is required")
+		v.Min("items.quantity", item.Quantity, 1, "Quantity must be at least 1")
+		v.NonNegative("items.cost_per_unit", item.CostPerUnit, "Cost per unit cannot be negative")
+	}
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+
+	input.CreatedBy = currentUsername(r)
+
+	purchase, err := h.purchaseSvc.CreatePurchase(r.Context(), input)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	response.Created(w, "Purchase order created", purchase)
+}
+
+// GetByID retrieves a purchase order with items
+// GET /purchases/{id}
+func (h *PurchaseHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid purchase ID")
+		return
+	}
+
+	purchase, err := h.purchaseSvc.GetPurchase(r.Context(), id)
+	if err == domain.ErrNotFound {
+		response.NotFound(w, "Purchase not found")
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "Failed to get purchase")
+		return
+	}
+
+	response.OK(w, "Purchase retrieved", purchase)
+}
+
+// List retrieves purchase orders with filtering
+// GET /purchases?supplier_id=&status=&date_from=&date_to=
+func (h *PurchaseHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.PurchaseFilter{Page: 1, PerPage: 20}
+	if supplierID := query.Get("supplier_id"); supplierID != "" {
+		if id, err := uuid.Parse(supplierID); err == nil {
+			filter.SupplierID = &id
+		}
+	}
+	if status := query.Get("status"); status != "" {
+		s := domain.PurchaseStatus(status)
+		filter.Status = &s
+	}
+	filter.DateFrom, filter.DateTo = parseDateRange(query.Get("date_from"), query.Get("date_to"))
+	if page := query.Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if perPage := query.Get("per_page"); perPage != "" {
+		if pp, err := strconv.Atoi(perPage); err == nil {
+			filter.PerPage = pp
+		}
+	}
+
+	purchases, total, err := h.purchaseSvc.ListPurchases(r.Context(), filter)
+	if err != nil {
+		response.InternalServerError(w, "Failed to list purchases")
+		return
+	}
+
+	meta := response.NewMeta(filter.Page, filter.PerPage, total)
+	response.SuccessWithMeta(w, http.StatusOK, "Purchases retrieved", purchases, meta)
+}
+
+// MarkOrdered marks a draft purchase order as sent to the supplier
+// POST /purchases/{id}/order
+func (h *PurchaseHandler) MarkOrdered(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid purchase ID")
+		return
+	}
+
+	purchase, err := h.purchaseSvc.MarkOrdered(r.Context(), id)
+	if err != nil {
+		writePurchaseError(w, err, "Failed to order purchase")
+		return
+	}
+
+	response.OK(w, "Purchase ordered", purchase)
+}
+
+// Receive receives a purchase order into stock
+// POST /purchases/{id}/receive
+func (h *PurchaseHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid purchase ID")
+		return
+	}
+
+	purchase, err := h.purchaseSvc.ReceivePurchase(r.Context(), id, currentUsername(r))
+	if err != nil {
+		writePurchaseError(w, err, "Failed to receive purchase")
+		return
+	}
+
+	h.publishStockUpdates(r, purchase)
+
+	response.OK(w, "Purchase received", purchase)
+}
+
+// Cancel cancels a purchase order that has not been received
+// POST /purchases/{id}/cancel
+func (h *PurchaseHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid purchase ID")
+		return
+	}
+
+	purchase, err := h.purchaseSvc.CancelPurchase(r.Context(), id)
+	if err != nil {
+		writePurchaseError(w, err, "Failed to cancel purchase")
+		return
+	}
+
+	response.OK(w, "Purchase cancelled", purchase)
+}
+
+// BulkRestock restocks several products at once as a received purchase
+// POST /inventory/restock/bulk
+func (h *PurchaseHandler) BulkRestock(w http.ResponseWriter, r *http.Request) {
+	var input domain.BulkRestockInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	v := validator.New()
+	v.Custom("items", len(input.Items) > 0, "At least one item is required")
+	for _, item := range input.Items {
+		v.Custom("items.product_id", item.ProductID != uuid.Nil, "Product ID is required")
+		v.Min("items.quantity", item.Quantity, 1, "Quantity must be at least 1")
+		v.NonNegative("items.cost_per_unit", item.CostPerUnit, "Cost per unit cannot be negative")
+	}
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+
+	username := currentUsername(r)
+	input.CreatedBy = &username
+
+	purchase, err := h.purchaseSvc.BulkRestock(r.Context(), input)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	h.publishStockUpdates(r, purchase)
+
+	response.Created(w, "Stock restocked successfully", purchase)
+}
+
+// GetSupplierSummary summarizes what was bought from a supplier
+// GET /suppliers/{id}/purchases/summary?date_from=&date_to=
+func (h *PurchaseHandler) GetSupplierSummary(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid supplier ID")
+		return
+	}
+
+	query := r.URL.Query()
+	dateFrom, dateTo := parseDateRange(query.Get("date_from"), query.Get("date_to"))
+
+	summary, err := h.purchaseSvc.GetSupplierSummary(r.Context(), id, dateFrom, dateTo)
+	if err == domain.ErrNotFound {
+		response.NotFound(w, "Supplier not found")
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "Failed to get supplier summary")
+		return
+	}
+
+	response.OK(w, "Supplier purchase summary retrieved", summary)
+}
+
+// publishStockUpdates invalidates product caches and pushes stock events for received items
+func (h *PurchaseHandler) publishStockUpdates(r *http.Request, purchase *domain.Purchase) {
+	_ = h.cache.InvalidatePattern(r.Context(), "products:list:*")
+	_ = h.cache.InvalidatePattern(r.Context(), "reports:*")
+
+	for _, item := range purchase.Items {
+		if item.Product == nil {
+			continue
+		}
+		h.event.Publish(service.EventStockUpdate, map[string]interface{}{
+			"product_id":    item.ProductID,
+			"current_stock": item.Product.CurrentStock,
+			"is_low_stock":  item.Product.CurrentStock <= item.Product.MinStockAlert,
+		})
+	}
+}
+
+func writePurchaseError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case domain.ErrNotFound:
+		response.NotFound(w, "Purchase not found")
+	case domain.ErrInvalidStatusTransition:
+		response.Conflict(w, "Purchase cannot move to the requested status")
+	default:
+		response.InternalServerError(w, fallback)
+	}
+}
@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/pkg/spreadsheet"
+)
+
+// Sheet layouts for the ?format=xlsx variants of the report and list endpoints.
+// Money columns use spreadsheet.Rupiah so they open formatted as Rupiah.
+
+func summarySheet(name string, rows ...[]any) spreadsheet.Sheet {
+	return spreadsheet.Sheet{Name: name, Rows: append([][]any{{"Item", "Value"}}, rows...)}
+}
+
+func dailySummaryRows(s DailyReportSummary) [][]any {
+	return [][]any{
+		{"Date", s.Date},
+		{"Total Sales", spreadsheet.Rupiah(s.TotalSales)},
+		{"Transactions", s.TotalTransactions},
+		{"Average Transaction", spreadsheet.Rupiah(s.AverageTransaction)},
+		{"Profit", spreadsheet.Rupiah(s.TotalProfit)},
+	}
+}
+
+func dailyReportSheets(report DailyReportResponse) []spreadsheet.Sheet {
+	hourly := [][]any{{"Hour", "Transactions", "Sales"}}
+	for _, h := range report.HourlySales {
+		hour, _ := h["hour"].(int)
+		sales, _ := h["sales"].(int64)
+		hourly = append(hourly, []any{fmt.Sprintf("%02d:00", hour), h["transactions"], spreadsheet.Rupiah(sales)})
+	}
+
+	top := [][]any{{"Product", "Quantity", "Sales"}}
+	for _, p := range report.TopProducts {
+		sales, _ := p["total_sales"].(int64)
+		top = append(top, []any{p["product_name"], p["total_quantity"], spreadsheet.Rupiah(sales)})
+	}
+
+	methods := [][]any{{"Method", "Transactions", "Amount", "Share (%)"}}
+	for _, m := range report.ByPaymentMethod {
+		methods = append(methods, []any{string(m.Method), m.Count, spreadsheet.Rupiah(m.Amount), m.Percentage})
+	}
+
+	return []spreadsheet.Sheet{
+		summarySheet("Summary", dailySummaryRows(report.Summary)...),
+		{Name: "Hourly Sales", Rows: hourly},
+		{Name: "Top Products", Rows: top},
+		{Name: "Payment Methods", Rows: methods},
+	}
+}
+
+func kasbonReportSheets(report *domain.KasbonReport) []spreadsheet.Sheet {
+	customers := [][]any{{"Customer", "Total Debt", "Total Payment", "Balance", "Credit Limit", "Remaining Credit", "Last Transaction"}}
+	for _, s := range report.Summaries {
+		customers = append(customers, []any{
+			s.CustomerName,
+			spreadsheet.Rupiah(s.TotalDebt),
+			spreadsheet.Rupiah(s.TotalPayment),
+			spreadsheet.Rupiah(s.CurrentBalance),
+			spreadsheet.Rupiah(s.CreditLimit),
+			spreadsheet.Rupiah(s.RemainingCredit),
+			timeCell(s.LastTransactionAt),
+		})
+	}
+
+	return []spreadsheet.Sheet{
+		summarySheet("Summary",
+			[]any{"Total Outstanding", spreadsheet.Rupiah(report.TotalOutstanding)},
+			[]any{"Customers", report.TotalCustomers},
+			[]any{"Customers With Debt", report.CustomersWithDebt},
+		),
+		{Name: "Customers", Rows: customers},
+	}
+}
+
+func inventoryReportSheets(report *domain.StockReport) []spreadsheet.Sheet {
+	lowStock := [][]any{{"Product", "Barcode", "Unit", "Stock", "Min Stock", "Deficit", "Cost Price"}}
+	for _, l := range report.LowStockProducts {
+		p := l.Product
+		lowStock = append(lowStock, []any{
+			p.Name, stringCell(p.Barcode), p.Unit, p.CurrentStock, p.MinStockAlert, l.DeficitAmount, spreadsheet.Rupiah(p.CostPrice),
+		})
+	}
+
+	return []spreadsheet.Sheet{
+		summarySheet("Summary",
+			[]any{"Products", report.TotalProducts},
+			[]any{"Stock Value", spreadsheet.Rupiah(report.TotalStockValue)},
+			[]any{"Low Stock", report.LowStockCount},
+			[]any{"Out Of Stock", report.OutOfStockCount},
+		),
+		{Name: "Low Stock", Rows: lowStock},
+	}
+}
+
+func dashboardSheets(d Dashboard) []spreadsheet.Sheet {
+	rows := append(dailySummaryRows(d.Today),
+		[]any{"Outstanding Kasbon", spreadsheet.Rupiah(d.TotalOutstanding)},
+		[]any{"Low Stock", d.LowStockCount},
+		[]any{"Out Of Stock", d.OutOfStockCount},
+	)
+	return []spreadsheet.Sheet{summarySheet("Dashboard", rows...)}
+}
+
+func profitLossSheets(report *domain.ProfitLossReport) []spreadsheet.Sheet {
+	expenses := [][]any{{"Category", "Amount", "Share (%)"}}
+	for _, e := range report.TopExpenses {
+		expenses = append(expenses, []any{e.CategoryName, spreadsheet.Rupiah(e.Amount), e.Percentage})
+	}
+
+	methods := [][]any{{"Method", "Amount"}}
+	for method, amount := range report.ByPaymentMethod {
+		methods = append(methods, []any{method, spreadsheet.Rupiah(amount)})
+	}
+	slices.SortFunc(methods[1:], func(a, b []any) int { return cmp.Compare(b[1].(spreadsheet.Rupiah), a[1].(spreadsheet.Rupiah)) })
+
+	return []spreadsheet.Sheet{
+		summarySheet("Profit & Loss",
+			[]any{"From", report.DateFrom.Format("2006-01-02")},
+			[]any{"To", report.DateTo.Format("2006-01-02")},
+			[]any{"Revenue", spreadsheet.Rupiah(report.TotalRevenue)},
+			[]any{"Tax Collected", spreadsheet.Rupiah(report.TotalTax)},
+			[]any{"COGS", spreadsheet.Rupiah(report.TotalCOGS)},
+			[]any{"Gross Profit", spreadsheet.Rupiah(report.GrossProfit)},
+			[]any{"Expenses", spreadsheet.Rupiah(report.TotalExpenses)},
+			[]any{"Net Profit", spreadsheet.Rupiah(report.NetProfit)},
+			[]any{"Margin (%)", report.ProfitMargin},
+		),
+		{Name: "Expenses", Rows: expenses},
+		{Name: "Payment Methods", Rows: methods},
+	}
+}
+
+func salesByMethodSheets(report *domain.SalesByMethodReport) []spreadsheet.Sheet {
+	methods := [][]any{{"Method", "Transactions", "Amount", "Share (%)"}}
+	for _, m := range report.Methods {
+		methods = append(methods, []any{string(m.Method), m.Count, spreadsheet.Rupiah(m.Amount), m.Percentage})
+	}
+
+	return []spreadsheet.Sheet{
+		summarySheet("Summary",
+			[]any{"From", report.DateFrom.Format("2006-01-02")},
+			[]any{"To", report.DateTo.Format("2006-01-02")},
+			[]any{"Total Sales", spreadsheet.Rupiah(report.TotalSales)},
+		),
+		{Name: "Payment Methods", Rows: methods},
+	}
+}
+
+func dailySummarySheets(summaries []domain.DailySummary) []spreadsheet.Sheet {
+	rows := [][]any{{"Date", "Transactions", "Sales", "Tax", "Cost", "Profit", "Cash", "Kasbon", "Transfer", "QRIS", "Kasbon Given", "Kasbon Paid"}}
+	for _, s := range summaries {
+		rows = append(rows, []any{
+			s.Date.Format("2006-01-02"), s.TotalTransactions,
+			spreadsheet.Rupiah(s.TotalSales), spreadsheet.Rupiah(s.TotalTax), spreadsheet.Rupiah(s.TotalCost), spreadsheet.Rupiah(s.TotalProfit),
+			spreadsheet.Rupiah(s.CashSales), spreadsheet.Rupiah(s.KasbonSales), spreadsheet.Rupiah(s.TransferSales), spreadsheet.Rupiah(s.QRISSales),
+			spreadsheet.Rupiah(s.TotalKasbonGiven), spreadsheet.Rupiah(s.TotalKasbonPaid),
+		})
+	}
+	return []spreadsheet.Sheet{{Name: "Daily Summaries", Rows: rows}}
+}
+
+func salesTrendSheets(report *domain.SalesTrendReport) []spreadsheet.Sheet {
+	period := func(name string, p domain.PeriodTotals) []any {
+		return []any{
+			name, p.DateFrom.Format("2006-01-02"), p.DateTo.Format("2006-01-02"), p.Transactions,
+			spreadsheet.Rupiah(p.Sales), spreadsheet.Rupiah(p.Cost), spreadsheet.Rupiah(p.Profit), spreadsheet.Rupiah(p.AverageTransaction),
+		}
+	}
+
+	summary := [][]any{
+		{"Period", "From", "To", "Transactions", "Sales", "Cost", "Profit", "Average Transaction"},
+		period("Current", report.Current),
+		period("Previous", report.Previous),
+		period("Last Year", report.LastYear),
+		{},
+		{"Growth (%)", "Sales", "Transactions", "Profit"},
+		{"vs Previous", floatCell(report.VsPrevious.Sales), floatCell(report.VsPrevious.Transactions), floatCell(report.VsPrevious.Profit)},
+		{"vs Last Year", floatCell(report.VsLastYear.Sales), floatCell(report.VsLastYear.Transactions), floatCell(report.VsLastYear.Profit)},
+	}
+
+	buckets := [][]any{{"Start", "Transactions", "Sales", "Cost", "Profit"}}
+	for _, b := range report.Buckets {
+		buckets = append(buckets, []any{
+			b.Start.Format("2006-01-02"), b.Transactions, spreadsheet.Rupiah(b.Sales), spreadsheet.Rupiah(b.Cost), spreadsheet.Rupiah(b.Profit),
+		})
+	}
+
+	categories := [][]any{{"Category", "Quantity", "Sales", "Profit", "Share (%)", "Previous Sales", "Growth (%)"}}
+	for _, c := range report.ByCategory {
+		categories = append(categories, []any{
+			c.CategoryName, c.Quantity, spreadsheet.Rupiah(c.Sales), spreadsheet.Rupiah(c.Profit), c.Share,
+			spreadsheet.Rupiah(c.PreviousSales), floatCell(c.Growth),
+		})
+	}
+
+	cashiers := [][]any{{"Cashier", "Transactions", "Sales", "Profit", "Average Transaction", "Share (%)", "Previous Sales", "Growth (%)"}}
+	for _, c := range report.ByCashier {
+		cashiers = append(cashiers, []any{
+			c.CashierName, c.Transactions, spreadsheet.Rupiah(c.Sales), spreadsheet.Rupiah(c.Profit), spreadsheet.Rupiah(c.AverageTransaction),
+			c.Share, spreadsheet.Rupiah(c.PreviousSales), floatCell(c.Growth),
+		})
+	}
+
+	return []spreadsheet.Sheet{
+		{Name: "Summary", Rows: summary},
+		{Name: "Trend", Rows: buckets},
+		{Name: "Categories", Rows: categories},
+		{Name: "Cashiers", Rows: cashiers},
+	}
+}
+
+func productPerformanceSheets(report *domain.ProductPerformanceReport) []spreadsheet.Sheet {
+	classes := [][]any{{"Class", "Products", "Revenue", "Share (%)"}}
+	for _, c := range report.Classes {
+		classes = append(classes, []any{string(c.Class), c.Products, spreadsheet.Rupiah(c.Revenue), c.Share})
+	}
+	classes = append(classes, []any{"Total", len(report.Products), spreadsheet.Rupiah(report.TotalRevenue)})
+
+	products := [][]any{{
+		"Class", "Product", "Quantity Sold", "Revenue", "Cost", "Profit", "Margin (%)", "Share (%)", "Cumulative (%)",
+		"Opening Stock", "Received", "Stock Sold", "Closing Stock", "Current Stock", "Stock Value",
+		"Sell-Through (%)", "Daily Sales", "Days of Cover",
+	}}
+	for _, p := range report.Products {
+		products = append(products, []any{
+			string(p.Class), p.ProductName, p.QuantitySold, spreadsheet.Rupiah(p.Revenue), spreadsheet.Rupiah(p.Cost),
+			spreadsheet.Rupiah(p.Profit), floatCell(p.Margin), p.RevenueShare, p.CumulativeShare,
+			p.OpeningStock, p.Received, p.StockSold, p.ClosingStock, p.CurrentStock, spreadsheet.Rupiah(p.StockValue),
+			floatCell(p.SellThrough), p.AverageDailySales, floatCell(p.DaysOfCover),
+		})
+	}
+
+	margins := [][]any{{"Rank", "Product", "Revenue", "Profit", "Margin (%)"}}
+	for _, m := range report.MarginRanking {
+		margins = append(margins, []any{m.Rank, m.ProductName, spreadsheet.Rupiah(m.Revenue), spreadsheet.Rupiah(m.Profit), m.Margin})
+	}
+
+	deadStock := [][]any{{"Product", "Current Stock", "Cost Price", "Stock Value", "Last Sold", "Days Since Last Sale"}}
+	for _, d := range report.DeadStock {
+		var daysSince any
+		if d.DaysSinceLastSale != nil {
+			daysSince = *d.DaysSinceLastSale
+		}
+		deadStock = append(deadStock, []any{
+			d.ProductName, d.CurrentStock, spreadsheet.Rupiah(d.CostPrice), spreadsheet.Rupiah(d.StockValue), timeCell(d.LastSoldAt), daysSince,
+		})
+	}
+	deadStock = append(deadStock, []any{"Total", nil, nil, spreadsheet.Rupiah(report.DeadStockValue)})
+
+	return []spreadsheet.Sheet{
+		{Name: "ABC Classes", Rows: classes},
+		{Name: "Products", Rows: products},
+		{Name: "Margins", Rows: margins},
+		{Name: "Dead Stock", Rows: deadStock},
+	}
+}
+
+func movementSheets(product *domain.Product, movements []domain.StockMovement) []spreadsheet.Sheet {
+	in, out := 0, 0
+	details := [][]any{{"Date", "Type", "Quantity", "Stock Before", "Stock After", "Cost Per Unit", "Batch", "Expiry", "Reference", "Notes", "Created By"}}
+	for _, m := range movements {
+		if m.Quantity > 0 {
+			in += m.Quantity
+		} else {
+			out -= m.Quantity
+		}
+
+		var cost any
+		if m.CostPerUnit != nil {
+			cost = spreadsheet.Rupiah(*m.CostPerUnit)
+		}
+		var expiry any
+		if m.ExpiryDate != nil {
+			expiry = m.ExpiryDate.Format("2006-01-02")
+		}
+		details = append(details, []any{
+			m.CreatedAt, string(m.Type), m.Quantity, m.StockBefore, m.StockAfter, cost,
+			stringCell(m.BatchNumber), expiry, stringCell(m.ReferenceType), stringCell(m.Notes), stringCell(m.CreatedBy),
+		})
+	}
+
+	return []spreadsheet.Sheet{
+		summarySheet("Summary",
+			[]any{"Product", product.Name},
+			[]any{"Barcode", stringCell(product.Barcode)},
+			[]any{"Current Stock", product.CurrentStock},
+			[]any{"Movements", len(movements)},
+			[]any{"Total In", in},
+			[]any{"Total Out", out},
+		),
+		{Name: "Movements", Rows: details},
+	}
+}
+
+func cashFlowSheets(records []domain.CashFlowRecord) []spreadsheet.Sheet {
+	var income, expense int64
+	details := [][]any{{"Date", "Type", "Category", "Amount", "Description", "Reference", "Created By"}}
+	for _, rec := range records {
+		if rec.Type == domain.CashFlowTypeIncome {
+			income += rec.Amount
+		} else {
+			expense += rec.Amount
+		}
+
+		var category any
+		if rec.Category != nil {
+			category = rec.Category.Name
+		}
+		details = append(details, []any{
+			rec.CreatedAt, string(rec.Type), category, spreadsheet.Rupiah(rec.Amount),
+			stringCell(rec.Description), stringCell(rec.ReferenceType), stringCell(rec.CreatedBy),
+		})
+	}
+
+	return []spreadsheet.Sheet{
+		summarySheet("Summary",
+			[]any{"Records", len(records)},
+			[]any{"Income", spreadsheet.Rupiah(income)},
+			[]any{"Expense", spreadsheet.Rupiah(expense)},
+			[]any{"Net", spreadsheet.Rupiah(income - expense)},
+		),
+		{Name: "Cash Flows", Rows: details},
+	}
+}
+
+// stringCell leaves a cell empty for a missing value
+func stringCell(s *string) any {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func floatCell(f *float64) any {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
+
+func timeCell(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
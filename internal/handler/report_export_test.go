@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/pkg/spreadsheet"
+)
+
+// jsonFields returns v as the JSON object the report endpoint sends
+func jsonFields(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal report: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Failed to unmarshal report: %v", err)
+	}
+	return fields
+}
+
+// number returns a numeric cell as JSON would decode it
+func number(t *testing.T, cell any) float64 {
+	t.Helper()
+	switch v := cell.(type) {
+	case spreadsheet.Rupiah:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	t.Fatalf("Expected a numeric cell, got %T %v", cell, cell)
+	return 0
+}
+
+// summaryValues maps the Item column of a summary sheet to its Value column
+func summaryValues(t *testing.T, sheet spreadsheet.Sheet) map[string]any {
+	t.Helper()
+	if !slices.Equal(sheet.Rows[0], []any{"Item", "Value"}) {
+		t.Fatalf("Expected an Item/Value header on %s, got %v", sheet.Name, sheet.Rows[0])
+	}
+	values := make(map[string]any, len(sheet.Rows)-1)
+	for _, row := range sheet.Rows[1:] {
+		values[row[0].(string)] = row[1]
+	}
+	return values
+}
+
+func TestProfitLossSheets_MatchReport(t *testing.T) {
+	report := &domain.ProfitLossReport{
+		DateFrom:        time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		DateTo:          time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC),
+		TotalRevenue:    1_000_000,
+		TotalTax:        110_000,
+		TotalCOGS:       700_000,
+		GrossProfit:     300_000,
+		TotalExpenses:   50_000,
+		NetProfit:       250_000,
+		ProfitMargin:    25,
+		ByPaymentMethod: map[string]int64{"cash": 600_000, "qris": 510_000},
+		TopExpenses:     []domain.ExpenseBreakdown{{CategoryName: "Listrik", Amount: 50_000, Percentage: 100}},
+	}
+	fields := jsonFields(t, report)
+	sheets := profitLossSheets(report)
+
+	if len(sheets) != 3 {
+		t.Fatalf("Expected 3 sheets, got %d", len(sheets))
+	}
+	summary := summaryValues(t, sheets[0])
+	if summary["From"] != "2024-05-01" || summary["To"] != "2024-05-31" {
+		t.Errorf("Expected the period 2024-05-01 to 2024-05-31, got %v to %v", summary["From"], summary["To"])
+	}
+	for label, key := range map[string]string{
+		"Revenue":       "total_revenue",
+		"Tax Collected": "total_tax",
+		"COGS":          "total_cogs",
+		"Gross Profit":  "gross_profit",
+		"Expenses":      "total_expenses",
+		"Net Profit":    "net_profit",
+		"Margin (%)":    "profit_margin",
+	} {
+		if got := number(t, summary[label]); got != fields[key] {
+			t.Errorf("Expected %s to match %s %v, got %v", label, key, fields[key], got)
+		}
+	}
+	if _, ok := summary["Revenue"].(spreadsheet.Rupiah); !ok {
+		t.Errorf("Expected Revenue formatted as Rupiah, got %T", summary["Revenue"])
+	}
+
+	expenses := sheets[1].Rows
+	if !slices.Equal(expenses[0], []any{"Category", "Amount", "Share (%)"}) || len(expenses) != 2 {
+		t.Fatalf("Expected one expense under its header, got %v", expenses)
+	}
+	if expenses[1][0] != "Listrik" || number(t, expenses[1][1]) != 50_000 {
+		t.Errorf("Expected Listrik at 50000, got %v", expenses[1])
+	}
+
+	// Payment methods are listed largest first
+	methods := sheets[2].Rows
+	want := [][]any{{"Method", "Amount"}, {"cash", spreadsheet.Rupiah(600_000)}, {"qris", spreadsheet.Rupiah(510_000)}}
+	if !slices.EqualFunc(methods, want, slices.Equal) {
+		t.Errorf("Expected payment methods %v, got %v", want, methods)
+	}
+}
+
+func TestDailySummarySheets_MatchReport(t *testing.T) {
+	summaries := []domain.DailySummary{{
+		Date:              time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC),
+		TotalTransactions: 12,
+		TotalSales:        250_000,
+		TotalTax:          27_500,
+		TotalCost:         180_000,
+		TotalProfit:       70_000,
+		TotalKasbonGiven:  20_000,
+		TotalKasbonPaid:   5_000,
+		CashSales:         200_000,
+		KasbonSales:       20_000,
+		TransferSales:     10_000,
+		QRISSales:         47_500,
+	}}
+	rows := dailySummarySheets(summaries)[0].Rows
+
+	header := []any{"Date", "Transactions", "Sales", "Tax", "Cost", "Profit", "Cash", "Kasbon", "Transfer", "QRIS", "Kasbon Given", "Kasbon Paid"}
+	if !slices.Equal(rows[0], header) {
+		t.Fatalf("Expected header %v, got %v", header, rows[0])
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected one row per day, got %d", len(rows)-1)
+	}
+
+	fields := jsonFields(t, summaries[0])
+	if rows[1][0] != "2024-05-15" {
+		t.Errorf("Expected the date 2024-05-15, got %v", rows[1][0])
+	}
+	keys := []string{
+		"total_transactions", "total_sales", "total_tax", "total_cost", "total_profit",
+		"cash_sales", "kasbon_sales", "transfer_sales", "qris_sales", "total_kasbon_given", "total_kasbon_paid",
+	}
+	for i, key := range keys {
+		if got := number(t, rows[1][i+1]); got != fields[key] {
+			t.Errorf("Expected %s to match %s %v, got %v", header[i+1], key, fields[key], got)
+		}
+	}
+}
+
+func TestCashFlowSheets_Totals(t *testing.T) {
+	at := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	description := "Bayar listrik"
+	records := []domain.CashFlowRecord{
+		{ID: uuid.New(), Type: domain.CashFlowTypeIncome, Amount: 100_000, CreatedAt: at},
+		{ID: uuid.New(), Type: domain.CashFlowTypeIncome, Amount: 25_000, CreatedAt: at},
+		{ID: uuid.New(), Type: domain.CashFlowTypeExpense, Amount: 40_000, Description: &description, CreatedAt: at,
+			Category: &domain.CashFlowCategory{Name: "Listrik"}},
+	}
+	sheets := cashFlowSheets(records)
+
+	summary := summaryValues(t, sheets[0])
+	for label, want := range map[string]float64{"Records": 3, "Income": 125_000, "Expense": 40_000, "Net": 85_000} {
+		if got := number(t, summary[label]); got != want {
+			t.Errorf("Expected %s %v, got %v", label, want, got)
+		}
+	}
+
+	details := sheets[1].Rows
+	if len(details) != 4 {
+		t.Fatalf("Expected one row per record, got %d", len(details)-1)
+	}
+	want := []any{at, "expense", "Listrik", spreadsheet.Rupiah(40_000), "Bayar listrik", nil, nil}
+	if !slices.Equal(details[3], want) {
+		t.Errorf("Expected %v, got %v", want, details[3])
+	}
+}
+
+func TestProductPerformanceSheets_Totals(t *testing.T) {
+	report := &domain.ProductPerformanceReport{
+		TotalRevenue: 900_000,
+		Classes: []domain.ProductClassSummary{
+			{Class: "A", Products: 1, Revenue: 700_000, Share: 77.78},
+			{Class: "B", Products: 1, Revenue: 200_000, Share: 22.22},
+		},
+		Products: []domain.ProductPerformance{
+			{ProductName: "Beras", Class: "A", Revenue: 700_000},
+			{ProductName: "Gula", Class: "B", Revenue: 200_000},
+		},
+		DeadStockValue: 45_000,
+		DeadStock: []domain.DeadStockItem{
+			{ProductName: "Sarden", CurrentStock: 3, CostPrice: 15_000, StockValue: 45_000},
+		},
+	}
+	fields := jsonFields(t, report)
+	sheets := productPerformanceSheets(report)
+
+	classes := sheets[0].Rows
+	total := classes[len(classes)-1]
+	if total[0] != "Total" || number(t, total[1]) != 2 || number(t, total[2]) != fields["total_revenue"] {
+		t.Errorf("Expected a total of 2 products and %v revenue, got %v", fields["total_revenue"], total)
+	}
+
+	products := sheets[1].Rows
+	if len(products) != 3 || products[1][1] != "Beras" || number(t, products[1][3]) != 700_000 {
+		t.Errorf("Expected Beras first at 700000, got %v", products[1:])
+	}
+
+	deadStock := sheets[3].Rows
+	total = deadStock[len(deadStock)-1]
+	if total[0] != "Total" || number(t, total[3]) != fields["dead_stock_value"] {
+		t.Errorf("Expected a dead stock total of %v, got %v", fields["dead_stock_value"], total)
+	}
+}
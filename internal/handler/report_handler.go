@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/eveeze/warung-backend/internal/domain"
 	"github.com/eveeze/warung-backend/internal/pkg/response"
 	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
 )
 
 // ReportHandler handles report endpoints
@@ -14,6 +19,8 @@ type ReportHandler struct {
 	kasbonRepo      *repository.KasbonRepository
 	inventoryRepo   *repository.InventoryRepository
 	productRepo     *repository.ProductRepository
+	auditRepo       *repository.AuditRepository
+	reportSvc       *service.ReportService
 }
 
 // NewReportHandler creates a new ReportHandler
@@ -22,12 +29,16 @@ func NewReportHandler(
 	kasbonRepo *repository.KasbonRepository,
 	inventoryRepo *repository.InventoryRepository,
 	productRepo *repository.ProductRepository,
+	auditRepo *repository.AuditRepository,
+	reportSvc *service.ReportService,
 ) *ReportHandler {
 	return &ReportHandler{
 		transactionRepo: transactionRepo,
 		kasbonRepo:      kasbonRepo,
 		inventoryRepo:   inventoryRepo,
 		productRepo:     productRepo,
+		auditRepo:       auditRepo,
+		reportSvc:       reportSvc,
 	}
 }
 
@@ -46,24 +57,42 @@ type DailyReportResponse struct {
 	Summary      DailyReportSummary       `json:"summary"`
 	HourlySales  []map[string]interface{} `json:"hourly_sales"`
 	TopProducts  []map[string]interface{} `json:"top_products"`
+	ByPaymentMethod []domain.MethodBreakdown `json:"by_payment_method"`
 }
 
-// GetDailyReport returns daily sales summary with details
+// dailyReportSummary converts a day's totals to the summary block of the daily report and dashboard
+func dailyReportSummary(s *domain.DailySummary) DailyReportSummary {
+	summary := DailyReportSummary{
+		Date:              s.Date.Format("2006-01-02"),
+		TotalSales:        s.TotalSales,
+		TotalTransactions: s.TotalTransactions,
+		EstimatedProfit:   s.TotalProfit,
+		TotalProfit:       s.TotalProfit,
+	}
+	if s.TotalTransactions > 0 {
+		summary.AverageTransaction = s.TotalSales / int64(s.TotalTransactions)
+	}
+	return summary
+}
+
+// GetDailyReport returns daily sales summary with details.
+// ?format=xlsx downloads it as a workbook instead.
 func (h *ReportHandler) GetDailyReport(w http.ResponseWriter, r *http.Request) {
 	dateStr := r.URL.Query().Get("date")
 	if dateStr == "" {
 		dateStr = time.Now().Format("2006-01-02")
 	}
-
-	sales, count, err := h.transactionRepo.GetDailySales(r.Context(), dateStr)
+	day, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
-		response.InternalServerError(w, "Failed to get daily sales")
+		response.BadRequest(w, "date must be YYYY-MM-DD")
 		return
 	}
 
-	profit, err := h.transactionRepo.GetDailyProfit(r.Context(), dateStr)
+	// Totals come from the rollup for closed days and live for today
+	summary, err := h.reportSvc.DailySummary(r.Context(), day)
 	if err != nil {
-		profit = 0
+		response.InternalServerError(w, "Failed to get daily sales")
+		return
 	}
 
 	hourly, err := h.transactionRepo.GetHourlySales(r.Context(), dateStr)
@@ -71,33 +100,35 @@ func (h *ReportHandler) GetDailyReport(w http.ResponseWriter, r *http.Request) {
 		hourly = []map[string]interface{}{}
 	}
 
-	topProducts, err := h.transactionRepo.GetTopProducts(r.Context(), dateStr, 5)
+	// group_by=parent rolls variants up into their parent product
+	byParent := r.URL.Query().Get("group_by") == "parent"
+	topProducts, err := h.transactionRepo.GetTopProducts(r.Context(), dateStr, 5, byParent)
 	if err != nil {
 		topProducts = []map[string]interface{}{}
 	}
 
-	avgTransaction := int64(0)
-	if count > 0 {
-		avgTransaction = sales / int64(count)
+	// Mixed payments are split across the tenders that paid them; refunds are netted out like the summary
+	byMethod, err := h.transactionRepo.GetSalesByMethod(r.Context(), dateStr, dateStr, true)
+	if err != nil {
+		byMethod = []domain.MethodBreakdown{}
 	}
 
 	report := DailyReportResponse{
-		Summary: DailyReportSummary{
-			Date:               dateStr,
-			TotalSales:         sales,
-			TotalTransactions:  count,
-			EstimatedProfit:    profit,
-			AverageTransaction: avgTransaction,
-			TotalProfit:        profit,
-		},
-		HourlySales: hourly,
-		TopProducts: topProducts,
+		Summary:         dailyReportSummary(summary),
+		HourlySales:     hourly,
+		TopProducts:     topProducts,
+		ByPaymentMethod: byMethod,
+	}
+
+	if wantsXLSX(r) {
+		writeWorkbook(w, r, h.auditRepo, "daily-report-"+dateStr, dailyReportSheets(report), "report", nil)
+		return
 	}
 
 	response.OK(w, "Daily report retrieved", report)
 }
 
-// GetKasbonReport returns kasbon summary
+// GetKasbonReport returns kasbon summary (?format=xlsx for a workbook)
 func (h *ReportHandler) GetKasbonReport(w http.ResponseWriter, r *http.Request) {
 	report, err := h.kasbonRepo.GetReport(r.Context())
 	if err != nil {
@@ -105,10 +136,15 @@ func (h *ReportHandler) GetKasbonReport(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if wantsXLSX(r) {
+		writeWorkbook(w, r, h.auditRepo, "kasbon-report-"+time.Now().Format("20060102"), kasbonReportSheets(report), "report", nil)
+		return
+	}
+
 	response.OK(w, "Kasbon report retrieved", report)
 }
 
-// GetInventoryReport returns stock inventory summary
+// GetInventoryReport returns stock inventory summary (?format=xlsx for a workbook)
 func (h *ReportHandler) GetInventoryReport(w http.ResponseWriter, r *http.Request) {
 	report, err := h.inventoryRepo.GetStockReport(r.Context())
 	if err != nil {
@@ -119,6 +155,11 @@ func (h *ReportHandler) GetInventoryReport(w http.ResponseWriter, r *http.Reques
 	lowStock, _ := h.productRepo.GetLowStockProducts(r.Context())
 	report.LowStockProducts = lowStock
 
+	if wantsXLSX(r) {
+		writeWorkbook(w, r, h.auditRepo, "inventory-report-"+time.Now().Format("20060102"), inventoryReportSheets(report), "report", nil)
+		return
+	}
+
 	response.OK(w, "Inventory report retrieved", report)
 }
 
@@ -130,30 +171,16 @@ type Dashboard struct {
 	OutOfStockCount int                `json:"out_of_stock_count"`
 }
 
-// GetDashboard returns dashboard summary
+// GetDashboard returns dashboard summary (?format=xlsx for a workbook)
 func (h *ReportHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
-	today := time.Now().Format("2006-01-02")
-
-	sales, count, _ := h.transactionRepo.GetDailySales(r.Context(), today)
-	profit, _ := h.transactionRepo.GetDailyProfit(r.Context(), today)
-
 	kasbonReport, _ := h.kasbonRepo.GetReport(r.Context())
 	stockReport, _ := h.inventoryRepo.GetStockReport(r.Context())
 
-	avgTransaction := int64(0)
-	if count > 0 {
-		avgTransaction = sales / int64(count)
-	}
-
-	dashboard := Dashboard{
-		Today: DailyReportSummary{
-			Date:               today,
-			TotalSales:         sales,
-			TotalTransactions:  count,
-			EstimatedProfit:    profit,
-			AverageTransaction: avgTransaction,
-			TotalProfit:        profit,
-		},
+	dashboard := Dashboard{}
+	if today, err := h.reportSvc.DailySummary(r.Context(), time.Now()); err == nil {
+		dashboard.Today = dailyReportSummary(today)
+	} else {
+		dashboard.Today.Date = time.Now().Format("2006-01-02")
 	}
 
 	if kasbonReport != nil {
@@ -164,5 +191,207 @@ func (h *ReportHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
 		dashboard.OutOfStockCount = stockReport.OutOfStockCount
 	}
 
+	if wantsXLSX(r) {
+		writeWorkbook(w, r, h.auditRepo, "dashboard-"+time.Now().Format("20060102"), dashboardSheets(dashboard), "report", nil)
+		return
+	}
+
 	response.OK(w, "Dashboard retrieved", dashboard)
 }
+
+// reportDateRange reads date_from and date_to (YYYY-MM-DD), defaulting to the current month so far
+func reportDateRange(r *http.Request) (time.Time, time.Time, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from, to := today.AddDate(0, 0, 1-today.Day()), today
+
+	if s := r.URL.Query().Get("date_from"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return from, to, fmt.Errorf("date_from must be YYYY-MM-DD")
+		}
+		from = t
+	}
+	if s := r.URL.Query().Get("date_to"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return from, to, fmt.Errorf("date_to must be YYYY-MM-DD")
+		}
+		to = t
+	}
+	return from, to, nil
+}
+
+// GetProfitLoss returns revenue, COGS, expenses and net profit over a date range
+// GET /reports/profit-loss?date_from=&date_to=&format=xlsx
+func (h *ReportHandler) GetProfitLoss(w http.ResponseWriter, r *http.Request) {
+	from, to, err := reportDateRange(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	report, err := h.reportSvc.ProfitLoss(r.Context(), from, to)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		response.InternalServerError(w, "Failed to get profit and loss report")
+		return
+	}
+
+	if wantsXLSX(r) {
+		name := fmt.Sprintf("profit-loss-%s-%s", from.Format("20060102"), to.Format("20060102"))
+		writeWorkbook(w, r, h.auditRepo, name, profitLossSheets(report), "report", nil)
+		return
+	}
+
+	response.OK(w, "Profit and loss report retrieved", report)
+}
+
+// GetSalesByMethod returns sales per payment method over a date range
+// GET /reports/sales-by-method?date_from=&date_to=&format=xlsx
+func (h *ReportHandler) GetSalesByMethod(w http.ResponseWriter, r *http.Request) {
+	from, to, err := reportDateRange(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	report, err := h.reportSvc.SalesByMethod(r.Context(), from, to)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		response.InternalServerError(w, "Failed to get sales by method report")
+		return
+	}
+
+	if wantsXLSX(r) {
+		name := fmt.Sprintf("sales-by-method-%s-%s", from.Format("20060102"), to.Format("20060102"))
+		writeWorkbook(w, r, h.auditRepo, name, salesByMethodSheets(report), "report", nil)
+		return
+	}
+
+	response.OK(w, "Sales by method report retrieved", report)
+}
+
+// GetDailySummaries returns the totals of each day in a range, from the rollup for closed days
+// and live for today
+// GET /reports/daily-summaries?date_from=&date_to=&format=xlsx
+func (h *ReportHandler) GetDailySummaries(w http.ResponseWriter, r *http.Request) {
+	from, to, err := reportDateRange(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	summaries, err := h.reportSvc.DailySummaries(r.Context(), from, to)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		response.InternalServerError(w, "Failed to get daily summaries")
+		return
+	}
+
+	if wantsXLSX(r) {
+		name := fmt.Sprintf("daily-summaries-%s-%s", from.Format("20060102"), to.Format("20060102"))
+		writeWorkbook(w, r, h.auditRepo, name, dailySummarySheets(summaries), "report", nil)
+		return
+	}
+
+	response.OK(w, "Daily summaries retrieved", summaries)
+}
+
+// RebuildDailySummaries rolls up the closed days of a range again, e.g. after correcting data by hand
+// POST /reports/daily-summaries/rebuild?date_from=&date_to=
+func (h *ReportHandler) RebuildDailySummaries(w http.ResponseWriter, r *http.Request) {
+	from, to, err := reportDateRange(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	rebuilt, err := h.reportSvc.RebuildDailySummaries(r.Context(), from, to)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		response.InternalServerError(w, "Failed to rebuild daily summaries")
+		return
+	}
+
+	response.OK(w, "Daily summaries rebuilt", map[string]int{"days": rebuilt})
+}
+
+// GetSalesTrend returns sales over a range in day, week or month buckets with period comparisons
+// GET /reports/sales-trend?date_from=&date_to=&interval=day|week|month&format=xlsx
+func (h *ReportHandler) GetSalesTrend(w http.ResponseWriter, r *http.Request) {
+	from, to, err := reportDateRange(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	interval := domain.TrendInterval(r.URL.Query().Get("interval"))
+	if interval == "" {
+		interval = domain.TrendIntervalDay
+	}
+
+	report, err := h.reportSvc.SalesTrend(r.Context(), from, to, interval)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		response.InternalServerError(w, "Failed to get sales trend")
+		return
+	}
+
+	if wantsXLSX(r) {
+		name := fmt.Sprintf("sales-trend-%s-%s", from.Format("20060102"), to.Format("20060102"))
+		writeWorkbook(w, r, h.auditRepo, name, salesTrendSheets(report), "report", nil)
+		return
+	}
+
+	response.OK(w, "Sales trend retrieved", report)
+}
+
+// GetProductPerformance returns ABC classes, margin ranking, sell-through and dead stock per product
+// GET /reports/product-performance?date_from=&date_to=&dead_stock_days=30&format=xlsx
+func (h *ReportHandler) GetProductPerformance(w http.ResponseWriter, r *http.Request) {
+	from, to, err := reportDateRange(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	deadStockDays := 30
+	if d := r.URL.Query().Get("dead_stock_days"); d != "" {
+		deadStockDays, err = strconv.Atoi(d)
+		if err != nil {
+			response.BadRequest(w, "dead_stock_days must be a number")
+			return
+		}
+	}
+
+	report, err := h.reportSvc.ProductPerformance(r.Context(), from, to, deadStockDays)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		response.InternalServerError(w, "Failed to get product performance")
+		return
+	}
+
+	if wantsXLSX(r) {
+		name := fmt.Sprintf("product-performance-%s-%s", from.Format("20060102"), to.Format("20060102"))
+		writeWorkbook(w, r, h.auditRepo, name, productPerformanceSheets(report), "report", nil)
+		return
+	}
+
+	response.OK(w, "Product performance retrieved", report)
+}
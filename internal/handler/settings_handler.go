@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/pkg/response"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// SettingsHandler handles app settings endpoints
+type SettingsHandler struct {
+	settingsSvc *service.SettingsService
+}
+
+// NewSettingsHandler creates a new SettingsHandler
+func NewSettingsHandler(settingsSvc *service.SettingsService) *SettingsHandler {
+	return &SettingsHandler{settingsSvc: settingsSvc}
+}
+
+// List retrieves every stored setting with its description
+// GET /settings
+func (h *SettingsHandler) List(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.settingsSvc.List(r.Context())
+	if err != nil {
+		response.InternalServerError(w, "Failed to get settings")
+		return
+	}
+
+	response.OK(w, "Settings retrieved", settings)
+}
+
+// GetStore retrieves the typed store settings (identity, receipt and tax)
+// GET /settings/store
+func (h *SettingsHandler) GetStore(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.settingsSvc.Store(r.Context())
+	if err != nil {
+		response.InternalServerError(w, "Failed to get store settings")
+		return
+	}
+
+	response.OK(w, "Store settings retrieved", settings)
+}
+
+// Update saves one or more settings given as a key-value object
+// PUT /settings
+func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var input map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	settings, err := h.settingsSvc.Update(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		response.InternalServerError(w, "Failed to update settings")
+		return
+	}
+
+	response.OK(w, "Settings updated", settings)
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/pkg/response"
+	"github.com/eveeze/warung-backend/internal/pkg/validator"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// SupplierHandler handles supplier endpoints
+type SupplierHandler struct {
+	repo *repository.SupplierRepository
+}
+
+// NewSupplierHandler creates a new SupplierHandler
+func NewSupplierHandler(repo *repository.SupplierRepository) *SupplierHandler {
+	return &SupplierHandler{repo: repo}
+}
+
+// Create creates a new supplier
+func (h *SupplierHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input domain.SupplierCreateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	v := validator.New()
+	v.Required("name", input.Name, "Name is required")
+	v.MinLength("name", input.Name, 2, "Name must be at least 2 characters")
+	if input.Phone != nil {
+		v.Phone("phone", *input.Phone, "Invalid phone number format")
+	}
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+
+	supplier, err := h.repo.Create(r.Context(), input)
+	if err != nil {
+		response.InternalServerError(w, "Failed to create supplier")
+		return
+	}
+
+	response.Created(w, "Supplier created successfully", supplier)
+}
+
+// GetByID retrieves a supplier by ID
+func (h *SupplierHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "Invalid supplier ID")
+		return
+	}
+
+	supplier, err := h.repo.GetByID(r.Context(), id)
+	if err == domain.ErrNotFound {
+		response.NotFound(w, "Supplier not found")
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "Failed to get supplier")
+		return
+	}
+
+	response.OK(w, "Supplier retrieved", supplier)
+}
+
+// List retrieves suppliers with filtering
+func (h *SupplierHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.SupplierFilter{Page: 1, PerPage: 20}
+	if search := query.Get("search"); search != "" {
+		filter.Search = &search
+	}
+	if isActive := query.Get("is_active"); isActive != "" {
+		active := isActive == "true"
+		filter.IsActive = &active
+	}
+	if page := query.Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if perPage := query.Get("per_page"); perPage != "" {
+		if pp, err := strconv.Atoi(perPage); err == nil {
+			filter.PerPage = pp
+		}
+	}
+
+	suppliers, total, err := h.repo.List(r.Context(), filter)
+	if err != nil {
+		response.InternalServerError(w, "Failed to list suppliers")
+		return
+	}
+
+	meta := response.NewMeta(filter.Page, filter.PerPage, total)
+	response.SuccessWithMeta(w, http.StatusOK, "Suppliers retrieved", suppliers, meta)
+}
+
+// Update updates a supplier
+func (h *SupplierHandler) Update(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "Invalid supplier ID")
+		return
+	}
+
+	var input domain.SupplierUpdateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	v := validator.New()
+	if input.Name != nil {
+		v.MinLength("name", *input.Name, 2, "Name must be at least 2 characters")
+	}
+	if input.Phone != nil {
+		v.Phone("phone", *input.Phone, "Invalid phone number format")
+	}
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+
+	supplier, err := h.repo.Update(r.Context(), id, input)
+	if err == domain.ErrNotFound {
+		response.NotFound(w, "Supplier not found")
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "Failed to update supplier")
+		return
+	}
+
+	response.OK(w, "Supplier updated", supplier)
+}
+
+// Delete soft deletes a supplier
+func (h *SupplierHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(w, "Invalid supplier ID")
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		if err == domain.ErrNotFound {
+			response.NotFound(w, "Supplier not found")
+			return
+		}
+		response.InternalServerError(w, "Failed to delete supplier")
+		return
+	}
+
+	response.OK(w, "Supplier deleted", nil)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/pkg/response"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// SyncHandler handles the offline sync endpoints used by the mobile POS
+type SyncHandler struct {
+	syncSvc        *service.SyncService
+	transactionSvc *service.TransactionService
+}
+
+// NewSyncHandler creates a new SyncHandler
+func NewSyncHandler(syncSvc *service.SyncService, transactionSvc *service.TransactionService) *SyncHandler {
+	return &SyncHandler{
+		syncSvc:        syncSvc,
+		transactionSvc: transactionSvc,
+	}
+}
+
+// Pull returns master data changed since a cursor
+// GET /sync/pull?since=
+func (h *SyncHandler) Pull(w http.ResponseWriter, r *http.Request) {
+	var since *time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			response.BadRequest(w, "since must be an RFC 3339 timestamp")
+			return
+		}
+		since = &t
+	}
+
+	result, err := h.syncSvc.Pull(r.Context(), since)
+	if err != nil {
+		response.InternalServerError(w, "Failed to pull changes")
+		return
+	}
+
+	response.OK(w, "Changes retrieved", result)
+}
+
+// Push replays transactions recorded offline and reports the outcome per transaction
+// POST /sync/push
+func (h *SyncHandler) Push(w http.ResponseWriter, r *http.Request) {
+	var input domain.SyncPushInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if len(input.Transactions) == 0 {
+		response.BadRequest(w, "No transactions to sync")
+		return
+	}
+
+	result, err := h.transactionSvc.ReplayOffline(r.Context(), input, currentUsername(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		response.InternalServerError(w, "Failed to sync transactions")
+		return
+	}
+
+	response.OK(w, "Transactions synced", result)
+}
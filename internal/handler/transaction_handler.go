@@ -2,6 +2,8 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,6 +11,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/pkg/receipt"
 	"github.com/eveeze/warung-backend/internal/pkg/response"
 	"github.com/eveeze/warung-backend/internal/pkg/validator"
 	"github.com/eveeze/warung-backend/internal/repository"
@@ -37,7 +40,15 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 	v := validator.New()
 	v.Custom("items", len(input.Items) > 0, "Cart cannot be empty")
 	v.InSlice("payment_method", string(input.PaymentMethod), 
-		[]string{"cash", "kasbon", "transfer", "qris"}, "Invalid payment method")
+		[]string{"cash", "kasbon", "transfer", "qris", "mixed"}, "Invalid payment method")
+	if input.PaymentMethod == domain.PaymentMethodMixed {
+		v.Custom("payments", len(input.Payments) >= 2, "Mixed payment requires at least two tenders")
+		for _, tender := range input.Payments {
+			v.InSlice("payments.method", string(tender.Method),
+				[]string{"cash", "kasbon", "transfer", "qris"}, "Invalid tender method")
+			v.Positive("payments.amount", tender.Amount, "Tender amount must be positive")
+		}
+	}
 	
 	for i, item := range input.Items {
 		v.Custom("items.product_id", item.ProductID != uuid.Nil, "Product ID is required")
@@ -58,8 +69,6 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		switch err {
 		case domain.ErrEmptyCart:
 			response.BadRequest(w, "Cart is empty")
-		case domain.ErrInsufficientStock:
-			response.BadRequest(w, err.Error())
 		case domain.ErrCreditLimitExceeded:
 			response.BadRequest(w, "Customer credit limit exceeded")
 		case domain.ErrInvalidPaymentAmount:
@@ -67,6 +76,12 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		case domain.ErrCustomerInactive:
 			response.BadRequest(w, "Customer is inactive")
 		default:
+			if errors.Is(err, domain.ErrInsufficientStock) || errors.Is(err, domain.ErrProductInactive) ||
+				errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrInvalidPaymentAmount) ||
+				errors.Is(err, domain.ErrInvalidInput) {
+				response.BadRequest(w, err.Error())
+				return
+			}
 			response.InternalServerError(w, err.Error())
 		}
 		return
@@ -161,12 +176,29 @@ func (h *TransactionHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.svc.CancelTransaction(r.Context(), id); err != nil {
-		switch err {
-		case domain.ErrNotFound:
+	var input domain.TransactionCancelInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	v := validator.New()
+	v.Required("reason", input.Reason, "Reason is required")
+	if v.HasErrors() {
+		response.ValidationError(w, v.Errors())
+		return
+	}
+	input.CancelledBy = currentUsername(r)
+
+	if err := h.svc.CancelTransaction(r.Context(), id, input); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
 			response.NotFound(w, "Transaction not found")
-		case domain.ErrTransactionCancelled:
+		case errors.Is(err, domain.ErrTransactionCancelled):
 			response.BadRequest(w, "Transaction is already cancelled")
+		case errors.Is(err, domain.ErrInvalidStatusTransition),
+			errors.Is(err, domain.ErrInsufficientStock),
+			errors.Is(err, domain.ErrInvalidInput):
+			response.BadRequest(w, err.Error())
 		default:
 			response.InternalServerError(w, "Failed to cancel transaction")
 		}
@@ -190,6 +222,10 @@ func (h *TransactionHandler) Calculate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result, err := h.svc.CalculateCart(r.Context(), input)
+	if errors.Is(err, domain.ErrInvalidInput) {
+		response.BadRequest(w, err.Error())
+		return
+	}
 	if err != nil {
 		response.InternalServerError(w, "Failed to calculate cart")
 		return
@@ -197,3 +233,143 @@ func (h *TransactionHandler) Calculate(w http.ResponseWriter, r *http.Request) {
 
 	response.OK(w, "Cart calculated", result)
 }
+
+// Receipt renders a transaction's receipt for a thermal printer
+// GET /transactions/{id}/receipt?format=pdf|escpos&width=58|80
+func (h *TransactionHandler) Receipt(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid transaction ID")
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "escpos" {
+		response.BadRequest(w, "Format must be pdf or escpos")
+		return
+	}
+	width := receipt.Width58
+	if wq := query.Get("width"); wq != "" {
+		width, err = strconv.Atoi(wq)
+		if err != nil || (width != receipt.Width58 && width != receipt.Width80) {
+			response.BadRequest(w, "Width must be 58 or 80")
+			return
+		}
+	}
+
+	transaction, settings, err := h.svc.PrintReceipt(r.Context(), id)
+	if err == domain.ErrNotFound {
+		response.NotFound(w, "Transaction not found")
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "Failed to prepare receipt")
+		return
+	}
+
+	data := receiptData(transaction, settings)
+	filename := fmt.Sprintf("struk_%s", transaction.InvoiceNumber)
+
+	if format == "escpos" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.bin", filename))
+		_, _ = w.Write(receipt.GenerateESCPOS(data, width))
+		return
+	}
+
+	doc, err := receipt.GeneratePDF(data, width)
+	if err != nil {
+		response.InternalServerError(w, "Failed to generate receipt")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%s.pdf", filename))
+	if err := doc.Output(w); err != nil {
+		fmt.Printf("Error writing receipt PDF: %v\n", err)
+	}
+}
+
+// receiptData maps a stored transaction onto the printed receipt
+func receiptData(t *domain.Transaction, settings *domain.StoreSettings) receipt.Data {
+	data := receipt.Data{
+		StoreName:     settings.StoreName,
+		StoreAddress:  settings.StoreAddress,
+		StorePhone:    settings.StorePhone,
+		Footer:        settings.ReceiptFooter,
+		InvoiceNumber: t.InvoiceNumber,
+		Date:          t.CreatedAt,
+		Reprint:       max(t.ReceiptPrintCount-1, 0),
+		Subtotal:      t.Subtotal,
+		Discount:      t.DiscountAmount,
+		Tax:           t.TaxAmount,
+		TaxInclusive:  t.TaxInclusive,
+		Total:         t.TotalAmount,
+		Change:        t.ChangeAmount,
+	}
+	if t.CashierName != nil {
+		data.Cashier = *t.CashierName
+	}
+	if t.Customer != nil {
+		data.Customer = t.Customer.Name
+	}
+	switch t.Status {
+	case domain.TransactionStatusCancelled:
+		data.StatusNote = "DIBATALKAN"
+	case domain.TransactionStatusRefunded:
+		data.StatusNote = "DIKEMBALIKAN"
+	case domain.TransactionStatusPending:
+		data.StatusNote = "BELUM DIBAYAR"
+	}
+
+	for _, item := range t.Items {
+		line := receipt.Item{
+			Name:              item.ProductName,
+			Quantity:          item.UnitQuantity,
+			Unit:              item.Unit,
+			UnitPrice:         item.UnitPrice,
+			Subtotal:          item.Subtotal,
+			PromotionDiscount: item.PromotionDiscount,
+			Discount:          item.DiscountAmount - item.PromotionDiscount,
+			Total:             item.TotalAmount,
+		}
+		if item.PricingTierName != nil {
+			line.Tier = *item.PricingTierName
+		}
+		if item.PromotionName != nil {
+			line.PromotionName = *item.PromotionName
+		}
+		data.Items = append(data.Items, line)
+	}
+
+	// Payment lines are stored net of change; the customer handed over cash plus change
+	if len(t.Payments) == 0 {
+		data.Tenders = []receipt.Tender{{Label: tenderLabel(t.PaymentMethod), Amount: t.AmountPaid}}
+	}
+	for _, p := range t.Payments {
+		amount := p.Amount
+		if p.Method == domain.PaymentMethodCash {
+			amount += t.ChangeAmount
+		}
+		data.Tenders = append(data.Tenders, receipt.Tender{Label: tenderLabel(p.Method), Amount: amount})
+	}
+
+	return data
+}
+
+func tenderLabel(method domain.PaymentMethod) string {
+	switch method {
+	case domain.PaymentMethodCash:
+		return "Tunai"
+	case domain.PaymentMethodTransfer:
+		return "Transfer"
+	case domain.PaymentMethodQRIS:
+		return "QRIS"
+	case domain.PaymentMethodKasbon:
+		return "Kasbon"
+	}
+	return string(method)
+}
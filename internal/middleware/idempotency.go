@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/pkg/response"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client-generated key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader marks a response served from a stored result
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyTTL        = 24 * time.Hour
+	idempotencyStaleAfter = 5 * time.Minute
+	idempotencyMaxKeyLen  = 255
+)
+
+type idempotencyResponseWriter struct {
+	http.ResponseWriter
+	buf        *bytes.Buffer
+	statusCode int
+}
+
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *idempotencyResponseWriter) WriteHeader(code int) {
+	w.statusCode = code
+}
+
+// Idempotency makes unsafe requests sent with an Idempotency-Key header run at most once.
+// The first response (unless it is a server error) is stored and replayed for retries
+// with the same key; reusing a key for a different request is rejected.
+// Requests without the header are passed through unchanged.
+func Idempotency(repo *repository.IdempotencyRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idempotencyMaxKeyLen {
+				response.BadRequest(w, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				response.BadRequest(w, "Invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := "anonymous"
+			if claims := GetUserFromContext(r.Context()); claims != nil {
+				scope = claims.UserID
+			}
+			hash := sha256.New()
+			hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+			hash.Write(body)
+			requestHash := hex.EncodeToString(hash.Sum(nil))
+
+			existing, claimed, err := repo.Begin(r.Context(), scope, key, requestHash, idempotencyTTL, idempotencyStaleAfter)
+			if err != nil {
+				response.InternalServerError(w, "Failed to check idempotency key")
+				return
+			}
+			if !claimed {
+				replayIdempotent(w, existing, requestHash)
+				return
+			}
+
+			// Storing the outcome must not depend on the client still waiting
+			storeCtx := context.WithoutCancel(r.Context())
+			completed := false
+			defer func() {
+				if !completed {
+					if err := repo.Release(storeCtx, scope, key); err != nil {
+						log.Printf("Failed to release idempotency key: %v", err)
+					}
+				}
+			}()
+
+			rw := &idempotencyResponseWriter{
+				ResponseWriter: w,
+				buf:            &bytes.Buffer{},
+				statusCode:     http.StatusOK,
+			}
+			next.ServeHTTP(rw, r)
+
+			// Server errors are not stored so the client can retry them
+			if rw.statusCode < http.StatusInternalServerError {
+				if err := repo.Complete(storeCtx, scope, key, rw.statusCode, w.Header().Get("Content-Type"), rw.buf.Bytes()); err != nil {
+					log.Printf("Failed to store idempotent response: %v", err)
+				} else {
+					completed = true
+				}
+			}
+
+			w.WriteHeader(rw.statusCode)
+			w.Write(rw.buf.Bytes())
+		})
+	}
+}
+
+// replayIdempotent answers a retry from the stored record
+func replayIdempotent(w http.ResponseWriter, rec *domain.IdempotencyRecord, requestHash string) {
+	if rec.RequestHash != requestHash {
+		response.UnprocessableEntity(w, "Idempotency-Key was already used for a different request")
+		return
+	}
+	if rec.Status != domain.IdempotencyStatusCompleted || rec.ResponseStatus == nil {
+		response.Conflict(w, "A request with this Idempotency-Key is still being processed")
+		return
+	}
+
+	if rec.ResponseContentType != nil && *rec.ResponseContentType != "" {
+		w.Header().Set("Content-Type", *rec.ResponseContentType)
+	}
+	w.Header().Set(IdempotencyReplayedHeader, "true")
+	w.WriteHeader(*rec.ResponseStatus)
+	w.Write(rec.ResponseBody)
+}
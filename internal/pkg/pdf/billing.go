@@ -18,6 +18,7 @@ type BillingData struct {
 	EndingBalance   int64
 	StoreName       string
 	StoreAddress    string
+	StorePhone      string
 	PaymentInst     string
 }
 
@@ -49,6 +50,10 @@ func GenerateBillingPDF(data BillingData) (*fpdf.Fpdf, error) {
 	pdf.SetFont("Arial", "", 10)
 	pdf.SetTextColor(darkGray[0], darkGray[1], darkGray[2])
 	pdf.Cell(0, 5, data.StoreAddress)
+	if data.StorePhone != "" {
+		pdf.Ln(5)
+		pdf.Cell(0, 5, "Telp: "+data.StorePhone)
+	}
 	pdf.Ln(15)
 
 	// -- Title & Info --
@@ -15,12 +15,18 @@ type RestockItem struct {
 	Deficit      int
 	Unit         string
 	CostPrice    int64
+
+	// Set for products with a demand forecast
+	Forecasted   bool
+	ReorderPoint int
+	SuggestedQty int // what the forecast says to order, before rounding
 }
 
 // RestockData holds data for generating restock PDF
 type RestockData struct {
 	StoreName    string
 	StoreAddress string
+	StorePhone   string
 	GeneratedAt  time.Time
 	Items        []RestockItem
 }
@@ -44,6 +50,10 @@ func GenerateRestockPDF(data RestockData) (*fpdf.Fpdf, error) {
 	pdf.SetFont("Arial", "", 10)
 	pdf.SetTextColor(darkGray[0], darkGray[1], darkGray[2])
 	pdf.Cell(0, 5, data.StoreAddress)
+	if data.StorePhone != "" {
+		pdf.Ln(5)
+		pdf.Cell(0, 5, "Telp: "+data.StorePhone)
+	}
 	pdf.Ln(15)
 
 	// Title
@@ -58,9 +68,9 @@ func GenerateRestockPDF(data RestockData) (*fpdf.Fpdf, error) {
 	pdf.Ln(10)
 
 	// Table columns
-	// Widths: Name(60), Current(25), Min(25), Deficit(25), Order(30), Unit(15) = 180mm
-	colWidths := []float64{60, 25, 25, 25, 30, 15}
-	headers := []string{"Nama Produk", "Stok", "Min", "Kurang", "Order", "Satuan"}
+	// Widths: Name(55), Current(20), Min(20), Reorder point(25), Deficit(20), Order(25), Unit(15) = 180mm
+	colWidths := []float64{55, 20, 20, 25, 20, 25, 15}
+	headers := []string{"Nama Produk", "Stok", "Min", "Titik Order", "Kurang", "Order", "Satuan"}
 
 	// Header row
 	pdf.SetFillColor(lightGray[0], lightGray[1], lightGray[2])
@@ -85,7 +95,7 @@ func GenerateRestockPDF(data RestockData) (*fpdf.Fpdf, error) {
 
 	for _, item := range data.Items {
 		// Product name (truncate if too long)
-		name := truncateString(item.ProductName, 30)
+		name := truncateString(item.ProductName, 27)
 		pdf.CellFormat(colWidths[0], 8, name, "LR", 0, "L", false, 0, "")
 
 		// Current stock
@@ -94,16 +104,26 @@ func GenerateRestockPDF(data RestockData) (*fpdf.Fpdf, error) {
 		// Min stock
 		pdf.CellFormat(colWidths[2], 8, fmt.Sprintf("%d", item.MinStock), "R", 0, "C", false, 0, "")
 
+		// Forecast reorder point
+		reorderPoint := "-"
+		if item.Forecasted {
+			reorderPoint = fmt.Sprintf("%d", item.ReorderPoint)
+		}
+		pdf.CellFormat(colWidths[3], 8, reorderPoint, "R", 0, "C", false, 0, "")
+
 		// Deficit (highlight negatives)
 		deficitStr := fmt.Sprintf("%d", item.Deficit)
-		pdf.CellFormat(colWidths[3], 8, deficitStr, "R", 0, "C", false, 0, "")
+		pdf.CellFormat(colWidths[4], 8, deficitStr, "R", 0, "C", false, 0, "")
 
 		// Suggested order (rounded up to nearest 5 or 10)
 		suggestedOrder := calculateSuggestedOrder(item.Deficit, item.MinStock)
-		pdf.CellFormat(colWidths[4], 8, fmt.Sprintf("%d", suggestedOrder), "R", 0, "C", false, 0, "")
+		if item.Forecasted {
+			suggestedOrder = roundOrder(item.SuggestedQty)
+		}
+		pdf.CellFormat(colWidths[5], 8, fmt.Sprintf("%d", suggestedOrder), "R", 0, "C", false, 0, "")
 
 		// Unit
-		pdf.CellFormat(colWidths[5], 8, item.Unit, "R", 0, "C", false, 0, "")
+		pdf.CellFormat(colWidths[6], 8, item.Unit, "R", 0, "C", false, 0, "")
 
 		pdf.Ln(-1)
 
@@ -125,12 +145,13 @@ func GenerateRestockPDF(data RestockData) (*fpdf.Fpdf, error) {
 	// Footer note
 	pdf.SetY(260)
 	pdf.SetFont("Arial", "I", 9)
-	pdf.MultiCell(0, 5, "Catatan: Kolom 'Order' adalah saran jumlah pembelian (sudah dibulatkan untuk kemudahan). Sesuaikan dengan kebutuhan dan modal yang tersedia.", "", "L", false)
+	pdf.MultiCell(0, 5, "Catatan: Kolom 'Order' adalah saran jumlah pembelian (sudah dibulatkan untuk kemudahan). Untuk produk dengan 'Titik Order', saran dihitung dari perkiraan penjualan harian selama waktu tunggu pengiriman. Sesuaikan dengan kebutuhan dan modal yang tersedia.", "", "L", false)
 
 	return pdf, nil
 }
 
-// calculateSuggestedOrder rounds up deficit to a convenient number
+// calculateSuggestedOrder rounds up deficit to a convenient number.
+// It is the fallback for products without a demand forecast.
 func calculateSuggestedOrder(deficit, minStock int) int {
 	// Add safety margin (20% of min stock or minimum 2)
 	safetyMargin := minStock / 5
@@ -138,9 +159,11 @@ func calculateSuggestedOrder(deficit, minStock int) int {
 		safetyMargin = 2
 	}
 
-	suggested := deficit + safetyMargin
+	return roundOrder(deficit + safetyMargin)
+}
 
-	// Round up to nearest 5 for small quantities, 10 for larger
+// roundOrder rounds an order quantity up to the nearest 5 for small quantities, 10 for larger
+func roundOrder(suggested int) int {
 	if suggested <= 20 {
 		return ((suggested + 4) / 5) * 5
 	}
@@ -0,0 +1,54 @@
+package receipt
+
+import (
+	"bytes"
+)
+
+// ESC/POS commands understood by common 58/80mm Bluetooth printers
+var (
+	escInit       = []byte{0x1B, 0x40}
+	escBoldOn     = []byte{0x1B, 0x45, 0x01}
+	escBoldOff    = []byte{0x1B, 0x45, 0x00}
+	escTallOn     = []byte{0x1D, 0x21, 0x01}
+	escTallOff    = []byte{0x1D, 0x21, 0x00}
+	escFeedAndCut = []byte{0x1B, 0x64, 0x04, 0x1D, 0x56, 0x42, 0x00}
+)
+
+// GenerateESCPOS renders the receipt as raw ESC/POS bytes for the given paper width
+func GenerateESCPOS(data Data, widthMM int) []byte {
+	var buf bytes.Buffer
+	buf.Write(escInit)
+
+	for _, line := range Layout(data, Columns(widthMM)) {
+		if line.Bold {
+			buf.Write(escBoldOn)
+		}
+		if line.Tall {
+			buf.Write(escTallOn)
+		}
+		buf.WriteString(ascii(line.Text))
+		buf.WriteByte('\n')
+		if line.Tall {
+			buf.Write(escTallOff)
+		}
+		if line.Bold {
+			buf.Write(escBoldOff)
+		}
+	}
+
+	buf.Write(escFeedAndCut)
+	return buf.Bytes()
+}
+
+// ascii replaces characters outside the printer's default code page
+func ascii(text string) string {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r < 0x20 || r > 0x7E {
+			out = append(out, '?')
+			continue
+		}
+		out = append(out, byte(r))
+	}
+	return string(out)
+}
@@ -0,0 +1,45 @@
+package receipt
+
+import (
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfFontSize   = 7.0 // Courier at 7pt is 1.48mm per character
+	pdfCharWidth  = pdfFontSize * 0.6 * 25.4 / 72
+	pdfLineHeight = 3.2
+	pdfMarginTop  = 4.0
+)
+
+// GeneratePDF renders the receipt on a roll-sized page of the given width (58 or 80mm)
+func GeneratePDF(data Data, widthMM int) (*fpdf.Fpdf, error) {
+	cols := Columns(widthMM)
+	lines := Layout(data, cols)
+
+	width := float64(widthMM)
+	height := pdfMarginTop*2 + float64(len(lines))*pdfLineHeight
+	left := (width - float64(cols)*pdfCharWidth) / 2
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: width, Ht: height},
+	})
+	pdf.SetMargins(left, pdfMarginTop, left)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	// Core fonts are cp1252; translate product names typed with accents
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, line := range lines {
+		style := ""
+		if line.Bold {
+			style = "B"
+		}
+		pdf.SetFont("Courier", style, pdfFontSize)
+		pdf.CellFormat(0, pdfLineHeight, tr(line.Text), "", 1, "L", false, 0, "")
+	}
+
+	return pdf, pdf.Error()
+}
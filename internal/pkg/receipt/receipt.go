@@ -0,0 +1,248 @@
+// Package receipt renders sales receipts for thermal printers.
+// A receipt is first laid out as fixed-width text lines, then written either
+// as a narrow PDF or as raw ESC/POS bytes, so both outputs look the same.
+package receipt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Paper widths in millimetres
+const (
+	Width58 = 58
+	Width80 = 80
+)
+
+// Data holds everything printed on a receipt
+type Data struct {
+	StoreName    string
+	StoreAddress string
+	StorePhone   string
+	Footer       string
+
+	InvoiceNumber string
+	Date          time.Time
+	Cashier       string
+	Customer      string
+	StatusNote    string // e.g. "DIBATALKAN"; empty for completed sales
+	Reprint       int    // 0 for the first print, n for the n-th reprint
+
+	Items []Item
+
+	Subtotal     int64 // sum of item totals
+	Discount     int64 // order-level discount
+	Tax          int64
+	TaxInclusive bool
+	Total        int64
+	Tenders      []Tender
+	Change       int64
+}
+
+// Item is one sold line
+type Item struct {
+	Name              string
+	Quantity          int
+	Unit              string
+	UnitPrice         int64
+	Tier              string
+	Subtotal          int64
+	PromotionName     string
+	PromotionDiscount int64
+	Discount          int64 // manual discount, excluding the promotion
+	Total             int64
+}
+
+// Tender is one payment line
+type Tender struct {
+	Label  string
+	Amount int64
+}
+
+// Line is one printed line, already padded to the paper's column count
+type Line struct {
+	Text string
+	Bold bool
+	Tall bool // double height on ESC/POS
+}
+
+// Columns returns the characters per line for a paper width
+func Columns(widthMM int) int {
+	if widthMM >= Width80 {
+		return 48
+	}
+	return 32
+}
+
+// Layout arranges the receipt into lines of exactly cols characters
+func Layout(data Data, cols int) []Line {
+	var lines []Line
+	add := func(text string, bold bool) {
+		lines = append(lines, Line{Text: text, Bold: bold})
+	}
+	center := func(text string, bold bool) {
+		for _, part := range wrap(text, cols) {
+			add(centered(part, cols), bold)
+		}
+	}
+	row := func(label, value string, bold bool) {
+		add(columns(label, value, cols), bold)
+	}
+	separator := func() {
+		add(strings.Repeat("-", cols), false)
+	}
+
+	for _, part := range wrap(data.StoreName, cols) {
+		lines = append(lines, Line{Text: centered(part, cols), Bold: true, Tall: true})
+	}
+	if data.StoreAddress != "" {
+		center(data.StoreAddress, false)
+	}
+	if data.StorePhone != "" {
+		center("Telp: "+data.StorePhone, false)
+	}
+	separator()
+
+	row("No", data.InvoiceNumber, false)
+	row("Tanggal", data.Date.Format("02/01/2006 15:04"), false)
+	if data.Cashier != "" {
+		row("Kasir", data.Cashier, false)
+	}
+	if data.Customer != "" {
+		row("Pelanggan", data.Customer, false)
+	}
+	if data.StatusNote != "" {
+		center("*** "+data.StatusNote+" ***", true)
+	}
+	if data.Reprint > 0 {
+		center(fmt.Sprintf("CETAK ULANG #%d", data.Reprint), false)
+	}
+	separator()
+
+	for _, item := range data.Items {
+		for _, part := range wrap(item.Name, cols) {
+			add(pad(part, cols), false)
+		}
+		qty := fmt.Sprintf("  %d %s x %s", item.Quantity, item.Unit, Money(item.UnitPrice))
+		row(qty, Money(item.Subtotal), false)
+		if item.Tier != "" {
+			add(pad("  ("+item.Tier+")", cols), false)
+		}
+		if item.PromotionDiscount > 0 {
+			row("  "+item.PromotionName, "-"+Money(item.PromotionDiscount), false)
+		}
+		if item.Discount > 0 {
+			row("  Diskon", "-"+Money(item.Discount), false)
+		}
+	}
+	separator()
+
+	row("Subtotal", Money(data.Subtotal), false)
+	if data.Discount > 0 {
+		row("Diskon", "-"+Money(data.Discount), false)
+	}
+	if data.Tax > 0 {
+		label := "Pajak"
+		if data.TaxInclusive {
+			label = "Pajak (termasuk)"
+		}
+		row(label, Money(data.Tax), false)
+	}
+	row("TOTAL", "Rp "+Money(data.Total), true)
+	separator()
+
+	for _, tender := range data.Tenders {
+		row(tender.Label, Money(tender.Amount), false)
+	}
+	if data.Change > 0 {
+		row("Kembali", Money(data.Change), false)
+	}
+
+	if data.Footer != "" {
+		separator()
+		center(data.Footer, false)
+	}
+
+	return lines
+}
+
+// Money formats an amount with dot thousand separators, e.g. 12.500
+func Money(amount int64) string {
+	negative := amount < 0
+	if negative {
+		amount = -amount
+	}
+	s := fmt.Sprintf("%d", amount)
+
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	if negative {
+		return "-" + b.String()
+	}
+	return b.String()
+}
+
+// columns puts label on the left and value on the right of a line,
+// cutting the label when both do not fit
+func columns(label, value string, cols int) string {
+	space := cols - utf8.RuneCountInString(value) - 1
+	if space < 0 {
+		return pad(value, cols)
+	}
+	label = cut(label, space)
+	return label + strings.Repeat(" ", cols-utf8.RuneCountInString(label)-utf8.RuneCountInString(value)) + value
+}
+
+func centered(text string, cols int) string {
+	text = cut(text, cols)
+	left := (cols - utf8.RuneCountInString(text)) / 2
+	return pad(strings.Repeat(" ", left)+text, cols)
+}
+
+func pad(text string, cols int) string {
+	text = cut(text, cols)
+	return text + strings.Repeat(" ", cols-utf8.RuneCountInString(text))
+}
+
+func cut(text string, cols int) string {
+	if utf8.RuneCountInString(text) <= cols {
+		return text
+	}
+	return string([]rune(text)[:cols])
+}
+
+// wrap breaks text into lines of at most cols characters on word boundaries
+func wrap(text string, cols int) []string {
+	var lines []string
+	var current string
+	for _, word := range strings.Fields(text) {
+		for utf8.RuneCountInString(word) > cols {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			lines = append(lines, string([]rune(word)[:cols]))
+			word = string([]rune(word)[cols:])
+		}
+		switch {
+		case current == "":
+			current = word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= cols:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
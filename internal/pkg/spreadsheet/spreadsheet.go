@@ -0,0 +1,213 @@
+// Package spreadsheet reads and writes the XLSX and CSV files used for imports and exports.
+package spreadsheet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Format is a spreadsheet file format
+type Format string
+
+const (
+	FormatXLSX Format = "xlsx"
+	FormatCSV  Format = "csv"
+)
+
+// ParseFormat returns the format named by s ("xlsx" or "csv"), defaulting to XLSX when s is empty
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "xlsx":
+		return FormatXLSX, nil
+	case "csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unsupported format %q (use xlsx or csv)", s)
+}
+
+// FormatOf returns the format of a file by its extension
+func FormatOf(filename string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(filename), ".")
+	if ext == "" {
+		return "", fmt.Errorf("file %q has no extension (use .xlsx or .csv)", filename)
+	}
+	return ParseFormat(ext)
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+// Read returns the rows of the first sheet of an XLSX file, or of a CSV file separated by
+// commas or semicolons. Trailing empty cells may be missing from a row.
+func Read(r io.Reader, format Format) ([][]string, error) {
+	if format == FormatCSV {
+		return readCSV(r)
+	}
+
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %s: %w", sheets[0], err)
+	}
+	return rows, nil
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+
+	// Excel saves CSV with semicolons when the system uses a decimal comma, as in Indonesia
+	firstLine, _ := br.Peek(4096)
+	if i := bytes.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	reader := csv.NewReader(br)
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff") // byte order mark
+	}
+	return rows, nil
+}
+
+// Rupiah is an amount that a workbook shows as Rupiah, e.g. "Rp 12.500".
+// CSV files get the plain number.
+type Rupiah int64
+
+// Sheet is one sheet of a workbook. The first row is the header.
+type Sheet struct {
+	Name string
+	Rows [][]any
+}
+
+// Write writes rows as a workbook with one sheet, or as CSV. The first row is the header.
+func Write(w io.Writer, format Format, sheet string, rows [][]any) error {
+	if format == FormatXLSX {
+		return WriteWorkbook(w, []Sheet{{Name: sheet, Rows: rows}})
+	}
+
+	cw := csv.NewWriter(w)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			if v != nil {
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteWorkbook writes sheets as an XLSX workbook, with bold headers, Rupiah formatting and
+// columns sized to their content
+func WriteWorkbook(w io.Writer, sheets []Sheet) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	rupiahFormat := `"Rp "#,##0;-"Rp "#,##0`
+	rupiah, err := f.NewStyle(&excelize.Style{CustomNumFmt: &rupiahFormat})
+	if err != nil {
+		return err
+	}
+	dateFormat := "yyyy-mm-dd hh:mm"
+	date, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			err = f.SetSheetName(f.GetSheetName(0), sheet.Name)
+		} else {
+			_, err = f.NewSheet(sheet.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		var widths []int
+		for r, row := range sheet.Rows {
+			for c, v := range row {
+				cell, err := excelize.CoordinatesToCellName(c+1, r+1)
+				if err != nil {
+					return err
+				}
+
+				style, width := 0, len(fmt.Sprint(v))
+				switch value := v.(type) {
+				case nil:
+					continue
+				case Rupiah:
+					v, style, width = int64(value), rupiah, width+width/3+3
+				case time.Time:
+					style, width = date, 16
+				}
+				if r == 0 {
+					style = bold
+				}
+
+				if err := f.SetCellValue(sheet.Name, cell, v); err != nil {
+					return err
+				}
+				if style != 0 {
+					if err := f.SetCellStyle(sheet.Name, cell, cell, style); err != nil {
+						return err
+					}
+				}
+				for len(widths) <= c {
+					widths = append(widths, 0)
+				}
+				widths[c] = max(widths[c], width)
+			}
+		}
+
+		for c, width := range widths {
+			col, err := excelize.ColumnNumberToName(c + 1)
+			if err != nil {
+				return err
+			}
+			if err := f.SetColWidth(sheet.Name, col, col, float64(min(max(width+2, 8), 60))); err != nil {
+				return err
+			}
+		}
+	}
+
+	return f.Write(w)
+}
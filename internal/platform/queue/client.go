@@ -2,6 +2,8 @@ package queue
 
 import (
 	"encoding/json"
+	"errors"
+	"time"
 
 	"github.com/hibiken/asynq"
 )
@@ -56,3 +58,24 @@ func (c *Client) EnqueueSendNotification(payload PayloadNotificationSend) error
 	_, err = c.client.Enqueue(task)
 	return err
 }
+
+// EnqueueIdempotent enqueues a raw payload under a fixed task ID.
+// A task that was already enqueued with the same ID counts as success.
+func (c *Client) EnqueueIdempotent(taskType string, payload []byte, taskID string) error {
+	task := asynq.NewTask(taskType, payload)
+	_, err := c.client.Enqueue(task, asynq.TaskID(taskID), asynq.Retention(24*time.Hour))
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+// EnqueueOutboxRelay asks the worker to drain the outbox; bursts collapse into one task
+func (c *Client) EnqueueOutboxRelay() error {
+	task := asynq.NewTask(TypeOutboxRelay, nil)
+	_, err := c.client.Enqueue(task, asynq.Unique(5*time.Second), asynq.MaxRetry(0))
+	if errors.Is(err, asynq.ErrDuplicateTask) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+type Scheduler struct {
+	scheduler *asynq.Scheduler
+}
+
+func NewScheduler(redisAddr string, redisPassword string) *Scheduler {
+	return &Scheduler{
+		scheduler: asynq.NewScheduler(
+			asynq.RedisClientOpt{Addr: redisAddr, Password: redisPassword},
+			// Cron specs follow the shop's clock, so "5 0 * * *" runs just after local midnight
+			&asynq.SchedulerOpts{Location: time.Local},
+		),
+	}
+}
+
+// Register enqueues a payload-less task on a cron spec such as "@every 30s" or "5 0 * * *"
+func (s *Scheduler) Register(cronspec string, taskType string) error {
+	_, err := s.scheduler.Register(cronspec, asynq.NewTask(taskType, nil))
+	return err
+}
+
+func (s *Scheduler) Start() error {
+	return s.scheduler.Start()
+}
+
+func (s *Scheduler) Shutdown() {
+	s.scheduler.Shutdown()
+}
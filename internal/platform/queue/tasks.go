@@ -5,6 +5,9 @@ const (
 	TypeNotificationSend = "notification:send"
 	TypeLowStockAlert    = "notification:low_stock"
 	TypeNewTransaction   = "notification:new_transaction"
+	TypeOutboxRelay      = "outbox:relay"
+	TypeOutboxPurge      = "outbox:purge"
+	TypeDailyRollup      = "report:daily_rollup"
 )
 
 // Task Payloads
@@ -29,3 +32,9 @@ type PayloadNewTransaction struct {
 	Amount        int64  `json:"amount"`
 	CashierName   string `json:"cashier_name"`
 }
+
+// PayloadDailyRollup names the day to roll up again; the nightly run has no date and
+// rolls up yesterday plus any closed day still missing a summary
+type PayloadDailyRollup struct {
+	Date string `json:"date,omitempty"` // YYYY-MM-DD
+}
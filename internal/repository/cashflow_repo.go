@@ -43,6 +43,19 @@ func (r *CashFlowRepository) GetCategories(ctx context.Context) ([]domain.CashFl
 	return categories, nil
 }
 
+func (r *CashFlowRepository) GetCategoryByName(ctx context.Context, name string) (*domain.CashFlowCategory, error) {
+	query := `SELECT id, name, type, description, is_active, created_at, updated_at FROM cash_flow_categories WHERE name = $1 LIMIT 1`
+	var c domain.CashFlowCategory
+	err := r.db.QueryRowContext(ctx, query, name).Scan(&c.ID, &c.Name, &c.Type, &c.Description, &c.IsActive, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
 // -- Drawer Sessions --
 
 func (r *CashFlowRepository) OpenDrawer(ctx context.Context, input domain.OpenDrawerInput) (*domain.CashDrawerSession, error) {
@@ -184,23 +197,35 @@ func (r *CashFlowRepository) ListCashFlows(ctx context.Context, filter domain.Ca
 	argIndex := 1
 
 	if filter.SessionID != nil {
-		whereClause += fmt.Sprintf(" AND drawer_session_id = $%d", argIndex)
+		whereClause += fmt.Sprintf(" AND cfp.drawer_session_id = $%d", argIndex)
 		args = append(args, *filter.SessionID)
 		argIndex++
 	}
 	if filter.CategoryID != nil {
-		whereClause += fmt.Sprintf(" AND category_id = $%d", argIndex)
+		whereClause += fmt.Sprintf(" AND cfp.category_id = $%d", argIndex)
 		args = append(args, *filter.CategoryID)
 		argIndex++
 	}
 	if filter.Type != nil {
-		whereClause += fmt.Sprintf(" AND type = $%d", argIndex)
+		whereClause += fmt.Sprintf(" AND cfp.type = $%d", argIndex)
 		args = append(args, *filter.Type)
 		argIndex++
 	}
+	if filter.DateFrom != nil {
+		whereClause += fmt.Sprintf(" AND cfp.created_at >= $%d", argIndex)
+		args = append(args, *filter.DateFrom)
+		argIndex++
+	}
+	if filter.DateTo != nil {
+		whereClause += fmt.Sprintf(" AND cfp.created_at <= $%d", argIndex)
+		args = append(args, *filter.DateTo)
+		argIndex++
+	}
 
 	var total int64
-	r.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM cash_flow_records %s", whereClause), args...).Scan(&total)
+	if err := r.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM cash_flow_records cfp %s", whereClause), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count cash flows: %w", err)
+	}
 
 	query := fmt.Sprintf(`
 		SELECT cfp.id, cfp.drawer_session_id, cfp.category_id, cfp.type, cfp.amount, cfp.description, cfp.reference_type, cfp.reference_id, cfp.created_by, cfp.created_at,
@@ -243,3 +268,44 @@ func (r *CashFlowRepository) ListCashFlows(ctx context.Context, filter domain.Ca
 
 	return records, total, nil
 }
+
+// GetExpensesByCategory returns operating expenses between two dates (inclusive, YYYY-MM-DD)
+// per category, largest first. Payouts the system records for refunds, supplier purchases and
+// consignment settlements are left out: they are already counted in revenue and COGS.
+func (r *CashFlowRepository) GetExpensesByCategory(ctx context.Context, dateFrom, dateTo string) ([]domain.ExpenseBreakdown, error) {
+	query := `
+		SELECT COALESCE(c.name, 'Lainnya'), SUM(cfp.amount)
+		FROM cash_flow_records cfp
+		LEFT JOIN cash_flow_categories c ON cfp.category_id = c.id
+		WHERE cfp.type = 'expense' AND DATE(cfp.created_at) BETWEEN $1 AND $2
+		  AND (cfp.reference_type IS NULL OR cfp.reference_type NOT IN ('refund', 'purchase', 'consignment_settlement'))
+		GROUP BY COALESCE(c.name, 'Lainnya')
+		ORDER BY SUM(cfp.amount) DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, dateFrom, dateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expenses by category: %w", err)
+	}
+	defer rows.Close()
+
+	expenses := make([]domain.ExpenseBreakdown, 0)
+	var total int64
+	for rows.Next() {
+		var e domain.ExpenseBreakdown
+		if err := rows.Scan(&e.CategoryName, &e.Amount); err != nil {
+			return nil, err
+		}
+		total += e.Amount
+		expenses = append(expenses, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if total > 0 {
+		for i := range expenses {
+			expenses[i].Percentage = float64(expenses[i].Amount) / float64(total) * 100
+		}
+	}
+	return expenses, nil
+}
@@ -22,8 +22,8 @@ func NewCategoryRepository(db *database.PostgresDB) *CategoryRepository {
 // Create inserts a new category
 func (r *CategoryRepository) Create(ctx context.Context, category *domain.Category) error {
 	query := `
-		INSERT INTO categories (id, name, description, parent_id, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO categories (id, name, description, parent_id, is_active, is_tax_exempt, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	category.ID = uuid.New()
 	category.CreatedAt = time.Now()
@@ -36,6 +36,7 @@ func (r *CategoryRepository) Create(ctx context.Context, category *domain.Catego
 		category.Description,
 		category.ParentID,
 		category.IsActive,
+		category.IsTaxExempt,
 		category.CreatedAt,
 		category.UpdatedAt,
 	)
@@ -47,7 +48,7 @@ func (r *CategoryRepository) FindAll(ctx context.Context, filter map[string]inte
 	// Critical: Join with products to get count
 	query := `
 		SELECT 
-			c.id, c.name, c.description, c.parent_id, c.is_active, c.created_at, c.updated_at,
+			c.id, c.name, c.description, c.parent_id, c.is_active, c.is_tax_exempt, c.created_at, c.updated_at,
 			COUNT(p.id) as product_count
 		FROM categories c
 		LEFT JOIN products p ON c.id = p.category_id AND p.is_active = true
@@ -73,7 +74,7 @@ func (r *CategoryRepository) FindAll(ctx context.Context, filter map[string]inte
 	for rows.Next() {
 		var c domain.CategoryResponse
 		if err := rows.Scan(
-			&c.ID, &c.Name, &c.Description, &c.ParentID, &c.IsActive, &c.CreatedAt, &c.UpdatedAt,
+			&c.ID, &c.Name, &c.Description, &c.ParentID, &c.IsActive, &c.IsTaxExempt, &c.CreatedAt, &c.UpdatedAt,
 			&c.ProductCount,
 		); err != nil {
 			return nil, err
@@ -86,13 +87,13 @@ func (r *CategoryRepository) FindAll(ctx context.Context, filter map[string]inte
 // FindByID retrieves a single category
 func (r *CategoryRepository) FindByID(ctx context.Context, id uuid.UUID) (*domain.Category, error) {
 	query := `
-		SELECT id, name, description, parent_id, is_active, created_at, updated_at
+		SELECT id, name, description, parent_id, is_active, is_tax_exempt, created_at, updated_at
 		FROM categories
 		WHERE id = $1 AND is_active = true
 	`
 	var c domain.Category
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&c.ID, &c.Name, &c.Description, &c.ParentID, &c.IsActive, &c.CreatedAt, &c.UpdatedAt,
+		&c.ID, &c.Name, &c.Description, &c.ParentID, &c.IsActive, &c.IsTaxExempt, &c.CreatedAt, &c.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil // Not found
@@ -103,12 +104,51 @@ func (r *CategoryRepository) FindByID(ctx context.Context, id uuid.UUID) (*domai
 	return &c, nil
 }
 
+// ListChangedSince returns categories changed after since, including deactivated ones.
+// A nil since returns every active category.
+func (r *CategoryRepository) ListChangedSince(ctx context.Context, since *time.Time) ([]domain.Category, error) {
+	query := `
+		SELECT id, name, description, parent_id, is_active, is_tax_exempt, created_at, updated_at
+		FROM categories
+		WHERE is_active = true
+		ORDER BY updated_at
+	`
+	var args []interface{}
+	if since != nil {
+		query = `
+			SELECT id, name, description, parent_id, is_active, is_tax_exempt, created_at, updated_at
+			FROM categories
+			WHERE updated_at > $1
+			ORDER BY updated_at
+		`
+		args = append(args, *since)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []domain.Category{}
+	for rows.Next() {
+		var c domain.Category
+		if err := rows.Scan(
+			&c.ID, &c.Name, &c.Description, &c.ParentID, &c.IsActive, &c.IsTaxExempt, &c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
 // Update modifies a category
 func (r *CategoryRepository) Update(ctx context.Context, category *domain.Category) error {
 	query := `
 		UPDATE categories
-		SET name = $1, description = $2, parent_id = $3, is_active = $4, updated_at = $5
-		WHERE id = $6
+		SET name = $1, description = $2, parent_id = $3, is_active = $4, is_tax_exempt = $5, updated_at = $6
+		WHERE id = $7
 	`
 	category.UpdatedAt = time.Now()
 	_, err := r.db.ExecContext(ctx, query,
@@ -116,6 +156,7 @@ func (r *CategoryRepository) Update(ctx context.Context, category *domain.Catego
 		category.Description,
 		category.ParentID,
 		category.IsActive,
+		category.IsTaxExempt,
 		category.UpdatedAt,
 		category.ID,
 	)
@@ -139,3 +180,22 @@ func (r *CategoryRepository) HasProducts(ctx context.Context, categoryID uuid.UU
 	err := r.db.QueryRowContext(ctx, query, categoryID).Scan(&exists)
 	return exists, err
 }
+
+// GetTaxExemptIDs retrieves the IDs of categories exempt from tax
+func (r *CategoryRepository) GetTaxExemptIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM categories WHERE is_tax_exempt = true`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
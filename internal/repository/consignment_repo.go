@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -92,20 +94,16 @@ func (r *ConsignmentRepository) ListConsignors(ctx context.Context) ([]domain.Co
 }
 
 // Settlements
-func (r *ConsignmentRepository) CreateSettlement(ctx context.Context, s *domain.ConsignmentSettlement) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
 
+// CreateSettlement inserts a settlement with its items (used within transaction)
+func (r *ConsignmentRepository) CreateSettlement(ctx context.Context, tx *sql.Tx, s *domain.ConsignmentSettlement) error {
 	query := `
-		INSERT INTO consignment_settlements (settlement_number, consignor_id, period_start, period_end, total_sales, commission_amount, consignor_amount, status, created_by)
-		VALUES (generate_settlement_number(), $1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO consignment_settlements (settlement_number, consignor_id, period_start, period_end, total_sales, commission_amount, consignor_amount, status, notes, created_by)
+		VALUES (generate_settlement_number(), $1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, settlement_number, created_at, updated_at
 	`
-	err = tx.QueryRowContext(ctx, query,
-		s.ConsignorID, s.PeriodStart, s.PeriodEnd, s.TotalSales, s.CommissionAmount, s.ConsignorAmount, s.Status, s.CreatedBy,
+	err := tx.QueryRowContext(ctx, query,
+		s.ConsignorID, s.PeriodStart, s.PeriodEnd, s.TotalSales, s.CommissionAmount, s.ConsignorAmount, s.Status, s.Notes, s.CreatedBy,
 	).Scan(&s.ID, &s.SettlementNumber, &s.CreatedAt, &s.UpdatedAt)
 	if err != nil {
 		return err
@@ -114,18 +112,302 @@ func (r *ConsignmentRepository) CreateSettlement(ctx context.Context, s *domain.
 	itemQuery := `
 		INSERT INTO consignment_settlement_items (settlement_id, product_id, product_name, quantity_sold, unit_price, total_sales, commission_rate, commission_amount, consignor_amount)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
 	`
-	for _, item := range s.Items {
-		_, err = tx.ExecContext(ctx, itemQuery,
+	for i := range s.Items {
+		item := &s.Items[i]
+		item.SettlementID = s.ID
+		err = tx.QueryRowContext(ctx, itemQuery,
 			s.ID, item.ProductID, item.ProductName, item.QuantitySold, item.UnitPrice,
 			item.TotalSales, item.CommissionRate, item.CommissionAmount, item.ConsignorAmount,
-		)
+		).Scan(&item.ID, &item.CreatedAt)
 		if err != nil {
 			return err
 		}
 	}
 
-	return tx.Commit()
+	return nil
+}
+
+// LockConsignor locks a consignor row so settlements for it are generated one at a time
+func (r *ConsignmentRepository) LockConsignor(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*domain.Consignor, error) {
+	query := `SELECT id, name, phone, address, bank_account, bank_name, notes, is_active, created_at, updated_at FROM consignors WHERE id = $1 FOR UPDATE`
+	var c domain.Consignor
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&c.ID, &c.Name, &c.Phone, &c.Address, &c.BankAccount, &c.BankName, &c.Notes, &c.IsActive, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetLastSettlementEnd returns the latest settled period end for a consignor, or nil if none
+func (r *ConsignmentRepository) GetLastSettlementEnd(ctx context.Context, tx *sql.Tx, consignorID uuid.UUID) (*time.Time, error) {
+	var periodEnd sql.NullTime
+	err := tx.QueryRowContext(ctx,
+		"SELECT MAX(period_end) FROM consignment_settlements WHERE consignor_id = $1", consignorID,
+	).Scan(&periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	if !periodEnd.Valid {
+		return nil, nil
+	}
+	return &periodEnd.Time, nil
+}
+
+// GetFirstSaleDate returns the date of the first completed sale of a consignor's products, or nil if none
+func (r *ConsignmentRepository) GetFirstSaleDate(ctx context.Context, tx *sql.Tx, consignorID uuid.UUID) (*time.Time, error) {
+	query := `
+		SELECT MIN(t.created_at)
+		FROM transaction_items ti
+		JOIN transactions t ON ti.transaction_id = t.id
+		JOIN products p ON ti.product_id = p.id
+		WHERE p.consignor_id = $1 AND t.status = 'completed'
+	`
+	var first sql.NullTime
+	if err := tx.QueryRowContext(ctx, query, consignorID).Scan(&first); err != nil {
+		return nil, err
+	}
+	if !first.Valid {
+		return nil, nil
+	}
+	return &first.Time, nil
+}
+
+// HasOverlappingSettlement checks whether a consignor already has a settlement covering any day of the period
+func (r *ConsignmentRepository) HasOverlappingSettlement(ctx context.Context, tx *sql.Tx, consignorID uuid.UUID, start, end time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM consignment_settlements
+			WHERE consignor_id = $1 AND period_start <= $3 AND period_end >= $2
+		)
+	`
+	var exists bool
+	err := tx.QueryRowContext(ctx, query, consignorID, start, end).Scan(&exists)
+	return exists, err
+}
+
+// GetConsignorSales aggregates net sales of a consignor's products between from (inclusive) and to (exclusive).
+// Only completed transactions count, and items returned through completed refunds are netted out
+// at their line's own price, the same basis the sales are summed on.
+func (r *ConsignmentRepository) GetConsignorSales(ctx context.Context, tx *sql.Tx, consignorID uuid.UUID, from, to time.Time) ([]domain.ConsignmentSettlementItem, error) {
+	query := `
+		WITH sold AS (
+			SELECT ti.product_id, SUM(ti.quantity) AS quantity, SUM(ti.total_amount) AS amount
+			FROM transaction_items ti
+			JOIN transactions t ON ti.transaction_id = t.id
+			JOIN products p ON ti.product_id = p.id
+			WHERE p.consignor_id = $1 AND t.status = 'completed'
+			  AND t.created_at >= $2 AND t.created_at < $3
+			GROUP BY ti.product_id
+		), returned AS (
+			SELECT ri.product_id, SUM(ri.quantity) AS quantity, SUM(ti.total_amount * ri.quantity / ti.quantity) AS amount
+			FROM refund_items ri
+			JOIN refund_records rr ON ri.refund_id = rr.id
+			JOIN transaction_items ti ON ri.transaction_item_id = ti.id
+			JOIN transactions t ON rr.transaction_id = t.id
+			JOIN products p ON ri.product_id = p.id
+			WHERE p.consignor_id = $1 AND t.status = 'completed' AND rr.status = 'completed'
+			  AND t.created_at >= $2 AND t.created_at < $3
+			GROUP BY ri.product_id
+		)
+		SELECT p.id, p.name, COALESCE(p.commission_rate, 0),
+			s.quantity - COALESCE(rt.quantity, 0),
+			s.amount - COALESCE(rt.amount, 0)
+		FROM sold s
+		JOIN products p ON s.product_id = p.id
+		LEFT JOIN returned rt ON rt.product_id = s.product_id
+		ORDER BY p.name
+	`
+
+	rows, err := tx.QueryContext(ctx, query, consignorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []domain.ConsignmentSettlementItem
+	for rows.Next() {
+		var item domain.ConsignmentSettlementItem
+		if err := rows.Scan(&item.ProductID, &item.ProductName, &item.CommissionRate, &item.QuantitySold, &item.TotalSales); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetSettlement retrieves a settlement with consignor and items
+func (r *ConsignmentRepository) GetSettlement(ctx context.Context, id uuid.UUID) (*domain.ConsignmentSettlement, error) {
+	query := `
+		SELECT s.id, s.settlement_number, s.consignor_id, s.period_start, s.period_end, s.total_sales,
+		       s.commission_amount, s.consignor_amount, s.status, s.notes, s.created_by, s.paid_by, s.paid_at,
+		       s.created_at, s.updated_at, c.name, c.bank_account, c.bank_name
+		FROM consignment_settlements s
+		JOIN consignors c ON s.consignor_id = c.id
+		WHERE s.id = $1
+	`
+	var s domain.ConsignmentSettlement
+	var c domain.Consignor
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&s.ID, &s.SettlementNumber, &s.ConsignorID, &s.PeriodStart, &s.PeriodEnd, &s.TotalSales,
+		&s.CommissionAmount, &s.ConsignorAmount, &s.Status, &s.Notes, &s.CreatedBy, &s.PaidBy, &s.PaidAt,
+		&s.CreatedAt, &s.UpdatedAt, &c.Name, &c.BankAccount, &c.BankName,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.ID = s.ConsignorID
+	s.Consignor = &c
+
+	itemQuery := `
+		SELECT id, settlement_id, product_id, product_name, quantity_sold, unit_price, total_sales,
+		       commission_rate, commission_amount, consignor_amount, created_at
+		FROM consignment_settlement_items WHERE settlement_id = $1 ORDER BY product_name
+	`
+	rows, err := r.db.QueryContext(ctx, itemQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item domain.ConsignmentSettlementItem
+		if err := rows.Scan(
+			&item.ID, &item.SettlementID, &item.ProductID, &item.ProductName, &item.QuantitySold, &item.UnitPrice,
+			&item.TotalSales, &item.CommissionRate, &item.CommissionAmount, &item.ConsignorAmount, &item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		s.Items = append(s.Items, item)
+	}
+	return &s, rows.Err()
+}
+
+// GetSettlementForUpdate retrieves a settlement header and locks the row (used within transaction)
+func (r *ConsignmentRepository) GetSettlementForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*domain.ConsignmentSettlement, error) {
+	query := `
+		SELECT id, settlement_number, consignor_id, period_start, period_end, total_sales,
+		       commission_amount, consignor_amount, status, notes, created_by, paid_by, paid_at, created_at, updated_at
+		FROM consignment_settlements WHERE id = $1
+		FOR UPDATE
+	`
+	var s domain.ConsignmentSettlement
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&s.ID, &s.SettlementNumber, &s.ConsignorID, &s.PeriodStart, &s.PeriodEnd, &s.TotalSales,
+		&s.CommissionAmount, &s.ConsignorAmount, &s.Status, &s.Notes, &s.CreatedBy, &s.PaidBy, &s.PaidAt, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSettlements lists settlements with filtering and pagination
+func (r *ConsignmentRepository) ListSettlements(ctx context.Context, filter domain.SettlementFilter) ([]domain.ConsignmentSettlement, int64, error) {
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.ConsignorID != nil {
+		whereClause += fmt.Sprintf(" AND s.consignor_id = $%d", argIndex)
+		args = append(args, *filter.ConsignorID)
+		argIndex++
+	}
+	if filter.Status != nil {
+		whereClause += fmt.Sprintf(" AND s.status = $%d", argIndex)
+		args = append(args, *filter.Status)
+		argIndex++
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM consignment_settlements s %s", whereClause), args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := filter.PerPage
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := fmt.Sprintf(`
+		SELECT s.id, s.settlement_number, s.consignor_id, s.period_start, s.period_end, s.total_sales,
+		       s.commission_amount, s.consignor_amount, s.status, s.notes, s.created_by, s.paid_by, s.paid_at,
+		       s.created_at, s.updated_at, c.name
+		FROM consignment_settlements s
+		JOIN consignors c ON s.consignor_id = c.id
+		%s
+		ORDER BY s.period_end DESC, s.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIndex, argIndex+1)
+	args = append(args, perPage, (page-1)*perPage)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var settlements []domain.ConsignmentSettlement
+	for rows.Next() {
+		var s domain.ConsignmentSettlement
+		var consignorName string
+		if err := rows.Scan(
+			&s.ID, &s.SettlementNumber, &s.ConsignorID, &s.PeriodStart, &s.PeriodEnd, &s.TotalSales,
+			&s.CommissionAmount, &s.ConsignorAmount, &s.Status, &s.Notes, &s.CreatedBy, &s.PaidBy, &s.PaidAt,
+			&s.CreatedAt, &s.UpdatedAt, &consignorName,
+		); err != nil {
+			return nil, 0, err
+		}
+		s.Consignor = &domain.Consignor{ID: s.ConsignorID, Name: consignorName}
+		settlements = append(settlements, s)
+	}
+	return settlements, total, rows.Err()
+}
+
+// UpdateSettlementStatus moves a settlement to a new status, stamping payment details when paid
+func (r *ConsignmentRepository) UpdateSettlementStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status domain.SettlementStatus, paidBy *string) error {
+	query := `
+		UPDATE consignment_settlements
+		SET status = $2,
+		    paid_by = COALESCE($3, paid_by),
+		    paid_at = CASE WHEN $2 = 'paid' THEN NOW() ELSE paid_at END,
+		    updated_at = NOW()
+		WHERE id = $1
+	`
+	result, err := tx.ExecContext(ctx, query, id, status, paidBy)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteSettlement deletes a settlement (used within transaction)
+func (r *ConsignmentRepository) DeleteSettlement(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM consignment_settlements WHERE id = $1", id)
+	return err
 }
 
 func (r *ConsignmentRepository) DeleteConsignor(ctx context.Context, id uuid.UUID) error {
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -74,6 +75,32 @@ func (r *CustomerRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain
 	return &customer, nil
 }
 
+// GetForUpdate retrieves a customer and locks the row until the transaction ends (used within
+// transaction), so debt checks and changes from concurrent sales are applied one at a time
+func (r *CustomerRepository) GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*domain.Customer, error) {
+	query := `
+		SELECT id, name, phone, address, notes, credit_limit, current_debt, is_active, created_at, updated_at
+		FROM customers
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	var customer domain.Customer
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&customer.ID, &customer.Name, &customer.Phone, &customer.Address,
+		&customer.Notes, &customer.CreditLimit, &customer.CurrentDebt,
+		&customer.IsActive, &customer.CreatedAt, &customer.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock customer: %w", err)
+	}
+
+	return &customer, nil
+}
+
 // List retrieves customers with filtering and pagination
 func (r *CustomerRepository) List(ctx context.Context, filter domain.CustomerFilter) ([]domain.Customer, int64, error) {
 	var conditions []string
@@ -165,6 +192,46 @@ func (r *CustomerRepository) List(ctx context.Context, filter domain.CustomerFil
 	return customers, total, rows.Err()
 }
 
+// ListChangedSince returns customers changed after since, including deactivated ones.
+// A nil since returns every active customer.
+func (r *CustomerRepository) ListChangedSince(ctx context.Context, since *time.Time) ([]domain.Customer, error) {
+	query := `
+		SELECT id, name, phone, address, notes, credit_limit, current_debt, is_active, created_at, updated_at
+		FROM customers
+		WHERE is_active = true
+		ORDER BY updated_at
+	`
+	var args []interface{}
+	if since != nil {
+		query = `
+			SELECT id, name, phone, address, notes, credit_limit, current_debt, is_active, created_at, updated_at
+			FROM customers
+			WHERE updated_at > $1
+			ORDER BY updated_at
+		`
+		args = append(args, *since)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed customers: %w", err)
+	}
+	defer rows.Close()
+
+	customers := []domain.Customer{}
+	for rows.Next() {
+		var c domain.Customer
+		if err := rows.Scan(
+			&c.ID, &c.Name, &c.Phone, &c.Address, &c.Notes,
+			&c.CreditLimit, &c.CurrentDebt, &c.IsActive, &c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan customer: %w", err)
+		}
+		customers = append(customers, c)
+	}
+	return customers, rows.Err()
+}
+
 // Update updates a customer
 func (r *CustomerRepository) Update(ctx context.Context, id uuid.UUID, input domain.CustomerUpdateInput) (*domain.Customer, error) {
 	var setClauses []string
@@ -258,13 +325,19 @@ func (r *CustomerRepository) AddDebt(ctx context.Context, id uuid.UUID, amount i
 }
 
 // SubtractDebt subtracts from the customer's current debt
-func (r *CustomerRepository) SubtractDebt(ctx context.Context, id uuid.UUID, amount int64) error {
+func (r *CustomerRepository) SubtractDebt(ctx context.Context, tx *sql.Tx, id uuid.UUID, amount int64) error {
 	query := `
 		UPDATE customers 
 		SET current_debt = GREATEST(0, current_debt - $1), updated_at = NOW() 
 		WHERE id = $2
 	`
-	result, err := r.db.ExecContext(ctx, query, amount, id)
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, query, amount, id)
+	} else {
+		result, err = r.db.ExecContext(ctx, query, amount, id)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to subtract debt: %w", err)
 	}
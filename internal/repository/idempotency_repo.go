@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// IdempotencyRepository handles idempotency key database operations
+type IdempotencyRepository struct {
+	db *database.PostgresDB
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository
+func NewIdempotencyRepository(db *database.PostgresDB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Begin claims a key for a new request. It returns claimed=true when the caller
+// should execute the request, otherwise the existing record is returned.
+// Keys older than ttl, and processing keys abandoned for longer than staleAfter, are reclaimed.
+func (r *IdempotencyRepository) Begin(ctx context.Context, scope, key, requestHash string, ttl, staleAfter time.Duration) (*domain.IdempotencyRecord, bool, error) {
+	var claimedKey string
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (scope, idempotency_key, request_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (scope, idempotency_key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash, status = 'processing',
+			response_status = NULL, response_content_type = NULL, response_body = NULL,
+			created_at = NOW(), completed_at = NULL
+		WHERE idempotency_keys.created_at < NOW() - $4::interval
+			OR (idempotency_keys.status = 'processing' AND idempotency_keys.created_at < NOW() - $5::interval)
+		RETURNING idempotency_key
+	`, scope, key, requestHash, interval(ttl), interval(staleAfter)).Scan(&claimedKey)
+	if err == nil {
+		return nil, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	var rec domain.IdempotencyRecord
+	err = r.db.QueryRowContext(ctx, `
+		SELECT scope, idempotency_key, request_hash, status, response_status,
+			response_content_type, response_body, created_at, completed_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2
+	`, scope, key).Scan(
+		&rec.Scope, &rec.Key, &rec.RequestHash, &rec.Status, &rec.ResponseStatus,
+		&rec.ResponseContentType, &rec.ResponseBody, &rec.CreatedAt, &rec.CompletedAt,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return &rec, false, nil
+}
+
+// Complete stores the response of a claimed key
+func (r *IdempotencyRepository) Complete(ctx context.Context, scope, key string, status int, contentType string, body []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status = 'completed', response_status = $3, response_content_type = $4,
+			response_body = $5, completed_at = NOW()
+		WHERE scope = $1 AND idempotency_key = $2
+	`, scope, key, status, contentType, body)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release frees a claimed key so the request can be retried
+func (r *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2 AND status = 'processing'
+	`, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func interval(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int64(d.Seconds()))
+}
@@ -24,8 +24,9 @@ func NewInventoryRepository(db *database.PostgresDB) *InventoryRepository {
 // CreateMovement creates a stock movement record
 func (r *InventoryRepository) CreateMovement(ctx context.Context, tx *sql.Tx, movement *domain.StockMovement) error {
 	query := `
-		INSERT INTO stock_movements (product_id, type, quantity, stock_before, stock_after, reference_type, reference_id, cost_per_unit, notes, created_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO stock_movements (product_id, type, quantity, stock_before, stock_after, reference_type, reference_id, cost_per_unit,
+			batch_number, expiry_date, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at
 	`
 
@@ -33,12 +34,14 @@ func (r *InventoryRepository) CreateMovement(ctx context.Context, tx *sql.Tx, mo
 	if tx != nil {
 		err = tx.QueryRowContext(ctx, query,
 			movement.ProductID, movement.Type, movement.Quantity, movement.StockBefore, movement.StockAfter,
-			movement.ReferenceType, movement.ReferenceID, movement.CostPerUnit, movement.Notes, movement.CreatedBy,
+			movement.ReferenceType, movement.ReferenceID, movement.CostPerUnit, movement.BatchNumber, movement.ExpiryDate,
+			movement.Notes, movement.CreatedBy,
 		).Scan(&movement.ID, &movement.CreatedAt)
 	} else {
 		err = r.db.QueryRowContext(ctx, query,
 			movement.ProductID, movement.Type, movement.Quantity, movement.StockBefore, movement.StockAfter,
-			movement.ReferenceType, movement.ReferenceID, movement.CostPerUnit, movement.Notes, movement.CreatedBy,
+			movement.ReferenceType, movement.ReferenceID, movement.CostPerUnit, movement.BatchNumber, movement.ExpiryDate,
+			movement.Notes, movement.CreatedBy,
 		).Scan(&movement.ID, &movement.CreatedAt)
 	}
 	return err
@@ -80,7 +83,8 @@ func (r *InventoryRepository) GetByProduct(ctx context.Context, productID uuid.U
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, product_id, type, quantity, stock_before, stock_after, reference_type, reference_id, cost_per_unit, notes, created_by, created_at
+		SELECT id, product_id, type, quantity, stock_before, stock_after, reference_type, reference_id, cost_per_unit,
+			batch_number, expiry_date, notes, created_by, created_at
 		FROM stock_movements %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d
 	`, whereClause, argIndex, argIndex+1)
 
@@ -97,7 +101,7 @@ func (r *InventoryRepository) GetByProduct(ctx context.Context, productID uuid.U
 		var m domain.StockMovement
 		if err := rows.Scan(
 			&m.ID, &m.ProductID, &m.Type, &m.Quantity, &m.StockBefore, &m.StockAfter,
-			&m.ReferenceType, &m.ReferenceID, &m.CostPerUnit, &m.Notes, &m.CreatedBy, &m.CreatedAt,
+			&m.ReferenceType, &m.ReferenceID, &m.CostPerUnit, &m.BatchNumber, &m.ExpiryDate, &m.Notes, &m.CreatedBy, &m.CreatedAt,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -106,8 +110,13 @@ func (r *InventoryRepository) GetByProduct(ctx context.Context, productID uuid.U
 	return movements, total, rows.Err()
 }
 
-// Restock adds stock to a product
+// Restock adds stock to a product, as a new lot when lot details are given
 func (r *InventoryRepository) Restock(ctx context.Context, input domain.RestockInput) (*domain.StockMovement, error) {
+	lot, err := input.Lot()
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -115,7 +124,8 @@ func (r *InventoryRepository) Restock(ctx context.Context, input domain.RestockI
 	defer tx.Rollback()
 
 	var currentStock int
-	err = tx.QueryRowContext(ctx, "SELECT current_stock FROM products WHERE id = $1", input.ProductID).Scan(&currentStock)
+	var isStockActive bool
+	err = tx.QueryRowContext(ctx, "SELECT current_stock, is_stock_active FROM products WHERE id = $1 FOR UPDATE", input.ProductID).Scan(&currentStock, &isStockActive)
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNotFound
 	}
@@ -123,6 +133,22 @@ func (r *InventoryRepository) Restock(ctx context.Context, input domain.RestockI
 		return nil, err
 	}
 
+	// Restocks entered per pack or dus are stored per base unit
+	if input.UnitID != nil {
+		var unit domain.ProductUnit
+		err := tx.QueryRowContext(ctx,
+			"SELECT conversion_factor FROM product_units WHERE id = $1 AND product_id = $2 AND is_active = true",
+			*input.UnitID, input.ProductID,
+		).Scan(&unit.ConversionFactor)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: unit %s not found for this product", domain.ErrInvalidInput, *input.UnitID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get product unit: %w", err)
+		}
+		input.Quantity, input.CostPerUnit = unit.ToBase(input.Quantity, input.CostPerUnit)
+	}
+
 	newStock := currentStock + input.Quantity
 
 	movement := &domain.StockMovement{
@@ -132,6 +158,8 @@ func (r *InventoryRepository) Restock(ctx context.Context, input domain.RestockI
 		StockBefore: currentStock,
 		StockAfter:  newStock,
 		CostPerUnit: &input.CostPerUnit,
+		BatchNumber: lot.BatchNumber,
+		ExpiryDate:  lot.ExpiryDate,
 		Notes:       input.Notes,
 		CreatedBy:   input.CreatedBy,
 	}
@@ -140,6 +168,16 @@ func (r *InventoryRepository) Restock(ctx context.Context, input domain.RestockI
 		return nil, err
 	}
 
+	if isStockActive && !lot.IsZero() {
+		if err := r.createBatch(ctx, tx, movement); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.receiveCost(ctx, tx, movement, input.CostPerUnit, isStockActive); err != nil {
+		return nil, err
+	}
+
 	if _, err := tx.ExecContext(ctx, "UPDATE products SET current_stock = $1, updated_at = NOW() WHERE id = $2", newStock, input.ProductID); err != nil {
 		return nil, err
 	}
@@ -156,7 +194,7 @@ func (r *InventoryRepository) Adjust(ctx context.Context, input domain.StockAdju
 	defer tx.Rollback()
 
 	var currentStock int
-	err = tx.QueryRowContext(ctx, "SELECT current_stock FROM products WHERE id = $1", input.ProductID).Scan(&currentStock)
+	err = tx.QueryRowContext(ctx, "SELECT current_stock FROM products WHERE id = $1 FOR UPDATE", input.ProductID).Scan(&currentStock)
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNotFound
 	}
@@ -183,6 +221,19 @@ func (r *InventoryRepository) Adjust(ctx context.Context, input domain.StockAdju
 		return nil, err
 	}
 
+	if newStock < currentStock {
+		if err := r.consumeBatches(ctx, tx, movement, currentStock-newStock, nil); err != nil {
+			return nil, err
+		}
+		if _, err := r.consumeCost(ctx, tx, input.ProductID, currentStock-newStock); err != nil {
+			return nil, err
+		}
+	} else if newStock > currentStock {
+		if err := r.addCostLayerAtAverage(ctx, tx, movement); err != nil {
+			return nil, err
+		}
+	}
+
 	if _, err := tx.ExecContext(ctx, "UPDATE products SET current_stock = $1, updated_at = NOW() WHERE id = $2", newStock, input.ProductID); err != nil {
 		return nil, err
 	}
@@ -190,48 +241,208 @@ func (r *InventoryRepository) Adjust(ctx context.Context, input domain.StockAdju
 	return movement, tx.Commit()
 }
 
-// DeductStock deducts stock for a sale (used within transaction)
-func (r *InventoryRepository) DeductStock(ctx context.Context, tx *sql.Tx, productID, transactionID uuid.UUID, quantity int, createdBy *string) error {
+// DeductStock deducts stock for a sale (used within transaction) and returns the cost of
+// the units sold under the given costing method.
+// The decrement is a single conditional UPDATE, so stock can never go below zero
+// even when two sales of the same product commit at the same time.
+// The sold units are taken from the product's lots first-expired-first-out and from its
+// cost layers oldest first.
+func (r *InventoryRepository) DeductStock(ctx context.Context, tx *sql.Tx, productID, transactionID uuid.UUID, quantity int,
+	method domain.CostingMethod, createdBy *string) (int64, error) {
+
+	var newStock int
+	var averageCost int64
+	err := tx.QueryRowContext(ctx, `
+		UPDATE products SET current_stock = current_stock - $1, updated_at = NOW()
+		WHERE id = $2 AND is_stock_active = true AND current_stock >= $1
+		RETURNING current_stock, cost_price
+	`, quantity, productID).Scan(&newStock, &averageCost)
+	if err == sql.ErrNoRows {
+		// Either the product does not track stock or there is not enough of it
+		var isStockActive bool
+		if err := tx.QueryRowContext(ctx, "SELECT is_stock_active, cost_price FROM products WHERE id = $1", productID).Scan(&isStockActive, &averageCost); err != nil {
+			if err == sql.ErrNoRows {
+				return 0, domain.ErrNotFound
+			}
+			return 0, err
+		}
+		if !isStockActive {
+			return averageCost * int64(quantity), nil // Non-tracked products sell at their cost price
+		}
+		return 0, domain.ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	fifoCost, err := r.consumeCost(ctx, tx, productID, quantity)
+	if err != nil {
+		return 0, err
+	}
+	cogs := averageCost * int64(quantity)
+	if method == domain.CostingFIFO {
+		cogs = fifoCost
+	}
+	unitCost := domain.DivRound(cogs, int64(quantity))
+
+	refType := "transaction"
+	movement := &domain.StockMovement{
+		ProductID:     productID,
+		Type:          domain.StockMovementTypeSale,
+		Quantity:      -quantity,
+		StockBefore:   newStock + quantity,
+		StockAfter:    newStock,
+		ReferenceType: &refType,
+		ReferenceID:   &transactionID,
+		CostPerUnit:   &unitCost,
+		CreatedBy:     createdBy,
+	}
+
+	if err := r.CreateMovement(ctx, tx, movement); err != nil {
+		return 0, err
+	}
+	if err := r.consumeBatches(ctx, tx, movement, quantity, &transactionID); err != nil {
+		return 0, err
+	}
+	return cogs, nil
+}
+
+// ReceiveStock adds purchased stock to a product and moves its average cost price (used within transaction).
+// Stock received with lot details becomes a new lot.
+func (r *InventoryRepository) ReceiveStock(ctx context.Context, tx *sql.Tx, productID uuid.UUID, quantity int, costPerUnit int64,
+	lot domain.StockLot, refType string, refID *uuid.UUID, notes *string, createdBy *string) (*domain.StockMovement, error) {
+
+	var currentStock int
+	var isStockActive bool
+	err := tx.QueryRowContext(ctx, "SELECT current_stock, is_stock_active FROM products WHERE id = $1 FOR UPDATE", productID).Scan(&currentStock, &isStockActive)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	newStock := currentStock + quantity
+
+	movement := &domain.StockMovement{
+		ProductID:     productID,
+		Type:          domain.StockMovementTypePurchase,
+		Quantity:      quantity,
+		StockBefore:   currentStock,
+		StockAfter:    newStock,
+		ReferenceType: &refType,
+		ReferenceID:   refID,
+		CostPerUnit:   &costPerUnit,
+		BatchNumber:   lot.BatchNumber,
+		ExpiryDate:    lot.ExpiryDate,
+		Notes:         notes,
+		CreatedBy:     createdBy,
+	}
+
+	if err := r.CreateMovement(ctx, tx, movement); err != nil {
+		return nil, err
+	}
+
+	if isStockActive && !lot.IsZero() {
+		if err := r.createBatch(ctx, tx, movement); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.receiveCost(ctx, tx, movement, costPerUnit, isStockActive); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET current_stock = $1, updated_at = NOW() WHERE id = $2", newStock, productID); err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// ReturnStock puts goods returned from a sale back into stock as a return movement (used within transaction).
+// The units go back into the lots the sale took them from, at the cost the sale booked for them.
+// Products that do not track stock are skipped and return a nil movement.
+func (r *InventoryRepository) ReturnStock(ctx context.Context, tx *sql.Tx, productID uuid.UUID, quantity int, transactionID uuid.UUID,
+	refType string, refID *uuid.UUID, notes *string, createdBy *string) (*domain.StockMovement, error) {
+
 	var currentStock int
 	var isStockActive bool
-	err := tx.QueryRowContext(ctx, "SELECT current_stock, is_stock_active FROM products WHERE id = $1", productID).Scan(&currentStock, &isStockActive)
+	err := tx.QueryRowContext(ctx, "SELECT current_stock, is_stock_active FROM products WHERE id = $1 FOR UPDATE", productID).Scan(&currentStock, &isStockActive)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !isStockActive {
-		return nil // Skip for non-tracked products
+		return nil, nil
 	}
 
-	if currentStock < quantity {
-		return domain.ErrInsufficientStock
+	unitCost, err := r.saleUnitCost(ctx, tx, productID, transactionID)
+	if err != nil {
+		return nil, err
 	}
 
-	newStock := currentStock - quantity
-	refType := "transaction"
+	newStock := currentStock + quantity
 
 	movement := &domain.StockMovement{
 		ProductID:     productID,
-		Type:          domain.StockMovementTypeSale,
-		Quantity:      -quantity,
+		Type:          domain.StockMovementTypeReturn,
+		Quantity:      quantity,
 		StockBefore:   currentStock,
 		StockAfter:    newStock,
 		ReferenceType: &refType,
-		ReferenceID:   &transactionID,
+		ReferenceID:   refID,
+		CostPerUnit:   &unitCost,
+		Notes:         notes,
 		CreatedBy:     createdBy,
 	}
 
 	if err := r.CreateMovement(ctx, tx, movement); err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = tx.ExecContext(ctx, "UPDATE products SET current_stock = $1, updated_at = NOW() WHERE id = $2", newStock, productID)
-	return err
+	if err := r.restoreBatches(ctx, tx, movement, transactionID); err != nil {
+		return nil, err
+	}
+
+	if err := r.receiveCost(ctx, tx, movement, unitCost, true); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET current_stock = $1, updated_at = NOW() WHERE id = $2", newStock, productID); err != nil {
+		return nil, err
+	}
+
+	return movement, nil
 }
 
-// RecordMovement records a stock movement with specified parameters (for external callers like stock opname)
+// RecordMovement records a stock movement with specified parameters (for external callers like stock opname
+// and repacking). It does not change current_stock.
+// Outgoing movements take their units from the product's lots and cost layers and, without a cost per
+// unit, record what the layers cost. Incoming movements with a cost per unit are received at that cost,
+// moving the average cost; without one they are layered at the average cost.
+// The returned cost is what an outgoing movement took out of stock, unrounded: the layers' cost, or the
+// given cost per unit times the quantity.
 func (r *InventoryRepository) RecordMovement(ctx context.Context, tx *sql.Tx, productID uuid.UUID, movementType domain.StockMovementType,
-	quantity, stockBefore, stockAfter int, refType string, refID *uuid.UUID, costPerUnit *int64, notes string, createdBy *string) error {
+	quantity, stockBefore, stockAfter int, refType string, refID *uuid.UUID, costPerUnit *int64, notes string, createdBy *string) (*domain.StockMovement, int64, error) {
+
+	var cost int64
+	if quantity < 0 {
+		var err error
+		cost, err = r.consumeCost(ctx, tx, productID, -quantity)
+		if err != nil {
+			return nil, 0, err
+		}
+		if costPerUnit == nil {
+			unitCost := domain.DivRound(cost, int64(-quantity))
+			costPerUnit = &unitCost
+		} else {
+			cost = *costPerUnit * int64(-quantity)
+		}
+	}
 
 	movement := &domain.StockMovement{
 		ProductID:     productID,
@@ -246,7 +457,143 @@ func (r *InventoryRepository) RecordMovement(ctx context.Context, tx *sql.Tx, pr
 		CreatedBy:     createdBy,
 	}
 
-	return r.CreateMovement(ctx, tx, movement)
+	if err := r.CreateMovement(ctx, tx, movement); err != nil {
+		return nil, 0, err
+	}
+
+	var err error
+	switch {
+	case quantity < 0:
+		err = r.consumeBatches(ctx, tx, movement, -quantity, nil)
+	case quantity > 0 && costPerUnit != nil:
+		err = r.receiveCost(ctx, tx, movement, *costPerUnit, true)
+	case quantity > 0:
+		err = r.addCostLayerAtAverage(ctx, tx, movement)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return movement, cost, nil
+}
+
+// Repack converts stock of one product into another, e.g. a sack of rice into 1 kg packs, as a
+// transfer_out and a transfer_in movement sharing one reference. The cost of the source units,
+// measured with the given costing method, is carried over to the target units.
+func (r *InventoryRepository) Repack(ctx context.Context, input domain.RepackInput, method domain.CostingMethod) (*domain.RepackResult, error) {
+	if input.SourceProductID == input.TargetProductID {
+		return nil, fmt.Errorf("%w: source and target must be different products", domain.ErrInvalidInput)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock both products in ID order, like checkout does
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, name, current_stock, is_stock_active, cost_price FROM products
+		WHERE id IN ($1, $2)
+		ORDER BY id
+		FOR UPDATE
+	`, input.SourceProductID, input.TargetProductID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock products: %w", err)
+	}
+	type stockRow struct {
+		name          string
+		currentStock  int
+		isStockActive bool
+		costPrice     int64
+	}
+	locked := make(map[uuid.UUID]stockRow, 2)
+	for rows.Next() {
+		var id uuid.UUID
+		var p stockRow
+		if err := rows.Scan(&id, &p.name, &p.currentStock, &p.isStockActive, &p.costPrice); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		locked[id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	source, ok := locked[input.SourceProductID]
+	if !ok {
+		return nil, fmt.Errorf("source product %s: %w", input.SourceProductID, domain.ErrNotFound)
+	}
+	target, ok := locked[input.TargetProductID]
+	if !ok {
+		return nil, fmt.Errorf("target product %s: %w", input.TargetProductID, domain.ErrNotFound)
+	}
+	if !source.isStockActive || !target.isStockActive {
+		return nil, fmt.Errorf("%w: both products must track stock", domain.ErrInvalidInput)
+	}
+	if source.currentStock < input.SourceQuantity {
+		return nil, fmt.Errorf("%w for %s (available: %d, requested: %d)",
+			domain.ErrInsufficientStock, source.name, source.currentStock, input.SourceQuantity)
+	}
+
+	notes := fmt.Sprintf("Repack %d %s into %d %s", input.SourceQuantity, source.name, input.TargetQuantity, target.name)
+	if input.Notes != nil && *input.Notes != "" {
+		notes += ": " + *input.Notes
+	}
+	repackID := uuid.New()
+
+	// Without a cost the outgoing movement is costed from the FIFO layers
+	var sourceCost *int64
+	if method != domain.CostingFIFO {
+		sourceCost = &source.costPrice
+	}
+	out, totalCost, err := r.RecordMovement(ctx, tx, input.SourceProductID, domain.StockMovementTypeTransferOut,
+		-input.SourceQuantity, source.currentStock, source.currentStock-input.SourceQuantity,
+		"repack", &repackID, sourceCost, notes, input.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// The target is received at the exact total, so no value is lost or made up in rounding
+	targetCost := domain.DivRound(totalCost, int64(input.TargetQuantity))
+	refType := "repack"
+	in := &domain.StockMovement{
+		ProductID:     input.TargetProductID,
+		Type:          domain.StockMovementTypeTransferIn,
+		Quantity:      input.TargetQuantity,
+		StockBefore:   target.currentStock,
+		StockAfter:    target.currentStock + input.TargetQuantity,
+		ReferenceType: &refType,
+		ReferenceID:   &repackID,
+		CostPerUnit:   &targetCost,
+		Notes:         &notes,
+		CreatedBy:     input.CreatedBy,
+	}
+	if err := r.CreateMovement(ctx, tx, in); err != nil {
+		return nil, err
+	}
+	if err := r.receiveTotalCost(ctx, tx, in, totalCost, true); err != nil {
+		return nil, err
+	}
+
+	for _, m := range []*domain.StockMovement{out, in} {
+		if _, err := tx.ExecContext(ctx, "UPDATE products SET current_stock = $1, updated_at = NOW() WHERE id = $2", m.StockAfter, m.ProductID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &domain.RepackResult{
+		RepackID:       repackID,
+		SourceMovement: *out,
+		TargetMovement: *in,
+		TotalCost:      totalCost,
+		TargetUnitCost: targetCost,
+	}, nil
 }
 
 // GetStockReport returns stock inventory report
@@ -265,3 +612,622 @@ func (r *InventoryRepository) GetStockReport(ctx context.Context) (*domain.Stock
 	)
 	return &report, err
 }
+
+// GetStockFlows returns, for every active product, the stock at the start and end of a period
+// (inclusive, YYYY-MM-DD) and what came in and went out as sales in between. The opening and
+// closing stock are worked back from the current stock through the later movements.
+func (r *InventoryRepository) GetStockFlows(ctx context.Context, dateFrom, dateTo string) ([]domain.ProductStockFlow, error) {
+	query := `
+		SELECT p.id, p.name, p.is_stock_active, p.cost_price, p.current_stock,
+		       p.current_stock - COALESCE(SUM(sm.quantity), 0),
+		       COALESCE(SUM(sm.quantity) FILTER (WHERE DATE(sm.created_at) <= $2 AND sm.type IN ('initial', 'purchase', 'transfer_in')), 0),
+		       -COALESCE(SUM(sm.quantity) FILTER (WHERE DATE(sm.created_at) <= $2 AND sm.type IN ('sale', 'return')), 0),
+		       p.current_stock - COALESCE(SUM(sm.quantity) FILTER (WHERE DATE(sm.created_at) > $2), 0)
+		FROM products p
+		LEFT JOIN stock_movements sm ON sm.product_id = p.id AND DATE(sm.created_at) >= $1
+		WHERE p.is_active = true
+		GROUP BY p.id
+		ORDER BY p.name
+	`
+	rows, err := r.db.QueryContext(ctx, query, dateFrom, dateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock flows: %w", err)
+	}
+	defer rows.Close()
+
+	flows := make([]domain.ProductStockFlow, 0)
+	for rows.Next() {
+		var f domain.ProductStockFlow
+		if err := rows.Scan(&f.ProductID, &f.ProductName, &f.IsStockActive, &f.CostPrice, &f.CurrentStock,
+			&f.OpeningStock, &f.Received, &f.Sold, &f.ClosingStock); err != nil {
+			return nil, err
+		}
+		flows = append(flows, f)
+	}
+	return flows, rows.Err()
+}
+
+// GetDeadStock returns active stock-tracked products with stock on hand and no completed sale
+// since a date (YYYY-MM-DD), largest stock value first. Products created after that date are
+// too new to count.
+func (r *InventoryRepository) GetDeadStock(ctx context.Context, since string) ([]domain.DeadStockItem, error) {
+	query := `
+		SELECT p.id, p.name, p.current_stock, p.cost_price, p.current_stock * p.cost_price, ls.last_sold_at
+		FROM products p
+		LEFT JOIN (
+			SELECT ti.product_id, MAX(t.created_at) AS last_sold_at
+			FROM transaction_items ti
+			JOIN transactions t ON t.id = ti.transaction_id
+			WHERE t.status = 'completed'
+			GROUP BY ti.product_id
+		) ls ON ls.product_id = p.id
+		WHERE p.is_active = true AND p.is_stock_active = true AND p.current_stock > 0
+		  AND DATE(p.created_at) < $1
+		  AND (ls.last_sold_at IS NULL OR DATE(ls.last_sold_at) < $1)
+		ORDER BY 5 DESC, p.name
+	`
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead stock: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]domain.DeadStockItem, 0)
+	for rows.Next() {
+		var item domain.DeadStockItem
+		if err := rows.Scan(&item.ProductID, &item.ProductName, &item.CurrentStock, &item.CostPrice, &item.StockValue, &item.LastSoldAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// createBatch opens a lot for the stock brought in by a movement (used within transaction)
+func (r *InventoryRepository) createBatch(ctx context.Context, tx *sql.Tx, movement *domain.StockMovement) error {
+	query := `
+		INSERT INTO stock_batches (product_id, batch_number, expiry_date, initial_quantity, remaining_quantity, cost_per_unit, movement_id)
+		VALUES ($1, $2, $3, $4, $4, $5, $6)
+	`
+	_, err := tx.ExecContext(ctx, query,
+		movement.ProductID, movement.BatchNumber, movement.ExpiryDate, movement.Quantity, movement.CostPerUnit, movement.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create stock batch: %w", err)
+	}
+	return nil
+}
+
+// openStock books the stock a product is created with as an initial movement at its cost price
+// (used within transaction), so that FIFO and FEFO have a layer and a lot to take it from
+func (r *InventoryRepository) openStock(ctx context.Context, tx *sql.Tx, product *domain.Product) error {
+	notes := "Opening stock"
+	movement := &domain.StockMovement{
+		ProductID:   product.ID,
+		Type:        domain.StockMovementTypeInitial,
+		Quantity:    product.CurrentStock,
+		StockBefore: 0,
+		StockAfter:  product.CurrentStock,
+		CostPerUnit: &product.CostPrice,
+		Notes:       &notes,
+	}
+	if err := r.CreateMovement(ctx, tx, movement); err != nil {
+		return fmt.Errorf("failed to record opening stock: %w", err)
+	}
+	if err := r.createBatch(ctx, tx, movement); err != nil {
+		return err
+	}
+	return r.receiveCost(ctx, tx, movement, product.CostPrice, true)
+}
+
+// consumeBatches takes the units of an outgoing movement from the product's lots (used within transaction).
+// Lots are used by earliest expiry, then lots without an expiry by age; expired lots come last
+// so they are only sold when nothing else is left. Units beyond what the lots hold come from
+// stock that is not in any lot, which keeps the lots within the product's stock.
+func (r *InventoryRepository) consumeBatches(ctx context.Context, tx *sql.Tx, movement *domain.StockMovement, quantity int, transactionID *uuid.UUID) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, remaining_quantity FROM stock_batches
+		WHERE product_id = $1 AND remaining_quantity > 0
+		ORDER BY (expiry_date IS NOT NULL AND expiry_date < CURRENT_DATE), expiry_date NULLS LAST, created_at, id
+		FOR UPDATE
+	`, movement.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to get stock batches: %w", err)
+	}
+
+	type take struct {
+		batchID  uuid.UUID
+		quantity int
+	}
+	var takes []take
+	for rows.Next() && quantity > 0 {
+		var batchID uuid.UUID
+		var remaining int
+		if err := rows.Scan(&batchID, &remaining); err != nil {
+			rows.Close()
+			return err
+		}
+		n := min(remaining, quantity)
+		takes = append(takes, take{batchID: batchID, quantity: n})
+		quantity -= n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range takes {
+		if err := r.allocateBatch(ctx, tx, t.batchID, movement.ID, transactionID, -t.quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreBatches puts the units of a return movement back into the lots the sale took them from
+// (used within transaction). Units the sale did not take from a lot stay outside any lot.
+func (r *InventoryRepository) restoreBatches(ctx context.Context, tx *sql.Tx, movement *domain.StockMovement, transactionID uuid.UUID) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.batch_id, -SUM(a.quantity)
+		FROM stock_batch_allocations a
+		JOIN stock_batches b ON b.id = a.batch_id
+		WHERE a.transaction_id = $1 AND b.product_id = $2
+		GROUP BY a.batch_id, b.expiry_date, b.created_at
+		HAVING SUM(a.quantity) < 0
+		ORDER BY b.expiry_date DESC NULLS FIRST, b.created_at DESC
+	`, transactionID, movement.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to get sold stock batches: %w", err)
+	}
+
+	quantity := movement.Quantity
+	type put struct {
+		batchID  uuid.UUID
+		quantity int
+	}
+	var puts []put
+	for rows.Next() && quantity > 0 {
+		var batchID uuid.UUID
+		var outstanding int
+		if err := rows.Scan(&batchID, &outstanding); err != nil {
+			rows.Close()
+			return err
+		}
+		n := min(outstanding, quantity)
+		puts = append(puts, put{batchID: batchID, quantity: n})
+		quantity -= n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range puts {
+		if err := r.allocateBatch(ctx, tx, p.batchID, movement.ID, &transactionID, p.quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocateBatch changes what is left in a lot and links the change to its movement
+func (r *InventoryRepository) allocateBatch(ctx context.Context, tx *sql.Tx, batchID, movementID uuid.UUID, transactionID *uuid.UUID, quantity int) error {
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE stock_batches SET remaining_quantity = remaining_quantity + $1, updated_at = NOW() WHERE id = $2",
+		quantity, batchID,
+	); err != nil {
+		return fmt.Errorf("failed to update stock batch: %w", err)
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_batch_allocations (batch_id, movement_id, transaction_id, quantity)
+		VALUES ($1, $2, $3, $4)
+	`, batchID, movementID, transactionID, quantity)
+	if err != nil {
+		return fmt.Errorf("failed to record stock batch allocation: %w", err)
+	}
+	return nil
+}
+
+// ListBatches retrieves the lots of a product, soonest to expire first.
+// Used-up lots are only included when includeEmpty is set.
+func (r *InventoryRepository) ListBatches(ctx context.Context, productID uuid.UUID, includeEmpty bool) ([]domain.StockBatch, error) {
+	query := `
+		SELECT id, product_id, batch_number, expiry_date, initial_quantity, remaining_quantity,
+			cost_per_unit, movement_id, created_at, updated_at
+		FROM stock_batches
+		WHERE product_id = $1 AND ($2 OR remaining_quantity > 0)
+		ORDER BY expiry_date NULLS LAST, created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, productID, includeEmpty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock batches: %w", err)
+	}
+	defer rows.Close()
+
+	batches := make([]domain.StockBatch, 0)
+	for rows.Next() {
+		var b domain.StockBatch
+		if err := rows.Scan(
+			&b.ID, &b.ProductID, &b.BatchNumber, &b.ExpiryDate, &b.InitialQuantity, &b.RemainingQuantity,
+			&b.CostPerUnit, &b.MovementID, &b.CreatedAt, &b.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+// WriteOffBatch removes stock from a lot as a damage movement
+func (r *InventoryRepository) WriteOffBatch(ctx context.Context, batchID uuid.UUID, input domain.BatchWriteOffInput) (*domain.BatchWriteOffResult, error) {
+	notes := "Lot written off"
+	if input.Notes != nil && *input.Notes != "" {
+		notes = *input.Notes
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	movement, value, err := r.writeOffBatch(ctx, tx, batchID, input.Quantity, notes, input.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &domain.BatchWriteOffResult{
+		Batches:   1,
+		Quantity:  -movement.Quantity,
+		Value:     value,
+		Movements: []domain.StockMovement{*movement},
+	}, nil
+}
+
+// WriteOffExpired writes off everything left in lots past their expiry date as damage movements
+func (r *InventoryRepository) WriteOffExpired(ctx context.Context, notes *string, createdBy *string) (*domain.BatchWriteOffResult, error) {
+	note := "Expired lot written off"
+	if notes != nil && *notes != "" {
+		note = *notes
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Ordered by product so products are locked in the same order as checkout
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM stock_batches
+		WHERE remaining_quantity > 0 AND expiry_date < CURRENT_DATE
+		ORDER BY product_id, expiry_date, created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired stock batches: %w", err)
+	}
+	var batchIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		batchIDs = append(batchIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &domain.BatchWriteOffResult{Movements: make([]domain.StockMovement, 0, len(batchIDs))}
+	for _, id := range batchIDs {
+		movement, value, err := r.writeOffBatch(ctx, tx, id, nil, note, createdBy)
+		if err != nil {
+			return nil, err
+		}
+		if movement == nil {
+			continue
+		}
+		result.Batches++
+		result.Quantity += -movement.Quantity
+		result.Value += value
+		result.Movements = append(result.Movements, *movement)
+	}
+
+	return result, tx.Commit()
+}
+
+// writeOffBatch posts a damage movement for quantity units of a lot, or all that is left when
+// quantity is nil. It returns the movement and its value; a nil movement means the lot is empty.
+func (r *InventoryRepository) writeOffBatch(ctx context.Context, tx *sql.Tx, batchID uuid.UUID, quantity *int, notes string, createdBy *string) (*domain.StockMovement, int64, error) {
+	var productID uuid.UUID
+	err := tx.QueryRowContext(ctx, "SELECT product_id FROM stock_batches WHERE id = $1", batchID).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return nil, 0, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get stock batch: %w", err)
+	}
+
+	// Product first, then lot: the same order sales use
+	var currentStock int
+	var costPrice int64
+	err = tx.QueryRowContext(ctx, "SELECT current_stock, cost_price FROM products WHERE id = $1 FOR UPDATE", productID).Scan(&currentStock, &costPrice)
+	if err == sql.ErrNoRows {
+		return nil, 0, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var b domain.StockBatch
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, product_id, batch_number, expiry_date, remaining_quantity, cost_per_unit
+		FROM stock_batches WHERE id = $1
+		FOR UPDATE
+	`, batchID).Scan(&b.ID, &b.ProductID, &b.BatchNumber, &b.ExpiryDate, &b.RemainingQuantity, &b.CostPerUnit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to lock stock batch: %w", err)
+	}
+
+	n := b.RemainingQuantity
+	if quantity != nil {
+		if *quantity < 1 || *quantity > b.RemainingQuantity {
+			return nil, 0, fmt.Errorf("%w: quantity must be between 1 and the %d left in the lot", domain.ErrInvalidInput, b.RemainingQuantity)
+		}
+		n = *quantity
+	}
+	if n == 0 {
+		return nil, 0, nil
+	}
+
+	cost := costPrice
+	if b.CostPerUnit != nil {
+		cost = *b.CostPerUnit
+	}
+	newStock := max(currentStock-n, 0)
+
+	refType := "stock_batch"
+	movement := &domain.StockMovement{
+		ProductID:     productID,
+		Type:          domain.StockMovementTypeDamage,
+		Quantity:      -n,
+		StockBefore:   currentStock,
+		StockAfter:    newStock,
+		ReferenceType: &refType,
+		ReferenceID:   &b.ID,
+		CostPerUnit:   &cost,
+		BatchNumber:   b.BatchNumber,
+		ExpiryDate:    b.ExpiryDate,
+		Notes:         &notes,
+		CreatedBy:     createdBy,
+	}
+
+	if err := r.CreateMovement(ctx, tx, movement); err != nil {
+		return nil, 0, err
+	}
+	if err := r.allocateBatch(ctx, tx, b.ID, movement.ID, nil, -n); err != nil {
+		return nil, 0, err
+	}
+	if _, err := r.consumeCost(ctx, tx, productID, n); err != nil {
+		return nil, 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET current_stock = $1, updated_at = NOW() WHERE id = $2", newStock, productID); err != nil {
+		return nil, 0, err
+	}
+
+	return movement, cost * int64(n), nil
+}
+
+// receiveCost books units coming into stock at unitCost (used within transaction): it moves the
+// product's weighted-average cost, records the change in the cost history and, for stock-tracked
+// products, opens a cost layer. The product row must already be locked.
+func (r *InventoryRepository) receiveCost(ctx context.Context, tx *sql.Tx, movement *domain.StockMovement, unitCost int64, isStockActive bool) error {
+	return r.receiveTotalCost(ctx, tx, movement, unitCost*int64(movement.Quantity), isStockActive)
+}
+
+// receiveTotalCost is receiveCost for units that cost totalCost together. When the total does not
+// divide evenly, the cost layers are split so that they still add up to it exactly.
+func (r *InventoryRepository) receiveTotalCost(ctx context.Context, tx *sql.Tx, movement *domain.StockMovement, totalCost int64, isStockActive bool) error {
+	var averageBefore int64
+	if err := tx.QueryRowContext(ctx, "SELECT cost_price FROM products WHERE id = $1", movement.ProductID).Scan(&averageBefore); err != nil {
+		return fmt.Errorf("failed to get cost price: %w", err)
+	}
+
+	// Products without stock tracking have no stock to average against
+	unitCost := domain.DivRound(totalCost, int64(movement.Quantity))
+	averageAfter := unitCost
+	if isStockActive {
+		base := int64(max(movement.StockBefore, 0))
+		averageAfter = domain.DivRound(base*averageBefore+totalCost, base+int64(movement.Quantity))
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET cost_price = $1, updated_at = NOW() WHERE id = $2", averageAfter, movement.ProductID); err != nil {
+		return fmt.Errorf("failed to update cost price: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO product_cost_history (product_id, movement_id, movement_type, quantity, unit_cost,
+			stock_before, average_cost_before, average_cost_after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, movement.ProductID, movement.ID, movement.Type, movement.Quantity, unitCost,
+		movement.StockBefore, averageBefore, averageAfter); err != nil {
+		return fmt.Errorf("failed to record cost history: %w", err)
+	}
+
+	if !isStockActive || movement.Quantity <= 0 {
+		return nil
+	}
+
+	// The remainder of the division goes into a second layer one rupiah dearer
+	quantity := int64(movement.Quantity)
+	base, extra := totalCost/quantity, totalCost%quantity
+	if extra > 0 {
+		if err := r.addCostLayer(ctx, tx, movement.ProductID, movement.ID, int(extra), base+1); err != nil {
+			return err
+		}
+	}
+	if quantity > extra {
+		return r.addCostLayer(ctx, tx, movement.ProductID, movement.ID, int(quantity-extra), base)
+	}
+	return nil
+}
+
+// addCostLayerAtAverage opens a cost layer for stock found rather than bought, such as a positive
+// adjustment, at the current average cost (used within transaction)
+func (r *InventoryRepository) addCostLayerAtAverage(ctx context.Context, tx *sql.Tx, movement *domain.StockMovement) error {
+	var average int64
+	if err := tx.QueryRowContext(ctx, "SELECT cost_price FROM products WHERE id = $1", movement.ProductID).Scan(&average); err != nil {
+		return fmt.Errorf("failed to get cost price: %w", err)
+	}
+	return r.addCostLayer(ctx, tx, movement.ProductID, movement.ID, movement.Quantity, average)
+}
+
+// addCostLayer opens a FIFO cost layer (used within transaction)
+func (r *InventoryRepository) addCostLayer(ctx context.Context, tx *sql.Tx, productID, movementID uuid.UUID, quantity int, unitCost int64) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO cost_layers (product_id, movement_id, unit_cost, initial_quantity, remaining_quantity)
+		VALUES ($1, $2, $3, $4, $4)
+	`, productID, movementID, unitCost, quantity); err != nil {
+		return fmt.Errorf("failed to create cost layer: %w", err)
+	}
+	return nil
+}
+
+// consumeCost takes quantity units out of the product's cost layers, oldest first, and returns
+// what they cost (used within transaction). Units beyond the open layers are costed at the average.
+func (r *InventoryRepository) consumeCost(ctx context.Context, tx *sql.Tx, productID uuid.UUID, quantity int) (int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, unit_cost, remaining_quantity FROM cost_layers
+		WHERE product_id = $1 AND remaining_quantity > 0
+		ORDER BY created_at, id
+		FOR UPDATE
+	`, productID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cost layers: %w", err)
+	}
+
+	type take struct {
+		layerID  uuid.UUID
+		quantity int
+	}
+	var takes []take
+	var cost int64
+	for rows.Next() && quantity > 0 {
+		var layerID uuid.UUID
+		var unitCost int64
+		var remaining int
+		if err := rows.Scan(&layerID, &unitCost, &remaining); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		n := min(remaining, quantity)
+		takes = append(takes, take{layerID: layerID, quantity: n})
+		cost += unitCost * int64(n)
+		quantity -= n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, t := range takes {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE cost_layers SET remaining_quantity = remaining_quantity - $1 WHERE id = $2",
+			t.quantity, t.layerID,
+		); err != nil {
+			return 0, fmt.Errorf("failed to update cost layer: %w", err)
+		}
+	}
+
+	if quantity > 0 {
+		var average int64
+		if err := tx.QueryRowContext(ctx, "SELECT cost_price FROM products WHERE id = $1", productID).Scan(&average); err != nil {
+			return 0, fmt.Errorf("failed to get cost price: %w", err)
+		}
+		cost += average * int64(quantity)
+	}
+	return cost, nil
+}
+
+// saleUnitCost returns the cost per unit a sale booked for a product, or the current average
+// cost when the sale has no line for it (used within transaction)
+func (r *InventoryRepository) saleUnitCost(ctx context.Context, tx *sql.Tx, productID, transactionID uuid.UUID) (int64, error) {
+	var cogs int64
+	var quantity int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(cogs_amount), 0), COALESCE(SUM(quantity), 0)
+		FROM transaction_items WHERE transaction_id = $1 AND product_id = $2
+	`, transactionID, productID).Scan(&cogs, &quantity)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sale cost: %w", err)
+	}
+	if quantity > 0 {
+		return domain.DivRound(cogs, quantity), nil
+	}
+
+	var average int64
+	if err := tx.QueryRowContext(ctx, "SELECT cost_price FROM products WHERE id = $1", productID).Scan(&average); err != nil {
+		return 0, fmt.Errorf("failed to get cost price: %w", err)
+	}
+	return average, nil
+}
+
+// GetCostLayers returns a product's open cost layers, oldest first
+func (r *InventoryRepository) GetCostLayers(ctx context.Context, productID uuid.UUID) ([]domain.CostLayer, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, product_id, movement_id, unit_cost, initial_quantity, remaining_quantity, created_at
+		FROM cost_layers
+		WHERE product_id = $1 AND remaining_quantity > 0
+		ORDER BY created_at, id
+	`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cost layers: %w", err)
+	}
+	defer rows.Close()
+
+	layers := make([]domain.CostLayer, 0)
+	for rows.Next() {
+		var l domain.CostLayer
+		if err := rows.Scan(&l.ID, &l.ProductID, &l.MovementID, &l.UnitCost, &l.InitialQuantity, &l.RemainingQuantity, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+	return layers, rows.Err()
+}
+
+// GetCostHistory returns the latest changes to a product's average cost, newest first
+func (r *InventoryRepository) GetCostHistory(ctx context.Context, productID uuid.UUID, limit int) ([]domain.CostHistoryEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, product_id, movement_id, movement_type, quantity, unit_cost,
+			stock_before, average_cost_before, average_cost_after, created_at
+		FROM product_cost_history
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2
+	`, productID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost history: %w", err)
+	}
+	defer rows.Close()
+
+	history := make([]domain.CostHistoryEntry, 0)
+	for rows.Next() {
+		var h domain.CostHistoryEntry
+		if err := rows.Scan(
+			&h.ID, &h.ProductID, &h.MovementID, &h.MovementType, &h.Quantity, &h.UnitCost,
+			&h.StockBefore, &h.AverageCostBefore, &h.AverageCostAfter, &h.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
@@ -102,6 +102,74 @@ func (r *KasbonRepository) CreatePayment(ctx context.Context, input domain.Kasbo
 	return &record, tx.Commit()
 }
 
+// CreateRefund reduces a customer's debt for returned goods (used within transaction).
+// The reduction is capped at the outstanding debt; the amount actually credited is
+// returned on the record, and nil is returned when there is no debt to reduce.
+func (r *KasbonRepository) CreateRefund(ctx context.Context, tx *sql.Tx, customerID uuid.UUID, transactionID *uuid.UUID, amount int64, notes *string, createdBy *string) (*domain.KasbonRecord, error) {
+	return r.reduceDebt(ctx, tx, domain.KasbonTypeRefund, customerID, transactionID, amount, notes, createdBy)
+}
+
+// GetRefundedAmount sums the kasbon already credited back by refunds of a sale (used within transaction)
+func (r *KasbonRepository) GetRefundedAmount(ctx context.Context, tx *sql.Tx, transactionID uuid.UUID) (int64, error) {
+	var amount int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM kasbon_records WHERE transaction_id = $1 AND type = 'refund'
+	`, transactionID).Scan(&amount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get refunded kasbon: %w", err)
+	}
+	return amount, nil
+}
+
+// CreateReversal takes back the debt of a cancelled sale (used within transaction).
+// Like CreateRefund it is capped at the outstanding debt and returns nil when there is none.
+func (r *KasbonRepository) CreateReversal(ctx context.Context, tx *sql.Tx, customerID uuid.UUID, transactionID *uuid.UUID, amount int64, notes *string, createdBy *string) (*domain.KasbonRecord, error) {
+	return r.reduceDebt(ctx, tx, domain.KasbonTypeReversal, customerID, transactionID, amount, notes, createdBy)
+}
+
+// reduceDebt records a debt reduction of the given type and lowers the customer's balance
+func (r *KasbonRepository) reduceDebt(ctx context.Context, tx *sql.Tx, kasbonType domain.KasbonType, customerID uuid.UUID, transactionID *uuid.UUID, amount int64, notes *string, createdBy *string) (*domain.KasbonRecord, error) {
+	var currentDebt int64
+	err := tx.QueryRowContext(ctx, "SELECT current_debt FROM customers WHERE id = $1 FOR UPDATE", customerID).Scan(&currentDebt)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer debt: %w", err)
+	}
+
+	if amount > currentDebt {
+		amount = currentDebt
+	}
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	newBalance := currentDebt - amount
+
+	query := `
+		INSERT INTO kasbon_records (customer_id, transaction_id, type, amount, balance_before, balance_after, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, customer_id, transaction_id, type, amount, balance_before, balance_after, notes, created_by, created_at
+	`
+
+	var record domain.KasbonRecord
+	err = tx.QueryRowContext(ctx, query, customerID, transactionID, kasbonType, amount, currentDebt, newBalance, notes, createdBy).Scan(
+		&record.ID, &record.CustomerID, &record.TransactionID, &record.Type,
+		&record.Amount, &record.BalanceBefore, &record.BalanceAfter, &record.Notes, &record.CreatedBy, &record.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kasbon %s: %w", kasbonType, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE customers SET current_debt = $1, updated_at = NOW() WHERE id = $2", newBalance, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update customer debt: %w", err)
+	}
+
+	return &record, nil
+}
+
 // GetByCustomer retrieves kasbon records for a customer
 func (r *KasbonRepository) GetByCustomer(ctx context.Context, customerID uuid.UUID, filter domain.KasbonFilter) ([]domain.KasbonRecord, int64, error) {
 	args := []interface{}{customerID}
@@ -234,3 +302,22 @@ func (r *KasbonRepository) GetReport(ctx context.Context) (*domain.KasbonReport,
 
 	return &report, rows.Err()
 }
+
+// GetDailyTotals returns the kasbon given and paid on a date (YYYY-MM-DD). Reversals and
+// refunds count against the day of the sale they undo, so given stays in line with that
+// day's kasbon sales.
+func (r *KasbonRepository) GetDailyTotals(ctx context.Context, date string) (given, paid int64, err error) {
+	query := `
+		SELECT
+			COALESCE(SUM(kr.amount) FILTER (WHERE kr.type = 'debt' AND DATE(kr.created_at) = $1), 0)
+			- COALESCE(SUM(kr.amount) FILTER (WHERE kr.type IN ('reversal', 'refund') AND DATE(t.created_at) = $1), 0),
+			COALESCE(SUM(kr.amount) FILTER (WHERE kr.type = 'payment' AND DATE(kr.created_at) = $1), 0)
+		FROM kasbon_records kr
+		LEFT JOIN transactions t ON t.id = kr.transaction_id
+		WHERE DATE(kr.created_at) = $1 OR DATE(t.created_at) = $1
+	`
+	if err := r.db.QueryRowContext(ctx, query, date).Scan(&given, &paid); err != nil {
+		return 0, 0, fmt.Errorf("failed to get kasbon totals: %w", err)
+	}
+	return given, paid, nil
+}
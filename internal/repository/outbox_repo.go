@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// OutboxRepository handles outbox event database operations
+type OutboxRepository struct {
+	db *database.PostgresDB
+}
+
+// NewOutboxRepository creates a new OutboxRepository
+func NewOutboxRepository(db *database.PostgresDB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Add records an event inside the caller's transaction.
+// An event with the same idempotency key is only stored once.
+func (r *OutboxRepository) Add(ctx context.Context, tx *sql.Tx, eventType, idempotencyKey string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox_events (event_type, idempotency_key, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (idempotency_key) DO NOTHING
+	`, eventType, idempotencyKey, data)
+	if err != nil {
+		return fmt.Errorf("failed to add outbox event: %w", err)
+	}
+	return nil
+}
+
+// ClaimPending locks a batch of due events; concurrent relays skip each other's rows
+func (r *OutboxRepository) ClaimPending(ctx context.Context, tx *sql.Tx, limit int) ([]domain.OutboxEvent, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, event_type, idempotency_key, payload, status, attempts, last_error,
+			available_at, published_at, created_at
+		FROM outbox_events
+		WHERE status = 'pending' AND available_at <= NOW()
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []domain.OutboxEvent
+	for rows.Next() {
+		var e domain.OutboxEvent
+		if err := rows.Scan(
+			&e.ID, &e.EventType, &e.IdempotencyKey, &e.Payload, &e.Status, &e.Attempts, &e.LastError,
+			&e.AvailableAt, &e.PublishedAt, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// MarkPublished marks an event as delivered
+func (r *OutboxRepository) MarkPublished(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET status = 'published', attempts = attempts + 1, last_error = NULL, published_at = NOW()
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event published: %w", err)
+	}
+	return nil
+}
+
+// MarkAttemptFailed records a failed delivery. The event is retried at retryAt,
+// or moved to failed when retryAt is nil.
+func (r *OutboxRepository) MarkAttemptFailed(ctx context.Context, tx *sql.Tx, id uuid.UUID, lastError string, retryAt *time.Time) error {
+	status := domain.OutboxStatusFailed
+	if retryAt != nil {
+		status = domain.OutboxStatusPending
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET status = $1, attempts = attempts + 1, last_error = $2, available_at = COALESCE($3, available_at)
+		WHERE id = $4
+	`, status, lastError, retryAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to record outbox attempt: %w", err)
+	}
+	return nil
+}
+
+// DeletePublished removes events delivered before the cutoff and returns how many were removed
+func (r *OutboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM outbox_events
+		WHERE status = 'published' AND published_at < $1
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox events: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/google/uuid"
 
@@ -149,24 +150,19 @@ func (r *POSRepository) UpdateCartStatus(ctx context.Context, id uuid.UUID, stat
 
 // -- Refunds --
 
-func (r *POSRepository) CreateRefund(ctx context.Context, refund *domain.RefundRecord) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
+// CreateRefund inserts a refund request with its items (used within transaction)
+func (r *POSRepository) CreateRefund(ctx context.Context, tx *sql.Tx, refund *domain.RefundRecord) error {
 	query := `
 		INSERT INTO refund_records (refund_number, transaction_id, customer_id, total_refund_amount, refund_method, status, reason, notes, requested_by, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
 		RETURNING id, refund_number, created_at, updated_at
 	`
-	err = tx.QueryRowContext(ctx, query,
+	err := tx.QueryRowContext(ctx, query,
 		refund.RefundNumber, refund.TransactionID, refund.CustomerID, refund.TotalRefundAmount, refund.RefundMethod, refund.Status,
 		refund.Reason, refund.Notes, refund.RequestedBy,
 	).Scan(&refund.ID, &refund.RefundNumber, &refund.CreatedAt, &refund.UpdatedAt)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create refund: %w", err)
 	}
 
 	itemQuery := `
@@ -182,11 +178,11 @@ func (r *POSRepository) CreateRefund(ctx context.Context, refund *domain.RefundR
 			item.Quantity, item.UnitPrice, item.RefundAmount, item.Reason, item.Restock,
 		).Scan(&item.ID, &item.CreatedAt)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to create refund item: %w", err)
 		}
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 func (r *POSRepository) GetRefund(ctx context.Context, id uuid.UUID) (*domain.RefundRecord, error) {
@@ -230,3 +226,103 @@ func (r *POSRepository) GetRefund(ctx context.Context, id uuid.UUID) (*domain.Re
 	}
 	return &refund, nil
 }
+
+// GetRefundForUpdate retrieves a refund with items and locks the refund row (used within transaction)
+func (r *POSRepository) GetRefundForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*domain.RefundRecord, error) {
+	query := `
+		SELECT id, refund_number, transaction_id, customer_id, total_refund_amount, refund_method, status, reason, notes, requested_by, approved_by, completed_at, created_at, updated_at
+		FROM refund_records WHERE id = $1
+		FOR UPDATE
+	`
+	var refund domain.RefundRecord
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&refund.ID, &refund.RefundNumber, &refund.TransactionID, &refund.CustomerID, &refund.TotalRefundAmount,
+		&refund.RefundMethod, &refund.Status, &refund.Reason, &refund.Notes, &refund.RequestedBy, &refund.ApprovedBy,
+		&refund.CompletedAt, &refund.CreatedAt, &refund.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock refund: %w", err)
+	}
+
+	itemQuery := `
+		SELECT id, refund_id, transaction_item_id, product_id, product_name, quantity, unit_price, refund_amount, reason, restock, created_at
+		FROM refund_items WHERE refund_id = $1
+		ORDER BY product_id
+	`
+	rows, err := tx.QueryContext(ctx, itemQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refund items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item domain.RefundItem
+		if err := rows.Scan(
+			&item.ID, &item.RefundID, &item.TransactionItemID, &item.ProductID, &item.ProductName,
+			&item.Quantity, &item.UnitPrice, &item.RefundAmount, &item.Reason, &item.Restock, &item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		refund.Items = append(refund.Items, item)
+	}
+	return &refund, rows.Err()
+}
+
+// UpdateRefundStatus moves a refund to a new status, recording who decided it
+// and stamping completed_at when completed (used within transaction)
+func (r *POSRepository) UpdateRefundStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status domain.RefundStatus, by *string) error {
+	query := `
+		UPDATE refund_records
+		SET status = $1,
+			approved_by = COALESCE($2, approved_by),
+			completed_at = CASE WHEN $1 = 'completed' THEN NOW() ELSE completed_at END,
+			updated_at = NOW()
+		WHERE id = $3
+	`
+	result, err := tx.ExecContext(ctx, query, status, by, id)
+	if err != nil {
+		return fmt.Errorf("failed to update refund status: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// GetRefundedQuantities sums refunded quantities per transaction item.
+// When completedOnly is false, pending and approved refunds are counted too so
+// that open requests reserve their quantities.
+func (r *POSRepository) GetRefundedQuantities(ctx context.Context, tx *sql.Tx, transactionID uuid.UUID, completedOnly bool) (map[uuid.UUID]int, error) {
+	query := `
+		SELECT ri.transaction_item_id, COALESCE(SUM(ri.quantity), 0)
+		FROM refund_items ri
+		JOIN refund_records rr ON ri.refund_id = rr.id
+		WHERE rr.transaction_id = $1
+	`
+	if completedOnly {
+		query += " AND rr.status = 'completed'"
+	} else {
+		query += " AND rr.status <> 'rejected'"
+	}
+	query += " GROUP BY ri.transaction_item_id"
+
+	rows, err := tx.QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunded quantities: %w", err)
+	}
+	defer rows.Close()
+
+	quantities := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var itemID uuid.UUID
+		var qty int
+		if err := rows.Scan(&itemID, &qty); err != nil {
+			return nil, err
+		}
+		quantities[itemID] = qty
+	}
+	return quantities, rows.Err()
+}
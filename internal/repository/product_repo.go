@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/eveeze/warung-backend/internal/database"
 	"github.com/eveeze/warung-backend/internal/domain"
@@ -51,32 +52,51 @@ func (r *ProductRepository) Create(ctx context.Context, input domain.ProductCrea
 		input.SKU = nil
 	}
 
+	if input.ParentID != nil {
+		if err := r.checkParent(ctx, uuid.Nil, &input); err != nil {
+			return nil, err
+		}
+	} else {
+		input.VariantName = nil
+	}
+
 	query := `
 		INSERT INTO products (
 			barcode, sku, name, description, category_id, consignor_id, unit,
 			base_price, cost_price, is_stock_active, current_stock,
-			min_stock_alert, max_stock, image_url, is_active
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			min_stock_alert, max_stock, image_url, is_active, parent_id, variant_name
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		RETURNING id, barcode, sku, name, description, category_id, consignor_id, unit,
 			base_price, cost_price, is_stock_active, current_stock,
-			min_stock_alert, max_stock, image_url, is_active, created_at, updated_at
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
 	`
 
 	var product domain.Product
-	err := r.db.QueryRowContext(ctx, query,
-		input.Barcode, input.SKU, input.Name, input.Description,
-		input.CategoryID, input.ConsignorID, input.Unit, input.BasePrice, input.CostPrice,
-		isStockActive, currentStock, minStockAlert, input.MaxStock, input.ImageURL,
-		isActive,
-	).Scan(
-		&product.ID, &product.Barcode, &product.SKU, &product.Name,
-		&product.Description, &product.CategoryID, &product.ConsignorID, &product.Unit,
-		&product.BasePrice, &product.CostPrice, &product.IsStockActive,
-		&product.CurrentStock, &product.MinStockAlert, &product.MaxStock,
-		&product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
-	)
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query,
+			input.Barcode, input.SKU, input.Name, input.Description,
+			input.CategoryID, input.ConsignorID, input.Unit, input.BasePrice, input.CostPrice,
+			isStockActive, currentStock, minStockAlert, input.MaxStock, input.ImageURL,
+			isActive, input.ParentID, input.VariantName,
+		).Scan(
+			&product.ID, &product.Barcode, &product.SKU, &product.Name,
+			&product.Description, &product.CategoryID, &product.ConsignorID, &product.Unit,
+			&product.BasePrice, &product.CostPrice, &product.IsStockActive,
+			&product.CurrentStock, &product.MinStockAlert, &product.MaxStock,
+			&product.ImageURL, &product.ParentID, &product.VariantName, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create product: %w", err)
+		}
+
+		// Opening stock gets a cost layer and a lot like any other receipt
+		if product.IsStockActive && product.CurrentStock > 0 {
+			return NewInventoryRepository(r.db).openStock(ctx, tx, &product)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create product: %w", err)
+		return nil, err
 	}
 
 	// Create pricing tiers if provided
@@ -91,6 +111,16 @@ func (r *ProductRepository) Create(ctx context.Context, input domain.ProductCrea
 		product.PricingTiers, _ = r.GetPricingTiers(ctx, product.ID)
 	}
 
+	// Create units if provided
+	if len(input.Units) > 0 {
+		for _, unitInput := range input.Units {
+			if _, err := r.CreateUnit(ctx, product.ID, unitInput); err != nil {
+				return nil, err
+			}
+		}
+		product.Units, _ = r.GetUnits(ctx, product.ID)
+	}
+
 	return &product, nil
 }
 
@@ -99,7 +129,7 @@ func (r *ProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 	query := `
 		SELECT id, barcode, sku, name, description, category_id, consignor_id, unit,
 			base_price, cost_price, is_stock_active, current_stock,
-			min_stock_alert, max_stock, image_url, is_active, created_at, updated_at
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
 		FROM products
 		WHERE id = $1
 	`
@@ -110,7 +140,7 @@ func (r *ProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 		&product.Description, &product.CategoryID, &product.ConsignorID, &product.Unit,
 		&product.BasePrice, &product.CostPrice, &product.IsStockActive,
 		&product.CurrentStock, &product.MinStockAlert, &product.MaxStock,
-		&product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		&product.ImageURL, &product.ParentID, &product.VariantName, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNotFound
@@ -119,18 +149,84 @@ func (r *ProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
 
-	// Load pricing tiers
+	// Load pricing tiers, units and variants
 	product.PricingTiers, _ = r.GetPricingTiers(ctx, product.ID)
+	product.Units, _ = r.GetUnits(ctx, product.ID)
+	if !product.IsVariant() {
+		product.Variants, _ = r.GetVariants(ctx, product.ID)
+	}
 
 	return &product, nil
 }
 
-// GetByBarcode retrieves a product by barcode
+// GetByIDsForUpdate loads products inside tx and locks their rows until it ends.
+// Rows are locked in ID order, so transactions locking overlapping sets cannot deadlock.
+// IDs that do not exist are missing from the result.
+func (r *ProductRepository) GetByIDsForUpdate(ctx context.Context, tx *sql.Tx, ids []uuid.UUID) (map[uuid.UUID]*domain.Product, error) {
+	products := make(map[uuid.UUID]*domain.Product, len(ids))
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	idStrings := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	query := `
+		SELECT id, barcode, sku, name, description, category_id, consignor_id, unit,
+			base_price, cost_price, is_stock_active, current_stock,
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
+		FROM products
+		WHERE id = ANY($1::uuid[])
+		ORDER BY id
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, idStrings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock products: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p domain.Product
+		if err := rows.Scan(
+			&p.ID, &p.Barcode, &p.SKU, &p.Name, &p.Description, &p.CategoryID, &p.ConsignorID,
+			&p.Unit, &p.BasePrice, &p.CostPrice, &p.IsStockActive, &p.CurrentStock,
+			&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.ParentID, &p.VariantName, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products[p.ID] = &p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tiersMap, err := r.GetPricingTiersBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	unitsMap, err := r.GetUnitsBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, p := range products {
+		p.PricingTiers = tiersMap[id]
+		p.Units = unitsMap[id]
+	}
+
+	return products, nil
+}
+
+// GetByBarcode retrieves a product by barcode. A barcode that belongs to one of a product's
+// units returns that product with ScannedUnit set.
 func (r *ProductRepository) GetByBarcode(ctx context.Context, barcode string) (*domain.Product, error) {
 	query := `
 		SELECT id, barcode, sku, name, description, category_id, consignor_id, unit,
 			base_price, cost_price, is_stock_active, current_stock,
-			min_stock_alert, max_stock, image_url, is_active, created_at, updated_at
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
 		FROM products
 		WHERE barcode = $1
 	`
@@ -141,20 +237,42 @@ func (r *ProductRepository) GetByBarcode(ctx context.Context, barcode string) (*
 		&product.Description, &product.CategoryID, &product.ConsignorID, &product.Unit,
 		&product.BasePrice, &product.CostPrice, &product.IsStockActive,
 		&product.CurrentStock, &product.MinStockAlert, &product.MaxStock,
-		&product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		&product.ImageURL, &product.ParentID, &product.VariantName, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, domain.ErrNotFound
+		return r.getByUnitBarcode(ctx, barcode)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product by barcode: %w", err)
 	}
 
 	product.PricingTiers, _ = r.GetPricingTiers(ctx, product.ID)
+	product.Units, _ = r.GetUnits(ctx, product.ID)
 
 	return &product, nil
 }
 
+// getByUnitBarcode retrieves the product owning an active unit with the given barcode
+func (r *ProductRepository) getByUnitBarcode(ctx context.Context, barcode string) (*domain.Product, error) {
+	var productID, unitID uuid.UUID
+	err := r.db.QueryRowContext(ctx,
+		"SELECT product_id, id FROM product_units WHERE barcode = $1 AND is_active = true", barcode,
+	).Scan(&productID, &unitID)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product unit by barcode: %w", err)
+	}
+
+	product, err := r.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	product.ScannedUnit = product.FindUnit(unitID)
+	return product, nil
+}
+
 // List retrieves products with filtering and pagination
 func (r *ProductRepository) List(ctx context.Context, filter domain.ProductFilter) ([]domain.Product, int64, error) {
 	// Build WHERE clause
@@ -163,10 +281,12 @@ func (r *ProductRepository) List(ctx context.Context, filter domain.ProductFilte
 	argIndex := 1
 
 	if filter.Search != nil && *filter.Search != "" {
-		conditions = append(conditions, fmt.Sprintf(
-			"(name ILIKE $%d OR barcode ILIKE $%d OR sku ILIKE $%d)",
-			argIndex, argIndex, argIndex,
-		))
+		match := fmt.Sprintf("name ILIKE $%d OR barcode ILIKE $%d OR sku ILIKE $%d", argIndex, argIndex, argIndex)
+		if filter.CollapseVariants {
+			// A parent is listed when one of its variants matches
+			match += " OR id IN (SELECT parent_id FROM products WHERE parent_id IS NOT NULL AND (" + match + "))"
+		}
+		conditions = append(conditions, "("+match+")")
 		args = append(args, "%"+*filter.Search+"%")
 		argIndex++
 	}
@@ -196,7 +316,15 @@ func (r *ProductRepository) List(ctx context.Context, filter domain.ProductFilte
 	}
 
 	if filter.LowStockOnly {
-		conditions = append(conditions, "is_stock_active = true AND current_stock <= min_stock_alert")
+		lowStock := "is_stock_active = true AND current_stock <= min_stock_alert"
+		if filter.CollapseVariants {
+			lowStock = "((" + lowStock + ") OR id IN (SELECT parent_id FROM products WHERE parent_id IS NOT NULL AND is_active = true AND " + lowStock + "))"
+		}
+		conditions = append(conditions, lowStock)
+	}
+
+	if filter.CollapseVariants {
+		conditions = append(conditions, "parent_id IS NULL")
 	}
 
 	whereClause := ""
@@ -240,7 +368,7 @@ func (r *ProductRepository) List(ctx context.Context, filter domain.ProductFilte
 	query := fmt.Sprintf(`
 		SELECT id, barcode, sku, name, description, category_id, consignor_id, unit,
 			base_price, cost_price, is_stock_active, current_stock,
-			min_stock_alert, max_stock, image_url, is_active, created_at, updated_at
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
 		FROM products
 		%s
 		ORDER BY %s %s
@@ -261,7 +389,7 @@ func (r *ProductRepository) List(ctx context.Context, filter domain.ProductFilte
 		if err := rows.Scan(
 			&p.ID, &p.Barcode, &p.SKU, &p.Name, &p.Description, &p.CategoryID, &p.ConsignorID,
 			&p.Unit, &p.BasePrice, &p.CostPrice, &p.IsStockActive, &p.CurrentStock,
-			&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+			&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.ParentID, &p.VariantName, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
 		); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan product: %w", err)
 		}
@@ -281,18 +409,202 @@ func (r *ProductRepository) List(ctx context.Context, filter domain.ProductFilte
 				products[i].PricingTiers = tiersMap[products[i].ID]
 			}
 		}
+		unitsMap, err := r.GetUnitsBatch(ctx, productIDs)
+		if err == nil {
+			for i := range products {
+				products[i].Units = unitsMap[products[i].ID]
+			}
+		}
+		if filter.CollapseVariants {
+			activeOnly := filter.IsActive != nil && *filter.IsActive
+			variantsMap, err := r.GetVariantsBatch(ctx, productIDs, activeOnly)
+			if err == nil {
+				for i := range products {
+					products[i].Variants = variantsMap[products[i].ID]
+				}
+			}
+		}
 	}
 
 	return products, total, rows.Err()
 }
 
-// Update updates a product
-func (r *ProductRepository) Update(ctx context.Context, id uuid.UUID, input domain.ProductUpdateInput) (*domain.Product, error) {
+// ListChangedSince returns products changed after since, including deactivated ones
+// and products whose pricing tiers or units changed. A nil since returns every active product.
+func (r *ProductRepository) ListChangedSince(ctx context.Context, since *time.Time) ([]domain.Product, error) {
+	query := `
+		SELECT id, barcode, sku, name, description, category_id, consignor_id, unit,
+			base_price, cost_price, is_stock_active, current_stock,
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
+		FROM products
+		WHERE is_active = true
+		ORDER BY updated_at
+	`
+	var args []interface{}
+	if since != nil {
+		query = `
+			SELECT id, barcode, sku, name, description, category_id, consignor_id, unit,
+				base_price, cost_price, is_stock_active, current_stock,
+				min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
+			FROM products
+			WHERE updated_at > $1
+				OR id IN (SELECT product_id FROM pricing_tiers WHERE updated_at > $1)
+				OR id IN (SELECT product_id FROM product_units WHERE updated_at > $1)
+			ORDER BY updated_at
+		`
+		args = append(args, *since)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed products: %w", err)
+	}
+	defer rows.Close()
+
+	products := []domain.Product{}
+	for rows.Next() {
+		var p domain.Product
+		if err := rows.Scan(
+			&p.ID, &p.Barcode, &p.SKU, &p.Name, &p.Description, &p.CategoryID, &p.ConsignorID,
+			&p.Unit, &p.BasePrice, &p.CostPrice, &p.IsStockActive, &p.CurrentStock,
+			&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.ParentID, &p.VariantName, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]uuid.UUID, len(products))
+	for i, p := range products {
+		productIDs[i] = p.ID
+	}
+	tiersMap, err := r.GetPricingTiersBatch(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	unitsMap, err := r.GetUnitsBatch(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].PricingTiers = tiersMap[products[i].ID]
+		products[i].Units = unitsMap[products[i].ID]
+	}
+
+	return products, nil
+}
+
+// ListAll returns every product, including inactive ones, with its pricing tiers, ordered by name
+func (r *ProductRepository) ListAll(ctx context.Context) ([]domain.Product, error) {
+	query := `
+		SELECT id, barcode, sku, name, description, category_id, consignor_id, unit,
+			base_price, cost_price, is_stock_active, current_stock,
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
+		FROM products
+		ORDER BY name, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+	defer rows.Close()
+
+	products := []domain.Product{}
+	for rows.Next() {
+		var p domain.Product
+		if err := rows.Scan(
+			&p.ID, &p.Barcode, &p.SKU, &p.Name, &p.Description, &p.CategoryID, &p.ConsignorID,
+			&p.Unit, &p.BasePrice, &p.CostPrice, &p.IsStockActive, &p.CurrentStock,
+			&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.ParentID, &p.VariantName, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]uuid.UUID, len(products))
+	for i, p := range products {
+		productIDs[i] = p.ID
+	}
+	tiersMap, err := r.GetPricingTiersBatch(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].PricingTiers = tiersMap[products[i].ID]
+	}
+
+	return products, nil
+}
+
+// GetCodeIndex returns the barcodes and SKUs of all products and the barcodes of active units
+func (r *ProductRepository) GetCodeIndex(ctx context.Context) (*domain.ProductCodeIndex, error) {
+	index := &domain.ProductCodeIndex{
+		ByBarcode:    make(map[string]uuid.UUID),
+		BySKU:        make(map[string]uuid.UUID),
+		UnitBarcodes: make(map[string]bool),
+		Variants:     make(map[uuid.UUID]bool),
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT id, barcode, sku, parent_id IS NOT NULL FROM products")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product codes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var barcode, sku *string
+		var isVariant bool
+		if err := rows.Scan(&id, &barcode, &sku, &isVariant); err != nil {
+			return nil, fmt.Errorf("failed to scan product codes: %w", err)
+		}
+		if barcode != nil {
+			index.ByBarcode[*barcode] = id
+		}
+		if sku != nil {
+			index.BySKU[*sku] = id
+		}
+		if isVariant {
+			index.Variants[id] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	unitRows, err := r.db.QueryContext(ctx, "SELECT barcode FROM product_units WHERE is_active = true AND barcode IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product unit barcodes: %w", err)
+	}
+	defer unitRows.Close()
+	for unitRows.Next() {
+		var barcode string
+		if err := unitRows.Scan(&barcode); err != nil {
+			return nil, fmt.Errorf("failed to scan product unit barcode: %w", err)
+		}
+		index.UnitBarcodes[barcode] = true
+	}
+
+	return index, unitRows.Err()
+}
+
+// Update updates a product. With a nil tx it runs in a transaction of its own.
+func (r *ProductRepository) Update(ctx context.Context, tx *sql.Tx, id uuid.UUID, input domain.ProductUpdateInput) (*domain.Product, error) {
 	// Get current product
 	product, err := r.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if product.IsVariant() && input.ChangesSharedAttributes() {
+		return nil, fmt.Errorf("%w: category, consignor and image of a variant are set on its parent", domain.ErrInvalidInput)
+	}
 
 	// Build update query dynamically
 	var setClauses []string
@@ -392,19 +704,35 @@ func (r *ProductRepository) Update(ctx context.Context, id uuid.UUID, input doma
 		WHERE id = $%d
 		RETURNING id, barcode, sku, name, description, category_id, consignor_id, unit,
 			base_price, cost_price, is_stock_active, current_stock,
-			min_stock_alert, max_stock, image_url, is_active, created_at, updated_at
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
 	`, strings.Join(setClauses, ", "), argIndex)
 
 	args = append(args, id)
 
+	// Variants follow the parent in the same transaction, so they never keep stale shared attributes
 	var p domain.Product
-	err = r.db.QueryRowContext(ctx, query, args...).Scan(
-		&p.ID, &p.Barcode, &p.SKU, &p.Name, &p.Description, &p.CategoryID, &p.ConsignorID,
-		&p.Unit, &p.BasePrice, &p.CostPrice, &p.IsStockActive, &p.CurrentStock,
-		&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
-	)
+	update := func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query, args...).Scan(
+			&p.ID, &p.Barcode, &p.SKU, &p.Name, &p.Description, &p.CategoryID, &p.ConsignorID,
+			&p.Unit, &p.BasePrice, &p.CostPrice, &p.IsStockActive, &p.CurrentStock,
+			&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.ParentID, &p.VariantName, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update product: %w", err)
+		}
+
+		if len(product.Variants) > 0 && input.ChangesSharedAttributes() {
+			return r.syncVariants(ctx, tx, p.ID)
+		}
+		return nil
+	}
+	if tx != nil {
+		err = update(tx)
+	} else {
+		err = r.db.WithTransaction(ctx, update)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to update product: %w", err)
+		return nil, err
 	}
 
 	p.PricingTiers = product.PricingTiers // Keep same pricing tiers
@@ -420,14 +748,14 @@ func (r *ProductRepository) ToggleActive(ctx context.Context, id uuid.UUID) (*do
 		WHERE id = $1
 		RETURNING id, barcode, sku, name, description, category_id, consignor_id, unit,
 			base_price, cost_price, is_stock_active, current_stock,
-			min_stock_alert, max_stock, image_url, is_active, created_at, updated_at
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
 	`
 
 	var p domain.Product
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&p.ID, &p.Barcode, &p.SKU, &p.Name, &p.Description, &p.CategoryID, &p.ConsignorID,
 		&p.Unit, &p.BasePrice, &p.CostPrice, &p.IsStockActive, &p.CurrentStock,
-		&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.ParentID, &p.VariantName, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNotFound
@@ -442,8 +770,192 @@ func (r *ProductRepository) ToggleActive(ctx context.Context, id uuid.UUID) (*do
 	return &p, nil
 }
 
+// Variant methods
 
+// GetVariants retrieves all variants of a product, with their pricing tiers and units
+func (r *ProductRepository) GetVariants(ctx context.Context, parentID uuid.UUID) ([]domain.Product, error) {
+	variantsMap, err := r.GetVariantsBatch(ctx, []uuid.UUID{parentID}, false)
+	if err != nil {
+		return nil, err
+	}
+	return variantsMap[parentID], nil
+}
 
+// GetVariantsBatch retrieves the variants of several parent products, with their pricing tiers and
+// units, keyed by parent ID
+func (r *ProductRepository) GetVariantsBatch(ctx context.Context, parentIDs []uuid.UUID, activeOnly bool) (map[uuid.UUID][]domain.Product, error) {
+	result := make(map[uuid.UUID][]domain.Product)
+	if len(parentIDs) == 0 {
+		return result, nil
+	}
+
+	idStrings := make(pq.StringArray, len(parentIDs))
+	for i, id := range parentIDs {
+		idStrings[i] = id.String()
+	}
+
+	query := `
+		SELECT id, barcode, sku, name, description, category_id, consignor_id, unit,
+			base_price, cost_price, is_stock_active, current_stock,
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
+		FROM products
+		WHERE parent_id = ANY($1::uuid[]) AND (is_active = true OR NOT $2)
+		ORDER BY parent_id, variant_name, name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, idStrings, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product variants: %w", err)
+	}
+	defer rows.Close()
+
+	var variants []domain.Product
+	for rows.Next() {
+		var p domain.Product
+		if err := rows.Scan(
+			&p.ID, &p.Barcode, &p.SKU, &p.Name, &p.Description, &p.CategoryID, &p.ConsignorID,
+			&p.Unit, &p.BasePrice, &p.CostPrice, &p.IsStockActive, &p.CurrentStock,
+			&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.ParentID, &p.VariantName, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan product variant: %w", err)
+		}
+		variants = append(variants, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	variantIDs := make([]uuid.UUID, len(variants))
+	for i, v := range variants {
+		variantIDs[i] = v.ID
+	}
+	tiersMap, err := r.GetPricingTiersBatch(ctx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	unitsMap, err := r.GetUnitsBatch(ctx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range variants {
+		v.PricingTiers = tiersMap[v.ID]
+		v.Units = unitsMap[v.ID]
+		result[*v.ParentID] = append(result[*v.ParentID], v)
+	}
+
+	return result, nil
+}
+
+// SetParent attaches a product to a parent as a variant, taking over the parent's category,
+// consignor and image, or detaches it when input.ParentID is nil. A detached product keeps
+// the category and consignor but not the image, which stays with the parent.
+func (r *ProductRepository) SetParent(ctx context.Context, id uuid.UUID, input domain.ProductParentInput) (*domain.Product, error) {
+	if input.ParentID == nil {
+		// Detaching a product that is not a variant changes nothing
+		_, err := r.db.ExecContext(ctx, `
+			UPDATE products SET parent_id = NULL, variant_name = NULL, image_url = NULL, updated_at = NOW()
+			WHERE id = $1 AND parent_id IS NOT NULL
+		`, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detach product variant: %w", err)
+		}
+		return r.GetByID(ctx, id)
+	}
+
+	if *input.ParentID == id {
+		return nil, fmt.Errorf("%w: a product cannot be its own parent", domain.ErrInvalidInput)
+	}
+	product, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(product.Variants) > 0 {
+		return nil, fmt.Errorf("%w: %s has variants of its own", domain.ErrInvalidInput, product.Name)
+	}
+
+	create := domain.ProductCreateInput{
+		Name:        product.Name,
+		Unit:        product.Unit,
+		ParentID:    input.ParentID,
+		VariantName: input.VariantName,
+	}
+	if err := r.checkParent(ctx, id, &create); err != nil {
+		return nil, err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE products
+		SET parent_id = $2, variant_name = $3, category_id = $4, consignor_id = $5, image_url = $6, updated_at = NOW()
+		WHERE id = $1
+	`, id, create.ParentID, create.VariantName, create.CategoryID, create.ConsignorID, create.ImageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach product variant: %w", err)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// checkParent validates input.ParentID and input.VariantName for the product id (uuid.Nil for a new
+// one) and copies the parent's category, consignor and image into input, and its name and unit when
+// they are not given. Variants cannot have variants of their own.
+func (r *ProductRepository) checkParent(ctx context.Context, id uuid.UUID, input *domain.ProductCreateInput) error {
+	if input.VariantName != nil {
+		name := strings.TrimSpace(*input.VariantName)
+		input.VariantName = &name
+	}
+	if input.VariantName == nil || *input.VariantName == "" {
+		return fmt.Errorf("%w: variant_name is required for a variant", domain.ErrInvalidInput)
+	}
+
+	var parentName, parentUnit string
+	var grandparentID *uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		SELECT name, unit, parent_id, category_id, consignor_id, image_url FROM products WHERE id = $1
+	`, *input.ParentID).Scan(&parentName, &parentUnit, &grandparentID, &input.CategoryID, &input.ConsignorID, &input.ImageURL)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("parent product %s: %w", *input.ParentID, domain.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get parent product: %w", err)
+	}
+	if grandparentID != nil {
+		return fmt.Errorf("%w: %s is itself a variant", domain.ErrInvalidInput, parentName)
+	}
+
+	var taken bool
+	err = r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM products
+			WHERE parent_id = $1 AND lower(variant_name) = lower($2) AND is_active = true AND id <> $3)
+	`, *input.ParentID, *input.VariantName, id).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check product variant: %w", err)
+	}
+	if taken {
+		return fmt.Errorf("%w: variant %q already exists for %s", domain.ErrAlreadyExists, *input.VariantName, parentName)
+	}
+
+	if input.Name == "" {
+		input.Name = parentName + " " + *input.VariantName
+	}
+	if input.Unit == "" {
+		input.Unit = parentUnit
+	}
+	return nil
+}
+
+// syncVariants copies a parent's category, consignor and image to its variants (used within transaction)
+func (r *ProductRepository) syncVariants(ctx context.Context, tx *sql.Tx, parentID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE products v
+		SET category_id = p.category_id, consignor_id = p.consignor_id, image_url = p.image_url, updated_at = NOW()
+		FROM products p
+		WHERE p.id = $1 AND v.parent_id = p.id
+	`, parentID)
+	if err != nil {
+		return fmt.Errorf("failed to update product variants: %w", err)
+	}
+	return nil
+}
 
 // UpdateStock updates the product stock
 func (r *ProductRepository) UpdateStock(ctx context.Context, id uuid.UUID, newStock int) error {
@@ -489,7 +1001,7 @@ func (r *ProductRepository) GetLowStockProducts(ctx context.Context) ([]domain.L
 	query := `
 		SELECT id, barcode, sku, name, description, category_id, consignor_id, unit,
 			base_price, cost_price, is_stock_active, current_stock,
-			min_stock_alert, max_stock, image_url, is_active, created_at, updated_at
+			min_stock_alert, max_stock, image_url, parent_id, variant_name, is_active, created_at, updated_at
 		FROM products
 		WHERE is_stock_active = true 
 			AND is_active = true 
@@ -509,7 +1021,7 @@ func (r *ProductRepository) GetLowStockProducts(ctx context.Context) ([]domain.L
 		if err := rows.Scan(
 			&p.ID, &p.Barcode, &p.SKU, &p.Name, &p.Description, &p.CategoryID, &p.ConsignorID,
 			&p.Unit, &p.BasePrice, &p.CostPrice, &p.IsStockActive, &p.CurrentStock,
-			&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+			&p.MinStockAlert, &p.MaxStock, &p.ImageURL, &p.ParentID, &p.VariantName, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
@@ -718,3 +1230,217 @@ func (r *ProductRepository) DeletePricingTier(ctx context.Context, tierID uuid.U
 	
 	return nil
 }
+
+// ReplacePricingTiers replaces all active pricing tiers of a product with tiers.
+// With a nil tx it runs in a transaction of its own.
+func (r *ProductRepository) ReplacePricingTiers(ctx context.Context, tx *sql.Tx, productID uuid.UUID, tiers []domain.PricingTierInput) error {
+	replace := func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE pricing_tiers SET is_active = false, updated_at = NOW() WHERE product_id = $1 AND is_active = true", productID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete pricing tiers: %w", err)
+		}
+		for _, tier := range tiers {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO pricing_tiers (product_id, name, min_quantity, max_quantity, price)
+				VALUES ($1, $2, $3, $4, $5)
+			`, productID, tier.Name, tier.MinQuantity, tier.MaxQuantity, tier.Price)
+			if err != nil {
+				return fmt.Errorf("failed to create pricing tier: %w", err)
+			}
+		}
+		return nil
+	}
+	if tx != nil {
+		return replace(tx)
+	}
+	return r.db.WithTransaction(ctx, replace)
+}
+
+// Product unit methods
+
+const productUnitColumns = `id, product_id, name, conversion_factor, barcode, price, is_active, created_at, updated_at`
+
+func scanProductUnit(row rowScanner) (*domain.ProductUnit, error) {
+	var u domain.ProductUnit
+	err := row.Scan(
+		&u.ID, &u.ProductID, &u.Name, &u.ConversionFactor, &u.Barcode,
+		&u.Price, &u.IsActive, &u.CreatedAt, &u.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// CreateUnit adds a unit to a product
+func (r *ProductRepository) CreateUnit(ctx context.Context, productID uuid.UUID, input domain.ProductUnitInput) (*domain.ProductUnit, error) {
+	if input.Barcode != nil && *input.Barcode == "" {
+		input.Barcode = nil
+	}
+	if err := r.checkUnitConflict(ctx, productID, uuid.Nil, input.Name, input.Barcode); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO product_units (product_id, name, conversion_factor, barcode, price)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + productUnitColumns
+
+	unit, err := scanProductUnit(r.db.QueryRowContext(ctx, query,
+		productID, input.Name, input.ConversionFactor, input.Barcode, input.Price,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product unit: %w", err)
+	}
+
+	return unit, nil
+}
+
+// GetUnits retrieves the active units of a product, smallest first
+func (r *ProductRepository) GetUnits(ctx context.Context, productID uuid.UUID) ([]domain.ProductUnit, error) {
+	unitsMap, err := r.GetUnitsBatch(ctx, []uuid.UUID{productID})
+	if err != nil {
+		return nil, err
+	}
+	return unitsMap[productID], nil
+}
+
+// GetUnitsBatch retrieves the active units of several products in a single query
+func (r *ProductRepository) GetUnitsBatch(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]domain.ProductUnit, error) {
+	result := make(map[uuid.UUID][]domain.ProductUnit)
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	idStrings := make(pq.StringArray, len(productIDs))
+	for i, id := range productIDs {
+		idStrings[i] = id.String()
+	}
+
+	query := `
+		SELECT ` + productUnitColumns + `
+		FROM product_units
+		WHERE product_id = ANY($1::uuid[]) AND is_active = true
+		ORDER BY product_id, conversion_factor ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, idStrings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product units: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := scanProductUnit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product unit: %w", err)
+		}
+		result[u.ProductID] = append(result[u.ProductID], *u)
+	}
+
+	return result, rows.Err()
+}
+
+// GetUnit retrieves a single product unit by ID
+func (r *ProductRepository) GetUnit(ctx context.Context, id uuid.UUID) (*domain.ProductUnit, error) {
+	query := `SELECT ` + productUnitColumns + ` FROM product_units WHERE id = $1`
+
+	u, err := scanProductUnit(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product unit: %w", err)
+	}
+	return u, nil
+}
+
+// UpdateUnit updates the fields of a product unit that are set in input.
+// An empty barcode removes it.
+func (r *ProductRepository) UpdateUnit(ctx context.Context, productID, unitID uuid.UUID, input domain.ProductUnitInput) (*domain.ProductUnit, error) {
+	unit, err := r.GetUnit(ctx, unitID)
+	if err != nil {
+		return nil, err
+	}
+	if unit.ProductID != productID || !unit.IsActive {
+		return nil, domain.ErrNotFound
+	}
+
+	if input.Name != "" {
+		unit.Name = input.Name
+	}
+	if input.ConversionFactor > 0 {
+		unit.ConversionFactor = input.ConversionFactor
+	}
+	if input.Barcode != nil {
+		unit.Barcode = input.Barcode
+		if *input.Barcode == "" {
+			unit.Barcode = nil
+		}
+	}
+	if input.Price != nil {
+		unit.Price = input.Price
+	}
+
+	if err := r.checkUnitConflict(ctx, productID, unitID, unit.Name, unit.Barcode); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE product_units
+		SET name = $1, conversion_factor = $2, barcode = $3, price = $4, updated_at = NOW()
+		WHERE id = $5
+		RETURNING ` + productUnitColumns
+
+	updated, err := scanProductUnit(r.db.QueryRowContext(ctx, query,
+		unit.Name, unit.ConversionFactor, unit.Barcode, unit.Price, unitID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update product unit: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteUnit soft deletes a product unit
+func (r *ProductRepository) DeleteUnit(ctx context.Context, productID, unitID uuid.UUID) error {
+	query := `UPDATE product_units SET is_active = false, updated_at = NOW() WHERE id = $1 AND product_id = $2 AND is_active = true`
+	result, err := r.db.ExecContext(ctx, query, unitID, productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete product unit: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// checkUnitConflict rejects a unit name already used by another active unit of the product, and a
+// barcode already used by any product or another active unit
+func (r *ProductRepository) checkUnitConflict(ctx context.Context, productID, unitID uuid.UUID, name string, barcode *string) error {
+	var nameTaken, barcodeTaken bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM product_units
+				WHERE product_id = $1 AND lower(name) = lower($2) AND is_active = true AND id <> $3),
+			$4::text IS NOT NULL AND (
+				EXISTS (SELECT 1 FROM products WHERE barcode = $4)
+				OR EXISTS (SELECT 1 FROM product_units WHERE barcode = $4 AND is_active = true AND id <> $3)
+			)
+	`, productID, name, unitID, barcode).Scan(&nameTaken, &barcodeTaken)
+	if err != nil {
+		return fmt.Errorf("failed to check product unit: %w", err)
+	}
+	if nameTaken {
+		return fmt.Errorf("%w: unit %q already exists for this product", domain.ErrAlreadyExists, name)
+	}
+	if barcodeTaken {
+		return fmt.Errorf("%w: barcode %s is already in use", domain.ErrAlreadyExists, *barcode)
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// PromotionRepository handles promotion database operations
+type PromotionRepository struct {
+	db *database.PostgresDB
+}
+
+// NewPromotionRepository creates a new PromotionRepository
+func NewPromotionRepository(db *database.PostgresDB) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+const promotionColumns = `
+	id, name, description, type, product_id, category_id,
+	discount_percent, bundle_quantity, bundle_price, buy_quantity, get_quantity,
+	starts_at, ends_at, days_of_week, start_time::text, end_time::text,
+	priority, is_active, created_by, created_at, updated_at
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPromotion(row rowScanner) (*domain.Promotion, error) {
+	var p domain.Promotion
+	var days pq.Int64Array
+	err := row.Scan(
+		&p.ID, &p.Name, &p.Description, &p.Type, &p.ProductID, &p.CategoryID,
+		&p.DiscountPercent, &p.BundleQuantity, &p.BundlePrice, &p.BuyQuantity, &p.GetQuantity,
+		&p.StartsAt, &p.EndsAt, &days, &p.StartTime, &p.EndTime,
+		&p.Priority, &p.IsActive, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range days {
+		p.DaysOfWeek = append(p.DaysOfWeek, int(d))
+	}
+	return &p, nil
+}
+
+func daysArray(days []int) interface{} {
+	if len(days) == 0 {
+		return nil
+	}
+	arr := make(pq.Int64Array, len(days))
+	for i, d := range days {
+		arr[i] = int64(d)
+	}
+	return arr
+}
+
+// Create creates a new promotion
+func (r *PromotionRepository) Create(ctx context.Context, input domain.PromotionInput) (*domain.Promotion, error) {
+	isActive := true
+	if input.IsActive != nil {
+		isActive = *input.IsActive
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO promotions (
+			name, description, type, product_id, category_id,
+			discount_percent, bundle_quantity, bundle_price, buy_quantity, get_quantity,
+			starts_at, ends_at, days_of_week, start_time, end_time,
+			priority, is_active, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		RETURNING %s
+	`, promotionColumns)
+
+	promo, err := scanPromotion(r.db.QueryRowContext(ctx, query,
+		input.Name, input.Description, input.Type, input.ProductID, input.CategoryID,
+		input.DiscountPercent, input.BundleQuantity, input.BundlePrice, input.BuyQuantity, input.GetQuantity,
+		input.StartsAt, input.EndsAt, daysArray(input.DaysOfWeek), input.StartTime, input.EndTime,
+		input.Priority, isActive, input.CreatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create promotion: %w", err)
+	}
+	return promo, nil
+}
+
+// GetByID retrieves a promotion by ID
+func (r *PromotionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Promotion, error) {
+	query := fmt.Sprintf(`SELECT %s FROM promotions WHERE id = $1`, promotionColumns)
+
+	promo, err := scanPromotion(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promotion: %w", err)
+	}
+	return promo, nil
+}
+
+// List retrieves promotions with filtering and pagination
+func (r *PromotionRepository) List(ctx context.Context, filter domain.PromotionFilter) ([]domain.Promotion, int64, error) {
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
+
+	if filter.Search != nil && *filter.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", argIndex))
+		args = append(args, "%"+*filter.Search+"%")
+		argIndex++
+	}
+	if filter.Type != nil {
+		conditions = append(conditions, fmt.Sprintf("type = $%d", argIndex))
+		args = append(args, *filter.Type)
+		argIndex++
+	}
+	if filter.ProductID != nil {
+		conditions = append(conditions, fmt.Sprintf("product_id = $%d", argIndex))
+		args = append(args, *filter.ProductID)
+		argIndex++
+	}
+	if filter.IsActive != nil {
+		conditions = append(conditions, fmt.Sprintf("is_active = $%d", argIndex))
+		args = append(args, *filter.IsActive)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM promotions %s", whereClause), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count promotions: %w", err)
+	}
+
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := filter.PerPage
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM promotions %s
+		ORDER BY is_active DESC, priority DESC, created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, promotionColumns, whereClause, argIndex, argIndex+1)
+	args = append(args, perPage, (page-1)*perPage)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list promotions: %w", err)
+	}
+	defer rows.Close()
+
+	var promotions []domain.Promotion
+	for rows.Next() {
+		promo, err := scanPromotion(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan promotion: %w", err)
+		}
+		promotions = append(promotions, *promo)
+	}
+	return promotions, total, rows.Err()
+}
+
+// Update replaces a promotion's definition
+func (r *PromotionRepository) Update(ctx context.Context, id uuid.UUID, input domain.PromotionInput) (*domain.Promotion, error) {
+	query := fmt.Sprintf(`
+		UPDATE promotions SET
+			name = $1, description = $2, type = $3, product_id = $4, category_id = $5,
+			discount_percent = $6, bundle_quantity = $7, bundle_price = $8, buy_quantity = $9, get_quantity = $10,
+			starts_at = $11, ends_at = $12, days_of_week = $13, start_time = $14, end_time = $15,
+			priority = $16, is_active = COALESCE($17, is_active), updated_at = NOW()
+		WHERE id = $18
+		RETURNING %s
+	`, promotionColumns)
+
+	promo, err := scanPromotion(r.db.QueryRowContext(ctx, query,
+		input.Name, input.Description, input.Type, input.ProductID, input.CategoryID,
+		input.DiscountPercent, input.BundleQuantity, input.BundlePrice, input.BuyQuantity, input.GetQuantity,
+		input.StartsAt, input.EndsAt, daysArray(input.DaysOfWeek), input.StartTime, input.EndTime,
+		input.Priority, input.IsActive, id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update promotion: %w", err)
+	}
+	return promo, nil
+}
+
+// Delete deactivates a promotion; sold items keep referencing it
+func (r *PromotionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE promotions SET is_active = false, updated_at = NOW() WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete promotion: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// GetActive retrieves active promotions whose period covers the given moment.
+// Weekday and daily time windows are checked by the caller with Promotion.IsActiveAt.
+func (r *PromotionRepository) GetActive(ctx context.Context, at time.Time) ([]domain.Promotion, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM promotions
+		WHERE is_active = true
+			AND (starts_at IS NULL OR starts_at <= $1)
+			AND (ends_at IS NULL OR ends_at > $1)
+		ORDER BY priority DESC
+	`, promotionColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active promotions: %w", err)
+	}
+	defer rows.Close()
+
+	var promotions []domain.Promotion
+	for rows.Next() {
+		promo, err := scanPromotion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %w", err)
+		}
+		promotions = append(promotions, *promo)
+	}
+	return promotions, rows.Err()
+}
+
+// GetUsage summarizes the discount given by each promotion on completed sales
+func (r *PromotionRepository) GetUsage(ctx context.Context, dateFrom, dateTo *time.Time) ([]domain.PromotionUsage, error) {
+	var args []interface{}
+	whereClause := "WHERE t.status = 'completed' AND ti.promotion_discount > 0"
+	argIndex := 1
+
+	if dateFrom != nil {
+		whereClause += fmt.Sprintf(" AND t.created_at >= $%d", argIndex)
+		args = append(args, *dateFrom)
+		argIndex++
+	}
+	if dateTo != nil {
+		whereClause += fmt.Sprintf(" AND t.created_at <= $%d", argIndex)
+		args = append(args, *dateTo)
+		argIndex++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT ti.promotion_id, COALESCE(MAX(ti.promotion_name), ''),
+			COUNT(DISTINCT ti.transaction_id), COALESCE(SUM(ti.quantity), 0),
+			COALESCE(SUM(ti.subtotal), 0), COALESCE(SUM(ti.promotion_discount), 0)
+		FROM transaction_items ti
+		JOIN transactions t ON t.id = ti.transaction_id
+		%s
+		GROUP BY ti.promotion_id
+		ORDER BY SUM(ti.promotion_discount) DESC
+	`, whereClause)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promotion usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make([]domain.PromotionUsage, 0)
+	for rows.Next() {
+		var u domain.PromotionUsage
+		if err := rows.Scan(&u.PromotionID, &u.PromotionName, &u.Transactions, &u.Quantity, &u.GrossSales, &u.TotalDiscount); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
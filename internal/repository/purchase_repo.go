@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// PurchaseRepository handles purchase order database operations
+type PurchaseRepository struct {
+	db *database.PostgresDB
+}
+
+// NewPurchaseRepository creates a new PurchaseRepository
+func NewPurchaseRepository(db *database.PostgresDB) *PurchaseRepository {
+	return &PurchaseRepository{db: db}
+}
+
+// Create creates a new purchase order with items (used within transaction),
+// numbering it with the given purchase prefix
+func (r *PurchaseRepository) Create(ctx context.Context, tx *sql.Tx, purchase *domain.Purchase, purchasePrefix string) error {
+	query := `
+		INSERT INTO purchases (purchase_number, supplier_id, total_amount, status, notes, created_by)
+		VALUES (generate_purchase_number($6), $1, $2, $3, $4, $5)
+		RETURNING id, purchase_number, created_at, updated_at
+	`
+
+	err := tx.QueryRowContext(ctx, query,
+		purchase.SupplierID, purchase.TotalAmount, purchase.Status, purchase.Notes, purchase.CreatedBy, purchasePrefix,
+	).Scan(&purchase.ID, &purchase.PurchaseNumber, &purchase.CreatedAt, &purchase.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create purchase: %w", err)
+	}
+
+	itemQuery := `
+		INSERT INTO purchase_items (purchase_id, product_id, quantity, cost_per_unit, total_cost, batch_number, expiry_date,
+			unit_name, unit_quantity, unit_cost)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+	for i := range purchase.Items {
+		item := &purchase.Items[i]
+		item.PurchaseID = purchase.ID
+
+		err = tx.QueryRowContext(ctx, itemQuery,
+			item.PurchaseID, item.ProductID, item.Quantity, item.CostPerUnit, item.TotalCost, item.BatchNumber, item.ExpiryDate,
+			item.UnitName, item.UnitQuantity, item.UnitCost,
+		).Scan(&item.ID, &item.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create purchase item: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetByID retrieves a purchase by ID with supplier and items
+func (r *PurchaseRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Purchase, error) {
+	query := `
+		SELECT p.id, p.purchase_number, p.supplier_id, p.total_amount, p.status, p.notes,
+			p.received_at, p.created_by, p.created_at, p.updated_at, s.name
+		FROM purchases p
+		LEFT JOIN suppliers s ON p.supplier_id = s.id
+		WHERE p.id = $1
+	`
+
+	var p domain.Purchase
+	var supplierName *string
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&p.ID, &p.PurchaseNumber, &p.SupplierID, &p.TotalAmount, &p.Status, &p.Notes,
+		&p.ReceivedAt, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt, &supplierName,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase: %w", err)
+	}
+
+	if p.SupplierID != nil && supplierName != nil {
+		p.Supplier = &domain.Supplier{ID: *p.SupplierID, Name: *supplierName}
+	}
+
+	p.Items, err = r.GetItems(ctx, nil, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetForUpdate retrieves a purchase header and locks the row (used within transaction)
+func (r *PurchaseRepository) GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*domain.Purchase, error) {
+	query := `
+		SELECT id, purchase_number, supplier_id, total_amount, status, notes,
+			received_at, created_by, created_at, updated_at
+		FROM purchases WHERE id = $1
+		FOR UPDATE
+	`
+
+	var p domain.Purchase
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&p.ID, &p.PurchaseNumber, &p.SupplierID, &p.TotalAmount, &p.Status, &p.Notes,
+		&p.ReceivedAt, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock purchase: %w", err)
+	}
+	return &p, nil
+}
+
+// GetItems retrieves purchase items with product names
+func (r *PurchaseRepository) GetItems(ctx context.Context, tx *sql.Tx, purchaseID uuid.UUID) ([]domain.PurchaseItem, error) {
+	query := `
+		SELECT pi.id, pi.purchase_id, pi.product_id, pi.quantity, pi.cost_per_unit, pi.total_cost, pi.created_at,
+			pi.batch_number, pi.expiry_date, pi.unit_name, pi.unit_quantity, pi.unit_cost,
+			pr.name, pr.barcode, pr.unit, pr.current_stock, pr.min_stock_alert
+		FROM purchase_items pi
+		JOIN products pr ON pi.product_id = pr.id
+		WHERE pi.purchase_id = $1
+		ORDER BY pi.product_id
+	`
+
+	var rows *sql.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, query, purchaseID)
+	} else {
+		rows, err = r.db.QueryContext(ctx, query, purchaseID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.PurchaseItem
+	for rows.Next() {
+		var item domain.PurchaseItem
+		var product domain.Product
+		if err := rows.Scan(
+			&item.ID, &item.PurchaseID, &item.ProductID, &item.Quantity, &item.CostPerUnit, &item.TotalCost, &item.CreatedAt,
+			&item.BatchNumber, &item.ExpiryDate, &item.UnitName, &item.UnitQuantity, &item.UnitCost, &product.Name, &product.Barcode, &product.Unit, &product.CurrentStock, &product.MinStockAlert,
+		); err != nil {
+			return nil, err
+		}
+		product.ID = item.ProductID
+		item.Product = &product
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// List retrieves purchases with filtering and pagination
+func (r *PurchaseRepository) List(ctx context.Context, filter domain.PurchaseFilter) ([]domain.Purchase, int64, error) {
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
+
+	if filter.SupplierID != nil {
+		conditions = append(conditions, fmt.Sprintf("p.supplier_id = $%d", argIndex))
+		args = append(args, *filter.SupplierID)
+		argIndex++
+	}
+	if filter.Status != nil {
+		conditions = append(conditions, fmt.Sprintf("p.status = $%d", argIndex))
+		args = append(args, *filter.Status)
+		argIndex++
+	}
+	if filter.DateFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("p.created_at >= $%d", argIndex))
+		args = append(args, *filter.DateFrom)
+		argIndex++
+	}
+	if filter.DateTo != nil {
+		conditions = append(conditions, fmt.Sprintf("p.created_at <= $%d", argIndex))
+		args = append(args, *filter.DateTo)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM purchases p %s", whereClause), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count purchases: %w", err)
+	}
+
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := filter.PerPage
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := fmt.Sprintf(`
+		SELECT p.id, p.purchase_number, p.supplier_id, p.total_amount, p.status, p.notes,
+			p.received_at, p.created_by, p.created_at, p.updated_at, s.name
+		FROM purchases p
+		LEFT JOIN suppliers s ON p.supplier_id = s.id
+		%s
+		ORDER BY p.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIndex, argIndex+1)
+
+	args = append(args, perPage, (page-1)*perPage)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list purchases: %w", err)
+	}
+	defer rows.Close()
+
+	var purchases []domain.Purchase
+	for rows.Next() {
+		var p domain.Purchase
+		var supplierName *string
+		if err := rows.Scan(
+			&p.ID, &p.PurchaseNumber, &p.SupplierID, &p.TotalAmount, &p.Status, &p.Notes,
+			&p.ReceivedAt, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt, &supplierName,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan purchase: %w", err)
+		}
+		if p.SupplierID != nil && supplierName != nil {
+			p.Supplier = &domain.Supplier{ID: *p.SupplierID, Name: *supplierName}
+		}
+		purchases = append(purchases, p)
+	}
+
+	return purchases, total, rows.Err()
+}
+
+// UpdateStatus updates the status of a purchase, stamping received_at when received
+func (r *PurchaseRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status domain.PurchaseStatus) error {
+	query := `
+		UPDATE purchases
+		SET status = $1,
+			received_at = CASE WHEN $1 = 'received' THEN NOW() ELSE received_at END,
+			updated_at = NOW()
+		WHERE id = $2
+	`
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, query, status, id)
+	} else {
+		result, err = r.db.ExecContext(ctx, query, status, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update purchase status: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// GetSupplierSummary aggregates received purchases from a supplier per product
+func (r *PurchaseRepository) GetSupplierSummary(ctx context.Context, supplierID uuid.UUID, dateFrom, dateTo *time.Time) (int, int64, []domain.SupplierProductSummary, error) {
+	args := []interface{}{supplierID}
+	whereClause := "WHERE p.supplier_id = $1 AND p.status = 'received'"
+	argIndex := 2
+
+	if dateFrom != nil {
+		whereClause += fmt.Sprintf(" AND p.received_at >= $%d", argIndex)
+		args = append(args, *dateFrom)
+		argIndex++
+	}
+	if dateTo != nil {
+		whereClause += fmt.Sprintf(" AND p.received_at <= $%d", argIndex)
+		args = append(args, *dateTo)
+		argIndex++
+	}
+
+	var count int
+	var totalAmount int64
+	totalsQuery := fmt.Sprintf("SELECT COUNT(*), COALESCE(SUM(p.total_amount), 0) FROM purchases p %s", whereClause)
+	if err := r.db.QueryRowContext(ctx, totalsQuery, args...).Scan(&count, &totalAmount); err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to summarize purchases: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT pi.product_id, pr.name, SUM(pi.quantity), SUM(pi.total_cost)
+		FROM purchase_items pi
+		JOIN purchases p ON pi.purchase_id = p.id
+		JOIN products pr ON pi.product_id = pr.id
+		%s
+		GROUP BY pi.product_id, pr.name
+		ORDER BY SUM(pi.total_cost) DESC
+	`, whereClause)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to summarize purchase items: %w", err)
+	}
+	defer rows.Close()
+
+	products := make([]domain.SupplierProductSummary, 0)
+	for rows.Next() {
+		var s domain.SupplierProductSummary
+		if err := rows.Scan(&s.ProductID, &s.ProductName, &s.TotalQuantity, &s.TotalCost); err != nil {
+			return 0, 0, nil, err
+		}
+		if s.TotalQuantity > 0 {
+			s.AvgCostPerUnit = s.TotalCost / int64(s.TotalQuantity)
+		}
+		products = append(products, s)
+	}
+
+	return count, totalAmount, products, rows.Err()
+}
@@ -90,9 +90,13 @@ func (r *RefillableRepository) RecordMovement(ctx context.Context, tx *sql.Tx, m
 		execer = r.db
 	}
 
-	// Get current balance/snapshot for history
+	// Get current balance/snapshot for history, locking the container when inside a transaction
+	snapshotQuery := "SELECT empty_count, full_count FROM refillable_containers WHERE id = $1"
+	if tx != nil {
+		snapshotQuery += " FOR UPDATE"
+	}
 	var empty, full int
-	err := execer.QueryRowContext(ctx, "SELECT empty_count, full_count FROM refillable_containers WHERE id = $1", m.ContainerID).Scan(&empty, &full)
+	err := execer.QueryRowContext(ctx, snapshotQuery, m.ContainerID).Scan(&empty, &full)
 	if err != nil {
 		return err
 	}
@@ -151,6 +155,45 @@ func (r *RefillableRepository) GetMovements(ctx context.Context, containerID uui
 	return movements, total, nil
 }
 
+// GetMovementsByReference retrieves the movements of one type recorded for a reference, such as a transaction
+func (r *RefillableRepository) GetMovementsByReference(ctx context.Context, tx *sql.Tx, movementType domain.ContainerMovementType, referenceType string, referenceID uuid.UUID) ([]domain.ContainerMovement, error) {
+	var queryer interface {
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	}
+	if tx != nil {
+		queryer = tx
+	} else {
+		queryer = r.db
+	}
+
+	query := `
+		SELECT id, container_id, type, empty_change, full_change, empty_before, empty_after,
+		       full_before, full_after, reference_type, reference_id, notes, created_by, created_at
+		FROM container_movements
+		WHERE type = $1 AND reference_type = $2 AND reference_id = $3
+		ORDER BY created_at
+	`
+	rows, err := queryer.QueryContext(ctx, query, movementType, referenceType, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []domain.ContainerMovement
+	for rows.Next() {
+		var m domain.ContainerMovement
+		if err := rows.Scan(
+			&m.ID, &m.ContainerID, &m.Type, &m.EmptyChange, &m.FullChange,
+			&m.EmptyBefore, &m.EmptyAfter, &m.FullBefore, &m.FullAfter,
+			&m.ReferenceType, &m.ReferenceID, &m.Notes, &m.CreatedBy, &m.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
+
 func (r *RefillableRepository) GetByProductID(ctx context.Context, productID uuid.UUID) (*domain.RefillableContainer, error) {
 	query := `
 		SELECT id, product_id, container_type, empty_count, full_count, notes, created_at, updated_at
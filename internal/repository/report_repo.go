@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// ReportRepository handles the daily_summaries rollup
+type ReportRepository struct {
+	db *database.PostgresDB
+}
+
+// NewReportRepository creates a new ReportRepository
+func NewReportRepository(db *database.PostgresDB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// UpsertDailySummary writes the rollup row for a day, replacing an earlier one
+func (r *ReportRepository) UpsertDailySummary(ctx context.Context, s domain.DailySummary) error {
+	query := `
+		INSERT INTO daily_summaries (date, total_transactions, total_sales, total_tax, total_cost, total_profit,
+			total_kasbon_given, total_kasbon_paid, cash_sales, kasbon_sales, transfer_sales, qris_sales)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (date) DO UPDATE SET
+			total_transactions = EXCLUDED.total_transactions,
+			total_sales = EXCLUDED.total_sales,
+			total_tax = EXCLUDED.total_tax,
+			total_cost = EXCLUDED.total_cost,
+			total_profit = EXCLUDED.total_profit,
+			total_kasbon_given = EXCLUDED.total_kasbon_given,
+			total_kasbon_paid = EXCLUDED.total_kasbon_paid,
+			cash_sales = EXCLUDED.cash_sales,
+			kasbon_sales = EXCLUDED.kasbon_sales,
+			transfer_sales = EXCLUDED.transfer_sales,
+			qris_sales = EXCLUDED.qris_sales
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		s.Date.Format("2006-01-02"), s.TotalTransactions, s.TotalSales, s.TotalTax, s.TotalCost, s.TotalProfit,
+		s.TotalKasbonGiven, s.TotalKasbonPaid, s.CashSales, s.KasbonSales, s.TransferSales, s.QRISSales,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save daily summary: %w", err)
+	}
+	return nil
+}
+
+// GetDailySummaries returns the rollup rows between two dates (inclusive, YYYY-MM-DD), oldest first
+func (r *ReportRepository) GetDailySummaries(ctx context.Context, dateFrom, dateTo string) ([]domain.DailySummary, error) {
+	query := `
+		SELECT date, total_transactions, total_sales, COALESCE(total_tax, 0), total_cost, total_profit,
+			total_kasbon_given, total_kasbon_paid, cash_sales, kasbon_sales, transfer_sales, qris_sales,
+			COALESCE(updated_at, created_at)
+		FROM daily_summaries
+		WHERE date BETWEEN $1 AND $2
+		ORDER BY date
+	`
+	rows, err := r.db.QueryContext(ctx, query, dateFrom, dateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make([]domain.DailySummary, 0)
+	for rows.Next() {
+		var s domain.DailySummary
+		var rolledUpAt time.Time
+		if err := rows.Scan(
+			&s.Date, &s.TotalTransactions, &s.TotalSales, &s.TotalTax, &s.TotalCost, &s.TotalProfit,
+			&s.TotalKasbonGiven, &s.TotalKasbonPaid, &s.CashSales, &s.KasbonSales, &s.TransferSales, &s.QRISSales,
+			&rolledUpAt,
+		); err != nil {
+			return nil, err
+		}
+		s.RolledUpAt = &rolledUpAt
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// GetUnsummarizedDates returns closed days before a date (YYYY-MM-DD) that had sales or kasbon
+// activity but no rollup row yet, oldest first
+func (r *ReportRepository) GetUnsummarizedDates(ctx context.Context, before string, limit int) ([]string, error) {
+	query := `
+		SELECT d FROM (
+			SELECT DISTINCT DATE(created_at) AS d FROM transactions WHERE DATE(created_at) < $1
+			UNION
+			SELECT DISTINCT DATE(created_at) FROM kasbon_records WHERE DATE(created_at) < $1
+		) days
+		WHERE NOT EXISTS (SELECT 1 FROM daily_summaries ds WHERE ds.date = days.d)
+		ORDER BY d
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unsummarized dates: %w", err)
+	}
+	defer rows.Close()
+
+	var dates []string
+	for rows.Next() {
+		var d time.Time
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	return dates, rows.Err()
+}
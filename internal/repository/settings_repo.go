@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// SettingsRepository handles app_settings database operations
+type SettingsRepository struct {
+	db *database.PostgresDB
+}
+
+// NewSettingsRepository creates a new SettingsRepository
+func NewSettingsRepository(db *database.PostgresDB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// GetAll retrieves every setting as a key-value map
+func (r *SettingsRepository) GetAll(ctx context.Context) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT key, value FROM app_settings")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		settings[key] = value
+	}
+	return settings, rows.Err()
+}
+
+// List retrieves every setting with its description
+func (r *SettingsRepository) List(ctx context.Context) ([]domain.AppSetting, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT key, value, description, updated_at FROM app_settings ORDER BY key")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settings: %w", err)
+	}
+	defer rows.Close()
+
+	var settings []domain.AppSetting
+	for rows.Next() {
+		var s domain.AppSetting
+		if err := rows.Scan(&s.Key, &s.Value, &s.Description, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+// Set inserts or updates a setting value
+func (r *SettingsRepository) Set(ctx context.Context, tx *sql.Tx, key, value string) error {
+	query := `
+		INSERT INTO app_settings (key, value) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, key, value)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, key, value)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update setting %s: %w", key, err)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/eveeze/warung-backend/internal/database"
 	"github.com/eveeze/warung-backend/internal/domain"
@@ -268,19 +269,29 @@ func (r *StockOpnameRepository) GetItemsWithVariance(ctx context.Context, sessio
 	return items, rows.Err()
 }
 
-// GetShoppingList generates a shopping list from low stock products
-func (r *StockOpnameRepository) GetShoppingList(ctx context.Context) ([]domain.ShoppingListItem, error) {
+// GetShoppingList generates a shopping list from low stock products: those at or below their
+// minimum stock, or at or below the reorder point forecast for them. The suggested quantity
+// refills up to max_stock (three times the minimum when unset).
+func (r *StockOpnameRepository) GetShoppingList(ctx context.Context, reorderPoints map[uuid.UUID]int) ([]domain.ShoppingListItem, error) {
+	ids := make(pq.StringArray, 0, len(reorderPoints))
+	points := make(pq.Int64Array, 0, len(reorderPoints))
+	for id, point := range reorderPoints {
+		ids = append(ids, id.String())
+		points = append(points, int64(point))
+	}
+
 	query := `
-		SELECT p.id, p.name, p.barcode, p.current_stock, p.min_stock_alert, p.cost_price,
+		SELECT p.id, p.name, p.barcode, p.unit, p.current_stock, p.min_stock_alert, p.max_stock, p.cost_price,
 			COALESCE(p.max_stock, p.min_stock_alert * 3) - p.current_stock as suggested_qty
 		FROM products p
+		LEFT JOIN unnest($1::uuid[], $2::bigint[]) AS f(product_id, reorder_point) ON f.product_id = p.id
 		WHERE p.is_active = true 
 			AND p.is_stock_active = true 
-			AND p.current_stock <= p.min_stock_alert
-		ORDER BY (p.current_stock::float / NULLIF(p.min_stock_alert, 0)) ASC, p.name
+			AND (p.current_stock <= p.min_stock_alert OR p.current_stock <= f.reorder_point)
+		ORDER BY (p.current_stock::float / NULLIF(GREATEST(p.min_stock_alert, f.reorder_point), 0)) ASC, p.name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, ids, points)
 	if err != nil {
 		return nil, err
 	}
@@ -289,43 +300,41 @@ func (r *StockOpnameRepository) GetShoppingList(ctx context.Context) ([]domain.S
 	var items []domain.ShoppingListItem
 	for rows.Next() {
 		var item domain.ShoppingListItem
-		var productName string
-		var barcode *string
-		var costPrice int64
+		var product domain.Product
 
 		if err := rows.Scan(
-			&item.ProductID, &productName, &barcode,
-			&item.CurrentStock, &item.MinStock, &costPrice, &item.SuggestedQty,
+			&item.ProductID, &product.Name, &product.Barcode, &product.Unit,
+			&item.CurrentStock, &item.MinStock, &product.MaxStock, &product.CostPrice, &item.SuggestedQty,
 		); err != nil {
 			return nil, err
 		}
 
-		estimatedCost := int64(item.SuggestedQty) * costPrice
+		estimatedCost := int64(item.SuggestedQty) * product.CostPrice
 		item.EstimatedCost = &estimatedCost
-		item.Product = &domain.Product{
-			ID:      item.ProductID,
-			Name:    productName,
-			Barcode: barcode,
-		}
+		product.ID = item.ProductID
+		product.CurrentStock = item.CurrentStock
+		product.MinStockAlert = item.MinStock
+		item.Product = &product
 		items = append(items, item)
 	}
 
 	return items, rows.Err()
 }
 
-// GetNearExpiryItems gets stock movements with expiry dates within the given days
+// GetNearExpiryItems gets stock lots that expire within the given days, or already have,
+// with the quantity still left in each lot
 func (r *StockOpnameRepository) GetNearExpiryItems(ctx context.Context, daysAhead int) ([]domain.NearExpiryItem, error) {
 	query := `
-		SELECT sm.product_id, p.name, p.barcode, sm.batch_number, sm.expiry_date,
-			(sm.expiry_date - CURRENT_DATE) as days_until_expiry,
-			sm.quantity, p.cost_price
-		FROM stock_movements sm
-		JOIN products p ON p.id = sm.product_id
-		WHERE sm.expiry_date IS NOT NULL 
-			AND sm.expiry_date <= CURRENT_DATE + ($1 * INTERVAL '1 day')
-			AND sm.expiry_date >= CURRENT_DATE
-			AND sm.type IN ('purchase', 'initial')
-		ORDER BY sm.expiry_date ASC, p.name
+		SELECT b.id, b.product_id, p.name, p.barcode, b.batch_number, b.expiry_date,
+			(b.expiry_date - CURRENT_DATE) as days_until_expiry,
+			b.remaining_quantity, COALESCE(b.cost_per_unit, p.cost_price)
+		FROM stock_batches b
+		JOIN products p ON p.id = b.product_id
+		WHERE b.expiry_date IS NOT NULL
+			AND b.expiry_date <= CURRENT_DATE + ($1 * INTERVAL '1 day')
+			AND b.remaining_quantity > 0
+			AND p.is_stock_active = true
+		ORDER BY b.expiry_date ASC, p.name
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, daysAhead)
@@ -338,7 +347,7 @@ func (r *StockOpnameRepository) GetNearExpiryItems(ctx context.Context, daysAhea
 	for rows.Next() {
 		var item domain.NearExpiryItem
 		if err := rows.Scan(
-			&item.ProductID, &item.ProductName, &item.Barcode, &item.BatchNumber,
+			&item.BatchID, &item.ProductID, &item.ProductName, &item.Barcode, &item.BatchNumber,
 			&item.ExpiryDate, &item.DaysUntilExpiry, &item.Quantity, &item.CostPrice,
 		); err != nil {
 			return nil, err
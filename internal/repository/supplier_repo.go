@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// SupplierRepository handles supplier database operations
+type SupplierRepository struct {
+	db *database.PostgresDB
+}
+
+// NewSupplierRepository creates a new SupplierRepository
+func NewSupplierRepository(db *database.PostgresDB) *SupplierRepository {
+	return &SupplierRepository{db: db}
+}
+
+// Create creates a new supplier
+func (r *SupplierRepository) Create(ctx context.Context, input domain.SupplierCreateInput) (*domain.Supplier, error) {
+	query := `
+		INSERT INTO suppliers (name, phone, address, notes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, name, phone, address, notes, is_active, created_at, updated_at
+	`
+
+	var s domain.Supplier
+	err := r.db.QueryRowContext(ctx, query,
+		input.Name, input.Phone, input.Address, input.Notes,
+	).Scan(
+		&s.ID, &s.Name, &s.Phone, &s.Address, &s.Notes, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create supplier: %w", err)
+	}
+
+	return &s, nil
+}
+
+// GetByID retrieves a supplier by ID
+func (r *SupplierRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Supplier, error) {
+	query := `
+		SELECT id, name, phone, address, notes, is_active, created_at, updated_at
+		FROM suppliers
+		WHERE id = $1
+	`
+
+	var s domain.Supplier
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&s.ID, &s.Name, &s.Phone, &s.Address, &s.Notes, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier: %w", err)
+	}
+
+	return &s, nil
+}
+
+// List retrieves suppliers with filtering and pagination
+func (r *SupplierRepository) List(ctx context.Context, filter domain.SupplierFilter) ([]domain.Supplier, int64, error) {
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
+
+	if filter.Search != nil && *filter.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%d OR phone ILIKE $%d)", argIndex, argIndex))
+		args = append(args, "%"+*filter.Search+"%")
+		argIndex++
+	}
+	if filter.IsActive != nil {
+		conditions = append(conditions, fmt.Sprintf("is_active = $%d", argIndex))
+		args = append(args, *filter.IsActive)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM suppliers %s", whereClause), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count suppliers: %w", err)
+	}
+
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := filter.PerPage
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, name, phone, address, notes, is_active, created_at, updated_at
+		FROM suppliers
+		%s
+		ORDER BY name ASC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIndex, argIndex+1)
+
+	args = append(args, perPage, (page-1)*perPage)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list suppliers: %w", err)
+	}
+	defer rows.Close()
+
+	var suppliers []domain.Supplier
+	for rows.Next() {
+		var s domain.Supplier
+		if err := rows.Scan(
+			&s.ID, &s.Name, &s.Phone, &s.Address, &s.Notes, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan supplier: %w", err)
+		}
+		suppliers = append(suppliers, s)
+	}
+
+	return suppliers, total, rows.Err()
+}
+
+// Update updates a supplier
+func (r *SupplierRepository) Update(ctx context.Context, id uuid.UUID, input domain.SupplierUpdateInput) (*domain.Supplier, error) {
+	query := `
+		UPDATE suppliers
+		SET name = COALESCE($2, name), phone = COALESCE($3, phone), address = COALESCE($4, address),
+		    notes = COALESCE($5, notes), is_active = COALESCE($6, is_active), updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, name, phone, address, notes, is_active, created_at, updated_at
+	`
+
+	var s domain.Supplier
+	err := r.db.QueryRowContext(ctx, query,
+		id, input.Name, input.Phone, input.Address, input.Notes, input.IsActive,
+	).Scan(
+		&s.ID, &s.Name, &s.Phone, &s.Address, &s.Notes, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update supplier: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Delete soft deletes a supplier
+func (r *SupplierRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE suppliers SET is_active = false, updated_at = NOW() WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete supplier: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -22,10 +23,11 @@ func NewTransactionRepository(db *database.PostgresDB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
-// Create creates a new transaction with items
-func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, transaction *domain.Transaction) error {
+// Create creates a new transaction with items, numbering it with the given invoice prefix.
+// A sale recorded offline is dated, and numbered, by when the device made it, not when it was pushed.
+func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, transaction *domain.Transaction, invoicePrefix string) error {
 	var invoiceNumber string
-	err := tx.QueryRowContext(ctx, "SELECT generate_invoice_number()").Scan(&invoiceNumber)
+	err := tx.QueryRowContext(ctx, "SELECT generate_invoice_number($1, $2)", invoicePrefix, transaction.ClientCreatedAt).Scan(&invoiceNumber)
 	if err != nil {
 		return fmt.Errorf("failed to generate invoice number: %w", err)
 	}
@@ -34,8 +36,9 @@ func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, transact
 	query := `
 		INSERT INTO transactions (
 			invoice_number, customer_id, subtotal, discount_amount, tax_amount,
-			total_amount, payment_method, amount_paid, change_amount, status, notes, cashier_name
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			total_amount, payment_method, amount_paid, change_amount, status, notes, cashier_name,
+			client_id, client_created_at, created_at, tax_inclusive
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, COALESCE($14, NOW()), $15)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -44,6 +47,7 @@ func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, transact
 		transaction.DiscountAmount, transaction.TaxAmount, transaction.TotalAmount,
 		transaction.PaymentMethod, transaction.AmountPaid, transaction.ChangeAmount,
 		transaction.Status, transaction.Notes, transaction.CashierName,
+		transaction.ClientID, transaction.ClientCreatedAt, transaction.TaxInclusive,
 	).Scan(&transaction.ID, &transaction.CreatedAt, &transaction.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create transaction: %w", err)
@@ -56,22 +60,43 @@ func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, transact
 		itemQuery := `
 			INSERT INTO transaction_items (
 				transaction_id, product_id, product_name, product_barcode,
-				quantity, unit, unit_price, cost_price, subtotal,
-				discount_amount, total_amount, pricing_tier_id, pricing_tier_name, notes
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+				quantity, unit, unit_price, cost_price, cogs_amount, subtotal,
+				discount_amount, total_amount, pricing_tier_id, pricing_tier_name, notes,
+				promotion_id, promotion_name, promotion_discount,
+				product_unit_id, unit_quantity, conversion_factor
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 			RETURNING id, created_at
 		`
 
 		err = tx.QueryRowContext(ctx, itemQuery,
 			item.TransactionID, item.ProductID, item.ProductName, item.ProductBarcode,
-			item.Quantity, item.Unit, item.UnitPrice, item.CostPrice, item.Subtotal,
+			item.Quantity, item.Unit, item.UnitPrice, item.CostPrice, item.COGSAmount, item.Subtotal,
 			item.DiscountAmount, item.TotalAmount, item.PricingTierID, item.PricingTierName, item.Notes,
+			item.PromotionID, item.PromotionName, item.PromotionDiscount,
+			item.ProductUnitID, item.UnitQuantity, item.ConversionFactor,
 		).Scan(&item.ID, &item.CreatedAt)
 		if err != nil {
 			return fmt.Errorf("failed to create transaction item: %w", err)
 		}
 	}
 
+	paymentQuery := `
+		INSERT INTO transaction_payments (transaction_id, method, amount, reference)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	for i := range transaction.Payments {
+		payment := &transaction.Payments[i]
+		payment.TransactionID = transaction.ID
+
+		err = tx.QueryRowContext(ctx, paymentQuery,
+			payment.TransactionID, payment.Method, payment.Amount, payment.Reference,
+		).Scan(&payment.ID, &payment.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create transaction payment: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -80,7 +105,9 @@ func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*dom
 	query := `
 		SELECT t.id, t.invoice_number, t.customer_id, t.subtotal, t.discount_amount, t.tax_amount,
 			t.total_amount, t.payment_method, t.amount_paid, t.change_amount, t.status, t.notes, t.cashier_name,
-			t.created_at, t.updated_at, c.name
+			t.created_at, t.updated_at, c.name, t.receipt_print_count, t.last_printed_at,
+			t.client_id, t.client_created_at, t.cancel_reason, t.cancelled_by, t.cancelled_at,
+			t.tax_inclusive
 		FROM transactions t
 		LEFT JOIN customers c ON t.customer_id = c.id
 		WHERE t.id = $1
@@ -92,6 +119,8 @@ func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*dom
 		&t.ID, &t.InvoiceNumber, &t.CustomerID, &t.Subtotal, &t.DiscountAmount,
 		&t.TaxAmount, &t.TotalAmount, &t.PaymentMethod, &t.AmountPaid, &t.ChangeAmount,
 		&t.Status, &t.Notes, &t.CashierName, &t.CreatedAt, &t.UpdatedAt, &customerName,
+		&t.ReceiptPrintCount, &t.LastPrintedAt, &t.ClientID, &t.ClientCreatedAt,
+		&t.CancelReason, &t.CancelledBy, &t.CancelledAt, &t.TaxInclusive,
 	)
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNotFound
@@ -108,15 +137,31 @@ func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*dom
 	}
 
 	t.Items, _ = r.GetItems(ctx, t.ID)
+	t.Payments, _ = r.GetPayments(ctx, t.ID)
 	return &t, nil
 }
 
+// GetByClientID retrieves a transaction by the ID an offline client generated for it
+func (r *TransactionRepository) GetByClientID(ctx context.Context, clientID uuid.UUID) (*domain.Transaction, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM transactions WHERE client_id = $1", clientID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction by client id: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
 // GetItems retrieves transaction items
 func (r *TransactionRepository) GetItems(ctx context.Context, transactionID uuid.UUID) ([]domain.TransactionItem, error) {
 	query := `
 		SELECT id, transaction_id, product_id, product_name, product_barcode,
-			quantity, unit, unit_price, cost_price, subtotal, discount_amount,
-			total_amount, pricing_tier_id, pricing_tier_name, notes, created_at
+			quantity, unit, unit_price, cost_price, cogs_amount, subtotal, discount_amount,
+			total_amount, pricing_tier_id, pricing_tier_name, notes, created_at,
+			promotion_id, promotion_name, COALESCE(promotion_discount, 0),
+			product_unit_id, unit_quantity, conversion_factor
 		FROM transaction_items WHERE transaction_id = $1 ORDER BY created_at
 	`
 
@@ -132,8 +177,10 @@ func (r *TransactionRepository) GetItems(ctx context.Context, transactionID uuid
 		if err := rows.Scan(
 			&item.ID, &item.TransactionID, &item.ProductID, &item.ProductName,
 			&item.ProductBarcode, &item.Quantity, &item.Unit, &item.UnitPrice,
-			&item.CostPrice, &item.Subtotal, &item.DiscountAmount, &item.TotalAmount,
+			&item.CostPrice, &item.COGSAmount, &item.Subtotal, &item.DiscountAmount, &item.TotalAmount,
 			&item.PricingTierID, &item.PricingTierName, &item.Notes, &item.CreatedAt,
+			&item.PromotionID, &item.PromotionName, &item.PromotionDiscount,
+			&item.ProductUnitID, &item.UnitQuantity, &item.ConversionFactor,
 		); err != nil {
 			return nil, err
 		}
@@ -142,6 +189,30 @@ func (r *TransactionRepository) GetItems(ctx context.Context, transactionID uuid
 	return items, rows.Err()
 }
 
+// GetPayments retrieves the tenders used to pay a transaction
+func (r *TransactionRepository) GetPayments(ctx context.Context, transactionID uuid.UUID) ([]domain.TransactionPayment, error) {
+	query := `
+		SELECT id, transaction_id, method, amount, reference, created_at
+		FROM transaction_payments WHERE transaction_id = $1 ORDER BY created_at, method
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []domain.TransactionPayment
+	for rows.Next() {
+		var p domain.TransactionPayment
+		if err := rows.Scan(&p.ID, &p.TransactionID, &p.Method, &p.Amount, &p.Reference, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
 // List retrieves transactions with filtering
 func (r *TransactionRepository) List(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transaction, int64, error) {
 	var conditions []string
@@ -243,10 +314,42 @@ func (r *TransactionRepository) List(ctx context.Context, filter domain.Transact
 	return transactions, total, rows.Err()
 }
 
+// GetForUpdate retrieves a transaction header and locks the row (used within transaction)
+func (r *TransactionRepository) GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*domain.Transaction, error) {
+	query := `
+		SELECT id, invoice_number, customer_id, subtotal, discount_amount, tax_amount,
+			total_amount, payment_method, amount_paid, change_amount, status, notes, cashier_name,
+			created_at, updated_at
+		FROM transactions WHERE id = $1
+		FOR UPDATE
+	`
+
+	var t domain.Transaction
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&t.ID, &t.InvoiceNumber, &t.CustomerID, &t.Subtotal, &t.DiscountAmount,
+		&t.TaxAmount, &t.TotalAmount, &t.PaymentMethod, &t.AmountPaid, &t.ChangeAmount,
+		&t.Status, &t.Notes, &t.CashierName, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock transaction: %w", err)
+	}
+	return &t, nil
+}
+
 // UpdateStatus updates transaction status
-func (r *TransactionRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.TransactionStatus) error {
+func (r *TransactionRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status domain.TransactionStatus) error {
 	query := `UPDATE transactions SET status = $1, updated_at = NOW() WHERE id = $2`
-	result, err := r.db.ExecContext(ctx, query, status, id)
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, query, status, id)
+	} else {
+		result, err = r.db.ExecContext(ctx, query, status, id)
+	}
 	if err != nil {
 		return err
 	}
@@ -256,6 +359,66 @@ func (r *TransactionRepository) UpdateStatus(ctx context.Context, id uuid.UUID,
 	return nil
 }
 
+// UpdateItemCost records the cost of goods sold of a transaction line once its stock is deducted (used within transaction)
+func (r *TransactionRepository) UpdateItemCost(ctx context.Context, tx *sql.Tx, itemID uuid.UUID, costPrice, cogsAmount int64) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE transaction_items SET cost_price = $1, cogs_amount = $2 WHERE id = $3",
+		costPrice, cogsAmount, itemID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update transaction item cost: %w", err)
+	}
+	return nil
+}
+
+// MarkCancelled sets a transaction to cancelled and records who cancelled it and why (used within transaction)
+func (r *TransactionRepository) MarkCancelled(ctx context.Context, tx *sql.Tx, id uuid.UUID, reason string, cancelledBy *string) error {
+	query := `
+		UPDATE transactions
+		SET status = 'cancelled', cancel_reason = $1, cancelled_by = $2, cancelled_at = NOW(), updated_at = NOW()
+		WHERE id = $3
+	`
+	result, err := tx.ExecContext(ctx, query, reason, cancelledBy, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel transaction: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// HasOpenRefunds checks if a transaction has refunds that are pending, approved or completed (used within transaction)
+func (r *TransactionRepository) HasOpenRefunds(ctx context.Context, tx *sql.Tx, id uuid.UUID) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM refund_records WHERE transaction_id = $1 AND status <> 'rejected')
+	`, id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check refunds: %w", err)
+	}
+	return exists, nil
+}
+
+// RecordReceiptPrint bumps the receipt print counter and returns the new count
+func (r *TransactionRepository) RecordReceiptPrint(ctx context.Context, id uuid.UUID) (int, time.Time, error) {
+	var count int
+	var printedAt time.Time
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE transactions
+		SET receipt_print_count = receipt_print_count + 1, last_printed_at = NOW()
+		WHERE id = $1
+		RETURNING receipt_print_count, last_printed_at
+	`, id).Scan(&count, &printedAt)
+	if err == sql.ErrNoRows {
+		return 0, time.Time{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to record receipt print: %w", err)
+	}
+	return count, printedAt, nil
+}
+
 // GetDailySales returns total sales for a date
 func (r *TransactionRepository) GetDailySales(ctx context.Context, date string) (int64, int, error) {
 	query := `SELECT COALESCE(SUM(total_amount), 0), COUNT(*) FROM transactions 
@@ -268,7 +431,7 @@ func (r *TransactionRepository) GetDailySales(ctx context.Context, date string)
 
 // GetDailyProfit returns profit for a date
 func (r *TransactionRepository) GetDailyProfit(ctx context.Context, date string) (int64, error) {
-	query := `SELECT COALESCE(SUM(ti.total_amount - (ti.cost_price * ti.quantity)), 0)
+	query := `SELECT COALESCE(SUM(ti.total_amount - ti.cogs_amount), 0)
 		FROM transaction_items ti JOIN transactions t ON t.id = ti.transaction_id
 		WHERE DATE(t.created_at) = $1 AND t.status = 'completed'`
 	var profit int64
@@ -276,6 +439,109 @@ func (r *TransactionRepository) GetDailyProfit(ctx context.Context, date string)
 	return profit, err
 }
 
+// GetSalesSummary returns the revenue, tax and cost of goods sold of completed sales between
+// two dates (inclusive, YYYY-MM-DD), net of completed refunds. Revenue excludes the tax collected
+// on the sale, whether it was added on top or included in the prices. Cancelled and fully
+// refunded sales are left out; partial refunds are split into their revenue and tax shares and
+// deducted from the sale's day, and the cost of restocked items is taken back out of COGS.
+func (r *TransactionRepository) GetSalesSummary(ctx context.Context, dateFrom, dateTo string) (revenue, tax, cogs int64, err error) {
+	query := `
+		WITH refunds AS (
+			SELECT COALESCE(rr.total_refund_amount * t.tax_amount / NULLIF(t.total_amount, 0), 0) AS tax,
+			       rr.total_refund_amount AS amount
+			FROM refund_records rr
+			JOIN transactions t ON t.id = rr.transaction_id
+			WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed' AND rr.status = 'completed'
+		), sales AS (
+			SELECT COALESCE(SUM(t.total_amount), 0) AS amount, COALESCE(SUM(t.tax_amount), 0) AS tax
+			FROM transactions t
+			WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed'
+		)
+		SELECT
+			(SELECT amount - tax FROM sales)
+			-
+			(SELECT COALESCE(SUM(amount - tax), 0) FROM refunds),
+			(SELECT tax FROM sales)
+			-
+			(SELECT COALESCE(SUM(tax), 0) FROM refunds),
+			(SELECT COALESCE(SUM(ti.cogs_amount), 0)
+			 FROM transaction_items ti
+			 JOIN transactions t ON t.id = ti.transaction_id
+			 WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed')
+			-
+			(SELECT COALESCE(SUM(ti.cogs_amount * ri.quantity / NULLIF(ti.quantity, 0)), 0)
+			 FROM refund_items ri
+			 JOIN refund_records rr ON rr.id = ri.refund_id
+			 JOIN transaction_items ti ON ti.id = ri.transaction_item_id
+			 JOIN transactions t ON t.id = ti.transaction_id
+			 WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed'
+			   AND rr.status = 'completed' AND ri.restock)
+	`
+	if err := r.db.QueryRowContext(ctx, query, dateFrom, dateTo).Scan(&revenue, &tax, &cogs); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get sales summary: %w", err)
+	}
+	return revenue, tax, cogs, nil
+}
+
+// GetSalesByMethod returns completed sales between two dates (inclusive, YYYY-MM-DD)
+// attributed to each tender, so mixed payments are split across their methods.
+// With netOfRefunds, completed refunds are taken off the sale's tenders in proportion to
+// what each tender paid.
+func (r *TransactionRepository) GetSalesByMethod(ctx context.Context, dateFrom, dateTo string, netOfRefunds bool) ([]domain.MethodBreakdown, error) {
+	query := `
+		SELECT tp.method, COALESCE(SUM(tp.amount), 0), COUNT(DISTINCT tp.transaction_id)
+		FROM transaction_payments tp
+		JOIN transactions t ON t.id = tp.transaction_id
+		WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed'
+		GROUP BY tp.method
+		ORDER BY SUM(tp.amount) DESC
+	`
+	if netOfRefunds {
+		query = `
+			SELECT tp.method,
+			       COALESCE(SUM(tp.amount - COALESCE(tp.amount * rf.amount / NULLIF(t.total_amount, 0), 0)), 0) AS net,
+			       COUNT(DISTINCT tp.transaction_id)
+			FROM transaction_payments tp
+			JOIN transactions t ON t.id = tp.transaction_id
+			LEFT JOIN (
+				SELECT transaction_id, SUM(total_refund_amount) AS amount
+				FROM refund_records
+				WHERE status = 'completed'
+				GROUP BY transaction_id
+			) rf ON rf.transaction_id = t.id
+			WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed'
+			GROUP BY tp.method
+			ORDER BY net DESC
+		`
+	}
+	rows, err := r.db.QueryContext(ctx, query, dateFrom, dateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales by method: %w", err)
+	}
+	defer rows.Close()
+
+	methods := make([]domain.MethodBreakdown, 0)
+	var total int64
+	for rows.Next() {
+		var m domain.MethodBreakdown
+		if err := rows.Scan(&m.Method, &m.Amount, &m.Count); err != nil {
+			return nil, err
+		}
+		total += m.Amount
+		methods = append(methods, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if total > 0 {
+		for i := range methods {
+			methods[i].Percentage = float64(methods[i].Amount) / float64(total) * 100
+		}
+	}
+	return methods, nil
+}
+
 // GetHourlySales returns sales grouped by hour for a specific date
 func (r *TransactionRepository) GetHourlySales(ctx context.Context, date string) ([]map[string]interface{}, error) {
 	query := `
@@ -310,8 +576,9 @@ func (r *TransactionRepository) GetHourlySales(ctx context.Context, date string)
 	return result, nil
 }
 
-// GetTopProducts returns top selling products by sales amount
-func (r *TransactionRepository) GetTopProducts(ctx context.Context, date string, limit int) ([]map[string]interface{}, error) {
+// GetTopProducts returns top selling products by sales amount.
+// With byParent, variants are rolled up into their parent product.
+func (r *TransactionRepository) GetTopProducts(ctx context.Context, date string, limit int, byParent bool) ([]map[string]interface{}, error) {
 	query := `
 		SELECT ti.product_id, ti.product_name, 
 		       SUM(ti.quantity) as total_quantity, 
@@ -323,6 +590,21 @@ func (r *TransactionRepository) GetTopProducts(ctx context.Context, date string,
 		ORDER BY total_sales DESC
 		LIMIT $2
 	`
+	if byParent {
+		query = `
+			SELECT COALESCE(pp.id, ti.product_id), COALESCE(pp.name, p.name),
+			       SUM(ti.quantity) as total_quantity,
+			       SUM(ti.total_amount) as total_sales
+			FROM transaction_items ti
+			JOIN transactions t ON t.id = ti.transaction_id
+			JOIN products p ON p.id = ti.product_id
+			LEFT JOIN products pp ON pp.id = p.parent_id
+			WHERE DATE(t.created_at) = $1 AND t.status = 'completed'
+			GROUP BY COALESCE(pp.id, ti.product_id), COALESCE(pp.name, p.name)
+			ORDER BY total_sales DESC
+			LIMIT $2
+		`
+	}
 	rows, err := r.db.QueryContext(ctx, query, date, limit)
 	if err != nil {
 		return nil, err
@@ -347,3 +629,200 @@ func (r *TransactionRepository) GetTopProducts(ctx context.Context, date string,
 	}
 	return result, nil
 }
+
+// refundedItemsQuery totals the completed refunds of each transaction item: the amount given
+// back, the quantity and the cost of the part that went back into stock
+const refundedItemsQuery = `
+	SELECT ri.transaction_item_id,
+	       SUM(ri.quantity) AS quantity,
+	       SUM(ri.refund_amount) AS amount,
+	       SUM(CASE WHEN ri.restock THEN ti.cogs_amount * ri.quantity / NULLIF(ti.quantity, 0) ELSE 0 END) AS cogs
+	FROM refund_items ri
+	JOIN refund_records rr ON rr.id = ri.refund_id
+	JOIN transaction_items ti ON ti.id = ri.transaction_item_id
+	WHERE rr.status = 'completed'
+	GROUP BY ri.transaction_item_id
+`
+
+// netSalesCTE is the completed sales between $1 and $2 (inclusive, YYYY-MM-DD), one row per
+// transaction with its amount, excluding tax, and COGS net of completed refunds, as in GetSalesSummary
+const netSalesCTE = `
+	WITH net_sales AS (
+		SELECT t.id, t.created_at, t.cashier_name,
+		       t.total_amount - t.tax_amount - COALESCE(SUM(rf.amount), 0)
+		           + COALESCE(SUM(rf.amount) * t.tax_amount / NULLIF(t.total_amount, 0), 0) AS amount,
+		       COALESCE(SUM(ti.cogs_amount - COALESCE(rf.cogs, 0)), 0) AS cogs
+		FROM transactions t
+		LEFT JOIN transaction_items ti ON ti.transaction_id = t.id
+		LEFT JOIN (` + refundedItemsQuery + `) rf ON rf.transaction_item_id = ti.id
+		WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed'
+		GROUP BY t.id
+	)
+`
+
+// GetSalesTrend returns completed sales between two dates (inclusive, YYYY-MM-DD), net of
+// refunds, per day, week or month. Buckets without sales are left out.
+func (r *TransactionRepository) GetSalesTrend(ctx context.Context, dateFrom, dateTo string, interval domain.TrendInterval) ([]domain.TrendBucket, error) {
+	query := netSalesCTE + `
+		SELECT DATE(date_trunc($3, created_at)), COALESCE(SUM(amount), 0), COUNT(*), COALESCE(SUM(cogs), 0)
+		FROM net_sales
+		GROUP BY 1
+		ORDER BY 1
+	`
+	rows, err := r.db.QueryContext(ctx, query, dateFrom, dateTo, string(interval))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales trend: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]domain.TrendBucket, 0)
+	for rows.Next() {
+		var b domain.TrendBucket
+		if err := rows.Scan(&b.Start, &b.Sales, &b.Transactions, &b.Cost); err != nil {
+			return nil, err
+		}
+		b.Profit = b.Sales - b.Cost
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// GetSalesByCashier returns completed sales between two dates (inclusive, YYYY-MM-DD) per cashier,
+// net of refunds, largest first. Sales without a cashier name are grouped under "".
+func (r *TransactionRepository) GetSalesByCashier(ctx context.Context, dateFrom, dateTo string) ([]domain.CashierSales, error) {
+	query := netSalesCTE + `
+		SELECT COALESCE(cashier_name, ''), COUNT(*), COALESCE(SUM(amount), 0), COALESCE(SUM(amount - cogs), 0)
+		FROM net_sales
+		GROUP BY 1
+		ORDER BY 3 DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, dateFrom, dateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales by cashier: %w", err)
+	}
+	defer rows.Close()
+
+	cashiers := make([]domain.CashierSales, 0)
+	for rows.Next() {
+		var c domain.CashierSales
+		if err := rows.Scan(&c.CashierName, &c.Transactions, &c.Sales, &c.Profit); err != nil {
+			return nil, err
+		}
+		if c.Transactions > 0 {
+			c.AverageTransaction = c.Sales / int64(c.Transactions)
+		}
+		cashiers = append(cashiers, c)
+	}
+	return cashiers, rows.Err()
+}
+
+// GetSalesByCategory returns item sales between two dates (inclusive, YYYY-MM-DD) per product
+// category, net of refunds, largest first. Products are grouped by their current category.
+// Returned units come off at their line's own price, and on tax-inclusive sales the tax is taken
+// out of each line in proportion, as netSalesCTE does for the whole sale. Line totals still leave
+// out cart-level discounts, so they need not add up to the sales total.
+func (r *TransactionRepository) GetSalesByCategory(ctx context.Context, dateFrom, dateTo string) ([]domain.CategorySales, error) {
+	query := `
+		SELECT l.category_id, COALESCE(l.category_name, 'Tanpa Kategori'),
+		       COALESCE(SUM(l.quantity), 0),
+		       COALESCE(SUM(l.amount - CASE WHEN l.tax_inclusive
+		           THEN COALESCE(l.amount * l.tax_amount / NULLIF(l.paid, 0), 0) ELSE 0 END), 0),
+		       COALESCE(SUM(l.cogs), 0)
+		FROM (
+			SELECT c.id AS category_id, c.name AS category_name,
+			       ti.quantity - COALESCE(rf.quantity, 0) AS quantity,
+			       ti.total_amount * (ti.quantity - COALESCE(rf.quantity, 0)) / NULLIF(ti.quantity, 0) AS amount,
+			       ti.cogs_amount - COALESCE(rf.cogs, 0) AS cogs,
+			       t.tax_inclusive, t.tax_amount, t.total_amount AS paid
+			FROM transaction_items ti
+			JOIN transactions t ON t.id = ti.transaction_id
+			JOIN products p ON p.id = ti.product_id
+			LEFT JOIN categories c ON c.id = p.category_id
+			LEFT JOIN (` + refundedItemsQuery + `) rf ON rf.transaction_item_id = ti.id
+			WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed'
+		) l
+		GROUP BY l.category_id, l.category_name
+		ORDER BY 4 DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, dateFrom, dateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales by category: %w", err)
+	}
+	defer rows.Close()
+
+	categories := make([]domain.CategorySales, 0)
+	for rows.Next() {
+		var c domain.CategorySales
+		if err := rows.Scan(&c.CategoryID, &c.CategoryName, &c.Quantity, &c.Sales, &c.Cost); err != nil {
+			return nil, err
+		}
+		c.Profit = c.Sales - c.Cost
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// GetProductSales returns item sales between two dates (inclusive, YYYY-MM-DD) per product, net
+// of refunds, largest first. Each product's lines are added up into one item carrying the
+// quantity, total and COGS, under the product's current name.
+func (r *TransactionRepository) GetProductSales(ctx context.Context, dateFrom, dateTo string) ([]domain.TransactionItem, error) {
+	query := `
+		SELECT ti.product_id, p.name,
+		       COALESCE(SUM(ti.quantity - COALESCE(rf.quantity, 0)), 0),
+		       COALESCE(SUM(ti.total_amount - COALESCE(rf.amount, 0)), 0),
+		       COALESCE(SUM(ti.cogs_amount - COALESCE(rf.cogs, 0)), 0)
+		FROM transaction_items ti
+		JOIN transactions t ON t.id = ti.transaction_id
+		JOIN products p ON p.id = ti.product_id
+		LEFT JOIN (` + refundedItemsQuery + `) rf ON rf.transaction_item_id = ti.id
+		WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed'
+		GROUP BY ti.product_id, p.name
+		ORDER BY 4 DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, dateFrom, dateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product sales: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]domain.TransactionItem, 0)
+	for rows.Next() {
+		var item domain.TransactionItem
+		if err := rows.Scan(&item.ProductID, &item.ProductName, &item.Quantity, &item.TotalAmount, &item.COGSAmount); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetDailyProductSales returns the units of each active stock-tracked product sold per day between
+// two dates (inclusive, YYYY-MM-DD), net of refunds. Days without sales are left out.
+func (r *TransactionRepository) GetDailyProductSales(ctx context.Context, dateFrom, dateTo string) ([]domain.ProductDailySales, error) {
+	query := `
+		SELECT ti.product_id, DATE(t.created_at), COALESCE(SUM(ti.quantity - COALESCE(rf.quantity, 0)), 0)
+		FROM transaction_items ti
+		JOIN transactions t ON t.id = ti.transaction_id
+		JOIN products p ON p.id = ti.product_id
+		LEFT JOIN (` + refundedItemsQuery + `) rf ON rf.transaction_item_id = ti.id
+		WHERE DATE(t.created_at) BETWEEN $1 AND $2 AND t.status = 'completed'
+		  AND p.is_active = true AND p.is_stock_active = true
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`
+	rows, err := r.db.QueryContext(ctx, query, dateFrom, dateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily product sales: %w", err)
+	}
+	defer rows.Close()
+
+	sales := make([]domain.ProductDailySales, 0)
+	for rows.Next() {
+		var s domain.ProductDailySales
+		if err := rows.Scan(&s.ProductID, &s.Date, &s.Quantity); err != nil {
+			return nil, err
+		}
+		sales = append(sales, s)
+	}
+	return sales, rows.Err()
+}
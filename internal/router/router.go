@@ -21,6 +21,7 @@ func New(
 	db *database.PostgresDB,
 	redis *database.RedisClient,
 	r2 *storage.R2Client,
+	eventSvc *service.EventService,
 ) http.Handler {
 	mux := http.NewServeMux()
 
@@ -39,6 +40,13 @@ func New(
 	consignmentRepo := repository.NewConsignmentRepository(db)
 	refillableRepo := repository.NewRefillableRepository(db)
 	categoryRepo := repository.NewCategoryRepository(db)
+	supplierRepo := repository.NewSupplierRepository(db)
+	purchaseRepo := repository.NewPurchaseRepository(db)
+	promotionRepo := repository.NewPromotionRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	reportRepo := repository.NewReportRepository(db)
 
 	// Initialize infrastructure
 	notificationRepo := repository.NewNotificationRepository(db)
@@ -47,43 +55,56 @@ func New(
 
 	// Initialize services
 	notificationSvc := service.NewNotificationService(notificationRepo, oneSignalClient, queueClient)
+	outboxSvc := service.NewOutboxService(db, outboxRepo, queueClient, eventSvc)
+	promotionSvc := service.NewPromotionService(promotionRepo, productRepo, categoryRepo)
+	settingsSvc := service.NewSettingsService(db, settingsRepo)
+	taxSvc := service.NewTaxService(settingsSvc, categoryRepo)
 
 	transactionSvc := service.NewTransactionService(
-		db, transactionRepo, productRepo, customerRepo, kasbonRepo, inventoryRepo, refillableRepo, notificationSvc,
+		db, transactionRepo, productRepo, customerRepo, kasbonRepo, inventoryRepo, refillableRepo, outboxSvc, promotionSvc, taxSvc, settingsSvc,
 	)
 	authSvc := service.NewAuthService(userRepo, cfg)
 	userSvc := service.NewUserService(userRepo) // New Service initialized
 	paymentSvc := service.NewPaymentService(db, paymentRepo, transactionRepo, &cfg.Midtrans)
-	stockOpnameSvc := service.NewStockOpnameService(db, stockOpnameRepo, productRepo, inventoryRepo)
+	forecastSvc := service.NewForecastService(transactionRepo, settingsSvc)
+	stockOpnameSvc := service.NewStockOpnameService(db, stockOpnameRepo, productRepo, inventoryRepo, forecastSvc)
 	cashFlowSvc := service.NewCashFlowService(db, cashFlowRepo)
-	posSvc := service.NewPOSService(db, posRepo, productRepo, transactionRepo, inventoryRepo)
-	consignmentSvc := service.NewConsignmentService(db, consignmentRepo, transactionRepo)
+	posSvc := service.NewPOSService(db, posRepo, productRepo, transactionRepo, inventoryRepo, kasbonRepo, cashFlowRepo, outboxSvc)
+	consignmentSvc := service.NewConsignmentService(db, consignmentRepo, transactionRepo, cashFlowRepo)
 	refillableSvc := service.NewRefillableService(db, refillableRepo)
 	categorySvc := service.NewCategoryService(categoryRepo)
-	eventSvc := service.NewEventService()
+	syncSvc := service.NewSyncService(productRepo, categoryRepo, customerRepo)
+	purchaseSvc := service.NewPurchaseService(db, purchaseRepo, supplierRepo, productRepo, inventoryRepo, cashFlowRepo, settingsSvc)
+	reportSvc := service.NewReportService(transactionRepo, cashFlowRepo, kasbonRepo, inventoryRepo, reportRepo)
 
 	// Initialize cache service
 	cacheSvc := service.NewCacheService(redis)
 
 	// Initialize handlers
 	healthHandler := handler.NewHealthHandler(db, redis, r2)
-	productHandler := handler.NewProductHandler(productRepo, r2, cacheSvc)
+	productImportSvc := service.NewProductImportService(db, productRepo, categoryRepo)
+	productHandler := handler.NewProductHandler(productRepo, r2, cacheSvc, productImportSvc)
 	customerHandler := handler.NewCustomerHandler(customerRepo)
 	transactionHandler := handler.NewTransactionHandler(transactionSvc, transactionRepo)
-	kasbonHandler := handler.NewKasbonHandler(kasbonRepo, customerRepo)
-	inventoryHandler := handler.NewInventoryHandler(inventoryRepo, productRepo, cacheSvc, eventSvc)
-	reportHandler := handler.NewReportHandler(transactionRepo, kasbonRepo, inventoryRepo, productRepo)
+	kasbonHandler := handler.NewKasbonHandler(kasbonRepo, customerRepo, settingsSvc)
+	inventoryHandler := handler.NewInventoryHandler(inventoryRepo, productRepo, cacheSvc, eventSvc, settingsSvc, stockOpnameSvc, auditRepo)
+	reportHandler := handler.NewReportHandler(transactionRepo, kasbonRepo, inventoryRepo, productRepo, auditRepo, reportSvc)
 	authHandler := handler.NewAuthHandler(authSvc)
 	userHandler := handler.NewUserHandler(userSvc) // New Handler initialized
 	paymentHandler := handler.NewPaymentHandler(paymentSvc)
 	stockOpnameHandler := handler.NewStockOpnameHandler(stockOpnameSvc)
-	cashFlowHandler := handler.NewCashFlowHandler(cashFlowSvc)
+	cashFlowHandler := handler.NewCashFlowHandler(cashFlowSvc, auditRepo)
 	posHandler := handler.NewPOSHandler(posSvc)
 	consignmentHandler := handler.NewConsignmentHandler(consignmentSvc)
 	refillableHandler := handler.NewRefillableHandler(refillableSvc)
 	categoryHandler := handler.NewCategoryHandler(categorySvc)
 	eventHandler := handler.NewEventHandler(eventSvc)
 	notificationHandler := handler.NewNotificationHandler(notificationSvc)
+	supplierHandler := handler.NewSupplierHandler(supplierRepo)
+	purchaseHandler := handler.NewPurchaseHandler(purchaseSvc, cacheSvc, eventSvc)
+	promotionHandler := handler.NewPromotionHandler(promotionSvc)
+	settingsHandler := handler.NewSettingsHandler(settingsSvc)
+	syncHandler := handler.NewSyncHandler(syncSvc, transactionSvc)
 
 	// Health check routes (Public)
 	mux.HandleFunc("GET /health", healthHandler.Health)
@@ -126,6 +147,13 @@ func New(
 		return authMiddleware(middleware.RequireRole("admin", "inventory")(http.HandlerFunc(h))).ServeHTTP
 	}
 
+	// Replays the stored response when a client retries with the same Idempotency-Key.
+	// Wrap inside an auth helper so keys are scoped per user.
+	idempotencyMiddleware := middleware.Idempotency(idempotencyRepo)
+	idempotent := func(h http.HandlerFunc) http.HandlerFunc {
+		return idempotencyMiddleware(http.HandlerFunc(h)).ServeHTTP
+	}
+
 	// ========================================================================
 	// USERS MANAGEMENT (Admin Only)
 	// ========================================================================
@@ -140,6 +168,14 @@ func New(
 	mux.HandleFunc("PUT "+apiPrefix+"/users/{id}", adminOnly(userHandler.Update))
 	mux.HandleFunc("DELETE "+apiPrefix+"/users/{id}", adminOnly(userHandler.Delete))
 
+	// ========================================================================
+	// APP SETTINGS
+	// ========================================================================
+	// Frontend: client.get('/settings/store') for receipt headers and tax mode
+	mux.HandleFunc("GET "+apiPrefix+"/settings", adminOnly(settingsHandler.List))
+	mux.HandleFunc("GET "+apiPrefix+"/settings/store", protected(settingsHandler.GetStore))
+	mux.HandleFunc("PUT "+apiPrefix+"/settings", adminOnly(settingsHandler.Update))
+
 	// ========================================================================
 	// OTHER MODULES
 	// ========================================================================
@@ -148,6 +184,8 @@ func New(
 	mux.HandleFunc("GET "+apiPrefix+"/products", protected(productHandler.List))
 	mux.HandleFunc("POST "+apiPrefix+"/products", adminOnly(productHandler.Create))
 	mux.HandleFunc("GET "+apiPrefix+"/products/search", protected(productHandler.GetByBarcode))
+	mux.HandleFunc("POST "+apiPrefix+"/products/import", adminOnly(productHandler.Import))
+	mux.HandleFunc("GET "+apiPrefix+"/products/export", adminOnly(productHandler.Export))
 	mux.HandleFunc("GET "+apiPrefix+"/products/low-stock", protected(productHandler.GetLowStock))
 	mux.HandleFunc("GET "+apiPrefix+"/products/{id}", protected(productHandler.GetByID))
 	mux.HandleFunc("PUT "+apiPrefix+"/products/{id}", adminOnly(productHandler.Update))
@@ -156,6 +194,11 @@ func New(
 	mux.HandleFunc("POST "+apiPrefix+"/products/{id}/pricing-tiers", adminOnly(productHandler.AddPricingTier))
 	mux.HandleFunc("PUT "+apiPrefix+"/products/{id}/pricing-tiers/{tierId}", adminOnly(productHandler.UpdatePricingTier))
 	mux.HandleFunc("DELETE "+apiPrefix+"/products/{id}/pricing-tiers/{tierId}", adminOnly(productHandler.DeletePricingTier))
+	mux.HandleFunc("POST "+apiPrefix+"/products/{id}/units", adminOnly(productHandler.AddUnit))
+	mux.HandleFunc("PUT "+apiPrefix+"/products/{id}/units/{unitId}", adminOnly(productHandler.UpdateUnit))
+	mux.HandleFunc("DELETE "+apiPrefix+"/products/{id}/units/{unitId}", adminOnly(productHandler.DeleteUnit))
+	mux.HandleFunc("POST "+apiPrefix+"/products/{id}/variants", adminOnly(productHandler.AddVariant))
+	mux.HandleFunc("PUT "+apiPrefix+"/products/{id}/parent", adminOnly(productHandler.SetParent))
 
 	// Customers
 	mux.HandleFunc("GET "+apiPrefix+"/customers", cashierAccess(customerHandler.List))
@@ -167,22 +210,57 @@ func New(
 	mux.HandleFunc("GET "+apiPrefix+"/kasbon/customers/{id}", cashierAccess(kasbonHandler.GetHistory))
 	mux.HandleFunc("GET "+apiPrefix+"/kasbon/customers/{id}/summary", cashierAccess(kasbonHandler.GetSummary))
 	mux.HandleFunc("GET "+apiPrefix+"/kasbon/customers/{id}/billing/pdf", cashierAccess(kasbonHandler.DownloadBillingPDF))
-	mux.HandleFunc("POST "+apiPrefix+"/kasbon/customers/{id}/payments", cashierAccess(kasbonHandler.RecordPayment))
+	mux.HandleFunc("POST "+apiPrefix+"/kasbon/customers/{id}/payments", cashierAccess(idempotent(kasbonHandler.RecordPayment)))
 
 	// Transactions
 	mux.HandleFunc("GET "+apiPrefix+"/transactions", cashierAccess(transactionHandler.List))
-	mux.HandleFunc("POST "+apiPrefix+"/transactions", cashierAccess(transactionHandler.Create))
+	mux.HandleFunc("POST "+apiPrefix+"/transactions", cashierAccess(idempotent(transactionHandler.Create)))
 	mux.HandleFunc("POST "+apiPrefix+"/transactions/calculate", cashierAccess(transactionHandler.Calculate))
 	mux.HandleFunc("GET "+apiPrefix+"/transactions/{id}", cashierAccess(transactionHandler.GetByID))
+	mux.HandleFunc("GET "+apiPrefix+"/transactions/{id}/receipt", cashierAccess(transactionHandler.Receipt))
 	mux.HandleFunc("POST "+apiPrefix+"/transactions/{id}/cancel", cashierAccess(transactionHandler.Cancel))
 
+	// Offline sync
+	mux.HandleFunc("GET "+apiPrefix+"/sync/pull", cashierAccess(syncHandler.Pull))
+	mux.HandleFunc("POST "+apiPrefix+"/sync/push", cashierAccess(syncHandler.Push))
+
 	// Inventory
 	mux.HandleFunc("POST "+apiPrefix+"/inventory/restock", inventoryAccess(inventoryHandler.Restock))
+	mux.HandleFunc("POST "+apiPrefix+"/inventory/restock/bulk", inventoryAccess(purchaseHandler.BulkRestock))
 	mux.HandleFunc("POST "+apiPrefix+"/inventory/adjust", adminOnly(inventoryHandler.Adjust))
+	mux.HandleFunc("POST "+apiPrefix+"/inventory/repack", inventoryAccess(inventoryHandler.Repack))
 	mux.HandleFunc("GET "+apiPrefix+"/inventory/low-stock", inventoryAccess(inventoryHandler.GetLowStock))
 	mux.HandleFunc("GET "+apiPrefix+"/inventory/report", inventoryAccess(inventoryHandler.GetReport))
 	mux.HandleFunc("GET "+apiPrefix+"/inventory/restock-list/pdf", inventoryAccess(inventoryHandler.DownloadRestockPDF))
 	mux.HandleFunc("GET "+apiPrefix+"/inventory/{productId}/movements", inventoryAccess(inventoryHandler.GetMovements))
+	mux.HandleFunc("GET "+apiPrefix+"/inventory/{productId}/batches", inventoryAccess(inventoryHandler.GetBatches))
+	mux.HandleFunc("GET "+apiPrefix+"/inventory/{productId}/cost-history", inventoryAccess(inventoryHandler.GetCostHistory))
+	mux.HandleFunc("POST "+apiPrefix+"/inventory/batches/write-off-expired", inventoryAccess(inventoryHandler.WriteOffExpired))
+	mux.HandleFunc("POST "+apiPrefix+"/inventory/batches/{id}/write-off", inventoryAccess(inventoryHandler.WriteOffBatch))
+
+	// Suppliers
+	mux.HandleFunc("GET "+apiPrefix+"/suppliers", inventoryAccess(supplierHandler.List))
+	mux.HandleFunc("POST "+apiPrefix+"/suppliers", adminOnly(supplierHandler.Create))
+	mux.HandleFunc("GET "+apiPrefix+"/suppliers/{id}", inventoryAccess(supplierHandler.GetByID))
+	mux.HandleFunc("PUT "+apiPrefix+"/suppliers/{id}", adminOnly(supplierHandler.Update))
+	mux.HandleFunc("DELETE "+apiPrefix+"/suppliers/{id}", adminOnly(supplierHandler.Delete))
+	mux.HandleFunc("GET "+apiPrefix+"/suppliers/{id}/purchases/summary", inventoryAccess(purchaseHandler.GetSupplierSummary))
+
+	// Purchases
+	mux.HandleFunc("GET "+apiPrefix+"/purchases", inventoryAccess(purchaseHandler.List))
+	mux.HandleFunc("POST "+apiPrefix+"/purchases", inventoryAccess(purchaseHandler.Create))
+	mux.HandleFunc("GET "+apiPrefix+"/purchases/{id}", inventoryAccess(purchaseHandler.GetByID))
+	mux.HandleFunc("POST "+apiPrefix+"/purchases/{id}/order", inventoryAccess(purchaseHandler.MarkOrdered))
+	mux.HandleFunc("POST "+apiPrefix+"/purchases/{id}/receive", inventoryAccess(purchaseHandler.Receive))
+	mux.HandleFunc("POST "+apiPrefix+"/purchases/{id}/cancel", inventoryAccess(purchaseHandler.Cancel))
+
+	// Promotions
+	mux.HandleFunc("GET "+apiPrefix+"/promotions", cashierAccess(promotionHandler.List))
+	mux.HandleFunc("POST "+apiPrefix+"/promotions", adminOnly(promotionHandler.Create))
+	mux.HandleFunc("GET "+apiPrefix+"/promotions/usage", adminOnly(promotionHandler.GetUsage))
+	mux.HandleFunc("GET "+apiPrefix+"/promotions/{id}", cashierAccess(promotionHandler.GetByID))
+	mux.HandleFunc("PUT "+apiPrefix+"/promotions/{id}", adminOnly(promotionHandler.Update))
+	mux.HandleFunc("DELETE "+apiPrefix+"/promotions/{id}", adminOnly(promotionHandler.Delete))
 
 	// Categories
 	mux.HandleFunc("GET "+apiPrefix+"/categories", protected(categoryHandler.List))
@@ -196,6 +274,12 @@ func New(
 	mux.HandleFunc("GET "+apiPrefix+"/reports/kasbon", adminOnly(reportHandler.GetKasbonReport))
 	mux.HandleFunc("GET "+apiPrefix+"/reports/inventory", adminOnly(reportHandler.GetInventoryReport))
 	mux.HandleFunc("GET "+apiPrefix+"/reports/dashboard", adminOnly(reportHandler.GetDashboard))
+	mux.HandleFunc("GET "+apiPrefix+"/reports/profit-loss", adminOnly(reportHandler.GetProfitLoss))
+	mux.HandleFunc("GET "+apiPrefix+"/reports/sales-by-method", adminOnly(reportHandler.GetSalesByMethod))
+	mux.HandleFunc("GET "+apiPrefix+"/reports/daily-summaries", adminOnly(reportHandler.GetDailySummaries))
+	mux.HandleFunc("GET "+apiPrefix+"/reports/sales-trend", adminOnly(reportHandler.GetSalesTrend))
+	mux.HandleFunc("GET "+apiPrefix+"/reports/product-performance", adminOnly(reportHandler.GetProductPerformance))
+	mux.HandleFunc("POST "+apiPrefix+"/reports/daily-summaries/rebuild", adminOnly(reportHandler.RebuildDailySummaries))
 
 	// Notifications
 	mux.HandleFunc("GET "+apiPrefix+"/notifications", protected(notificationHandler.GetNotifications))
@@ -224,7 +308,7 @@ func New(
 	mux.HandleFunc("POST "+apiPrefix+"/cashflow/drawer/close", cashierAccess(cashFlowHandler.CloseDrawer))
 	mux.HandleFunc("GET "+apiPrefix+"/cashflow/drawer/current", cashierAccess(cashFlowHandler.GetCurrentSession))
 	mux.HandleFunc("GET "+apiPrefix+"/cashflow/categories", cashierAccess(cashFlowHandler.GetCategories))
-	mux.HandleFunc("POST "+apiPrefix+"/cashflow", cashierAccess(cashFlowHandler.RecordCashFlow))
+	mux.HandleFunc("POST "+apiPrefix+"/cashflow", cashierAccess(idempotent(cashFlowHandler.RecordCashFlow)))
 	mux.HandleFunc("GET "+apiPrefix+"/cashflow", cashierAccess(cashFlowHandler.ListCashFlows))
 
 	// POS Features
@@ -233,13 +317,23 @@ func New(
 	mux.HandleFunc("GET "+apiPrefix+"/pos/held-carts/{id}", cashierAccess(posHandler.GetHeldCart))
 	mux.HandleFunc("POST "+apiPrefix+"/pos/held-carts/{id}/resume", cashierAccess(posHandler.ResumeCart))
 	mux.HandleFunc("POST "+apiPrefix+"/pos/held-carts/{id}/discard", cashierAccess(posHandler.DiscardCart))
-	mux.HandleFunc("POST "+apiPrefix+"/pos/refunds", cashierAccess(posHandler.CreateRefund))
+	mux.HandleFunc("POST "+apiPrefix+"/pos/refunds", cashierAccess(idempotent(posHandler.CreateRefund)))
+	mux.HandleFunc("GET "+apiPrefix+"/pos/refunds/{id}", cashierAccess(posHandler.GetRefund))
+	mux.HandleFunc("POST "+apiPrefix+"/pos/refunds/{id}/approve", adminOnly(posHandler.ApproveRefund))
+	mux.HandleFunc("POST "+apiPrefix+"/pos/refunds/{id}/reject", adminOnly(posHandler.RejectRefund))
+	mux.HandleFunc("POST "+apiPrefix+"/pos/refunds/{id}/complete", adminOnly(posHandler.CompleteRefund))
 
 	// Consignment
 	mux.HandleFunc("POST "+apiPrefix+"/consignors", adminOnly(consignmentHandler.CreateConsignor))
 	mux.HandleFunc("GET "+apiPrefix+"/consignors", adminOnly(consignmentHandler.ListConsignors))
 	mux.HandleFunc("PUT "+apiPrefix+"/consignors/{id}", adminOnly(consignmentHandler.UpdateConsignor))
 	mux.HandleFunc("DELETE "+apiPrefix+"/consignors/{id}", adminOnly(consignmentHandler.DeleteConsignor))
+	mux.HandleFunc("POST "+apiPrefix+"/consignors/{id}/settlements", adminOnly(consignmentHandler.GenerateSettlement))
+	mux.HandleFunc("GET "+apiPrefix+"/consignment/settlements", adminOnly(consignmentHandler.ListSettlements))
+	mux.HandleFunc("GET "+apiPrefix+"/consignment/settlements/{id}", adminOnly(consignmentHandler.GetSettlement))
+	mux.HandleFunc("DELETE "+apiPrefix+"/consignment/settlements/{id}", adminOnly(consignmentHandler.DeleteSettlement))
+	mux.HandleFunc("POST "+apiPrefix+"/consignment/settlements/{id}/confirm", adminOnly(consignmentHandler.ConfirmSettlement))
+	mux.HandleFunc("POST "+apiPrefix+"/consignment/settlements/{id}/pay", adminOnly(consignmentHandler.PaySettlement))
 
 	// Refillables
 	mux.HandleFunc("GET "+apiPrefix+"/refillables", inventoryAccess(refillableHandler.GetContainers))
@@ -34,6 +34,7 @@ func (s *CategoryService) Create(ctx context.Context, input domain.CategoryCreat
 		Name:        input.Name,
 		Description: input.Description,
 		ParentID:    input.ParentID,
+		IsTaxExempt: input.IsTaxExempt,
 	}
 
 	if err := s.categoryRepo.Create(ctx, category); err != nil {
@@ -67,6 +68,9 @@ func (s *CategoryService) Update(ctx context.Context, id uuid.UUID, input domain
 	if input.IsActive != nil {
 		existing.IsActive = *input.IsActive
 	}
+	if input.IsTaxExempt != nil {
+		existing.IsTaxExempt = *input.IsTaxExempt
+	}
 
 	if err := s.categoryRepo.Update(ctx, existing); err != nil {
 		return nil, err
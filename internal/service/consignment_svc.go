@@ -2,6 +2,10 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -10,17 +14,22 @@ import (
 	"github.com/eveeze/warung-backend/internal/repository"
 )
 
+// consignmentPayoutCategory is the cash flow category used for consignor payouts
+const consignmentPayoutCategory = "Pembayaran Titipan"
+
 type ConsignmentService struct {
 	db              *database.PostgresDB
 	consignmentRepo *repository.ConsignmentRepository
 	transactionRepo *repository.TransactionRepository
+	cashFlowRepo    *repository.CashFlowRepository
 }
 
-func NewConsignmentService(db *database.PostgresDB, consignmentRepo *repository.ConsignmentRepository, transactionRepo *repository.TransactionRepository) *ConsignmentService {
+func NewConsignmentService(db *database.PostgresDB, consignmentRepo *repository.ConsignmentRepository, transactionRepo *repository.TransactionRepository, cashFlowRepo *repository.CashFlowRepository) *ConsignmentService {
 	return &ConsignmentService{
 		db:              db,
 		consignmentRepo: consignmentRepo,
 		transactionRepo: transactionRepo,
+		cashFlowRepo:    cashFlowRepo,
 	}
 }
 
@@ -40,14 +49,194 @@ func (s *ConsignmentService) DeleteConsignor(ctx context.Context, id uuid.UUID)
 	return s.consignmentRepo.DeleteConsignor(ctx, id)
 }
 
-// GenerateSettlement calculates sales for a consignor in a period
-// Simplified logic: Query transactions for products belonging to consignor
-func (s *ConsignmentService) GenerateSettlement(ctx context.Context, consignorID uuid.UUID, createdBy string) (*domain.ConsignmentSettlement, error) {
-	// Logic to query transactions... omitting detailed implementation for brevity as "best effort vanilla"
-	// Would involve:
-	// 1. Get products by consignor_id
-	// 2. Sum sales from transaction_items for those products within default period (e.g. last 30 days or since last settlement)
-	
-	// Returning placeholder logic
-	return &domain.ConsignmentSettlement{ConsignorID: consignorID, Status: domain.SettlementStatusDraft}, nil
+// GenerateSettlement calculates what is owed to a consignor for a period and saves it as a draft.
+// The period defaults to the day after the last settlement through yesterday.
+func (s *ConsignmentService) GenerateSettlement(ctx context.Context, input domain.GenerateSettlementInput) (*domain.ConsignmentSettlement, error) {
+	var settlementID uuid.UUID
+
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := s.consignmentRepo.LockConsignor(ctx, tx, input.ConsignorID); err != nil {
+			return err
+		}
+
+		periodEnd := dateOnly(time.Now().AddDate(0, 0, -1))
+		if input.PeriodEnd != nil {
+			periodEnd = dateOnly(*input.PeriodEnd)
+		}
+
+		var periodStart time.Time
+		if input.PeriodStart != nil {
+			periodStart = dateOnly(*input.PeriodStart)
+		} else {
+			lastEnd, err := s.consignmentRepo.GetLastSettlementEnd(ctx, tx, input.ConsignorID)
+			if err != nil {
+				return err
+			}
+			if lastEnd != nil {
+				periodStart = dateOnly(*lastEnd).AddDate(0, 0, 1)
+			} else {
+				firstSale, err := s.consignmentRepo.GetFirstSaleDate(ctx, tx, input.ConsignorID)
+				if err != nil {
+					return err
+				}
+				if firstSale == nil {
+					return fmt.Errorf("%w: no sales to settle for this consignor", domain.ErrInvalidInput)
+				}
+				periodStart = dateOnly(*firstSale)
+			}
+		}
+
+		if periodStart.After(periodEnd) {
+			return fmt.Errorf("%w: nothing to settle, period start %s is after period end %s", domain.ErrInvalidInput,
+				periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02"))
+		}
+
+		overlaps, err := s.consignmentRepo.HasOverlappingSettlement(ctx, tx, input.ConsignorID, periodStart, periodEnd)
+		if err != nil {
+			return err
+		}
+		if overlaps {
+			return fmt.Errorf("%w: a settlement already covers part of this period", domain.ErrInvalidInput)
+		}
+
+		sales, err := s.consignmentRepo.GetConsignorSales(ctx, tx, input.ConsignorID, periodStart, periodEnd.AddDate(0, 0, 1))
+		if err != nil {
+			return fmt.Errorf("failed to calculate consignor sales: %w", err)
+		}
+
+		settlement := &domain.ConsignmentSettlement{
+			ConsignorID: input.ConsignorID,
+			PeriodStart: periodStart,
+			PeriodEnd:   periodEnd,
+			Status:      domain.SettlementStatusDraft,
+			Notes:       input.Notes,
+			CreatedBy:   &input.CreatedBy,
+			Items:       make([]domain.ConsignmentSettlementItem, 0, len(sales)),
+		}
+
+		for _, item := range sales {
+			if item.QuantitySold <= 0 || item.TotalSales <= 0 {
+				continue
+			}
+			item.UnitPrice = item.TotalSales / int64(item.QuantitySold)
+			item.CommissionAmount = int64(math.Round(float64(item.TotalSales) * item.CommissionRate / 100))
+			item.ConsignorAmount = item.TotalSales - item.CommissionAmount
+
+			settlement.TotalSales += item.TotalSales
+			settlement.CommissionAmount += item.CommissionAmount
+			settlement.ConsignorAmount += item.ConsignorAmount
+			settlement.Items = append(settlement.Items, item)
+		}
+
+		if err := s.consignmentRepo.CreateSettlement(ctx, tx, settlement); err != nil {
+			return fmt.Errorf("failed to save settlement: %w", err)
+		}
+		settlementID = settlement.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.consignmentRepo.GetSettlement(ctx, settlementID)
+}
+
+func (s *ConsignmentService) GetSettlement(ctx context.Context, id uuid.UUID) (*domain.ConsignmentSettlement, error) {
+	return s.consignmentRepo.GetSettlement(ctx, id)
+}
+
+func (s *ConsignmentService) ListSettlements(ctx context.Context, filter domain.SettlementFilter) ([]domain.ConsignmentSettlement, int64, error) {
+	return s.consignmentRepo.ListSettlements(ctx, filter)
+}
+
+// ConfirmSettlement locks in a draft settlement so it can be paid
+func (s *ConsignmentService) ConfirmSettlement(ctx context.Context, id uuid.UUID) (*domain.ConsignmentSettlement, error) {
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		settlement, err := s.consignmentRepo.GetSettlementForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !settlement.CanTransitionTo(domain.SettlementStatusConfirmed) {
+			return domain.ErrInvalidStatusTransition
+		}
+		return s.consignmentRepo.UpdateSettlementStatus(ctx, tx, id, domain.SettlementStatusConfirmed, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.consignmentRepo.GetSettlement(ctx, id)
+}
+
+// PaySettlement marks a confirmed settlement as paid and records the payout as a cash flow expense
+func (s *ConsignmentService) PaySettlement(ctx context.Context, id uuid.UUID, paidBy string) (*domain.ConsignmentSettlement, error) {
+	session, err := s.cashFlowRepo.GetCurrentSession(ctx)
+	if err != nil && err != domain.ErrNotFound {
+		return nil, err
+	}
+	var sessionID *uuid.UUID
+	if session != nil {
+		sessionID = &session.ID
+	}
+
+	category, err := s.cashFlowRepo.GetCategoryByName(ctx, consignmentPayoutCategory)
+	if err != nil && err != domain.ErrNotFound {
+		return nil, err
+	}
+	var categoryID *uuid.UUID
+	if category != nil {
+		categoryID = &category.ID
+	}
+
+	err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		settlement, err := s.consignmentRepo.GetSettlementForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !settlement.CanTransitionTo(domain.SettlementStatusPaid) {
+			return domain.ErrInvalidStatusTransition
+		}
+
+		if err := s.consignmentRepo.UpdateSettlementStatus(ctx, tx, id, domain.SettlementStatusPaid, &paidBy); err != nil {
+			return err
+		}
+
+		if settlement.ConsignorAmount > 0 {
+			refType := "consignment_settlement"
+			description := fmt.Sprintf("Consignment settlement %s", settlement.SettlementNumber)
+			input := domain.CashFlowInput{
+				CategoryID:  categoryID,
+				Type:        domain.CashFlowTypeExpense,
+				Amount:      settlement.ConsignorAmount,
+				Description: &description,
+				CreatedBy:   paidBy,
+			}
+			if _, err := s.cashFlowRepo.RecordCashFlow(ctx, tx, input, sessionID, &refType, &settlement.ID); err != nil {
+				return fmt.Errorf("failed to record payout: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.consignmentRepo.GetSettlement(ctx, id)
+}
+
+// DeleteSettlement discards a draft settlement so the period can be regenerated
+func (s *ConsignmentService) DeleteSettlement(ctx context.Context, id uuid.UUID) error {
+	return s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		settlement, err := s.consignmentRepo.GetSettlementForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if settlement.Status != domain.SettlementStatusDraft {
+			return domain.ErrInvalidStatusTransition
+		}
+		return s.consignmentRepo.DeleteSettlement(ctx, tx, id)
+	})
+}
+
+// dateOnly keeps the calendar date of t at local midnight
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
 }
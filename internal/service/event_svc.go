@@ -8,7 +8,9 @@ import (
 type EventType string
 
 const (
-	EventStockUpdate EventType = "stock_update"
+	EventStockUpdate    EventType = "stock_update"
+	EventLowStock       EventType = "low_stock"
+	EventNewTransaction EventType = "new_transaction"
 )
 
 type Event struct {
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+const (
+	// forecastHistoryDays is how many closed days of sales a forecast looks at; eight
+	// weeks show every weekday eight times
+	forecastHistoryDays = 56
+	// movingAverageDays is the window of the moving average and of the demand variability
+	movingAverageDays = 28
+	// smoothingAlpha is the weight exponential smoothing gives the latest day
+	smoothingAlpha = 0.3
+	// safetyStockZ sizes the safety stock to cover demand in about 95% of lead times
+	safetyStockZ = 1.65
+)
+
+// ForecastService forecasts product demand from daily sales history for restock planning
+type ForecastService struct {
+	transactionRepo *repository.TransactionRepository
+	settingsSvc     *SettingsService
+}
+
+// NewForecastService creates a new ForecastService
+func NewForecastService(transactionRepo *repository.TransactionRepository, settingsSvc *SettingsService) *ForecastService {
+	return &ForecastService{
+		transactionRepo: transactionRepo,
+		settingsSvc:     settingsSvc,
+	}
+}
+
+// Options returns the forecast options from the store settings
+func (s *ForecastService) Options(ctx context.Context) (domain.ForecastOptions, error) {
+	settings, err := s.settingsSvc.Store(ctx)
+	if err != nil {
+		return domain.ForecastOptions{}, err
+	}
+	return domain.ForecastOptions{
+		Method:       settings.ForecastMethod,
+		LeadTimeDays: settings.ForecastLeadTimeDays,
+		CoverDays:    settings.ForecastCoverDays,
+	}, nil
+}
+
+// Forecast returns the demand forecast of every active stock-tracked product that sold in the
+// last forecastHistoryDays closed days, keyed by product. Products without sales in that
+// window have no history to forecast from and are left out.
+func (s *ForecastService) Forecast(ctx context.Context, opts domain.ForecastOptions) (map[uuid.UUID]domain.DemandForecast, error) {
+	if !opts.Method.IsValid() {
+		return nil, fmt.Errorf("%w: forecast method must be moving_average or exponential_smoothing", domain.ErrInvalidInput)
+	}
+	if opts.LeadTimeDays < 0 || opts.CoverDays < 1 {
+		return nil, fmt.Errorf("%w: lead time must not be negative and cover days must be at least 1", domain.ErrInvalidInput)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	first := today.AddDate(0, 0, -forecastHistoryDays)
+
+	sales, err := s.transactionRepo.GetDailyProductSales(ctx, first.Format("2006-01-02"), today.AddDate(0, 0, -1).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[uuid.UUID][]float64)
+	for _, sale := range sales {
+		days, ok := history[sale.ProductID]
+		if !ok {
+			days = make([]float64, forecastHistoryDays)
+			history[sale.ProductID] = days
+		}
+		if i := int(sale.Date.Sub(first).Hours() / 24); i >= 0 && i < len(days) {
+			days[i] += float64(sale.Quantity)
+		}
+	}
+
+	forecasts := make(map[uuid.UUID]domain.DemandForecast, len(history))
+	for productID, days := range history {
+		forecast := forecastDemand(days, first, today, opts)
+		forecast.ProductID = productID
+		forecasts[productID] = forecast
+	}
+	return forecasts, nil
+}
+
+// forecastDemand forecasts daily demand from the units sold on each day from first on, and sums
+// it over the lead time and cover days starting at start. The safety stock covers the day-to-day
+// variation of recent sales over the lead time.
+func forecastDemand(history []float64, first, start time.Time, opts domain.ForecastOptions) domain.DemandForecast {
+	recent := history[max(len(history)-movingAverageDays, 0):]
+
+	var daily func(day time.Time) float64
+	switch opts.Method {
+	case domain.ForecastExponentialSmoothing:
+		level := smoothedLevel(history, smoothingAlpha)
+		daily = func(time.Time) float64 { return level }
+	default:
+		level := mean(recent)
+		factors := weekdayFactors(history, first)
+		daily = func(day time.Time) float64 { return level * factors[day.Weekday()] }
+	}
+
+	var leadTimeDemand, coverDemand float64
+	for i := 0; i < opts.LeadTimeDays+opts.CoverDays; i++ {
+		if demand := daily(start.AddDate(0, 0, i)); i < opts.LeadTimeDays {
+			leadTimeDemand += demand
+		} else {
+			coverDemand += demand
+		}
+	}
+
+	safetyStock := int(math.Ceil(safetyStockZ * stdDev(recent) * math.Sqrt(float64(max(opts.LeadTimeDays, 1)))))
+	return domain.DemandForecast{
+		Method:         opts.Method,
+		HistoryDays:    len(history),
+		DailyDemand:    (leadTimeDemand + coverDemand) / float64(opts.LeadTimeDays+opts.CoverDays),
+		LeadTimeDays:   opts.LeadTimeDays,
+		LeadTimeDemand: leadTimeDemand,
+		SafetyStock:    safetyStock,
+		ReorderPoint:   int(math.Ceil(leadTimeDemand)) + safetyStock,
+		TargetStock:    int(math.Ceil(leadTimeDemand+coverDemand)) + safetyStock,
+	}
+}
+
+// weekdayFactors returns how each weekday's average sales compare with the overall average.
+// A weekday that never sold gets 0; without any sales every factor is 1.
+func weekdayFactors(history []float64, first time.Time) [7]float64 {
+	var sums, counts [7]float64
+	for i, units := range history {
+		weekday := first.AddDate(0, 0, i).Weekday()
+		sums[weekday] += units
+		counts[weekday]++
+	}
+
+	factors := [7]float64{1, 1, 1, 1, 1, 1, 1}
+	overall := mean(history)
+	if overall <= 0 {
+		return factors
+	}
+	for weekday := range factors {
+		if counts[weekday] > 0 {
+			factors[weekday] = sums[weekday] / counts[weekday] / overall
+		}
+	}
+	return factors
+}
+
+// smoothedLevel runs simple exponential smoothing over the history and returns the final level
+func smoothedLevel(history []float64, alpha float64) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+	level := history[0]
+	for _, units := range history[1:] {
+		level = alpha*units + (1-alpha)*level
+	}
+	return level
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
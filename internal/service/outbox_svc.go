@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/platform/queue"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+const (
+	outboxBatchSize   = 50
+	outboxMaxAttempts = 10
+
+	// Published events are kept this long for inspection, then purged
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// outboxEvents maps queue task types to the SSE events published alongside them
+var outboxEvents = map[string]EventType{
+	queue.TypeLowStockAlert:  EventLowStock,
+	queue.TypeNewTransaction: EventNewTransaction,
+}
+
+// OutboxService records side effects inside business transactions and relays them after commit
+type OutboxService struct {
+	db          *database.PostgresDB
+	outboxRepo  *repository.OutboxRepository
+	queueClient *queue.Client
+	eventSvc    *EventService
+}
+
+// NewOutboxService creates a new OutboxService
+func NewOutboxService(db *database.PostgresDB, outboxRepo *repository.OutboxRepository, queueClient *queue.Client, eventSvc *EventService) *OutboxService {
+	return &OutboxService{
+		db:          db,
+		outboxRepo:  outboxRepo,
+		queueClient: queueClient,
+		eventSvc:    eventSvc,
+	}
+}
+
+// Add records an event in the caller's transaction; nothing is published if it rolls back
+func (s *OutboxService) Add(ctx context.Context, tx *sql.Tx, eventType, idempotencyKey string, payload interface{}) error {
+	return s.outboxRepo.Add(ctx, tx, eventType, idempotencyKey, payload)
+}
+
+// Kick asks the worker to relay right away instead of waiting for the schedule.
+// Failure is harmless: the scheduled relay picks the events up.
+func (s *OutboxService) Kick() {
+	if s.queueClient == nil {
+		return
+	}
+	if err := s.queueClient.EnqueueOutboxRelay(); err != nil {
+		log.Printf("Failed to kick outbox relay: %v", err)
+	}
+}
+
+// Relay publishes one batch of due events and returns how many were delivered
+func (s *OutboxService) Relay(ctx context.Context) (int, error) {
+	published := 0
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		events, err := s.outboxRepo.ClaimPending(ctx, tx, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := s.publish(event); err != nil {
+				attempts := event.Attempts + 1
+				var retryAt *time.Time
+				if attempts < outboxMaxAttempts {
+					next := time.Now().Add(outboxBackoff(attempts))
+					retryAt = &next
+				}
+				log.Printf("Outbox event %s (%s) attempt %d failed: %v", event.ID, event.EventType, attempts, err)
+				if err := s.outboxRepo.MarkAttemptFailed(ctx, tx, event.ID, err.Error(), retryAt); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.outboxRepo.MarkPublished(ctx, tx, event.ID); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	return published, err
+}
+
+// HandleRelayTask drains the outbox until no due events remain
+func (s *OutboxService) HandleRelayTask(ctx context.Context, t *asynq.Task) error {
+	for {
+		n, err := s.Relay(ctx)
+		if err != nil {
+			return err
+		}
+		if n < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// HandlePurgeTask deletes events published longer ago than the retention period
+func (s *OutboxService) HandlePurgeTask(ctx context.Context, t *asynq.Task) error {
+	n, err := s.outboxRepo.DeletePublished(ctx, time.Now().Add(-outboxRetention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("Purged %d published outbox events", n)
+	}
+	return nil
+}
+
+// publish enqueues the task under its idempotency key, then notifies SSE clients
+func (s *OutboxService) publish(event domain.OutboxEvent) error {
+	if err := s.queueClient.EnqueueIdempotent(event.EventType, event.Payload, event.IdempotencyKey); err != nil {
+		return err
+	}
+	if eventType, ok := outboxEvents[event.EventType]; ok && s.eventSvc != nil {
+		s.eventSvc.Publish(eventType, json.RawMessage(event.Payload))
+	}
+	return nil
+}
+
+// outboxBackoff grows the retry delay exponentially up to one hour
+func outboxBackoff(attempts int) time.Duration {
+	delay := time.Duration(1<<min(attempts, 12)) * time.Second
+	return min(delay, time.Hour)
+}
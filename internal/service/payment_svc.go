@@ -218,7 +218,7 @@ func (s *PaymentService) HandleNotification(ctx context.Context, notification do
 
 	// Update transaction status if payment is successful
 	if status.IsSuccess() {
-		if err := s.transactionRepo.UpdateStatus(ctx, paymentRecord.TransactionID, domain.TransactionStatusCompleted); err != nil {
+		if err := s.transactionRepo.UpdateStatus(ctx, nil, paymentRecord.TransactionID, domain.TransactionStatusCompleted); err != nil {
 			return fmt.Errorf("failed to update transaction status: %w", err)
 		}
 	}
@@ -261,7 +261,7 @@ func (s *PaymentService) ManualVerify(ctx context.Context, paymentID uuid.UUID,
 	}
 
 	// Update transaction status
-	if err := s.transactionRepo.UpdateStatus(ctx, paymentRecord.TransactionID, domain.TransactionStatusCompleted); err != nil {
+	if err := s.transactionRepo.UpdateStatus(ctx, nil, paymentRecord.TransactionID, domain.TransactionStatusCompleted); err != nil {
 		return fmt.Errorf("failed to update transaction status: %w", err)
 	}
 
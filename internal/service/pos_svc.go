@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -12,12 +13,18 @@ import (
 	"github.com/eveeze/warung-backend/internal/repository"
 )
 
+// refundExpenseCategory is the cash flow category used for cash refund payouts
+const refundExpenseCategory = "Refund Penjualan"
+
 type POSService struct {
 	db              *database.PostgresDB
 	posRepo         *repository.POSRepository
 	productRepo     *repository.ProductRepository
 	transactionRepo *repository.TransactionRepository
 	inventoryRepo   *repository.InventoryRepository
+	kasbonRepo      *repository.KasbonRepository
+	cashFlowRepo    *repository.CashFlowRepository
+	outboxSvc       *OutboxService
 }
 
 func NewPOSService(
@@ -26,6 +33,9 @@ func NewPOSService(
 	productRepo *repository.ProductRepository,
 	transactionRepo *repository.TransactionRepository,
 	inventoryRepo *repository.InventoryRepository,
+	kasbonRepo *repository.KasbonRepository,
+	cashFlowRepo *repository.CashFlowRepository,
+	outboxSvc *OutboxService,
 ) *POSService {
 	return &POSService{
 		db:              db,
@@ -33,6 +43,9 @@ func NewPOSService(
 		productRepo:     productRepo,
 		transactionRepo: transactionRepo,
 		inventoryRepo:   inventoryRepo,
+		kasbonRepo:      kasbonRepo,
+		cashFlowRepo:    cashFlowRepo,
+		outboxSvc:       outboxSvc,
 	}
 }
 
@@ -120,80 +133,295 @@ func (s *POSService) DiscardCart(ctx context.Context, id uuid.UUID) error {
 
 // -- Refunds --
 
+// CreateRefund records a pending refund request. Each item is capped at the quantity
+// sold minus what other non-rejected refunds already claim, and its amount is the
+// matching share of what the customer paid for the line, after the order discount and with tax.
 func (s *POSService) CreateRefund(ctx context.Context, input domain.CreateRefundInput) (*domain.RefundRecord, error) {
+	if len(input.Items) == 0 {
+		return nil, fmt.Errorf("refund must have at least one item")
+	}
+
 	transaction, err := s.transactionRepo.GetByID(ctx, input.TransactionID)
 	if err != nil {
-		return nil, fmt.Errorf("transaction not found: %w", err)
+		return nil, err
 	}
 
 	refund := &domain.RefundRecord{
+		RefundNumber:  fmt.Sprintf("REF-%d-%s", time.Now().UnixNano(), uuid.New().String()[:8]),
 		TransactionID: input.TransactionID,
 		CustomerID:    transaction.CustomerID,
 		RefundMethod:  input.RefundMethod,
-		Status:        domain.RefundStatusPending, // Auto approve for now? Or keep pending. Let's say pending.
+		Status:        domain.RefundStatusPending,
 		Reason:        input.Reason,
 		Notes:         input.Notes,
 		RequestedBy:   &input.RequestedBy,
 	}
 
-	// Generate refund number
-	refundNumber := fmt.Sprintf("REF-%d-%s", time.Now().UnixNano(), uuid.New().String()[:8])
-	refund.RefundNumber = refundNumber
+	err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// Lock the sale so concurrent requests see each other's quantities
+		locked, err := s.transactionRepo.GetForUpdate(ctx, tx, input.TransactionID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != domain.TransactionStatusCompleted {
+			return fmt.Errorf("only completed transactions can be refunded")
+		}
 
-	// Validate items and calculate total
-	var totalRefund int64
-	for _, itemInput := range input.Items {
-		var txItem *domain.TransactionItem
-		for _, ti := range transaction.Items {
-			if ti.ID == itemInput.TransactionItemID {
-				txItem = &ti
-				break
+		refunded, err := s.posRepo.GetRefundedQuantities(ctx, tx, input.TransactionID, false)
+		if err != nil {
+			return err
+		}
+		paid := paidPerLine(transaction)
+
+		requested := make(map[uuid.UUID]int)
+		for _, itemInput := range input.Items {
+			if itemInput.Quantity <= 0 {
+				return fmt.Errorf("refund quantity must be positive")
 			}
+
+			var txItem *domain.TransactionItem
+			for i := range transaction.Items {
+				if transaction.Items[i].ID == itemInput.TransactionItemID {
+					txItem = &transaction.Items[i]
+					break
+				}
+			}
+			if txItem == nil {
+				return fmt.Errorf("transaction item not found: %s", itemInput.TransactionItemID)
+			}
+
+			already := refunded[txItem.ID] + requested[txItem.ID]
+			if already+itemInput.Quantity > txItem.Quantity {
+				return fmt.Errorf("refund quantity for %s exceeds remaining quantity %d", txItem.ProductName, txItem.Quantity-already)
+			}
+			requested[txItem.ID] += itemInput.Quantity
+
+			// Prorate on cumulative quantities so rounding never exceeds the line total
+			refundAmount := prorate(paid[txItem.ID], already+itemInput.Quantity, txItem.Quantity) -
+				prorate(paid[txItem.ID], already, txItem.Quantity)
+			refund.TotalRefundAmount += refundAmount
+
+			refund.Items = append(refund.Items, domain.RefundItem{
+				TransactionItemID: txItem.ID,
+				ProductID:         txItem.ProductID,
+				ProductName:       txItem.ProductName,
+				Quantity:          itemInput.Quantity,
+				UnitPrice:         txItem.BaseUnitPrice(), // refunds are counted in base units
+				RefundAmount:      refundAmount,
+				Reason:            itemInput.Reason,
+				Restock:           itemInput.Restock,
+			})
 		}
-		if txItem == nil {
-			return nil, fmt.Errorf("transaction item not found: %s", itemInput.TransactionItemID)
+
+		return s.posRepo.CreateRefund(ctx, tx, refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// GetRefund retrieves a refund with items
+func (s *POSService) GetRefund(ctx context.Context, id uuid.UUID) (*domain.RefundRecord, error) {
+	return s.posRepo.GetRefund(ctx, id)
+}
+
+// ApproveRefund approves a pending refund so it can be completed
+func (s *POSService) ApproveRefund(ctx context.Context, id uuid.UUID, approvedBy string) (*domain.RefundRecord, error) {
+	return s.decideRefund(ctx, id, domain.RefundStatusApproved, approvedBy)
+}
+
+// RejectRefund rejects a pending refund, releasing its quantities for other requests
+func (s *POSService) RejectRefund(ctx context.Context, id uuid.UUID, rejectedBy string) (*domain.RefundRecord, error) {
+	return s.decideRefund(ctx, id, domain.RefundStatusRejected, rejectedBy)
+}
+
+func (s *POSService) decideRefund(ctx context.Context, id uuid.UUID, status domain.RefundStatus, by string) (*domain.RefundRecord, error) {
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		refund, err := s.posRepo.GetRefundForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
 		}
-		if itemInput.Quantity > txItem.Quantity {
-			return nil, fmt.Errorf("refund quantity exceeds sold quantity")
+		if !refund.CanTransitionTo(status) {
+			return domain.ErrInvalidStatusTransition
 		}
+		return s.posRepo.UpdateRefundStatus(ctx, tx, id, status, &by)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.posRepo.GetRefund(ctx, id)
+}
 
-		refundAmount := txItem.UnitPrice * int64(itemInput.Quantity) // Approximate prorated logic
-		totalRefund += refundAmount
-
-		refund.Items = append(refund.Items, domain.RefundItem{
-			TransactionItemID: txItem.ID,
-			ProductID:         txItem.ProductID,
-			ProductName:       txItem.ProductName,
-			Quantity:          itemInput.Quantity,
-			UnitPrice:         txItem.UnitPrice,
-			RefundAmount:      refundAmount,
-			Reason:            itemInput.Reason,
-			Restock:           itemInput.Restock,
-		})
+// CompleteRefund settles an approved refund in one database transaction:
+// restock items flagged for it, reduce the customer's debt when the sale was kasbon,
+// pay the rest out of the drawer for cash refunds, and mark the sale refunded once
+// every item has been fully returned. A refund of a sale from a closed day queues
+// that day's summary to be rolled up again.
+func (s *POSService) CompleteRefund(ctx context.Context, id uuid.UUID, completedBy string) (*domain.RefundRecord, error) {
+	sessionID, categoryID, err := s.refundExpenseTarget(ctx)
+	if err != nil {
+		return nil, err
 	}
-	refund.TotalRefundAmount = totalRefund
 
-	// Ensure refund total doesn't exceed transaction total?
-	// Simplified logic for now.
+	err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		refund, err := s.posRepo.GetRefundForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !refund.CanTransitionTo(domain.RefundStatusCompleted) {
+			return domain.ErrInvalidStatusTransition
+		}
+
+		transaction, err := s.transactionRepo.GetForUpdate(ctx, tx, refund.TransactionID)
+		if err != nil {
+			return err
+		}
+		if transaction.Status != domain.TransactionStatusCompleted {
+			return fmt.Errorf("transaction %s is %s and cannot be refunded", transaction.InvoiceNumber, transaction.Status)
+		}
+
+		notes := fmt.Sprintf("Refund %s for %s", refund.RefundNumber, transaction.InvoiceNumber)
 
-	if err := s.posRepo.CreateRefund(ctx, refund); err != nil {
+		// Items are loaded ordered by product ID, which keeps product locks in a stable order
+		for _, item := range refund.Items {
+			if !item.Restock {
+				continue
+			}
+			if _, err := s.inventoryRepo.ReturnStock(ctx, tx, item.ProductID, item.Quantity, transaction.ID,
+				"refund", &refund.ID, &notes, &completedBy); err != nil {
+				return fmt.Errorf("failed to restock %s: %w", item.ProductName, err)
+			}
+		}
+
+		transaction.Payments, err = s.transactionRepo.GetPayments(ctx, transaction.ID)
+		if err != nil {
+			return err
+		}
+
+		// The part of the sale that went to kasbon is settled against the debt first,
+		// less what earlier refunds of the same sale already credited back
+		payout := refund.TotalRefundAmount
+		if kasbonAmount := transaction.KasbonAmount(); kasbonAmount > 0 && transaction.CustomerID != nil {
+			credited, err := s.kasbonRepo.GetRefundedAmount(ctx, tx, transaction.ID)
+			if err != nil {
+				return err
+			}
+			if remaining := kasbonAmount - credited; remaining > 0 {
+				record, err := s.kasbonRepo.CreateRefund(ctx, tx, *transaction.CustomerID, &transaction.ID, min(payout, remaining), &notes, &completedBy)
+				if err != nil {
+					return err
+				}
+				if record != nil {
+					payout -= record.Amount
+				}
+			}
+		}
+
+		if refund.RefundMethod == domain.RefundMethodCash && payout > 0 {
+			refType := "refund"
+			input := domain.CashFlowInput{
+				CategoryID:  categoryID,
+				Type:        domain.CashFlowTypeExpense,
+				Amount:      payout,
+				Description: &notes,
+				CreatedBy:   completedBy,
+			}
+			if _, err := s.cashFlowRepo.RecordCashFlow(ctx, tx, input, sessionID, &refType, &refund.ID); err != nil {
+				return fmt.Errorf("failed to record refund expense: %w", err)
+			}
+		}
+
+		if err := s.posRepo.UpdateRefundStatus(ctx, tx, id, domain.RefundStatusCompleted, nil); err != nil {
+			return err
+		}
+
+		// Refunds count against the day of the sale, which may already be rolled up
+		if err := queueRollup(ctx, tx, s.outboxSvc, transaction.CreatedAt, "daily_rollup:refund:"+refund.ID.String()); err != nil {
+			return err
+		}
+
+		return s.markRefundedIfFullyReturned(ctx, tx, transaction.ID)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return refund, nil
+	return s.posRepo.GetRefund(ctx, id)
 }
 
-// In a real system, you'd have an ApproveRefund method that restores stock and updates financials.
-// For now, let's keep it simple: CreateRefund is the request. Maybe "approve" automatically if admin?
-// User asked for "Implement features", so let's stick to Creating Requests for now to save time if fine. 
-// But "Refund/Return" implies stock logic.
-// Let's create an "ApproveRefund" method.
+// markRefundedIfFullyReturned flips a sale to refunded once completed refunds cover every item
+func (s *POSService) markRefundedIfFullyReturned(ctx context.Context, tx *sql.Tx, transactionID uuid.UUID) error {
+	items, err := s.transactionRepo.GetItems(ctx, transactionID)
+	if err != nil {
+		return err
+	}
 
-/*
-func (s *POSService) ApproveRefund(ctx context.Context, refundID uuid.UUID, approvedBy string) error {
-	// 1. Get Refund
-	// 2. Update Status to Approved/Completed
-	// 3. If Restock == true, update inventory
-	// 4. Update financials if needed
+	refunded, err := s.posRepo.GetRefundedQuantities(ctx, tx, transactionID, true)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if refunded[item.ID] < item.Quantity {
+			return nil
+		}
+	}
+
+	return s.transactionRepo.UpdateStatus(ctx, tx, transactionID, domain.TransactionStatusRefunded)
+}
+
+// refundExpenseTarget resolves the open drawer session and category for refund payouts
+func (s *POSService) refundExpenseTarget(ctx context.Context) (*uuid.UUID, *uuid.UUID, error) {
+	var sessionID, categoryID *uuid.UUID
+
+	session, err := s.cashFlowRepo.GetCurrentSession(ctx)
+	if err != nil && err != domain.ErrNotFound {
+		return nil, nil, err
+	}
+	if session != nil {
+		sessionID = &session.ID
+	}
+
+	category, err := s.cashFlowRepo.GetCategoryByName(ctx, refundExpenseCategory)
+	if err != nil && err != domain.ErrNotFound {
+		return nil, nil, err
+	}
+	if category != nil {
+		categoryID = &category.ID
+	}
+
+	return sessionID, categoryID, nil
+}
+
+// paidPerLine spreads what the customer paid for a sale, after the order discount and with tax,
+// over its lines in proportion to the line totals. Rounding is carried forward, so the lines add
+// up to the sale total.
+func paidPerLine(transaction *domain.Transaction) map[uuid.UUID]int64 {
+	var whole int64
+	for _, item := range transaction.Items {
+		whole += item.TotalAmount
+	}
+
+	paid := make(map[uuid.UUID]int64, len(transaction.Items))
+	var cumulative, allocated int64
+	for _, item := range transaction.Items {
+		if whole <= 0 {
+			break
+		}
+		cumulative += item.TotalAmount
+		share := transaction.TotalAmount*cumulative/whole - allocated
+		paid[item.ID] = share
+		allocated += share
+	}
+	return paid
+}
+
+// prorate returns the share of total for part out of whole units
+func prorate(total int64, part, whole int) int64 {
+	if whole <= 0 {
+		return 0
+	}
+	return total * int64(part) / int64(whole)
 }
-*/
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// ProductImportService imports and exports the product catalogue as spreadsheet rows
+type ProductImportService struct {
+	db           *database.PostgresDB
+	productRepo  *repository.ProductRepository
+	categoryRepo *repository.CategoryRepository
+}
+
+// NewProductImportService creates a new ProductImportService
+func NewProductImportService(db *database.PostgresDB, productRepo *repository.ProductRepository, categoryRepo *repository.CategoryRepository) *ProductImportService {
+	return &ProductImportService{
+		db:           db,
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// importRow is a valid row of an import file
+type importRow struct {
+	line      int
+	productID *uuid.UUID // existing product with the row's barcode or SKU
+	create    domain.ProductCreateInput
+	update    domain.ProductUpdateInput
+	tiers     []domain.PricingTierInput
+	hasTiers  bool // the file has a pricing_tiers column
+}
+
+// Export returns the products in the import template, header first
+func (s *ProductImportService) Export(ctx context.Context) ([][]any, error) {
+	products, err := s.productRepo.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categoryNames, err := s.categoryNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]any, 0, len(products)+1)
+	header := make([]any, len(domain.ProductImportColumns))
+	for i, col := range domain.ProductImportColumns {
+		header[i] = col
+	}
+	rows = append(rows, header)
+
+	for _, p := range products {
+		var category string
+		if p.CategoryID != nil {
+			category = categoryNames[*p.CategoryID]
+		}
+		var maxStock any
+		if p.MaxStock != nil {
+			maxStock = *p.MaxStock
+		}
+		rows = append(rows, []any{
+			safeCell(deref(p.Barcode)), safeCell(deref(p.SKU)), safeCell(p.Name), safeCell(category), safeCell(p.Unit),
+			p.BasePrice, p.CostPrice, p.IsStockActive, p.CurrentStock, p.MinStockAlert, maxStock, p.IsActive,
+			safeCell(domain.FormatPricingTiers(p.PricingTiers)),
+		})
+	}
+	return rows, nil
+}
+
+// Import validates the rows of an import file, header first, and creates or updates one product
+// per row. Rows whose barcode, or failing that SKU, belongs to a product update it; other rows
+// create a product.
+// Nothing is written in a dry run or when any row has an error. Rows that fail while being
+// written are reported and the others are still imported.
+func (s *ProductImportService) Import(ctx context.Context, rows [][]string, dryRun bool) (*domain.ProductImportResult, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", domain.ErrInvalidInput)
+	}
+
+	result := &domain.ProductImportResult{DryRun: dryRun, Errors: []domain.ProductImportRowError{}}
+
+	columns := make(map[string]int)
+	for i, h := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"name", "unit", "base_price"} {
+		if _, ok := columns[required]; !ok {
+			result.Errors = append(result.Errors, domain.ProductImportRowError{
+				Row: 1, Column: required, Message: "column is missing",
+			})
+		}
+	}
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	index, err := s.productRepo.GetCodeIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs, err := s.categoryIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seenBarcodes := make(map[string]int)
+	seenSKUs := make(map[string]int)
+	var valid []importRow
+
+	for i, cells := range rows[1:] {
+		line := i + 2
+		cell := func(col string) string {
+			idx, ok := columns[col]
+			if !ok || idx >= len(cells) {
+				return ""
+			}
+			return unescapeCell(strings.TrimSpace(cells[idx]))
+		}
+		if isBlankRow(cells) {
+			continue
+		}
+		result.Rows++
+
+		errorsBefore := len(result.Errors)
+		fail := func(col, format string, args ...any) {
+			result.Errors = append(result.Errors, domain.ProductImportRowError{
+				Row: line, Column: col, Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		row := importRow{line: line}
+
+		barcode := cell("barcode")
+		if barcode != "" {
+			if first, ok := seenBarcodes[barcode]; ok {
+				fail("barcode", "barcode %s is also on row %d", barcode, first)
+			}
+			seenBarcodes[barcode] = line
+			if id, ok := index.ByBarcode[barcode]; ok {
+				row.productID = &id
+			} else if index.UnitBarcodes[barcode] {
+				fail("barcode", "barcode %s belongs to a product unit", barcode)
+			}
+		}
+
+		// Products without a barcode are found by their SKU, so an export imports back cleanly
+		sku := cell("sku")
+		if sku != "" {
+			if first, ok := seenSKUs[sku]; ok {
+				fail("sku", "SKU %s is also on row %d", sku, first)
+			}
+			seenSKUs[sku] = line
+			owner, ok := index.BySKU[sku]
+			switch {
+			case ok && row.productID == nil:
+				row.productID = &owner
+			case ok && owner != *row.productID:
+				fail("sku", "SKU %s is used by another product", sku)
+			}
+		}
+		isNew := row.productID == nil
+
+		name := cell("name")
+		switch {
+		case name == "" && isNew:
+			fail("name", "name is required")
+		case name != "" && len(name) < 2:
+			fail("name", "name must be at least 2 characters")
+		}
+		unit := cell("unit")
+		if unit == "" && isNew {
+			fail("unit", "unit is required")
+		}
+
+		var categoryID *uuid.UUID
+		if categoryName := cell("category"); categoryName != "" {
+			if id, ok := categoryIDs[strings.ToLower(categoryName)]; ok {
+				categoryID = &id
+			} else {
+				fail("category", "unknown category %q", categoryName)
+			}
+		}
+		if !isNew && index.Variants[*row.productID] {
+			categoryID = nil // set on the parent
+		}
+
+		basePrice := parseAmountCell(cell("base_price"), "base_price", fail)
+		switch {
+		case basePrice == nil && isNew && cell("base_price") == "":
+			fail("base_price", "base price is required")
+		case basePrice != nil && *basePrice <= 0:
+			fail("base_price", "base price must be positive")
+		}
+		costPrice := parseAmountCell(cell("cost_price"), "cost_price", fail)
+		if costPrice != nil && *costPrice < 0 {
+			fail("cost_price", "cost price cannot be negative")
+		}
+		currentStock := parseCountCell(cell("current_stock"), "current_stock", fail)
+		minStockAlert := parseCountCell(cell("min_stock_alert"), "min_stock_alert", fail)
+		maxStock := parseCountCell(cell("max_stock"), "max_stock", fail)
+		isStockActive := parseBoolCell(cell("is_stock_active"), "is_stock_active", fail)
+		isActive := parseBoolCell(cell("is_active"), "is_active", fail)
+
+		if _, ok := columns["pricing_tiers"]; ok {
+			row.hasTiers = true
+			tiers, err := domain.ParsePricingTiers(cell("pricing_tiers"))
+			if err != nil {
+				fail("pricing_tiers", "%v", err)
+			}
+			row.tiers = tiers
+		}
+
+		if len(result.Errors) > errorsBefore {
+			continue
+		}
+
+		if isNew {
+			result.Created++
+			row.create = domain.ProductCreateInput{
+				Barcode:       optional(barcode),
+				SKU:           optional(sku),
+				Name:          name,
+				CategoryID:    categoryID,
+				Unit:          unit,
+				BasePrice:     *basePrice,
+				IsStockActive: isStockActive,
+				CurrentStock:  currentStock,
+				MinStockAlert: minStockAlert,
+				MaxStock:      maxStock,
+				IsActive:      isActive,
+				PricingTiers:  row.tiers,
+			}
+			if costPrice != nil {
+				row.create.CostPrice = *costPrice
+			}
+		} else {
+			// Stock of existing products changes through restocks and adjustments only
+			result.Updated++
+			row.update = domain.ProductUpdateInput{
+				Barcode:       optional(barcode),
+				SKU:           optional(sku),
+				Name:          optional(name),
+				CategoryID:    categoryID,
+				Unit:          optional(unit),
+				BasePrice:     basePrice,
+				CostPrice:     costPrice,
+				IsStockActive: isStockActive,
+				MinStockAlert: minStockAlert,
+				MaxStock:      maxStock,
+				IsActive:      isActive,
+			}
+		}
+		valid = append(valid, row)
+	}
+
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	result.Applied = true
+	for _, row := range valid {
+		if err := s.apply(ctx, row); err != nil {
+			result.Errors = append(result.Errors, domain.ProductImportRowError{Row: row.line, Message: err.Error()})
+			if row.productID == nil {
+				result.Created--
+			} else {
+				result.Updated--
+			}
+		}
+	}
+	return result, nil
+}
+
+// apply writes one valid import row. An update and its pricing tiers are written together,
+// so a failed row leaves the product as it was.
+func (s *ProductImportService) apply(ctx context.Context, row importRow) error {
+	if row.productID == nil {
+		_, err := s.productRepo.Create(ctx, row.create)
+		return err
+	}
+	return s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := s.productRepo.Update(ctx, tx, *row.productID, row.update); err != nil {
+			return err
+		}
+		if row.hasTiers {
+			return s.productRepo.ReplacePricingTiers(ctx, tx, *row.productID, row.tiers)
+		}
+		return nil
+	})
+}
+
+// categoryIDs maps the lowercase names of active categories to their IDs
+func (s *ProductImportService) categoryIDs(ctx context.Context) (map[string]uuid.UUID, error) {
+	categories, err := s.categoryRepo.FindAll(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]uuid.UUID, len(categories))
+	for _, c := range categories {
+		ids[strings.ToLower(strings.TrimSpace(c.Name))] = c.ID
+	}
+	return ids, nil
+}
+
+// categoryNames maps the IDs of active categories to their names
+func (s *ProductImportService) categoryNames(ctx context.Context) (map[uuid.UUID]string, error) {
+	categories, err := s.categoryRepo.FindAll(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+	return names, nil
+}
+
+type cellError func(col, format string, args ...any)
+
+// amountDecimals matches a decimal part, which rupiah amounts only have as zeros ("3500.00", "3.500,00")
+var amountDecimals = regexp.MustCompile(`[.,]\d{1,2}$`)
+
+// parseAmountCell reads a rupiah amount such as "3500", "3.500", "3,500" or "Rp 3.500,00".
+// It returns nil for an empty cell.
+func parseAmountCell(value, col string, fail cellError) *int64 {
+	if value == "" {
+		return nil
+	}
+	s := strings.TrimSpace(strings.TrimPrefix(strings.ReplaceAll(value, " ", ""), "Rp"))
+	if decimals := amountDecimals.FindString(s); decimals != "" {
+		if strings.Trim(decimals[1:], "0") != "" {
+			fail(col, "%q is not a whole rupiah amount", value)
+			return nil
+		}
+		s = strings.TrimSuffix(s, decimals)
+	}
+	s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		fail(col, "%q is not a number", value)
+		return nil
+	}
+	return &n
+}
+
+// parseCountCell reads a non-negative whole number, or nil for an empty cell
+func parseCountCell(value, col string, fail cellError) *int {
+	if value == "" {
+		return nil
+	}
+	amount := parseAmountCell(value, col, fail)
+	if amount == nil {
+		return nil
+	}
+	if *amount < 0 {
+		fail(col, "cannot be negative")
+		return nil
+	}
+	n := int(*amount)
+	return &n
+}
+
+// parseBoolCell reads yes/no values in English or Indonesian, or nil for an empty cell
+func parseBoolCell(value, col string, fail cellError) *bool {
+	var b bool
+	switch strings.ToLower(value) {
+	case "":
+		return nil
+	case "true", "yes", "y", "ya", "1":
+		b = true
+	case "false", "no", "n", "tidak", "0":
+		b = false
+	default:
+		fail(col, "%q is not true or false", value)
+		return nil
+	}
+	return &b
+}
+
+// formulaPrefixes are the first characters that make a spreadsheet program read a cell as a formula
+const formulaPrefixes = "=+-@\t\r"
+
+// safeCell keeps spreadsheet programs from running a text cell as a formula by prefixing cells
+// that start with a formula character with an apostrophe
+func safeCell(s string) string {
+	if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// unescapeCell undoes safeCell on an imported cell
+func unescapeCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+func isBlankRow(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
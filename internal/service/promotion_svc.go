@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// PromotionService handles promotion definitions and evaluation
+type PromotionService struct {
+	promotionRepo *repository.PromotionRepository
+	productRepo   *repository.ProductRepository
+	categoryRepo  *repository.CategoryRepository
+}
+
+// NewPromotionService creates a new PromotionService
+func NewPromotionService(promotionRepo *repository.PromotionRepository, productRepo *repository.ProductRepository, categoryRepo *repository.CategoryRepository) *PromotionService {
+	return &PromotionService{
+		promotionRepo: promotionRepo,
+		productRepo:   productRepo,
+		categoryRepo:  categoryRepo,
+	}
+}
+
+// CreatePromotion validates and creates a promotion
+func (s *PromotionService) CreatePromotion(ctx context.Context, input domain.PromotionInput) (*domain.Promotion, error) {
+	if err := s.validate(ctx, input); err != nil {
+		return nil, err
+	}
+	return s.promotionRepo.Create(ctx, input)
+}
+
+// GetPromotion retrieves a promotion by ID
+func (s *PromotionService) GetPromotion(ctx context.Context, id uuid.UUID) (*domain.Promotion, error) {
+	return s.promotionRepo.GetByID(ctx, id)
+}
+
+// ListPromotions lists promotions
+func (s *PromotionService) ListPromotions(ctx context.Context, filter domain.PromotionFilter) ([]domain.Promotion, int64, error) {
+	return s.promotionRepo.List(ctx, filter)
+}
+
+// UpdatePromotion validates and replaces a promotion's definition
+func (s *PromotionService) UpdatePromotion(ctx context.Context, id uuid.UUID, input domain.PromotionInput) (*domain.Promotion, error) {
+	if err := s.validate(ctx, input); err != nil {
+		return nil, err
+	}
+	return s.promotionRepo.Update(ctx, id, input)
+}
+
+// DeletePromotion deactivates a promotion
+func (s *PromotionService) DeletePromotion(ctx context.Context, id uuid.UUID) error {
+	return s.promotionRepo.Delete(ctx, id)
+}
+
+// GetUsage summarizes the promo cost of each promotion in a period
+func (s *PromotionService) GetUsage(ctx context.Context, dateFrom, dateTo *time.Time) ([]domain.PromotionUsage, error) {
+	return s.promotionRepo.GetUsage(ctx, dateFrom, dateTo)
+}
+
+// ActiveAt returns the promotions running at the given moment
+func (s *PromotionService) ActiveAt(ctx context.Context, at time.Time) ([]domain.Promotion, error) {
+	candidates, err := s.promotionRepo.GetActive(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]domain.Promotion, 0, len(candidates))
+	for _, promo := range candidates {
+		if promo.IsActiveAt(at) {
+			active = append(active, promo)
+		}
+	}
+	return active, nil
+}
+
+// validate checks the fields each promotion type needs and that the scope exists
+func (s *PromotionService) validate(ctx context.Context, input domain.PromotionInput) error {
+	switch input.Type {
+	case domain.PromotionTypePercentage:
+		if input.DiscountPercent == nil || *input.DiscountPercent <= 0 || *input.DiscountPercent > 100 {
+			return fmt.Errorf("%w: discount_percent must be between 0 and 100", domain.ErrInvalidInput)
+		}
+	case domain.PromotionTypeBundle:
+		if input.BundleQuantity == nil || *input.BundleQuantity < 2 {
+			return fmt.Errorf("%w: bundle_quantity must be at least 2", domain.ErrInvalidInput)
+		}
+		if input.BundlePrice == nil || *input.BundlePrice < 0 {
+			return fmt.Errorf("%w: bundle_price is required", domain.ErrInvalidInput)
+		}
+	case domain.PromotionTypeBuyXGetY:
+		if input.BuyQuantity == nil || *input.BuyQuantity < 1 || input.GetQuantity == nil || *input.GetQuantity < 1 {
+			return fmt.Errorf("%w: buy_quantity and get_quantity must be at least 1", domain.ErrInvalidInput)
+		}
+	default:
+		return fmt.Errorf("%w: unknown promotion type %q", domain.ErrInvalidInput, input.Type)
+	}
+
+	if input.ProductID == nil && input.CategoryID == nil {
+		return fmt.Errorf("%w: product_id or category_id is required", domain.ErrInvalidInput)
+	}
+	if input.ProductID != nil {
+		if _, err := s.productRepo.GetByID(ctx, *input.ProductID); err != nil {
+			if err == domain.ErrNotFound {
+				return fmt.Errorf("%w: product %s not found", domain.ErrInvalidInput, *input.ProductID)
+			}
+			return err
+		}
+	}
+	if input.CategoryID != nil {
+		category, err := s.categoryRepo.FindByID(ctx, *input.CategoryID)
+		if err != nil {
+			return err
+		}
+		if category == nil {
+			return fmt.Errorf("%w: category %s not found", domain.ErrInvalidInput, *input.CategoryID)
+		}
+	}
+
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", domain.ErrInvalidInput)
+	}
+	for _, d := range input.DaysOfWeek {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("%w: days_of_week must be between 0 (Sunday) and 6 (Saturday)", domain.ErrInvalidInput)
+		}
+	}
+	for _, clock := range []*string{input.StartTime, input.EndTime} {
+		if clock == nil {
+			continue
+		}
+		if _, err := time.Parse("15:04", *clock); err != nil {
+			return fmt.Errorf("%w: times must use HH:MM", domain.ErrInvalidInput)
+		}
+	}
+	if input.StartTime != nil && input.EndTime != nil && *input.EndTime <= *input.StartTime {
+		return fmt.Errorf("%w: end_time must be after start_time", domain.ErrInvalidInput)
+	}
+
+	return nil
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// purchaseExpenseCategory is the cash flow category used for stock purchases
+const purchaseExpenseCategory = "Pembelian Stok"
+
+// PurchaseService handles supplier purchase order business logic
+type PurchaseService struct {
+	db            *database.PostgresDB
+	purchaseRepo  *repository.PurchaseRepository
+	supplierRepo  *repository.SupplierRepository
+	productRepo   *repository.ProductRepository
+	inventoryRepo *repository.InventoryRepository
+	cashFlowRepo  *repository.CashFlowRepository
+	settingsSvc   *SettingsService
+}
+
+// NewPurchaseService creates a new PurchaseService
+func NewPurchaseService(
+	db *database.PostgresDB,
+	purchaseRepo *repository.PurchaseRepository,
+	supplierRepo *repository.SupplierRepository,
+	productRepo *repository.ProductRepository,
+	inventoryRepo *repository.InventoryRepository,
+	cashFlowRepo *repository.CashFlowRepository,
+	settingsSvc *SettingsService,
+) *PurchaseService {
+	return &PurchaseService{
+		db:            db,
+		purchaseRepo:  purchaseRepo,
+		supplierRepo:  supplierRepo,
+		productRepo:   productRepo,
+		inventoryRepo: inventoryRepo,
+		cashFlowRepo:  cashFlowRepo,
+		settingsSvc:   settingsSvc,
+	}
+}
+
+// CreatePurchase creates a draft purchase order
+func (s *PurchaseService) CreatePurchase(ctx context.Context, input domain.PurchaseCreateInput) (*domain.Purchase, error) {
+	purchase, err := s.buildPurchase(ctx, input.SupplierID, input.Items, input.Notes, input.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	purchase.Status = domain.PurchaseStatusDraft
+
+	settings, err := s.settingsSvc.Store(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		return s.purchaseRepo.Create(ctx, tx, purchase, settings.PurchasePrefix)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.purchaseRepo.GetByID(ctx, purchase.ID)
+}
+
+// GetPurchase retrieves a purchase order with items
+func (s *PurchaseService) GetPurchase(ctx context.Context, id uuid.UUID) (*domain.Purchase, error) {
+	return s.purchaseRepo.GetByID(ctx, id)
+}
+
+// ListPurchases lists purchase orders
+func (s *PurchaseService) ListPurchases(ctx context.Context, filter domain.PurchaseFilter) ([]domain.Purchase, int64, error) {
+	return s.purchaseRepo.List(ctx, filter)
+}
+
+// MarkOrdered moves a draft purchase order to ordered
+func (s *PurchaseService) MarkOrdered(ctx context.Context, id uuid.UUID) (*domain.Purchase, error) {
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		purchase, err := s.purchaseRepo.GetForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if purchase.Status != domain.PurchaseStatusDraft {
+			return domain.ErrInvalidStatusTransition
+		}
+		return s.purchaseRepo.UpdateStatus(ctx, tx, id, domain.PurchaseStatusOrdered)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.purchaseRepo.GetByID(ctx, id)
+}
+
+// CancelPurchase cancels a purchase order that has not been received yet
+func (s *PurchaseService) CancelPurchase(ctx context.Context, id uuid.UUID) (*domain.Purchase, error) {
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		purchase, err := s.purchaseRepo.GetForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !purchase.CanReceive() {
+			return domain.ErrInvalidStatusTransition
+		}
+		return s.purchaseRepo.UpdateStatus(ctx, tx, id, domain.PurchaseStatusCancelled)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.purchaseRepo.GetByID(ctx, id)
+}
+
+// ReceivePurchase receives a purchase order into stock.
+// Each line posts a purchase stock movement and updates the product cost price,
+// and the purchase total is recorded as a cash flow expense.
+func (s *PurchaseService) ReceivePurchase(ctx context.Context, id uuid.UUID, receivedBy string) (*domain.Purchase, error) {
+	sessionID, categoryID, err := s.expenseTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		purchase, err := s.purchaseRepo.GetForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !purchase.CanReceive() {
+			return domain.ErrInvalidStatusTransition
+		}
+
+		purchase.Items, err = s.purchaseRepo.GetItems(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.receive(ctx, tx, purchase, receivedBy, sessionID, categoryID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.purchaseRepo.GetByID(ctx, id)
+}
+
+// BulkRestock records a restock of several products as a received purchase
+func (s *PurchaseService) BulkRestock(ctx context.Context, input domain.BulkRestockInput) (*domain.Purchase, error) {
+	items := make([]domain.PurchaseItemInput, len(input.Items))
+	for i, item := range input.Items {
+		items[i] = domain.PurchaseItemInput{
+			ProductID:   item.ProductID,
+			UnitID:      item.UnitID,
+			Quantity:    item.Quantity,
+			CostPerUnit: item.CostPerUnit,
+			LotInput:    item.LotInput,
+		}
+	}
+
+	createdBy := "system"
+	if input.CreatedBy != nil {
+		createdBy = *input.CreatedBy
+	}
+
+	purchase, err := s.buildPurchase(ctx, input.SupplierID, items, input.Notes, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	purchase.Status = domain.PurchaseStatusDraft
+
+	sessionID, categoryID, err := s.expenseTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.settingsSvc.Store(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := s.purchaseRepo.Create(ctx, tx, purchase, settings.PurchasePrefix); err != nil {
+			return err
+		}
+		return s.receive(ctx, tx, purchase, createdBy, sessionID, categoryID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.purchaseRepo.GetByID(ctx, purchase.ID)
+}
+
+// GetSupplierSummary summarizes received purchases from a supplier in a period
+func (s *PurchaseService) GetSupplierSummary(ctx context.Context, supplierID uuid.UUID, dateFrom, dateTo *time.Time) (*domain.SupplierPurchaseSummary, error) {
+	supplier, err := s.supplierRepo.GetByID(ctx, supplierID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, total, products, err := s.purchaseRepo.GetSupplierSummary(ctx, supplierID, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SupplierPurchaseSummary{
+		Supplier:      *supplier,
+		DateFrom:      dateFrom,
+		DateTo:        dateTo,
+		PurchaseCount: count,
+		TotalAmount:   total,
+		Products:      products,
+	}, nil
+}
+
+// buildPurchase validates the supplier and items and computes line totals
+func (s *PurchaseService) buildPurchase(ctx context.Context, supplierID *uuid.UUID, items []domain.PurchaseItemInput, notes *string, createdBy string) (*domain.Purchase, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("purchase must have at least one item")
+	}
+
+	if supplierID != nil {
+		supplier, err := s.supplierRepo.GetByID(ctx, *supplierID)
+		if err != nil {
+			if err == domain.ErrNotFound {
+				return nil, fmt.Errorf("supplier %s not found", *supplierID)
+			}
+			return nil, err
+		}
+		if !supplier.IsActive {
+			return nil, fmt.Errorf("supplier %s is inactive", supplier.Name)
+		}
+	}
+
+	purchase := &domain.Purchase{
+		SupplierID: supplierID,
+		Notes:      notes,
+		CreatedBy:  &createdBy,
+		Items:      make([]domain.PurchaseItem, 0, len(items)),
+	}
+
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity must be positive")
+		}
+		if item.CostPerUnit < 0 {
+			return nil, fmt.Errorf("cost per unit cannot be negative")
+		}
+		lot, err := item.Lot()
+		if err != nil {
+			return nil, err
+		}
+
+		product, err := s.productRepo.GetByID(ctx, item.ProductID)
+		if err != nil {
+			if err == domain.ErrNotFound {
+				return nil, fmt.Errorf("product %s not found", item.ProductID)
+			}
+			return nil, err
+		}
+
+		totalCost := item.CostPerUnit * int64(item.Quantity)
+		line := domain.PurchaseItem{
+			ProductID:   item.ProductID,
+			Quantity:    item.Quantity,
+			CostPerUnit: item.CostPerUnit,
+			TotalCost:   totalCost,
+			BatchNumber: lot.BatchNumber,
+			ExpiryDate:  lot.ExpiryDate,
+		}
+
+		// Stock and cost are kept per base unit; the line total stays what was paid
+		if item.UnitID != nil {
+			unit := product.FindUnit(*item.UnitID)
+			if unit == nil {
+				return nil, fmt.Errorf("unit %s not found for %s", *item.UnitID, product.Name)
+			}
+			unitQuantity, unitCost := item.Quantity, item.CostPerUnit
+			line.Quantity, line.CostPerUnit = unit.ToBase(item.Quantity, item.CostPerUnit)
+			line.UnitName = &unit.Name
+			line.UnitQuantity = &unitQuantity
+			line.UnitCost = &unitCost
+		}
+
+		purchase.Items = append(purchase.Items, line)
+		purchase.TotalAmount += totalCost
+	}
+
+	return purchase, nil
+}
+
+// expenseTarget resolves the drawer session and category for purchase expenses
+func (s *PurchaseService) expenseTarget(ctx context.Context) (*uuid.UUID, *uuid.UUID, error) {
+	var sessionID, categoryID *uuid.UUID
+
+	session, err := s.cashFlowRepo.GetCurrentSession(ctx)
+	if err != nil && err != domain.ErrNotFound {
+		return nil, nil, err
+	}
+	if session != nil {
+		sessionID = &session.ID
+	}
+
+	category, err := s.cashFlowRepo.GetCategoryByName(ctx, purchaseExpenseCategory)
+	if err != nil && err != domain.ErrNotFound {
+		return nil, nil, err
+	}
+	if category != nil {
+		categoryID = &category.ID
+	}
+
+	return sessionID, categoryID, nil
+}
+
+// receive posts stock for each purchase line, marks it received and records the expense
+func (s *PurchaseService) receive(ctx context.Context, tx *sql.Tx, purchase *domain.Purchase, receivedBy string, sessionID, categoryID *uuid.UUID) error {
+	// Lock products in a stable order to avoid deadlocks with concurrent receipts
+	items := make([]domain.PurchaseItem, len(purchase.Items))
+	copy(items, purchase.Items)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ProductID.String() < items[j].ProductID.String()
+	})
+
+	notes := fmt.Sprintf("Purchase %s", purchase.PurchaseNumber)
+	for _, item := range items {
+		lot := domain.StockLot{BatchNumber: item.BatchNumber, ExpiryDate: item.ExpiryDate}
+		if _, err := s.inventoryRepo.ReceiveStock(ctx, tx, item.ProductID, item.Quantity, item.CostPerUnit, lot,
+			"purchase", &purchase.ID, &notes, &receivedBy); err != nil {
+			return fmt.Errorf("failed to receive stock for %s: %w", item.ProductID, err)
+		}
+	}
+
+	if err := s.purchaseRepo.UpdateStatus(ctx, tx, purchase.ID, domain.PurchaseStatusReceived); err != nil {
+		return err
+	}
+
+	if purchase.TotalAmount > 0 {
+		refType := "purchase"
+		input := domain.CashFlowInput{
+			CategoryID:  categoryID,
+			Type:        domain.CashFlowTypeExpense,
+			Amount:      purchase.TotalAmount,
+			Description: &notes,
+			CreatedBy:   receivedBy,
+		}
+		if _, err := s.cashFlowRepo.RecordCashFlow(ctx, tx, input, sessionID, &refType, &purchase.ID); err != nil {
+			return fmt.Errorf("failed to record purchase expense: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,712 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/platform/queue"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+const (
+	// maxReportDays bounds the date range of the day-by-day reports
+	maxReportDays = 366
+	// rollupBacklogDays is how many missing closed days one nightly run catches up on
+	rollupBacklogDays = 31
+	// classAShare and classBShare are the cumulative revenue shares (percent) that close the
+	// A and B classes of the product performance report
+	classAShare = 80
+	classBShare = 95
+	// maxDeadStockDays bounds how far back the dead stock list looks for a sale
+	maxDeadStockDays = 365
+)
+
+// ReportService builds the reports that combine sales with the cash book,
+// and keeps the daily_summaries rollup of closed days
+type ReportService struct {
+	transactionRepo *repository.TransactionRepository
+	cashFlowRepo    *repository.CashFlowRepository
+	kasbonRepo      *repository.KasbonRepository
+	inventoryRepo   *repository.InventoryRepository
+	reportRepo      *repository.ReportRepository
+}
+
+// NewReportService creates a new ReportService
+func NewReportService(
+	transactionRepo *repository.TransactionRepository,
+	cashFlowRepo *repository.CashFlowRepository,
+	kasbonRepo *repository.KasbonRepository,
+	inventoryRepo *repository.InventoryRepository,
+	reportRepo *repository.ReportRepository,
+) *ReportService {
+	return &ReportService{
+		transactionRepo: transactionRepo,
+		cashFlowRepo:    cashFlowRepo,
+		kasbonRepo:      kasbonRepo,
+		inventoryRepo:   inventoryRepo,
+		reportRepo:      reportRepo,
+	}
+}
+
+// ProfitLoss returns revenue, COGS and operating expenses between two dates (inclusive).
+// Sales are net of refunds and tax; cancelled and fully refunded sales are left out.
+func (s *ReportService) ProfitLoss(ctx context.Context, dateFrom, dateTo time.Time) (*domain.ProfitLossReport, error) {
+	from, to, err := reportRange(dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+
+	revenue, tax, cogs, err := s.transactionRepo.GetSalesSummary(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	methods, err := s.transactionRepo.GetSalesByMethod(ctx, from, to, true)
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := s.cashFlowRepo.GetExpensesByCategory(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.ProfitLossReport{
+		DateFrom:        dateFrom,
+		DateTo:          dateTo,
+		TotalRevenue:    revenue,
+		TotalTax:        tax,
+		TotalCOGS:       cogs,
+		GrossProfit:     revenue - cogs,
+		ByPaymentMethod: make(map[string]int64, len(methods)),
+		TopExpenses:     expenses,
+	}
+	for _, e := range expenses {
+		report.TotalExpenses += e.Amount
+	}
+	report.NetProfit = report.GrossProfit - report.TotalExpenses
+	if revenue > 0 {
+		report.ProfitMargin = float64(report.NetProfit) / float64(revenue) * 100
+	}
+	for _, m := range methods {
+		report.ByPaymentMethod[string(m.Method)] = m.Amount
+	}
+
+	return report, nil
+}
+
+// SalesByMethod returns sales between two dates (inclusive) per tender, net of refunds
+func (s *ReportService) SalesByMethod(ctx context.Context, dateFrom, dateTo time.Time) (*domain.SalesByMethodReport, error) {
+	from, to, err := reportRange(dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := s.transactionRepo.GetSalesByMethod(ctx, from, to, true)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.SalesByMethodReport{
+		DateFrom: dateFrom,
+		DateTo:   dateTo,
+		Methods:  methods,
+	}
+	for _, m := range methods {
+		report.TotalSales += m.Amount
+	}
+	return report, nil
+}
+
+// reportRange formats an inclusive date range for the repository queries
+func reportRange(dateFrom, dateTo time.Time) (string, string, error) {
+	if dateTo.Before(dateFrom) {
+		return "", "", fmt.Errorf("%w: date_to is before date_from", domain.ErrInvalidInput)
+	}
+	return dateFrom.Format("2006-01-02"), dateTo.Format("2006-01-02"), nil
+}
+
+// SalesTrend returns sales between two dates (inclusive) in day, week or month buckets, compared
+// with the same number of days right before and with the same dates a year earlier. Category
+// and cashier breakdowns are compared with the period right before.
+func (s *ReportService) SalesTrend(ctx context.Context, dateFrom, dateTo time.Time, interval domain.TrendInterval) (*domain.SalesTrendReport, error) {
+	if !interval.IsValid() {
+		return nil, fmt.Errorf("%w: interval must be day, week or month", domain.ErrInvalidInput)
+	}
+	if _, _, err := dailyRange(dateFrom, dateTo); err != nil {
+		return nil, err
+	}
+
+	days := int(dateTo.Sub(dateFrom).Hours()/24) + 1
+	previousFrom, previousTo := dateFrom.AddDate(0, 0, -days), dateFrom.AddDate(0, 0, -1)
+	lastYearFrom, lastYearTo := dateFrom.AddDate(-1, 0, 0), dateTo.AddDate(-1, 0, 0)
+
+	buckets, current, err := s.periodTrend(ctx, dateFrom, dateTo, interval)
+	if err != nil {
+		return nil, err
+	}
+	_, previous, err := s.periodTrend(ctx, previousFrom, previousTo, interval)
+	if err != nil {
+		return nil, err
+	}
+	_, lastYear, err := s.periodTrend(ctx, lastYearFrom, lastYearTo, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory, err := s.categoryComparison(ctx, dateFrom, dateTo, previousFrom, previousTo)
+	if err != nil {
+		return nil, err
+	}
+	byCashier, err := s.cashierComparison(ctx, dateFrom, dateTo, previousFrom, previousTo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SalesTrendReport{
+		Interval:   interval,
+		Current:    current,
+		Previous:   previous,
+		LastYear:   lastYear,
+		VsPrevious: periodGrowth(current, previous),
+		VsLastYear: periodGrowth(current, lastYear),
+		Buckets:    buckets,
+		ByCategory: byCategory,
+		ByCashier:  byCashier,
+	}, nil
+}
+
+// periodTrend returns the buckets of a period, with empty buckets filled in, and their totals
+func (s *ReportService) periodTrend(ctx context.Context, dateFrom, dateTo time.Time, interval domain.TrendInterval) ([]domain.TrendBucket, domain.PeriodTotals, error) {
+	totals := domain.PeriodTotals{DateFrom: dateFrom, DateTo: dateTo}
+
+	found, err := s.transactionRepo.GetSalesTrend(ctx, dateFrom.Format("2006-01-02"), dateTo.Format("2006-01-02"), interval)
+	if err != nil {
+		return nil, totals, err
+	}
+	byStart := make(map[string]domain.TrendBucket, len(found))
+	for _, b := range found {
+		byStart[b.Start.Format("2006-01-02")] = b
+		totals.Sales += b.Sales
+		totals.Transactions += b.Transactions
+		totals.Cost += b.Cost
+	}
+	totals.Profit = totals.Sales - totals.Cost
+	if totals.Transactions > 0 {
+		totals.AverageTransaction = totals.Sales / int64(totals.Transactions)
+	}
+
+	buckets := make([]domain.TrendBucket, 0, len(found))
+	for start := bucketStart(dateFrom, interval); !start.After(dateTo); start = nextBucket(start, interval) {
+		b, ok := byStart[start.Format("2006-01-02")]
+		if !ok {
+			b = domain.TrendBucket{Start: start}
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, totals, nil
+}
+
+func (s *ReportService) categoryComparison(ctx context.Context, dateFrom, dateTo, previousFrom, previousTo time.Time) ([]domain.CategorySales, error) {
+	current, err := s.transactionRepo.GetSalesByCategory(ctx, dateFrom.Format("2006-01-02"), dateTo.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.transactionRepo.GetSalesByCategory(ctx, previousFrom.Format("2006-01-02"), previousTo.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Uncategorized products share the empty key
+	key := func(c domain.CategorySales) string {
+		if c.CategoryID == nil {
+			return ""
+		}
+		return c.CategoryID.String()
+	}
+	previousSales := make(map[string]int64, len(previous))
+	for _, c := range previous {
+		previousSales[key(c)] = c.Sales
+	}
+	var total int64
+	for _, c := range current {
+		total += c.Sales
+	}
+	for i := range current {
+		c := &current[i]
+		c.PreviousSales = previousSales[key(*c)]
+		c.Growth = growth(c.Sales, c.PreviousSales)
+		if total > 0 {
+			c.Share = float64(c.Sales) / float64(total) * 100
+		}
+	}
+	return current, nil
+}
+
+func (s *ReportService) cashierComparison(ctx context.Context, dateFrom, dateTo, previousFrom, previousTo time.Time) ([]domain.CashierSales, error) {
+	current, err := s.transactionRepo.GetSalesByCashier(ctx, dateFrom.Format("2006-01-02"), dateTo.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.transactionRepo.GetSalesByCashier(ctx, previousFrom.Format("2006-01-02"), previousTo.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	previousSales := make(map[string]int64, len(previous))
+	for _, c := range previous {
+		previousSales[c.CashierName] = c.Sales
+	}
+	var total int64
+	for _, c := range current {
+		total += c.Sales
+	}
+	for i := range current {
+		c := &current[i]
+		c.PreviousSales = previousSales[c.CashierName]
+		c.Growth = growth(c.Sales, c.PreviousSales)
+		if total > 0 {
+			c.Share = float64(c.Sales) / float64(total) * 100
+		}
+	}
+	return current, nil
+}
+
+// bucketStart returns the first day of the bucket holding a date
+func bucketStart(day time.Time, interval domain.TrendInterval) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case domain.TrendIntervalWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case domain.TrendIntervalMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+func nextBucket(start time.Time, interval domain.TrendInterval) time.Time {
+	switch interval {
+	case domain.TrendIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case domain.TrendIntervalMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func periodGrowth(current, previous domain.PeriodTotals) domain.PeriodGrowth {
+	return domain.PeriodGrowth{
+		Sales:        growth(current.Sales, previous.Sales),
+		Transactions: growth(int64(current.Transactions), int64(previous.Transactions)),
+		Profit:       growth(current.Profit, previous.Profit),
+	}
+}
+
+// growth is the change from previous to current in percent, or nil when previous is not positive
+func growth(current, previous int64) *float64 {
+	if previous <= 0 {
+		return nil
+	}
+	g := float64(current-previous) / float64(previous) * 100
+	return &g
+}
+
+// ProductPerformance classifies products into A, B and C by their share of revenue between two
+// dates (inclusive), ranks them by margin and works out sell-through and days of cover from the
+// stock movements. Dead stock is stock on hand that has not sold in the last deadStockDays days.
+func (s *ReportService) ProductPerformance(ctx context.Context, dateFrom, dateTo time.Time, deadStockDays int) (*domain.ProductPerformanceReport, error) {
+	from, to, err := reportRange(dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+	if deadStockDays < 1 || deadStockDays > maxDeadStockDays {
+		return nil, fmt.Errorf("%w: dead_stock_days must be between 1 and %d", domain.ErrInvalidInput, maxDeadStockDays)
+	}
+
+	sales, err := s.transactionRepo.GetProductSales(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	flows, err := s.inventoryRepo.GetStockFlows(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := dateTo
+	if end.After(today) {
+		end = today
+	}
+	days := int(end.Sub(dateFrom).Hours()/24) + 1
+
+	report := &domain.ProductPerformanceReport{
+		DateFrom:      dateFrom,
+		DateTo:        dateTo,
+		Days:          max(days, 0),
+		DeadStockDays: deadStockDays,
+	}
+
+	products := make([]domain.ProductPerformance, 0, len(flows))
+	index := make(map[uuid.UUID]int, len(flows))
+	for _, item := range sales {
+		index[item.ProductID] = len(products)
+		products = append(products, domain.ProductPerformance{
+			ProductID:    item.ProductID,
+			ProductName:  item.ProductName,
+			QuantitySold: item.Quantity,
+			Revenue:      item.TotalAmount,
+			Cost:         item.COGSAmount,
+			Profit:       item.Profit(),
+		})
+		report.TotalRevenue += item.TotalAmount
+	}
+	for _, f := range flows {
+		i, ok := index[f.ProductID]
+		if !ok {
+			i = len(products)
+			products = append(products, domain.ProductPerformance{ProductID: f.ProductID, ProductName: f.ProductName})
+		}
+		if f.IsStockActive {
+			applyStockFlow(&products[i], f, report.Days)
+		}
+	}
+
+	slices.SortStableFunc(products, func(a, b domain.ProductPerformance) int {
+		return cmp.Or(cmp.Compare(b.Revenue, a.Revenue), cmp.Compare(a.ProductName, b.ProductName))
+	})
+	report.Classes = classifyProducts(products, report.TotalRevenue)
+	report.Products = products
+	report.MarginRanking = marginRanking(products)
+
+	since := today.AddDate(0, 0, -deadStockDays)
+	deadStock, err := s.inventoryRepo.GetDeadStock(ctx, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	for i := range deadStock {
+		item := &deadStock[i]
+		report.DeadStockValue += item.StockValue
+		if item.LastSoldAt != nil {
+			local := item.LastSoldAt.In(time.Local)
+			soldOn := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+			daysSince := int(today.Sub(soldOn).Hours() / 24)
+			item.DaysSinceLastSale = &daysSince
+		}
+	}
+	report.DeadStock = deadStock
+
+	return report, nil
+}
+
+// applyStockFlow fills in the stock figures of a product and the ratios derived from them
+func applyStockFlow(p *domain.ProductPerformance, f domain.ProductStockFlow, days int) {
+	p.OpeningStock = f.OpeningStock
+	p.Received = f.Received
+	p.StockSold = f.Sold
+	p.ClosingStock = f.ClosingStock
+	p.CurrentStock = f.CurrentStock
+	p.StockValue = int64(max(f.CurrentStock, 0)) * f.CostPrice
+
+	if available := f.OpeningStock + f.Received; available > 0 {
+		sellThrough := float64(f.Sold) / float64(available) * 100
+		p.SellThrough = &sellThrough
+	}
+	if days > 0 {
+		p.AverageDailySales = float64(f.Sold) / float64(days)
+	}
+	if p.AverageDailySales > 0 {
+		cover := float64(max(f.CurrentStock, 0)) / p.AverageDailySales
+		p.DaysOfCover = &cover
+	}
+}
+
+// classifyProducts sets the share, cumulative share and ABC class of products sorted by revenue,
+// largest first, and totals each class. A product belongs to A while the products before it make
+// up less than classAShare percent of revenue, and to B below classBShare.
+func classifyProducts(products []domain.ProductPerformance, totalRevenue int64) []domain.ProductClassSummary {
+	classes := []domain.ProductClassSummary{
+		{Class: domain.ProductClassA},
+		{Class: domain.ProductClassB},
+		{Class: domain.ProductClassC},
+	}
+
+	var cumulative float64
+	for i := range products {
+		p := &products[i]
+		if p.Revenue > 0 {
+			margin := float64(p.Profit) / float64(p.Revenue) * 100
+			p.Margin = &margin
+		}
+
+		class := 2
+		if p.Revenue > 0 && totalRevenue > 0 {
+			p.RevenueShare = float64(p.Revenue) / float64(totalRevenue) * 100
+			switch {
+			case cumulative < classAShare:
+				class = 0
+			case cumulative < classBShare:
+				class = 1
+			}
+			cumulative += p.RevenueShare
+		}
+		p.CumulativeShare = cumulative
+		p.Class = classes[class].Class
+
+		classes[class].Products++
+		classes[class].Revenue += p.Revenue
+	}
+
+	for i := range classes {
+		if totalRevenue > 0 {
+			classes[i].Share = float64(classes[i].Revenue) / float64(totalRevenue) * 100
+		}
+	}
+	return classes
+}
+
+// marginRanking ranks the products with revenue by margin, then by profit
+func marginRanking(products []domain.ProductPerformance) []domain.ProductMargin {
+	ranking := make([]domain.ProductMargin, 0, len(products))
+	for _, p := range products {
+		if p.Margin == nil {
+			continue
+		}
+		ranking = append(ranking, domain.ProductMargin{
+			ProductID:   p.ProductID,
+			ProductName: p.ProductName,
+			Revenue:     p.Revenue,
+			Profit:      p.Profit,
+			Margin:      *p.Margin,
+		})
+	}
+
+	slices.SortStableFunc(ranking, func(a, b domain.ProductMargin) int {
+		return cmp.Or(cmp.Compare(b.Margin, a.Margin), cmp.Compare(b.Profit, a.Profit))
+	})
+	for i := range ranking {
+		ranking[i].Rank = i + 1
+	}
+	return ranking
+}
+
+// DailySummary returns the totals of one day: live for today, from the rollup for closed days
+func (s *ReportService) DailySummary(ctx context.Context, day time.Time) (*domain.DailySummary, error) {
+	summaries, err := s.DailySummaries(ctx, day, day)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		// A future date has nothing to report yet
+		return &domain.DailySummary{Date: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC), Live: true}, nil
+	}
+	return &summaries[0], nil
+}
+
+// DailySummaries returns one summary per day between two dates (inclusive), oldest first.
+// Closed days come from the rollup, and one that was never rolled up is computed and saved
+// on the way; today is computed live and days after today are left out.
+func (s *ReportService) DailySummaries(ctx context.Context, dateFrom, dateTo time.Time) ([]domain.DailySummary, error) {
+	from, to, err := dailyRange(dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now().Format("2006-01-02")
+	to = min(to, today)
+
+	rolledUp, err := s.reportRepo.GetDailySummaries(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]domain.DailySummary, len(rolledUp))
+	for _, summary := range rolledUp {
+		byDate[summary.Date.Format("2006-01-02")] = summary
+	}
+
+	summaries := make([]domain.DailySummary, 0)
+	for day := dateFrom; day.Format("2006-01-02") <= to; day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		if summary, ok := byDate[date]; ok && date < today {
+			summaries = append(summaries, summary)
+			continue
+		}
+
+		summary, err := s.computeDailySummary(ctx, day)
+		if err != nil {
+			return nil, err
+		}
+		if date < today {
+			if err := s.reportRepo.UpsertDailySummary(ctx, *summary); err != nil {
+				return nil, err
+			}
+			now := time.Now()
+			summary.RolledUpAt = &now
+		} else {
+			summary.Live = true
+		}
+		summaries = append(summaries, *summary)
+	}
+	return summaries, nil
+}
+
+// RollupDay recomputes a closed day and saves it to daily_summaries
+func (s *ReportService) RollupDay(ctx context.Context, day time.Time) error {
+	if day.Format("2006-01-02") >= time.Now().Format("2006-01-02") {
+		return fmt.Errorf("%w: only closed days can be rolled up", domain.ErrInvalidInput)
+	}
+
+	summary, err := s.computeDailySummary(ctx, day)
+	if err != nil {
+		return err
+	}
+	return s.reportRepo.UpsertDailySummary(ctx, *summary)
+}
+
+// RebuildDailySummaries rolls up the closed days between two dates (inclusive) again and
+// returns how many were written; today and later are skipped
+func (s *ReportService) RebuildDailySummaries(ctx context.Context, dateFrom, dateTo time.Time) (int, error) {
+	_, to, err := dailyRange(dateFrom, dateTo)
+	if err != nil {
+		return 0, err
+	}
+
+	to = min(to, time.Now().AddDate(0, 0, -1).Format("2006-01-02"))
+	rebuilt := 0
+	for day := dateFrom; day.Format("2006-01-02") <= to; day = day.AddDate(0, 0, 1) {
+		if err := s.RollupDay(ctx, day); err != nil {
+			return rebuilt, err
+		}
+		rebuilt++
+	}
+	return rebuilt, nil
+}
+
+// RollupClosedDays rolls up yesterday and catches up on closed days that were never rolled up.
+// It returns the number of days written.
+func (s *ReportService) RollupClosedDays(ctx context.Context) (int, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	missing, err := s.reportRepo.GetUnsummarizedDates(ctx, today.Format("2006-01-02"), rollupBacklogDays)
+	if err != nil {
+		return 0, err
+	}
+
+	days := []time.Time{today.AddDate(0, 0, -1)}
+	for _, date := range missing {
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return 0, err
+		}
+		if !day.Equal(days[0]) {
+			days = append(days, day)
+		}
+	}
+
+	for i, day := range days {
+		if err := s.RollupDay(ctx, day); err != nil {
+			return i, err
+		}
+	}
+	return len(days), nil
+}
+
+// HandleRollupTask runs the nightly rollup, or re-runs one day named in the payload
+// after a back-dated cancellation or refund
+func (s *ReportService) HandleRollupTask(ctx context.Context, t *asynq.Task) error {
+	var payload queue.PayloadDailyRollup
+	if len(t.Payload()) > 0 {
+		if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+			return fmt.Errorf("invalid rollup payload: %v: %w", err, asynq.SkipRetry)
+		}
+	}
+
+	if payload.Date == "" {
+		n, err := s.RollupClosedDays(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("INFO: Rolled up %d daily summaries", n)
+		return nil
+	}
+
+	day, err := time.Parse("2006-01-02", payload.Date)
+	if err != nil {
+		return fmt.Errorf("invalid rollup date %q: %w", payload.Date, asynq.SkipRetry)
+	}
+	return s.RollupDay(ctx, day)
+}
+
+// computeDailySummary totals one day from transactions, refunds and kasbon records
+func (s *ReportService) computeDailySummary(ctx context.Context, day time.Time) (*domain.DailySummary, error) {
+	date := day.Format("2006-01-02")
+
+	_, count, err := s.transactionRepo.GetDailySales(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily sales: %w", err)
+	}
+	revenue, tax, cogs, err := s.transactionRepo.GetSalesSummary(ctx, date, date)
+	if err != nil {
+		return nil, err
+	}
+	methods, err := s.transactionRepo.GetSalesByMethod(ctx, date, date, true)
+	if err != nil {
+		return nil, err
+	}
+	given, paid, err := s.kasbonRepo.GetDailyTotals(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &domain.DailySummary{
+		Date:              time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+		TotalTransactions: count,
+		TotalSales:        revenue,
+		TotalTax:          tax,
+		TotalCost:         cogs,
+		TotalProfit:       revenue - cogs,
+		TotalKasbonGiven:  given,
+		TotalKasbonPaid:   paid,
+	}
+	for _, m := range methods {
+		switch m.Method {
+		case domain.PaymentMethodCash:
+			summary.CashSales = m.Amount
+		case domain.PaymentMethodKasbon:
+			summary.KasbonSales = m.Amount
+		case domain.PaymentMethodTransfer:
+			summary.TransferSales = m.Amount
+		case domain.PaymentMethodQRIS:
+			summary.QRISSales = m.Amount
+		}
+	}
+	return summary, nil
+}
+
+// dailyRange is reportRange for the reports that go day by day, which are limited to maxReportDays
+func dailyRange(dateFrom, dateTo time.Time) (string, string, error) {
+	if dateTo.Sub(dateFrom) >= maxReportDays*24*time.Hour {
+		return "", "", fmt.Errorf("%w: date range is limited to %d days", domain.ErrInvalidInput, maxReportDays)
+	}
+	return reportRange(dateFrom, dateTo)
+}
+
+// queueRollup asks the worker to roll up the day of a sale again when a cancellation or
+// refund lands after that day has closed. It is written to the outbox in the caller's
+// transaction, so the re-run only happens if the change commits.
+func queueRollup(ctx context.Context, tx *sql.Tx, outboxSvc *OutboxService, saleAt time.Time, key string) error {
+	date := saleAt.In(time.Local).Format("2006-01-02")
+	if date >= time.Now().Format("2006-01-02") {
+		return nil
+	}
+	return outboxSvc.Add(ctx, tx, queue.TypeDailyRollup, key, queue.PayloadDailyRollup{Date: date})
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// settingsCacheTTL bounds how long another instance's update can stay invisible
+const settingsCacheTTL = time.Minute
+
+var numberPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9/-]{1,10}$`)
+
+// settingValidators lists the editable keys and how each value is checked
+var settingValidators = map[string]func(value string) error{
+	domain.SettingStoreName:           requiredText(100),
+	domain.SettingStoreAddress:        optionalText(255),
+	domain.SettingStorePhone:          optionalText(30),
+	domain.SettingReceiptFooter:       optionalText(255),
+	domain.SettingPaymentInstructions: optionalText(500),
+	domain.SettingInvoicePrefix:       numberPrefix,
+	domain.SettingPurchasePrefix:      numberPrefix,
+	domain.SettingTaxRate: func(value string) error {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 100 {
+			return fmt.Errorf("must be a number between 0 and 100")
+		}
+		return nil
+	},
+	domain.SettingTaxInclusive: func(value string) error {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		return nil
+	},
+	domain.SettingTaxRounding: func(value string) error {
+		switch domain.TaxRounding(value) {
+		case domain.TaxRoundingNearest, domain.TaxRoundingUp, domain.TaxRoundingDown:
+			return nil
+		}
+		return fmt.Errorf("must be nearest, up or down")
+	},
+	domain.SettingCostingMethod: func(value string) error {
+		switch domain.CostingMethod(value) {
+		case domain.CostingAverage, domain.CostingFIFO:
+			return nil
+		}
+		return fmt.Errorf("must be average or fifo")
+	},
+	domain.SettingForecastMethod: func(value string) error {
+		if !domain.ForecastMethod(value).IsValid() {
+			return fmt.Errorf("must be moving_average or exponential_smoothing")
+		}
+		return nil
+	},
+	domain.SettingForecastLeadTimeDays: wholeNumber(0, 60),
+	domain.SettingForecastCoverDays:    wholeNumber(1, 90),
+}
+
+func requiredText(maxLen int) func(string) error {
+	return func(value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("is required")
+		}
+		return optionalText(maxLen)(value)
+	}
+}
+
+func optionalText(maxLen int) func(string) error {
+	return func(value string) error {
+		if len(value) > maxLen {
+			return fmt.Errorf("must be at most %d characters", maxLen)
+		}
+		return nil
+	}
+}
+
+func wholeNumber(lo, hi int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < lo || n > hi {
+			return fmt.Errorf("must be a whole number between %d and %d", lo, hi)
+		}
+		return nil
+	}
+}
+
+func numberPrefix(value string) error {
+	if !numberPrefixPattern.MatchString(value) {
+		return fmt.Errorf("must be 1-10 letters, digits, '-' or '/'")
+	}
+	return nil
+}
+
+// SettingsService serves app settings from an in-memory cache
+type SettingsService struct {
+	db           *database.PostgresDB
+	settingsRepo *repository.SettingsRepository
+
+	mu       sync.RWMutex
+	cached   *domain.StoreSettings
+	cachedAt time.Time
+}
+
+// NewSettingsService creates a new SettingsService
+func NewSettingsService(db *database.PostgresDB, settingsRepo *repository.SettingsRepository) *SettingsService {
+	return &SettingsService{
+		db:           db,
+		settingsRepo: settingsRepo,
+	}
+}
+
+// Store returns the typed store settings, loading them on a cache miss
+func (s *SettingsService) Store(ctx context.Context) (*domain.StoreSettings, error) {
+	s.mu.RLock()
+	if s.cached != nil && time.Since(s.cachedAt) < settingsCacheTTL {
+		settings := *s.cached
+		s.mu.RUnlock()
+		return &settings, nil
+	}
+	s.mu.RUnlock()
+
+	values, err := s.settingsRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings := parseStoreSettings(values)
+
+	s.mu.Lock()
+	s.cached = &settings
+	s.cachedAt = time.Now()
+	s.mu.Unlock()
+
+	return &settings, nil
+}
+
+// List returns every stored setting with its description
+func (s *SettingsService) List(ctx context.Context) ([]domain.AppSetting, error) {
+	return s.settingsRepo.List(ctx)
+}
+
+// Update validates and saves the given settings, then drops the cache
+func (s *SettingsService) Update(ctx context.Context, values map[string]string) (*domain.StoreSettings, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: no settings given", domain.ErrInvalidInput)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key, value := range values {
+		validate, ok := settingValidators[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown setting %q", domain.ErrInvalidInput, key)
+		}
+		if err := validate(value); err != nil {
+			return nil, fmt.Errorf("%w: %s %v", domain.ErrInvalidInput, key, err)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		for _, key := range keys {
+			if err := s.settingsRepo.Set(ctx, tx, key, values[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Invalidate()
+	return s.Store(ctx)
+}
+
+// Invalidate drops the cached settings
+func (s *SettingsService) Invalidate() {
+	s.mu.Lock()
+	s.cached = nil
+	s.mu.Unlock()
+}
+
+// parseStoreSettings maps raw values onto StoreSettings.
+// Values are validated on write, so anything unparsable keeps its default.
+func parseStoreSettings(values map[string]string) domain.StoreSettings {
+	settings := domain.DefaultStoreSettings()
+
+	if v := values[domain.SettingStoreName]; v != "" {
+		settings.StoreName = v
+	}
+	settings.StoreAddress = values[domain.SettingStoreAddress]
+	settings.StorePhone = values[domain.SettingStorePhone]
+	if v, ok := values[domain.SettingReceiptFooter]; ok {
+		settings.ReceiptFooter = v
+	}
+	settings.PaymentInstructions = values[domain.SettingPaymentInstructions]
+	if v := values[domain.SettingInvoicePrefix]; numberPrefix(v) == nil {
+		settings.InvoicePrefix = v
+	}
+	if v := values[domain.SettingPurchasePrefix]; numberPrefix(v) == nil {
+		settings.PurchasePrefix = v
+	}
+	if rate, err := strconv.ParseFloat(values[domain.SettingTaxRate], 64); err == nil && rate >= 0 && rate <= 100 {
+		settings.TaxRate = rate
+	}
+	if inclusive, err := strconv.ParseBool(values[domain.SettingTaxInclusive]); err == nil {
+		settings.TaxInclusive = inclusive
+	}
+	if settingValidators[domain.SettingTaxRounding](values[domain.SettingTaxRounding]) == nil {
+		settings.TaxRounding = domain.TaxRounding(values[domain.SettingTaxRounding])
+	}
+	if settingValidators[domain.SettingCostingMethod](values[domain.SettingCostingMethod]) == nil {
+		settings.CostingMethod = domain.CostingMethod(values[domain.SettingCostingMethod])
+	}
+	if settingValidators[domain.SettingForecastMethod](values[domain.SettingForecastMethod]) == nil {
+		settings.ForecastMethod = domain.ForecastMethod(values[domain.SettingForecastMethod])
+	}
+	if settingValidators[domain.SettingForecastLeadTimeDays](values[domain.SettingForecastLeadTimeDays]) == nil {
+		settings.ForecastLeadTimeDays, _ = strconv.Atoi(values[domain.SettingForecastLeadTimeDays])
+	}
+	if settingValidators[domain.SettingForecastCoverDays](values[domain.SettingForecastCoverDays]) == nil {
+		settings.ForecastCoverDays, _ = strconv.Atoi(values[domain.SettingForecastCoverDays])
+	}
+
+	return settings
+}
@@ -19,6 +19,7 @@ type StockOpnameService struct {
 	opnameRepo   *repository.StockOpnameRepository
 	productRepo  *repository.ProductRepository
 	inventoryRepo *repository.InventoryRepository
+	forecastSvc   *ForecastService
 }
 
 // NewStockOpnameService creates a new StockOpnameService
@@ -27,12 +28,14 @@ func NewStockOpnameService(
 	opnameRepo *repository.StockOpnameRepository,
 	productRepo *repository.ProductRepository,
 	inventoryRepo *repository.InventoryRepository,
+	forecastSvc *ForecastService,
 ) *StockOpnameService {
 	return &StockOpnameService{
 		db:           db,
 		opnameRepo:   opnameRepo,
 		productRepo:  productRepo,
 		inventoryRepo: inventoryRepo,
+		forecastSvc:   forecastSvc,
 	}
 }
 
@@ -182,7 +185,7 @@ func (s *StockOpnameService) FinalizeSession(ctx context.Context, input domain.F
 
 					// Record adjustment movement
 					movementType := domain.StockMovementTypeAdjustment
-					if err := s.inventoryRepo.RecordMovement(ctx, tx, item.ProductID, movementType, 
+					if _, _, err := s.inventoryRepo.RecordMovement(ctx, tx, item.ProductID, movementType, 
 						item.Variance, item.SystemStock, item.PhysicalStock, 
 						"stock_opname", &input.SessionID, nil, 
 						fmt.Sprintf("Stock Opname Adjustment - Session: %s", session.SessionCode),
@@ -266,28 +269,63 @@ func (s *StockOpnameService) GetVarianceReport(ctx context.Context, sessionID uu
 	}, nil
 }
 
-// GetShoppingList generates a shopping list from low stock products
+// GetShoppingList generates a shopping list from low stock products.
+// Products with recent sales are also listed once they reach their forecast reorder point, and
+// their suggested quantity comes from the forecast; the others fall back to min and max stock.
 func (s *StockOpnameService) GetShoppingList(ctx context.Context) (*domain.ShoppingList, error) {
-	items, err := s.opnameRepo.GetShoppingList(ctx)
+	opts, err := s.forecastSvc.Options(ctx)
+	if err != nil {
+		return nil, err
+	}
+	forecasts, err := s.forecastSvc.Forecast(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reorderPoints := make(map[uuid.UUID]int, len(forecasts))
+	for id, f := range forecasts {
+		reorderPoints[id] = f.ReorderPoint
+	}
+	items, err := s.opnameRepo.GetShoppingList(ctx, reorderPoints)
 	if err != nil {
 		return nil, err
 	}
 
 	var totalCost int64
-	for _, item := range items {
+	for i := range items {
+		item := &items[i]
+		if f, ok := forecasts[item.ProductID]; ok {
+			item.Forecast = &f
+			item.SuggestedQty = forecastOrderQty(f, item.Product)
+			estimatedCost := int64(item.SuggestedQty) * item.Product.CostPrice
+			item.EstimatedCost = &estimatedCost
+		}
 		if item.EstimatedCost != nil {
 			totalCost += *item.EstimatedCost
 		}
 	}
 
 	return &domain.ShoppingList{
-		GeneratedAt: time.Now(),
-		TotalItems:  len(items),
-		TotalCost:   totalCost,
-		Items:       items,
+		GeneratedAt:    time.Now(),
+		ForecastMethod: opts.Method,
+		LeadTimeDays:   opts.LeadTimeDays,
+		CoverDays:      opts.CoverDays,
+		TotalItems:     len(items),
+		TotalCost:      totalCost,
+		Items:          items,
 	}, nil
 }
 
+// forecastOrderQty is what to order to bring a product up to its forecast target stock, never
+// below its minimum stock and, when set, never above its maximum
+func forecastOrderQty(f domain.DemandForecast, p *domain.Product) int {
+	target := max(f.TargetStock, p.MinStockAlert)
+	if p.MaxStock != nil {
+		target = min(target, *p.MaxStock)
+	}
+	return max(target-p.CurrentStock, 0)
+}
+
 // GetNearExpiryReport generates a report of items nearing expiry
 func (s *StockOpnameService) GetNearExpiryReport(ctx context.Context, daysAhead int) (*domain.NearExpiryReport, error) {
 	if daysAhead <= 0 {
@@ -300,16 +338,21 @@ func (s *StockOpnameService) GetNearExpiryReport(ctx context.Context, daysAhead
 	}
 
 	var totalValue int64
+	expired := 0
 	for _, item := range items {
 		totalValue += item.CostPrice * int64(item.Quantity)
+		if item.DaysUntilExpiry < 0 {
+			expired++
+		}
 	}
 
 	return &domain.NearExpiryReport{
-		GeneratedAt: time.Now(),
-		DaysAhead:   daysAhead,
-		TotalItems:  len(items),
-		TotalValue:  totalValue,
-		Items:       items,
+		GeneratedAt:  time.Now(),
+		DaysAhead:    daysAhead,
+		TotalItems:   len(items),
+		ExpiredItems: expired,
+		TotalValue:   totalValue,
+		Items:        items,
 	}, nil
 }
 
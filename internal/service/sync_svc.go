@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// syncCursorOverlap re-sends rows changed shortly before the cursor, so a write
+// that committed after the previous pull read its table is not missed
+const syncCursorOverlap = time.Minute
+
+// SyncService serves master data deltas to offline-capable POS clients
+type SyncService struct {
+	productRepo  *repository.ProductRepository
+	categoryRepo *repository.CategoryRepository
+	customerRepo *repository.CustomerRepository
+}
+
+// NewSyncService creates a new SyncService
+func NewSyncService(
+	productRepo *repository.ProductRepository,
+	categoryRepo *repository.CategoryRepository,
+	customerRepo *repository.CustomerRepository,
+) *SyncService {
+	return &SyncService{
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		customerRepo: customerRepo,
+	}
+}
+
+// Pull returns products (with pricing tiers), categories and customers changed since the cursor.
+// Without a cursor it returns a full snapshot of active records. Rows near the cursor may be
+// sent again, so clients should upsert by ID.
+func (s *SyncService) Pull(ctx context.Context, since *time.Time) (*domain.SyncPullResult, error) {
+	cursor := time.Now()
+
+	var from *time.Time
+	if since != nil {
+		t := since.Add(-syncCursorOverlap)
+		from = &t
+	}
+
+	products, err := s.productRepo.ListChangedSince(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categoryRepo.ListChangedSince(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	customers, err := s.customerRepo.ListChangedSince(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SyncPullResult{
+		Cursor:     cursor,
+		Full:       since == nil,
+		Products:   products,
+		Categories: categories,
+		Customers:  customers,
+	}, nil
+}
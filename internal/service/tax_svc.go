@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// TaxService builds the tax configuration from app settings and category exemptions
+type TaxService struct {
+	settingsSvc  *SettingsService
+	categoryRepo *repository.CategoryRepository
+}
+
+// NewTaxService creates a new TaxService
+func NewTaxService(settingsSvc *SettingsService, categoryRepo *repository.CategoryRepository) *TaxService {
+	return &TaxService{
+		settingsSvc:  settingsSvc,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// Config returns the current tax configuration
+func (s *TaxService) Config(ctx context.Context) (*domain.TaxConfig, error) {
+	settings, err := s.settingsSvc.Store(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &domain.TaxConfig{
+		Rate:              settings.TaxRate,
+		Inclusive:         settings.TaxInclusive,
+		Rounding:          settings.TaxRounding,
+		ExemptCategoryIDs: make(map[uuid.UUID]bool),
+	}
+
+	if cfg.Rate > 0 {
+		exempt, err := s.categoryRepo.GetTaxExemptIDs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tax exempt categories: %w", err)
+		}
+		for _, id := range exempt {
+			cfg.ExemptCategoryIDs[id] = true
+		}
+	}
+
+	return cfg, nil
+}
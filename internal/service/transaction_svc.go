@@ -3,12 +3,17 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/eveeze/warung-backend/internal/database"
 	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/platform/queue"
 	"github.com/eveeze/warung-backend/internal/repository"
 )
 
@@ -21,7 +26,10 @@ type TransactionService struct {
 	kasbonRepo      *repository.KasbonRepository
 	inventoryRepo   *repository.InventoryRepository
 	refillableRepo  *repository.RefillableRepository
-	notificationSvc *NotificationService
+	outboxSvc       *OutboxService
+	promotionSvc    *PromotionService
+	taxSvc          *TaxService
+	settingsSvc     *SettingsService
 }
 
 // NewTransactionService creates a new TransactionService
@@ -33,7 +41,10 @@ func NewTransactionService(
 	kasbonRepo *repository.KasbonRepository,
 	inventoryRepo *repository.InventoryRepository,
 	refillableRepo *repository.RefillableRepository,
-	notificationSvc *NotificationService,
+	outboxSvc *OutboxService,
+	promotionSvc *PromotionService,
+	taxSvc *TaxService,
+	settingsSvc *SettingsService,
 ) *TransactionService {
 	return &TransactionService{
 		db:              db,
@@ -43,7 +54,10 @@ func NewTransactionService(
 		kasbonRepo:      kasbonRepo,
 		inventoryRepo:   inventoryRepo,
 		refillableRepo:  refillableRepo,
-		notificationSvc: notificationSvc,
+		outboxSvc:       outboxSvc,
+		promotionSvc:    promotionSvc,
+		taxSvc:          taxSvc,
+		settingsSvc:     settingsSvc,
 	}
 }
 
@@ -58,6 +72,16 @@ func (s *TransactionService) CalculateCart(ctx context.Context, input domain.Car
 		Subtotal: 0,
 	}
 
+	promotions, err := s.promotionSvc.ActiveAt(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	taxConfig, err := s.taxSvc.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var taxable int64
 	for _, item := range input.Items {
 		product, err := s.productRepo.GetByID(ctx, item.ProductID)
 		if err != nil {
@@ -71,18 +95,39 @@ func (s *TransactionService) CalculateCart(ctx context.Context, input domain.Car
 			continue
 		}
 
-		unitPrice, tierName, _ := product.CalculatePrice(item.Quantity)
+		unit, baseQuantity, err := sellingUnit(product, item.UnitID, item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		unitPrice, tierName, _ := product.CalculateUnitPrice(unit, item.Quantity)
 		subtotal := unitPrice * int64(item.Quantity)
+		var promo *domain.Promotion
+		var promoDiscount int64
+		if unit == nil {
+			promo, promoDiscount = domain.BestPromotion(promotions, product, item.Quantity, unitPrice)
+		}
 
 		itemResult := domain.CartItemResult{
-			ProductID:   product.ID,
-			ProductName: product.Name,
-			Quantity:    item.Quantity,
-			Unit:        product.Unit,
-			UnitPrice:   unitPrice,
-			TierName:    tierName,
-			Subtotal:    subtotal,
-			IsAvailable: product.CanSell(item.Quantity),
+			ProductID:    product.ID,
+			ProductName:  product.Name,
+			Quantity:     item.Quantity,
+			Unit:         product.Unit,
+			UnitPrice:    unitPrice,
+			BaseQuantity: baseQuantity,
+			TierName:     tierName,
+			Subtotal:     subtotal,
+			IsAvailable:  product.CanSell(baseQuantity),
+			TotalAmount:  subtotal - promoDiscount,
+			TaxExempt:    taxConfig.IsExempt(product),
+		}
+		if unit != nil {
+			itemResult.Unit = unit.Name
+		}
+		if promo != nil {
+			itemResult.PromotionID = &promo.ID
+			itemResult.PromotionName = &promo.Name
+			itemResult.PromotionDiscount = promoDiscount
 		}
 
 		if product.IsStockActive {
@@ -91,12 +136,47 @@ func (s *TransactionService) CalculateCart(ctx context.Context, input domain.Car
 		}
 
 		result.Items = append(result.Items, itemResult)
-		result.Subtotal += subtotal
+		result.Subtotal += itemResult.TotalAmount
+		result.PromotionDiscount += promoDiscount
+		if !itemResult.TaxExempt {
+			taxable += itemResult.TotalAmount
+		}
+	}
+
+	if input.DiscountAmount != nil {
+		result.DiscountAmount = *input.DiscountAmount
+	}
+	if result.DiscountAmount < 0 || result.DiscountAmount > result.Subtotal {
+		return nil, fmt.Errorf("%w: discount_amount must be between 0 and the subtotal", domain.ErrInvalidInput)
 	}
+	result.TaxRate = taxConfig.Rate
+	result.TaxInclusive = taxConfig.Inclusive
+	result.TaxAmount = taxConfig.Calculate(result.Subtotal, taxable, result.DiscountAmount)
+	result.TotalAmount = taxConfig.Total(result.Subtotal, result.DiscountAmount, result.TaxAmount)
 
 	return result, nil
 }
 
+// sellingUnit resolves the unit a cart line is sold in and its quantity in base units.
+// A nil unit means the product's base unit.
+func sellingUnit(product *domain.Product, unitID *uuid.UUID, quantity int) (*domain.ProductUnit, int, error) {
+	if unitID == nil {
+		return nil, quantity, nil
+	}
+	unit := product.FindUnit(*unitID)
+	if unit == nil {
+		return nil, 0, fmt.Errorf("%w: unit %s does not belong to %s", domain.ErrInvalidInput, *unitID, product.Name)
+	}
+	return unit, quantity * unit.ConversionFactor, nil
+}
+
+// stockDeduction is a pending stock decrement for one cart line
+type stockDeduction struct {
+	itemIndex int
+	productID uuid.UUID
+	quantity  int
+}
+
 // CreateTransaction processes a checkout
 func (s *TransactionService) CreateTransaction(ctx context.Context, input domain.TransactionCreateInput) (*domain.Transaction, error) {
 	if len(input.Items) == 0 {
@@ -105,13 +185,16 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, input domain
 
 	// Validate customer for kasbon
 	if input.PaymentMethod == domain.PaymentMethodKasbon && input.CustomerID == nil {
-		return nil, fmt.Errorf("customer is required for kasbon payment")
+		return nil, fmt.Errorf("%w: customer is required for kasbon payment", domain.ErrInvalidInput)
 	}
 
 	var customer *domain.Customer
 	if input.CustomerID != nil {
 		var err error
 		customer, err = s.customerRepo.GetByID(ctx, *input.CustomerID)
+		if err == domain.ErrNotFound {
+			return nil, fmt.Errorf("customer %s: %w", *input.CustomerID, domain.ErrNotFound)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -120,98 +203,161 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, input domain
 		}
 	}
 
+	// Promotions are evaluated once at checkout time for the whole cart;
+	// an offline sale is priced with the promotions running when it was made
+	soldAt := time.Now()
+	if input.ClientCreatedAt != nil {
+		soldAt = *input.ClientCreatedAt
+	}
+	promotions, err := s.promotionSvc.ActiveAt(ctx, soldAt)
+	if err != nil {
+		return nil, err
+	}
+	taxConfig, err := s.taxSvc.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.settingsSvc.Store(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build transaction within a database transaction
 	var transaction *domain.Transaction
 
-	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+	err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
 		transaction = &domain.Transaction{
-			CustomerID:     input.CustomerID,
-			PaymentMethod:  input.PaymentMethod,
-			AmountPaid:     input.AmountPaid,
-			Status:         domain.TransactionStatusCompleted,
-			Notes:          input.Notes,
-			CashierName:    input.CashierName,
-			Items:          make([]domain.TransactionItem, 0, len(input.Items)),
+			CustomerID:      input.CustomerID,
+			PaymentMethod:   input.PaymentMethod,
+			AmountPaid:      input.AmountPaid,
+			Status:          domain.TransactionStatusCompleted,
+			Notes:           input.Notes,
+			CashierName:     input.CashierName,
+			ClientID:        input.ClientID,
+			ClientCreatedAt: input.ClientCreatedAt,
+			Items:           make([]domain.TransactionItem, 0, len(input.Items)),
 		}
 
-		var subtotal int64
+		var subtotal, taxable int64
 		var refillableMovements []*domain.ContainerMovement
+		var lowStockAlerts []queue.PayloadLowStock
+		var deductions []stockDeduction
+
+		// Lock every product in the cart before checking stock, so a concurrent
+		// sale of the same product waits here instead of overselling
+		productIDs := make([]uuid.UUID, 0, len(input.Items))
+		for _, itemInput := range input.Items {
+			productIDs = append(productIDs, itemInput.ProductID)
+		}
+		products, err := s.productRepo.GetByIDsForUpdate(ctx, tx, productIDs)
+		if err != nil {
+			return err
+		}
 
 		// Process each item
 		for _, itemInput := range input.Items {
-			product, err := s.productRepo.GetByID(ctx, itemInput.ProductID)
-			if err != nil {
-				return fmt.Errorf("product %s not found", itemInput.ProductID)
+			product, ok := products[itemInput.ProductID]
+			if !ok {
+				return fmt.Errorf("product %s: %w", itemInput.ProductID, domain.ErrNotFound)
 			}
 
 			if !product.IsActive {
-				return fmt.Errorf("product %s is inactive", product.Name)
+				return fmt.Errorf("%w: %s", domain.ErrProductInactive, product.Name)
+			}
+
+			// Stock is counted in base units; a dus of 24 takes 24 pcs
+			unit, baseQuantity, err := sellingUnit(product, itemInput.UnitID, itemInput.Quantity)
+			if err != nil {
+				return err
 			}
 
-			if !product.CanSell(itemInput.Quantity) {
-				return fmt.Errorf("insufficient stock for %s (available: %d, requested: %d)",
-					product.Name, product.CurrentStock, itemInput.Quantity)
+			if !product.CanSell(baseQuantity) {
+				return fmt.Errorf("%w for %s (available: %d, requested: %d)",
+					domain.ErrInsufficientStock, product.Name, product.CurrentStock, baseQuantity)
 			}
 
-			unitPrice, tierName, tierID := product.CalculatePrice(itemInput.Quantity)
+			unitPrice, tierName, tierID := product.CalculateUnitPrice(unit, itemInput.Quantity)
 			itemSubtotal := unitPrice * int64(itemInput.Quantity)
 			discountAmount := int64(0)
 			if itemInput.DiscountAmount != nil {
 				discountAmount = *itemInput.DiscountAmount
 			}
+
+			// The promotion stacks on top of a manual discount but never below zero.
+			// Promotions are defined per base unit, so lines sold in a larger unit do not get them.
+			var promo *domain.Promotion
+			var promoDiscount int64
+			if unit == nil {
+				promo, promoDiscount = domain.BestPromotion(promotions, product, itemInput.Quantity, unitPrice)
+			}
+			promoDiscount = min(promoDiscount, max(itemSubtotal-discountAmount, 0))
+			discountAmount += promoDiscount
 			totalAmount := itemSubtotal - discountAmount
 
 			item := domain.TransactionItem{
-				ProductID:       product.ID,
-				ProductName:     product.Name,
-				ProductBarcode:  product.Barcode,
-				Quantity:        itemInput.Quantity,
-				Unit:            product.Unit,
-				UnitPrice:       unitPrice,
-				CostPrice:       product.CostPrice,
-				Subtotal:        itemSubtotal,
-				DiscountAmount:  discountAmount,
-				TotalAmount:     totalAmount,
-				PricingTierID:   tierID,
-				Notes:           itemInput.Notes,
+				ProductID:        product.ID,
+				ProductName:      product.Name,
+				ProductBarcode:   product.Barcode,
+				Quantity:         baseQuantity,
+				Unit:             product.Unit,
+				UnitPrice:        unitPrice,
+				CostPrice:        product.CostPrice,
+				COGSAmount:       product.CostPrice * int64(baseQuantity),
+				Subtotal:         itemSubtotal,
+				DiscountAmount:   discountAmount,
+				TotalAmount:      totalAmount,
+				PricingTierID:    tierID,
+				Notes:            itemInput.Notes,
+				UnitQuantity:     itemInput.Quantity,
+				ConversionFactor: 1,
+			}
+			if unit != nil {
+				item.ProductUnitID = &unit.ID
+				item.Unit = unit.Name
+				item.ConversionFactor = unit.ConversionFactor
+				if unit.Barcode != nil {
+					item.ProductBarcode = unit.Barcode
+				}
 			}
 			if tierID != nil {
 				item.PricingTierName = &tierName
 			}
+			if promo != nil && promoDiscount > 0 {
+				item.PromotionID = &promo.ID
+				item.PromotionName = &promo.Name
+				item.PromotionDiscount = promoDiscount
+			}
 
 			transaction.Items = append(transaction.Items, item)
 			subtotal += totalAmount
+			if !taxConfig.IsExempt(product) {
+				taxable += totalAmount
+			}
 
-			// Deduct stock
-			if err := s.inventoryRepo.DeductStock(ctx, tx, product.ID, uuid.Nil, itemInput.Quantity, input.CashierName); err != nil {
-				return err
+			// Stock is deducted once the transaction row exists, so the movement can reference it.
+			// Track what is left here so a product listed on several lines is checked against the remainder.
+			newStock := product.CurrentStock - baseQuantity
+			if product.IsStockActive {
+				product.CurrentStock = newStock
+				deductions = append(deductions, stockDeduction{
+					itemIndex: len(transaction.Items) - 1,
+					productID: product.ID,
+					quantity:  baseQuantity,
+				})
 			}
 
-			// Check for Low Stock Alert (Async)
-			// Calculate remaining stock after deduction
-			newStock := product.CurrentStock - itemInput.Quantity 
-			// Assuming MinStock is available on product or default to 5
+			// Low stock alerts go through the outbox so they are only sent if the sale commits
 			minStock := 5
 			if product.MinStockAlert > 0 {
 				minStock = product.MinStockAlert
 			}
-
 			if newStock <= minStock {
-				// We do this asynchronously so it doesn't block the transaction? 
-				// Actually, we should allow the transaction to commit first. 
-				// Ideally, we'd use the event outbox pattern, but for now, we'll fire it here.
-				// However, if the transaction rolls back, we might have sent a fake alert?
-				// Since we are using Asynq, we can just enqueue the task. 
-				// BUT: If s.db.WithTransaction fails, this enqueue still happened? 
-				// No, ideally we enqueue AFTER commit.
-				// But we are inside the closure. 
-				// Let's create a list of post-commit actions.
-				// For now, simple implementation: Enqueue here. If tx fails, stock isn't updated, but alert is sent.
-				// This is a known trade-off. Correct way: Return list of alerts to send after commit.
-				// However, let's keep it simple as requested.
-				if s.notificationSvc != nil {
-					_ = s.notificationSvc.EnqueueLowStock(product.ID.String(), product.Name, newStock, minStock)
-				}
+				lowStockAlerts = append(lowStockAlerts, queue.PayloadLowStock{
+					ProductID:    product.ID.String(),
+					ProductName:  product.Name,
+					CurrentStock: newStock,
+					MinStock:     minStock,
+				})
 			}
 
 			// Check if Refillable
@@ -223,8 +369,8 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, input domain
 			}
 			if container != nil {
 				// Swap logic: Full -Qty, Empty +Qty
-				emptyChange := itemInput.Quantity
-				fullChange := -itemInput.Quantity
+				emptyChange := baseQuantity
+				fullChange := -baseQuantity
 				
 				if err := s.refillableRepo.UpdateContainerStock(ctx, tx, container.ID, emptyChange, fullChange); err != nil {
 					return fmt.Errorf("failed to update container stock: %w", err)
@@ -252,35 +398,60 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, input domain
 		if input.DiscountAmount != nil {
 			transaction.DiscountAmount = *input.DiscountAmount
 		}
-		transaction.TaxAmount = 0
-		if input.TaxAmount != nil {
-			transaction.TaxAmount = *input.TaxAmount
+		if transaction.DiscountAmount < 0 || transaction.DiscountAmount > subtotal {
+			return fmt.Errorf("%w: discount_amount must be between 0 and the subtotal", domain.ErrInvalidInput)
 		}
-		transaction.TotalAmount = subtotal - transaction.DiscountAmount + transaction.TaxAmount
 
-		// Calculate change
-		if input.PaymentMethod == domain.PaymentMethodCash {
-			if input.AmountPaid < transaction.TotalAmount {
-				return domain.ErrInvalidPaymentAmount
-			}
-			transaction.ChangeAmount = input.AmountPaid - transaction.TotalAmount
+		// Tax is always computed server-side; a client-supplied amount is only a cross-check
+		transaction.TaxAmount = taxConfig.Calculate(subtotal, taxable, transaction.DiscountAmount)
+		transaction.TaxInclusive = taxConfig.Inclusive
+		if input.TaxAmount != nil && *input.TaxAmount != transaction.TaxAmount {
+			return fmt.Errorf("%w: tax_amount %d does not match calculated tax %d", domain.ErrInvalidInput, *input.TaxAmount, transaction.TaxAmount)
+		}
+		transaction.TotalAmount = taxConfig.Total(subtotal, transaction.DiscountAmount, transaction.TaxAmount)
+
+		// Validate tenders, calculate change and split the total into payment lines
+		kasbonAmount, err := settlePayment(transaction, input)
+		if err != nil {
+			return err
+		}
+		if kasbonAmount > 0 && customer == nil {
+			return fmt.Errorf("%w: customer is required for kasbon payment", domain.ErrInvalidInput)
 		}
 
 		// Create transaction record
-		if err := s.transactionRepo.Create(ctx, tx, transaction); err != nil {
+		if err := s.transactionRepo.Create(ctx, tx, transaction, settings.InvoicePrefix); err != nil {
 			return err
 		}
 
+		// Deduct stock and book the actual cost of the units sold
+		for _, d := range deductions {
+			cogs, err := s.inventoryRepo.DeductStock(ctx, tx, d.productID, transaction.ID, d.quantity, settings.CostingMethod, input.CashierName)
+			if err != nil {
+				return err
+			}
+			item := &transaction.Items[d.itemIndex]
+			item.COGSAmount = cogs
+			item.CostPrice = domain.DivRound(cogs, int64(d.quantity))
+			if err := s.transactionRepo.UpdateItemCost(ctx, tx, item.ID, item.CostPrice, item.COGSAmount); err != nil {
+				return err
+			}
+		}
+
 		// Handle kasbon
-		if input.PaymentMethod == domain.PaymentMethodKasbon {
-			// Check credit limit
-			if !customer.CanAddDebt(transaction.TotalAmount) {
+		if kasbonAmount > 0 {
+			// Check the credit limit against the locked row, so two kasbon sales for the same
+			// customer cannot both pass on the same outstanding debt
+			locked, err := s.customerRepo.GetForUpdate(ctx, tx, customer.ID)
+			if err != nil {
+				return err
+			}
+			if !locked.CanAddDebt(kasbonAmount) {
 				return domain.ErrCreditLimitExceeded
 			}
 
 			// Create kasbon record
-			_, err := s.kasbonRepo.CreateDebt(ctx, tx, *input.CustomerID, &transaction.ID, transaction.TotalAmount, input.Notes, input.CashierName)
-			if err != nil {
+			if _, err := s.kasbonRepo.CreateDebt(ctx, tx, *input.CustomerID, &transaction.ID, kasbonAmount, input.Notes, input.CashierName); err != nil {
 				return err
 			}
 		}
@@ -293,56 +464,417 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, input domain
 			}
 		}
 
-		return nil
+		// Post-commit side effects, keyed by transaction so a relay retry never duplicates them
+		for _, alert := range lowStockAlerts {
+			key := fmt.Sprintf("low_stock:%s:%s", transaction.ID, alert.ProductID)
+			if err := s.outboxSvc.Add(ctx, tx, queue.TypeLowStockAlert, key, alert); err != nil {
+				return err
+			}
+		}
+		// An offline sale from a day that has closed changes that day's rollup
+		if transaction.ClientCreatedAt != nil {
+			if err := queueRollup(ctx, tx, s.outboxSvc, *transaction.ClientCreatedAt, "daily_rollup:sync:"+transaction.ID.String()); err != nil {
+				return err
+			}
+		}
+		cashierName := ""
+		if input.CashierName != nil {
+			cashierName = *input.CashierName
+		}
+		return s.outboxSvc.Add(ctx, tx, queue.TypeNewTransaction, "new_transaction:"+transaction.ID.String(), queue.PayloadNewTransaction{
+			TransactionID: transaction.ID.String(),
+			Amount:        transaction.TotalAmount,
+			CashierName:   cashierName,
+		})
 	})
 
 	if err != nil {
 		return nil, err
 	}
+	s.outboxSvc.Kick()
 
 	// Reload transaction with all relations
 	return s.transactionRepo.GetByID(ctx, transaction.ID)
 }
 
-// CancelTransaction cancels a transaction
-func (s *TransactionService) CancelTransaction(ctx context.Context, id uuid.UUID) error {
+// maxSyncBatch bounds how many offline sales a single push may replay
+const maxSyncBatch = 200
+
+const (
+	// maxOfflineAge is how long ago an offline sale may have been made to still be replayed
+	maxOfflineAge = 30 * 24 * time.Hour
+	// maxClockSkew is how far ahead of the server a device clock may run
+	maxClockSkew = 5 * time.Minute
+)
+
+// ReplayOffline checks out sales recorded while the device was offline, in the order given.
+// Each sale runs in its own database transaction, so a sale that no longer fits the current
+// stock, product or credit state is reported as a conflict without failing the rest.
+// Sales pushed before (same client ID) are reported as duplicates. An unexpected error stops
+// the replay; the remaining sales are returned as not processed so order is preserved.
+func (s *TransactionService) ReplayOffline(ctx context.Context, input domain.SyncPushInput, cashierName string) (*domain.SyncPushResult, error) {
+	if len(input.Transactions) > maxSyncBatch {
+		return nil, fmt.Errorf("%w: at most %d transactions per push", domain.ErrInvalidInput, maxSyncBatch)
+	}
+	seen := make(map[uuid.UUID]bool, len(input.Transactions))
+	for _, item := range input.Transactions {
+		if item.ClientID == uuid.Nil {
+			return nil, fmt.Errorf("%w: client_id is required", domain.ErrInvalidInput)
+		}
+		if seen[item.ClientID] {
+			return nil, fmt.Errorf("%w: client_id %s appears more than once", domain.ErrInvalidInput, item.ClientID)
+		}
+		seen[item.ClientID] = true
+	}
+
+	result := &domain.SyncPushResult{Results: make([]domain.SyncPushItemResult, 0, len(input.Transactions))}
+	for i, item := range input.Transactions {
+		itemResult, err := s.replayOfflineTransaction(ctx, item, cashierName)
+		if err != nil {
+			log.Printf("Offline sync stopped at %s: %v", item.ClientID, err)
+			for _, rest := range input.Transactions[i:] {
+				result.Results = append(result.Results, domain.SyncPushItemResult{
+					ClientID: rest.ClientID,
+					Status:   domain.SyncItemNotProcessed,
+				})
+			}
+			break
+		}
+
+		switch itemResult.Status {
+		case domain.SyncItemCreated:
+			result.Created++
+		case domain.SyncItemDuplicate:
+			result.Duplicates++
+		case domain.SyncItemConflict:
+			result.Conflicts++
+		}
+		result.Results = append(result.Results, itemResult)
+	}
+
+	return result, nil
+}
+
+func (s *TransactionService) replayOfflineTransaction(ctx context.Context, item domain.OfflineTransactionInput, cashierName string) (domain.SyncPushItemResult, error) {
+	result := domain.SyncPushItemResult{ClientID: item.ClientID}
+
+	existing, err := s.transactionRepo.GetByClientID(ctx, item.ClientID)
+	if err == nil {
+		result.Status = domain.SyncItemDuplicate
+		result.TransactionID = &existing.ID
+		result.InvoiceNumber = &existing.InvoiceNumber
+		return result, nil
+	}
+	if err != domain.ErrNotFound {
+		return result, err
+	}
+
+	// The sale is booked at the time the device made it, which must be plausible
+	if at := item.ClientCreatedAt; at != nil {
+		now := time.Now()
+		if at.After(now.Add(maxClockSkew)) || at.Before(now.Add(-maxOfflineAge)) {
+			result.Status = domain.SyncItemConflict
+			result.Conflict = &domain.SyncConflict{
+				Code:    domain.SyncConflictInvalid,
+				Message: fmt.Sprintf("client_created_at must be within the last %d days and not in the future", int(maxOfflineAge.Hours()/24)),
+			}
+			return result, nil
+		}
+	}
+
+	input := item.Transaction
+	input.ClientID = &item.ClientID
+	input.ClientCreatedAt = item.ClientCreatedAt
+	if input.CashierName == nil && cashierName != "" {
+		input.CashierName = &cashierName
+	}
+	for _, line := range input.Items {
+		if line.ProductID == uuid.Nil || line.Quantity < 1 {
+			result.Status = domain.SyncItemConflict
+			result.Conflict = &domain.SyncConflict{
+				Code:    domain.SyncConflictInvalid,
+				Message: "every item needs a product_id and a quantity of at least 1",
+			}
+			return result, nil
+		}
+	}
+
+	transaction, err := s.CreateTransaction(ctx, input)
+	if err != nil {
+		if conflict := syncConflict(err); conflict != nil {
+			result.Status = domain.SyncItemConflict
+			result.Conflict = conflict
+			return result, nil
+		}
+		// A concurrent push of the same sale loses on the unique client_id
+		if existing, lookupErr := s.transactionRepo.GetByClientID(ctx, item.ClientID); lookupErr == nil {
+			result.Status = domain.SyncItemDuplicate
+			result.TransactionID = &existing.ID
+			result.InvoiceNumber = &existing.InvoiceNumber
+			return result, nil
+		}
+		return result, err
+	}
+
+	result.Status = domain.SyncItemCreated
+	result.TransactionID = &transaction.ID
+	result.InvoiceNumber = &transaction.InvoiceNumber
+	return result, nil
+}
+
+// syncConflict maps a checkout error to a conflict the device can show the cashier,
+// or nil when the error is not caused by the sale itself
+func syncConflict(err error) *domain.SyncConflict {
+	var code domain.SyncConflictCode
+	switch {
+	case errors.Is(err, domain.ErrInsufficientStock):
+		code = domain.SyncConflictInsufficientStock
+	case errors.Is(err, domain.ErrProductInactive):
+		code = domain.SyncConflictProductInactive
+	case errors.Is(err, domain.ErrCreditLimitExceeded):
+		code = domain.SyncConflictCreditLimit
+	case errors.Is(err, domain.ErrCustomerInactive):
+		code = domain.SyncConflictCustomerInactive
+	case errors.Is(err, domain.ErrNotFound):
+		code = domain.SyncConflictNotFound
+	case errors.Is(err, domain.ErrEmptyCart),
+		errors.Is(err, domain.ErrInvalidPaymentAmount),
+		errors.Is(err, domain.ErrInvalidInput):
+		code = domain.SyncConflictInvalid
+	default:
+		return nil
+	}
+	return &domain.SyncConflict{Code: code, Message: err.Error()}
+}
+
+// PrintReceipt loads a transaction for its receipt and counts the print,
+// so reprints can be marked on paper
+func (s *TransactionService) PrintReceipt(ctx context.Context, id uuid.UUID) (*domain.Transaction, *domain.StoreSettings, error) {
 	transaction, err := s.transactionRepo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	settings, err := s.settingsSvc.Store(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if transaction.Status == domain.TransactionStatusCancelled {
-		return domain.ErrTransactionCancelled
+	count, printedAt, err := s.transactionRepo.RecordReceiptPrint(ctx, id)
+	if err != nil {
+		return nil, nil, err
 	}
+	transaction.ReceiptPrintCount = count
+	transaction.LastPrintedAt = &printedAt
+
+	return transaction, settings, nil
+}
+
+// CancelTransaction voids a sale and undoes its effects in one database transaction:
+// stock comes back as return movements, kasbon is taken back with a reversal record,
+// and refillable container swaps are reversed. The reason and cancelling user are
+// stored on the transaction, and a sale from a closed day queues that day's summary
+// to be rolled up again.
+func (s *TransactionService) CancelTransaction(ctx context.Context, id uuid.UUID, input domain.TransactionCancelInput) error {
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return fmt.Errorf("%w: reason is required", domain.ErrInvalidInput)
+	}
+	var cancelledBy *string
+	if input.CancelledBy != "" {
+		cancelledBy = &input.CancelledBy
+	}
+	notes := "Transaction cancelled: " + reason
 
 	return s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
-		// Restore stock for each item
+		// Lock the sale first so two cancellations cannot both restore its stock
+		locked, err := s.transactionRepo.GetForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		switch locked.Status {
+		case domain.TransactionStatusCancelled:
+			return domain.ErrTransactionCancelled
+		case domain.TransactionStatusRefunded:
+			return fmt.Errorf("%w: refunded transactions cannot be cancelled", domain.ErrInvalidStatusTransition)
+		}
+
+		// A refund already returned part of the sale; cancelling would restock and credit it twice.
+		// Refunds lock the sale too, so none can be created while this check holds.
+		hasRefunds, err := s.transactionRepo.HasOpenRefunds(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if hasRefunds {
+			return fmt.Errorf("%w: transactions with pending, approved or completed refunds cannot be cancelled", domain.ErrInvalidStatusTransition)
+		}
+
+		transaction, err := s.transactionRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// Lock the products in ID order, the same order checkout uses
+		productIDs := make([]uuid.UUID, 0, len(transaction.Items))
 		for _, item := range transaction.Items {
-			product, err := s.productRepo.GetByID(ctx, item.ProductID)
-			if err != nil {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		if _, err := s.productRepo.GetByIDsForUpdate(ctx, tx, productIDs); err != nil {
+			return err
+		}
+
+		// Restore stock for each item as a return movement referencing the sale
+		for _, item := range transaction.Items {
+			_, err := s.inventoryRepo.ReturnStock(ctx, tx, item.ProductID, item.Quantity, id, "transaction", &id, &notes, cancelledBy)
+			if err == domain.ErrNotFound {
 				continue
 			}
-
-			if product.IsStockActive {
-				newStock := product.CurrentStock + item.Quantity
-				if err := s.productRepo.UpdateStock(ctx, product.ID, newStock); err != nil {
-					return err
-				}
+			if err != nil {
+				return err
 			}
 		}
 
-		// Update transaction status
-		if err := s.transactionRepo.UpdateStatus(ctx, id, domain.TransactionStatusCancelled); err != nil {
-			return err
+		// Undo the full/empty swaps recorded for the sale
+		sold, err := s.refillableRepo.GetMovementsByReference(ctx, tx, domain.ContainerMovementSaleExchange, "transaction", id)
+		if err != nil {
+			return fmt.Errorf("failed to get container movements: %w", err)
+		}
+		for _, m := range sold {
+			refType := "transaction"
+			reversal := &domain.ContainerMovement{
+				ContainerID:   m.ContainerID,
+				Type:          domain.ContainerMovementSaleCancel,
+				EmptyChange:   -m.EmptyChange,
+				FullChange:    -m.FullChange,
+				ReferenceType: &refType,
+				ReferenceID:   &id,
+				Notes:         &notes,
+				CreatedBy:     cancelledBy,
+			}
+			if err := s.refillableRepo.RecordMovement(ctx, tx, reversal); err != nil {
+				return fmt.Errorf("failed to record container movement: %w", err)
+			}
+			if reversal.EmptyAfter < 0 || reversal.FullAfter < 0 {
+				return fmt.Errorf("%w: not enough empty containers left to reverse the exchange", domain.ErrInsufficientStock)
+			}
+			if err := s.refillableRepo.UpdateContainerStock(ctx, tx, m.ContainerID, reversal.EmptyChange, reversal.FullChange); err != nil {
+				return fmt.Errorf("failed to update container stock: %w", err)
+			}
 		}
 
-		// Handle kasbon reversal if applicable
-		if transaction.PaymentMethod == domain.PaymentMethodKasbon && transaction.CustomerID != nil {
-			if err := s.customerRepo.SubtractDebt(ctx, *transaction.CustomerID, transaction.TotalAmount); err != nil {
+		// Take back the debt charged to kasbon; any part the customer already paid off
+		// is no longer outstanding and has to be settled outside kasbon
+		if kasbonAmount := transaction.KasbonAmount(); kasbonAmount > 0 && transaction.CustomerID != nil {
+			if _, err := s.kasbonRepo.CreateReversal(ctx, tx, *transaction.CustomerID, &id, kasbonAmount, &notes, cancelledBy); err != nil {
 				return err
 			}
 		}
 
-		return nil
+		// A sale from a closed day changes that day's rollup
+		if err := queueRollup(ctx, tx, s.outboxSvc, transaction.CreatedAt, "daily_rollup:cancel:"+id.String()); err != nil {
+			return err
+		}
+
+		return s.transactionRepo.MarkCancelled(ctx, tx, id, reason, cancelledBy)
 	})
 }
+
+// settlePayment validates the tenders against the transaction total and fills in
+// amount paid, change and the payment lines. It returns the amount to charge to kasbon.
+//
+// Single-method payments become one line for the full total. A mixed payment takes
+// a list of tenders: non-cash tenders may not exceed the total, change only comes
+// out of cash, and a kasbon tender must cover exactly what the other tenders leave unpaid.
+func settlePayment(transaction *domain.Transaction, input domain.TransactionCreateInput) (int64, error) {
+	total := transaction.TotalAmount
+
+	switch input.PaymentMethod {
+	case domain.PaymentMethodCash:
+		if input.AmountPaid < total {
+			return 0, domain.ErrInvalidPaymentAmount
+		}
+		transaction.ChangeAmount = input.AmountPaid - total
+		transaction.Payments = paymentLines(domain.TransactionPayment{Method: domain.PaymentMethodCash, Amount: total})
+		return 0, nil
+
+	case domain.PaymentMethodKasbon:
+		transaction.Payments = paymentLines(domain.TransactionPayment{Method: domain.PaymentMethodKasbon, Amount: total})
+		return total, nil
+
+	case domain.PaymentMethodTransfer, domain.PaymentMethodQRIS:
+		transaction.Payments = paymentLines(domain.TransactionPayment{Method: input.PaymentMethod, Amount: total})
+		return 0, nil
+
+	case domain.PaymentMethodMixed:
+		return settleMixedPayment(transaction, input.Payments)
+	}
+
+	return 0, fmt.Errorf("%w: unsupported payment method %q", domain.ErrInvalidInput, input.PaymentMethod)
+}
+
+func settleMixedPayment(transaction *domain.Transaction, tenders []domain.TenderInput) (int64, error) {
+	if len(tenders) < 2 {
+		return 0, fmt.Errorf("%w: mixed payment requires at least two tenders", domain.ErrInvalidPaymentAmount)
+	}
+
+	total := transaction.TotalAmount
+	var cash, nonCash, kasbon int64
+	kasbonTenders := 0
+	var lines []domain.TransactionPayment
+
+	for _, tender := range tenders {
+		if tender.Amount <= 0 {
+			return 0, fmt.Errorf("%w: tender amount must be positive", domain.ErrInvalidPaymentAmount)
+		}
+		switch tender.Method {
+		case domain.PaymentMethodCash:
+			cash += tender.Amount
+		case domain.PaymentMethodTransfer, domain.PaymentMethodQRIS:
+			nonCash += tender.Amount
+			lines = append(lines, domain.TransactionPayment{Method: tender.Method, Amount: tender.Amount, Reference: tender.Reference})
+		case domain.PaymentMethodKasbon:
+			kasbon += tender.Amount
+			kasbonTenders++
+		default:
+			return 0, fmt.Errorf("%w: invalid tender method %q", domain.ErrInvalidPaymentAmount, tender.Method)
+		}
+	}
+
+	if kasbonTenders > 1 {
+		return 0, fmt.Errorf("%w: only one kasbon tender is allowed", domain.ErrInvalidPaymentAmount)
+	}
+	if nonCash > total {
+		return 0, fmt.Errorf("%w: non-cash tenders exceed the total of %d", domain.ErrInvalidPaymentAmount, total)
+	}
+
+	paid := cash + nonCash
+	if kasbonTenders == 1 {
+		if paid >= total || kasbon != total-paid {
+			return 0, fmt.Errorf("%w: kasbon may only cover the unpaid remainder of %d", domain.ErrInvalidPaymentAmount, max(total-paid, 0))
+		}
+	} else if paid < total {
+		return 0, domain.ErrInvalidPaymentAmount
+	}
+
+	// Change can only be handed back from cash, and nonCash <= total guarantees it fits
+	change := max(paid-total, 0)
+	transaction.AmountPaid = paid
+	transaction.ChangeAmount = change
+
+	transaction.Payments = paymentLines(
+		domain.TransactionPayment{Method: domain.PaymentMethodCash, Amount: cash - change},
+		domain.TransactionPayment{Method: domain.PaymentMethodKasbon, Amount: kasbon},
+	)
+	transaction.Payments = append(transaction.Payments, lines...)
+	return kasbon, nil
+}
+
+// paymentLines drops zero-amount lines, which the payments table does not accept
+func paymentLines(payments ...domain.TransactionPayment) []domain.TransactionPayment {
+	lines := make([]domain.TransactionPayment, 0, len(payments))
+	for _, p := range payments {
+		if p.Amount > 0 {
+			lines = append(lines, p)
+		}
+	}
+	return lines
+}
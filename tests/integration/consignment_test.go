@@ -0,0 +1,140 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// TestConsignmentSettlement sells a consignor's product with an order discount, refunds part of it,
+// then generates, confirms and pays the settlement
+func TestConsignmentSettlement(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	posSvc := newTestPOSService(db, productRepo)
+	consignmentRepo := repository.NewConsignmentRepository(db)
+	consignmentSvc := service.NewConsignmentService(db, consignmentRepo,
+		repository.NewTransactionRepository(db), repository.NewCashFlowRepository(db))
+	ctx := context.Background()
+
+	consignor, err := consignmentRepo.CreateConsignor(ctx, domain.CreateConsignorInput{
+		Name: "Settlement Test " + uuid.New().String()[:8],
+	})
+	if err != nil {
+		t.Fatalf("Failed to create consignor: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM consignors WHERE id = $1", consignor.ID); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+
+	product := createStockedProduct(t, db, productRepo, 20)
+	if _, err := db.ExecContext(ctx, "UPDATE products SET consignor_id = $1, commission_rate = 10 WHERE id = $2",
+		consignor.ID, product.ID); err != nil {
+		t.Fatalf("Failed to assign consignor: %v", err)
+	}
+
+	// 10 units at 1000 less a 1000 order discount
+	input := cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 10})
+	discount := int64(1000)
+	input.DiscountAmount = &discount
+	sale, err := svc.CreateTransaction(ctx, input)
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	// The refund pays back 2 units at 900, but they were counted as sold at 1000
+	refund := requestRefund(t, db, posSvc, sale.ID, domain.RefundItemInput{TransactionItemID: sale.Items[0].ID, Quantity: 2})
+	if _, err := posSvc.ApproveRefund(ctx, refund.ID, "tester"); err != nil {
+		t.Fatalf("Failed to approve refund: %v", err)
+	}
+	if _, err := posSvc.CompleteRefund(ctx, refund.ID, "tester"); err != nil {
+		t.Fatalf("Failed to complete refund: %v", err)
+	}
+
+	today := time.Now()
+	settlement, err := consignmentSvc.GenerateSettlement(ctx, domain.GenerateSettlementInput{
+		ConsignorID: consignor.ID,
+		PeriodStart: &today,
+		PeriodEnd:   &today,
+		CreatedBy:   "tester",
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate settlement: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, query := range []string{
+			"DELETE FROM cash_flow_records WHERE reference_type = 'consignment_settlement' AND reference_id = $1",
+			"DELETE FROM consignment_settlements WHERE id = $1",
+		} {
+			if _, err := db.ExecContext(ctx, query, settlement.ID); err != nil {
+				t.Logf("Cleanup failed: %v", err)
+			}
+		}
+	})
+
+	if settlement.Status != domain.SettlementStatusDraft || len(settlement.Items) != 1 {
+		t.Fatalf("Expected a draft settlement with one line, got %s with %d", settlement.Status, len(settlement.Items))
+	}
+	item := settlement.Items[0]
+	if item.QuantitySold != 8 || item.TotalSales != 8000 {
+		t.Errorf("Expected 8 sold for 8000, got %d for %d", item.QuantitySold, item.TotalSales)
+	}
+	if settlement.CommissionAmount != 800 || settlement.ConsignorAmount != 7200 {
+		t.Errorf("Expected commission 800 and 7200 owed, got %d and %d", settlement.CommissionAmount, settlement.ConsignorAmount)
+	}
+
+	// A draft cannot be paid before it is confirmed
+	if _, err := consignmentSvc.PaySettlement(ctx, settlement.ID, "tester"); !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Fatalf("Expected paying a draft to be rejected, got %v", err)
+	}
+
+	confirmed, err := consignmentSvc.ConfirmSettlement(ctx, settlement.ID)
+	if err != nil {
+		t.Fatalf("Failed to confirm settlement: %v", err)
+	}
+	if confirmed.Status != domain.SettlementStatusConfirmed {
+		t.Errorf("Expected status confirmed, got %s", confirmed.Status)
+	}
+
+	paid, err := consignmentSvc.PaySettlement(ctx, settlement.ID, "tester")
+	if err != nil {
+		t.Fatalf("Failed to pay settlement: %v", err)
+	}
+	if paid.Status != domain.SettlementStatusPaid || paid.PaidBy == nil || *paid.PaidBy != "tester" {
+		t.Errorf("Expected status paid by tester, got %s", paid.Status)
+	}
+
+	var payout int64
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM cash_flow_records
+		WHERE type = 'expense' AND reference_type = 'consignment_settlement' AND reference_id = $1
+	`, settlement.ID).Scan(&payout); err != nil {
+		t.Fatalf("Failed to read cash flow: %v", err)
+	}
+	if payout != 7200 {
+		t.Errorf("Expected a payout expense of 7200, got %d", payout)
+	}
+
+	if _, err := consignmentSvc.PaySettlement(ctx, settlement.ID, "tester"); !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Errorf("Expected paying twice to be rejected, got %v", err)
+	}
+
+	// The paid period cannot be settled again
+	if _, err := consignmentSvc.GenerateSettlement(ctx, domain.GenerateSettlementInput{
+		ConsignorID: consignor.ID,
+		PeriodStart: &today,
+		PeriodEnd:   &today,
+		CreatedBy:   "tester",
+	}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected an overlapping settlement to be rejected, got %v", err)
+	}
+}
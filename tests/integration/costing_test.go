@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// TestCosting_AverageAndFIFO receives two purchases at different costs and prices the same sale
+// under both costing methods
+func TestCosting_AverageAndFIFO(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	_, productRepo := newTestTransactionService(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	product := createStockedProduct(t, db, productRepo, 0)
+	ctx := context.Background()
+
+	for _, cost := range []int64{1000, 2000} {
+		if _, err := inventoryRepo.Restock(ctx, domain.RestockInput{
+			ProductID:   product.ID,
+			Quantity:    5,
+			CostPerUnit: cost,
+		}); err != nil {
+			t.Fatalf("Failed to restock: %v", err)
+		}
+	}
+
+	reloaded, err := productRepo.GetByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload product: %v", err)
+	}
+	if reloaded.CostPrice != 1500 {
+		t.Errorf("Expected average cost 1500, got %d", reloaded.CostPrice)
+	}
+
+	for method, want := range map[domain.CostingMethod]int64{
+		domain.CostingAverage: 7 * 1500,
+		domain.CostingFIFO:    5*1000 + 2*2000,
+	} {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		// The movement needs no real sale; the transaction is rolled back
+		cogs, err := inventoryRepo.DeductStock(ctx, tx, product.ID, product.ID, 7, method, nil)
+		tx.Rollback()
+		if err != nil {
+			t.Fatalf("Failed to deduct stock: %v", err)
+		}
+		if cogs != want {
+			t.Errorf("Expected %s COGS %d, got %d", method, want, cogs)
+		}
+	}
+}
+
+// TestCosting_OpeningStock creates a product with stock and checks FIFO sells it at its cost price
+// before a later, dearer purchase, and that the stock is in a lot
+func TestCosting_OpeningStock(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	_, productRepo := newTestTransactionService(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	product := createStockedProduct(t, db, productRepo, 5)
+	ctx := context.Background()
+
+	batches, err := inventoryRepo.ListBatches(ctx, product.ID, false)
+	if err != nil {
+		t.Fatalf("Failed to list batches: %v", err)
+	}
+	if len(batches) != 1 || batches[0].RemainingQuantity != 5 {
+		t.Errorf("Expected one lot of 5 for the opening stock, got %+v", batches)
+	}
+
+	if _, err := inventoryRepo.Restock(ctx, domain.RestockInput{
+		ProductID:   product.ID,
+		Quantity:    5,
+		CostPerUnit: 2000,
+	}); err != nil {
+		t.Fatalf("Failed to restock: %v", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	cogs, err := inventoryRepo.DeductStock(ctx, tx, product.ID, product.ID, 7, domain.CostingFIFO, nil)
+	tx.Rollback()
+	if err != nil {
+		t.Fatalf("Failed to deduct stock: %v", err)
+	}
+	if want := int64(5*800 + 2*2000); cogs != want {
+		t.Errorf("Expected FIFO COGS %d, got %d", want, cogs)
+	}
+}
@@ -0,0 +1,148 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/platform/queue"
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// TestDailySummary_BackDatedCancelRerunsRollup reads a closed day from the rollup and checks
+// that cancelling one of its sales queues a re-run that brings the day up to date
+func TestDailySummary_BackDatedCancelRerunsRollup(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	reportSvc := service.NewReportService(repository.NewTransactionRepository(db), repository.NewCashFlowRepository(db),
+		repository.NewKasbonRepository(db), repository.NewInventoryRepository(db), repository.NewReportRepository(db))
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	if err := reportSvc.RollupDay(ctx, yesterday); err != nil {
+		t.Fatalf("Failed to roll up: %v", err)
+	}
+	before, err := reportSvc.DailySummary(ctx, yesterday)
+	if err != nil {
+		t.Fatalf("Failed to get summary: %v", err)
+	}
+	if before.Live {
+		t.Error("Expected a closed day to be read from the rollup")
+	}
+
+	// A sale recorded yesterday that the rollup has not seen yet
+	sale, err := svc.CreateTransaction(ctx, cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 2}))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE transactions SET created_at = created_at - INTERVAL '1 day' WHERE id = $1", sale.ID); err != nil {
+		t.Fatalf("Failed to back-date transaction: %v", err)
+	}
+	key := "daily_rollup:cancel:" + sale.ID.String()
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM outbox_events WHERE idempotency_key = $1", key); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+		if err := reportSvc.RollupDay(ctx, yesterday); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+
+	if err := reportSvc.RollupDay(ctx, yesterday); err != nil {
+		t.Fatalf("Failed to roll up: %v", err)
+	}
+	withSale, err := reportSvc.DailySummary(ctx, yesterday)
+	if err != nil {
+		t.Fatalf("Failed to get summary: %v", err)
+	}
+	if got := withSale.TotalSales - before.TotalSales; got != 2000 {
+		t.Errorf("Expected the rollup to grow by 2000, got %d", got)
+	}
+	if got := withSale.CashSales - before.CashSales; got != 2000 {
+		t.Errorf("Expected cash sales to grow by 2000, got %d", got)
+	}
+
+	if err := svc.CancelTransaction(ctx, sale.ID, domain.TransactionCancelInput{Reason: "Rollup test"}); err != nil {
+		t.Fatalf("Failed to cancel transaction: %v", err)
+	}
+
+	var payload []byte
+	if err := db.QueryRowContext(ctx,
+		"SELECT payload FROM outbox_events WHERE idempotency_key = $1 AND event_type = $2", key, queue.TypeDailyRollup,
+	).Scan(&payload); err != nil {
+		t.Fatalf("Expected a rollup re-run in the outbox: %v", err)
+	}
+	if err := reportSvc.HandleRollupTask(ctx, asynq.NewTask(queue.TypeDailyRollup, payload)); err != nil {
+		t.Fatalf("Failed to run rollup task: %v", err)
+	}
+
+	after, err := reportSvc.DailySummary(ctx, yesterday)
+	if err != nil {
+		t.Fatalf("Failed to get summary: %v", err)
+	}
+	if after.TotalSales != before.TotalSales || after.TotalTransactions != before.TotalTransactions {
+		t.Errorf("Expected the day back at %d from %d sales, got %d from %d",
+			before.TotalSales, before.TotalTransactions, after.TotalSales, after.TotalTransactions)
+	}
+}
+
+// TestDailySummary_ExcludesTax checks that a closed day's rollup keeps tax out of sales and
+// profit while the tenders still show what was paid
+func TestDailySummary_ExcludesTax(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	useTaxSettings(t, db, "10", "false")
+	svc, productRepo := newTestTransactionService(db)
+	reportSvc := service.NewReportService(repository.NewTransactionRepository(db), repository.NewCashFlowRepository(db),
+		repository.NewKasbonRepository(db), repository.NewInventoryRepository(db), repository.NewReportRepository(db))
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	if err := reportSvc.RollupDay(ctx, yesterday); err != nil {
+		t.Fatalf("Failed to roll up: %v", err)
+	}
+	before, err := reportSvc.DailySummary(ctx, yesterday)
+	if err != nil {
+		t.Fatalf("Failed to get summary: %v", err)
+	}
+
+	sale, err := svc.CreateTransaction(ctx, cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 2}))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE transactions SET created_at = created_at - INTERVAL '1 day' WHERE id = $1", sale.ID); err != nil {
+		t.Fatalf("Failed to back-date transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := reportSvc.RollupDay(ctx, yesterday); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+
+	if err := reportSvc.RollupDay(ctx, yesterday); err != nil {
+		t.Fatalf("Failed to roll up: %v", err)
+	}
+	after, err := reportSvc.DailySummary(ctx, yesterday)
+	if err != nil {
+		t.Fatalf("Failed to get summary: %v", err)
+	}
+
+	// 2 sold at 1000 (cost 800) with 10% tax on top
+	if got := after.TotalSales - before.TotalSales; got != 2000 {
+		t.Errorf("Expected sales to grow by 2000, got %d", got)
+	}
+	if got := after.TotalTax - before.TotalTax; got != 200 {
+		t.Errorf("Expected tax to grow by 200, got %d", got)
+	}
+	if got := after.TotalProfit - before.TotalProfit; got != 400 {
+		t.Errorf("Expected profit to grow by 400, got %d", got)
+	}
+	if got := after.CashSales - before.CashSales; got != 2200 {
+		t.Errorf("Expected cash sales to grow by 2200, got %d", got)
+	}
+}
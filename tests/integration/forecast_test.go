@@ -0,0 +1,81 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// TestForecast_ReorderPointFromHistory sells 2 units a day for the last 14 days and checks both
+// forecast methods and that the product lands on the shopping list
+func TestForecast_ReorderPointFromHistory(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	forecastSvc := service.NewForecastService(transactionRepo, service.NewSettingsService(db, repository.NewSettingsRepository(db)))
+	opnameSvc := service.NewStockOpnameService(db, repository.NewStockOpnameRepository(db), productRepo, inventoryRepo, forecastSvc)
+	product := createStockedProduct(t, db, productRepo, 50)
+	ctx := context.Background()
+
+	for day := 1; day <= 14; day++ {
+		sale, err := svc.CreateTransaction(ctx, cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 2}))
+		if err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+		if _, err := db.ExecContext(ctx,
+			fmt.Sprintf("UPDATE transactions SET created_at = created_at - INTERVAL '%d day' WHERE id = $1", day), sale.ID,
+		); err != nil {
+			t.Fatalf("Failed to back-date transaction: %v", err)
+		}
+	}
+
+	forecast := func(method domain.ForecastMethod) domain.DemandForecast {
+		forecasts, err := forecastSvc.Forecast(ctx, domain.ForecastOptions{Method: method, LeadTimeDays: 2, CoverDays: 7})
+		if err != nil {
+			t.Fatalf("Failed to forecast: %v", err)
+		}
+		f, ok := forecasts[product.ID]
+		if !ok {
+			t.Fatalf("Expected a %s forecast for the product", method)
+		}
+		return f
+	}
+
+	// 28 units over the last 28 days, spread evenly over the weekdays
+	ma := forecast(domain.ForecastMovingAverage)
+	if ma.DailyDemand != 1 || ma.LeadTimeDemand != 2 {
+		t.Errorf("Expected 1 unit a day and 2 over the lead time, got %+v", ma)
+	}
+	if ma.ReorderPoint != 2+ma.SafetyStock || ma.TargetStock != 9+ma.SafetyStock || ma.SafetyStock <= 0 {
+		t.Errorf("Unexpected reorder figures: %+v", ma)
+	}
+
+	// Smoothing follows the recent 2 units a day
+	ses := forecast(domain.ForecastExponentialSmoothing)
+	if math.Abs(ses.DailyDemand-2) > 0.05 {
+		t.Errorf("Expected about 2 units a day, got %+v", ses)
+	}
+
+	if _, err := db.ExecContext(ctx, "UPDATE products SET current_stock = 1 WHERE id = $1", product.ID); err != nil {
+		t.Fatalf("Failed to lower stock: %v", err)
+	}
+	list, err := opnameSvc.GetShoppingList(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get shopping list: %v", err)
+	}
+	for _, item := range list.Items {
+		if item.ProductID == product.ID {
+			if item.Forecast == nil || item.SuggestedQty < item.Forecast.TargetStock-1 {
+				t.Errorf("Expected a forecast-based order, got %+v", item)
+			}
+			return
+		}
+	}
+	t.Error("Expected the product on the shopping list")
+}
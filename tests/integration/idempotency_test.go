@@ -0,0 +1,121 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/middleware"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// newIdempotencyKey returns a fresh key that is removed after the test
+func newIdempotencyKey(t *testing.T, db *database.PostgresDB) string {
+	key := "test-" + uuid.New().String()
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(context.Background(), "DELETE FROM idempotency_keys WHERE idempotency_key = $1", key); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+	return key
+}
+
+// idempotentPost sends a POST with the given key and body through handler
+func idempotentPost(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transactions", strings.NewReader(body))
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestIdempotency_ReplaysStoredResponse retries a request and checks the handler runs once
+// and the retry gets the first response back
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	key := newIdempotencyKey(t, db)
+
+	var calls atomic.Int32
+	handler := middleware.Idempotency(repository.NewIdempotencyRepository(db))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"invoice_number":"INV-1"}`))
+	}))
+
+	first := idempotentPost(handler, key, `{"total":5000}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", first.Code)
+	}
+
+	retry := idempotentPost(handler, key, `{"total":5000}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected the retry to replay 201 %s, got %d %s", first.Body, retry.Code, retry.Body)
+	}
+	if retry.Header().Get(middleware.IdempotencyReplayedHeader) != "true" {
+		t.Error("Expected the retry marked as replayed")
+	}
+	if retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected the stored content type replayed, got %q", retry.Header().Get("Content-Type"))
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected the handler to run once, ran %d times", calls.Load())
+	}
+
+	other := idempotentPost(handler, key, `{"total":9000}`)
+	if other.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected the key reused for a different body to be rejected with 422, got %d", other.Code)
+	}
+}
+
+// TestIdempotency_InFlightConflict sends a retry while the first request is still running,
+// then checks that a server error frees the key for another attempt
+func TestIdempotency_InFlightConflict(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	key := newIdempotencyKey(t, db)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	handler := middleware.Idempotency(repository.NewIdempotencyRepository(db))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- idempotentPost(handler, key, `{}`) }()
+	select {
+	case <-started:
+	case first := <-done:
+		t.Fatalf("Expected the first request to reach the handler, got %d %s", first.Code, first.Body)
+	}
+
+	inFlight := idempotentPost(handler, key, `{}`)
+	if inFlight.Code != http.StatusConflict {
+		t.Errorf("Expected 409 while the first request is running, got %d", inFlight.Code)
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected the first request to fail with 500, got %d", first.Code)
+	}
+
+	retry := idempotentPost(handler, key, `{}`)
+	if retry.Code != http.StatusCreated || retry.Header().Get(middleware.IdempotencyReplayedHeader) != "" {
+		t.Errorf("Expected the retry after a server error to run again, got %d", retry.Code)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected the handler to run twice, ran %d times", calls.Load())
+	}
+}
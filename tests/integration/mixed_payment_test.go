@@ -0,0 +1,120 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// TestCreateTransaction_MixedPayment checks how a split payment of a 5000 sale is validated,
+// how change is given and what each tender is booked at
+func TestCreateTransaction_MixedPayment(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	customer := createKasbonCustomer(t, db, 0)
+	product := createStockedProduct(t, db, productRepo, 100)
+	ctx := context.Background()
+
+	tender := func(method domain.PaymentMethod, amount int64) domain.TenderInput {
+		return domain.TenderInput{Method: method, Amount: amount}
+	}
+
+	tests := []struct {
+		name     string
+		tenders  []domain.TenderInput
+		wantErr  error
+		paid     int64
+		change   int64
+		payments map[domain.PaymentMethod]int64
+	}{
+		{
+			name:     "cash change",
+			tenders:  []domain.TenderInput{tender(domain.PaymentMethodQRIS, 2500), tender(domain.PaymentMethodCash, 3000)},
+			paid:     5500,
+			change:   500,
+			payments: map[domain.PaymentMethod]int64{domain.PaymentMethodQRIS: 2500, domain.PaymentMethodCash: 2500},
+		},
+		{
+			name:     "exact split",
+			tenders:  []domain.TenderInput{tender(domain.PaymentMethodTransfer, 1000), tender(domain.PaymentMethodCash, 4000)},
+			paid:     5000,
+			payments: map[domain.PaymentMethod]int64{domain.PaymentMethodTransfer: 1000, domain.PaymentMethodCash: 4000},
+		},
+		{
+			name:     "kasbon remainder",
+			tenders:  []domain.TenderInput{tender(domain.PaymentMethodCash, 2000), tender(domain.PaymentMethodKasbon, 3000)},
+			paid:     2000,
+			payments: map[domain.PaymentMethod]int64{domain.PaymentMethodCash: 2000, domain.PaymentMethodKasbon: 3000},
+		},
+		{
+			name:    "kasbon more than the remainder",
+			tenders: []domain.TenderInput{tender(domain.PaymentMethodCash, 2000), tender(domain.PaymentMethodKasbon, 4000)},
+			wantErr: domain.ErrInvalidPaymentAmount,
+		},
+		{
+			name:    "kasbon when already paid",
+			tenders: []domain.TenderInput{tender(domain.PaymentMethodCash, 5000), tender(domain.PaymentMethodKasbon, 1000)},
+			wantErr: domain.ErrInvalidPaymentAmount,
+		},
+		{
+			name:    "non-cash overpayment",
+			tenders: []domain.TenderInput{tender(domain.PaymentMethodQRIS, 6000), tender(domain.PaymentMethodCash, 100)},
+			wantErr: domain.ErrInvalidPaymentAmount,
+		},
+		{
+			name:    "underpayment",
+			tenders: []domain.TenderInput{tender(domain.PaymentMethodCash, 2000), tender(domain.PaymentMethodTransfer, 2000)},
+			wantErr: domain.ErrInvalidPaymentAmount,
+		},
+		{
+			name:    "single tender",
+			tenders: []domain.TenderInput{tender(domain.PaymentMethodCash, 5000)},
+			wantErr: domain.ErrInvalidPaymentAmount,
+		},
+		{
+			name:    "zero tender",
+			tenders: []domain.TenderInput{tender(domain.PaymentMethodCash, 5000), tender(domain.PaymentMethodQRIS, 0)},
+			wantErr: domain.ErrInvalidPaymentAmount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sale, err := svc.CreateTransaction(ctx, domain.TransactionCreateInput{
+				Items:         []domain.TransactionItemInput{{ProductID: product.ID, Quantity: 5}},
+				PaymentMethod: domain.PaymentMethodMixed,
+				Payments:      tt.tenders,
+				CustomerID:    &customer.ID,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create transaction: %v", err)
+			}
+
+			if sale.AmountPaid != tt.paid || sale.ChangeAmount != tt.change {
+				t.Errorf("Expected %d paid with %d change, got %d with %d", tt.paid, tt.change, sale.AmountPaid, sale.ChangeAmount)
+			}
+			got := make(map[domain.PaymentMethod]int64, len(sale.Payments))
+			var booked int64
+			for _, p := range sale.Payments {
+				got[p.Method] += p.Amount
+				booked += p.Amount
+			}
+			for method, amount := range tt.payments {
+				if got[method] != amount {
+					t.Errorf("Expected %s booked at %d, got %d", method, amount, got[method])
+				}
+			}
+			if len(got) != len(tt.payments) || booked != sale.TotalAmount {
+				t.Errorf("Expected tenders %v adding up to %d, got %v", tt.payments, sale.TotalAmount, got)
+			}
+		})
+	}
+}
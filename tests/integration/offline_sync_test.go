@@ -0,0 +1,207 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// offlineSale is one offline sale of a product, made at the given time
+func offlineSale(productID uuid.UUID, quantity int, at *time.Time) domain.OfflineTransactionInput {
+	return domain.OfflineTransactionInput{
+		ClientID:        uuid.New(),
+		ClientCreatedAt: at,
+		Transaction:     cashSale(domain.TransactionItemInput{ProductID: productID, Quantity: quantity}),
+	}
+}
+
+// TestReplayOffline_DuplicateClientID pushes the same sale twice and checks it is only sold once
+func TestReplayOffline_DuplicateClientID(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	sale := offlineSale(product.ID, 2, nil)
+	first, err := svc.ReplayOffline(ctx, domain.SyncPushInput{Transactions: []domain.OfflineTransactionInput{sale}}, "tester")
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if first.Created != 1 || first.Results[0].Status != domain.SyncItemCreated {
+		t.Fatalf("Expected the sale created, got %+v", first.Results[0])
+	}
+
+	second, err := svc.ReplayOffline(ctx, domain.SyncPushInput{Transactions: []domain.OfflineTransactionInput{sale}}, "tester")
+	if err != nil {
+		t.Fatalf("Failed to push again: %v", err)
+	}
+	got := second.Results[0]
+	if second.Duplicates != 1 || got.Status != domain.SyncItemDuplicate {
+		t.Fatalf("Expected the second push reported as a duplicate, got %+v", got)
+	}
+	if got.TransactionID == nil || *got.TransactionID != *first.Results[0].TransactionID {
+		t.Errorf("Expected the duplicate to point at transaction %s, got %v", *first.Results[0].TransactionID, got.TransactionID)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 8 {
+		t.Errorf("Expected stock 8 after one sale of 2, got %d", stock)
+	}
+
+	_, err = svc.ReplayOffline(ctx, domain.SyncPushInput{Transactions: []domain.OfflineTransactionInput{sale, sale}}, "tester")
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected a client_id repeated within a push to be rejected, got %v", err)
+	}
+}
+
+// TestReplayOffline_StockConflict pushes two sales that do not both fit the stock and checks
+// that the second is reported as a conflict while the first is kept
+func TestReplayOffline_StockConflict(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	product := createStockedProduct(t, db, productRepo, 3)
+	ctx := context.Background()
+
+	result, err := svc.ReplayOffline(ctx, domain.SyncPushInput{Transactions: []domain.OfflineTransactionInput{
+		offlineSale(product.ID, 2, nil),
+		offlineSale(product.ID, 2, nil),
+	}}, "tester")
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if result.Created != 1 || result.Conflicts != 1 {
+		t.Fatalf("Expected one sale created and one conflict, got %+v", result)
+	}
+	conflict := result.Results[1].Conflict
+	if conflict == nil || conflict.Code != domain.SyncConflictInsufficientStock {
+		t.Errorf("Expected an insufficient_stock conflict, got %+v", conflict)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 1 {
+		t.Errorf("Expected stock 1, got %d", stock)
+	}
+}
+
+// TestReplayOffline_BatchLimit rejects a push with more sales than one batch may hold
+func TestReplayOffline_BatchLimit(t *testing.T) {
+	svc, _ := newTestTransactionService(nil)
+
+	sales := make([]domain.OfflineTransactionInput, 201)
+	for i := range sales {
+		sales[i] = offlineSale(uuid.New(), 1, nil)
+	}
+	_, err := svc.ReplayOffline(context.Background(), domain.SyncPushInput{Transactions: sales}, "tester")
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected a push of 201 sales to be rejected, got %v", err)
+	}
+}
+
+// TestReplayOffline_BooksSaleAtClientTime checks that an offline sale is dated when it was made,
+// queues its closed day for a rollup, and that implausible times are rejected
+func TestReplayOffline_BooksSaleAtClientTime(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	madeAt := time.Now().AddDate(0, 0, -2).Truncate(time.Second)
+	future := time.Now().Add(time.Hour)
+	stale := time.Now().AddDate(0, 0, -60)
+	result, err := svc.ReplayOffline(ctx, domain.SyncPushInput{Transactions: []domain.OfflineTransactionInput{
+		offlineSale(product.ID, 1, &madeAt),
+		offlineSale(product.ID, 1, &future),
+		offlineSale(product.ID, 1, &stale),
+	}}, "tester")
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if result.Created != 1 || result.Conflicts != 2 {
+		t.Fatalf("Expected one sale created and two rejected, got %+v", result)
+	}
+	for _, r := range result.Results[1:] {
+		if r.Conflict == nil || r.Conflict.Code != domain.SyncConflictInvalid {
+			t.Errorf("Expected an invalid conflict for %s, got %+v", r.ClientID, r.Conflict)
+		}
+	}
+
+	id := *result.Results[0].TransactionID
+	if invoice := *result.Results[0].InvoiceNumber; !strings.Contains(invoice, madeAt.Format("20060102")) {
+		t.Errorf("Expected the invoice numbered on %s, got %s", madeAt.Format("2006-01-02"), invoice)
+	}
+	key := "daily_rollup:sync:" + id.String()
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM outbox_events WHERE idempotency_key = $1", key); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+
+	var createdAt time.Time
+	if err := db.QueryRowContext(ctx, "SELECT created_at FROM transactions WHERE id = $1", id).Scan(&createdAt); err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if !createdAt.Equal(madeAt) {
+		t.Errorf("Expected the sale dated %s, got %s", madeAt, createdAt)
+	}
+
+	var queued int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM outbox_events WHERE idempotency_key = $1", key).Scan(&queued); err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	if queued != 1 {
+		t.Errorf("Expected a rollup of the sale's day queued, got %d", queued)
+	}
+}
+
+// TestReplayOffline_PromotionAtClientTime checks that an offline sale gets the promotions that ran
+// when it was made, not the ones running when it is pushed
+func TestReplayOffline_PromotionAtClientTime(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	// Half price, only on the weekday two days ago
+	madeAt := time.Now().AddDate(0, 0, -2)
+	percent := 50.0
+	promo, err := repository.NewPromotionRepository(db).Create(ctx, domain.PromotionInput{
+		Name:            "Offline Test " + uuid.New().String()[:8],
+		Type:            domain.PromotionTypePercentage,
+		ProductID:       &product.ID,
+		DiscountPercent: &percent,
+		DaysOfWeek:      []int{int(madeAt.Weekday())},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM promotions WHERE id = $1", promo.ID); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+
+	result, err := svc.ReplayOffline(ctx, domain.SyncPushInput{Transactions: []domain.OfflineTransactionInput{
+		offlineSale(product.ID, 2, &madeAt),
+		offlineSale(product.ID, 2, nil),
+	}}, "tester")
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if result.Created != 2 {
+		t.Fatalf("Expected both sales created, got %+v", result)
+	}
+
+	transactionRepo := repository.NewTransactionRepository(db)
+	for i, want := range []int64{1000, 0} {
+		sale, err := transactionRepo.GetByID(ctx, *result.Results[i].TransactionID)
+		if err != nil {
+			t.Fatalf("Failed to read transaction: %v", err)
+		}
+		if got := sale.Items[0].PromotionDiscount; got != want {
+			t.Errorf("Sale %d: expected a promotion discount of %d, got %d", i, want, got)
+		}
+	}
+}
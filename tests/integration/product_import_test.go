@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// TestProductImport rejects a file with bad rows, then imports it fixed and updates by barcode
+func TestProductImport(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	productRepo := repository.NewProductRepository(db)
+	svc := service.NewProductImportService(db, productRepo, repository.NewCategoryRepository(db))
+	ctx := context.Background()
+
+	barcode := "IMPORT-" + uuid.New().String()[:8]
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM products WHERE barcode = $1", barcode); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+
+	header := []string{"Barcode", "Name", "Category", "Unit", "Base_Price", "Pricing_Tiers"}
+	result, err := svc.Import(ctx, [][]string{
+		header,
+		{barcode, "Import Test", "No Such Category " + barcode, "pcs", "3.500", "10+=3000"},
+		{barcode, "Import Test Again", "", "pcs", "3500", ""},
+	}, false)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if result.Applied || len(result.Errors) != 2 {
+		t.Fatalf("Expected an unknown category and a duplicate barcode and nothing written, got %+v", result)
+	}
+
+	rows := [][]string{header, {barcode, "Import Test", "", "pcs", "Rp 3.500,00", "Grosir:10-49=3000; 50+=2800"}}
+	if result, err = svc.Import(ctx, rows, false); err != nil || !result.Applied || result.Created != 1 {
+		t.Fatalf("Expected one product created, got %+v (%v)", result, err)
+	}
+
+	product, err := productRepo.GetByBarcode(ctx, barcode)
+	if err != nil {
+		t.Fatalf("Failed to get imported product: %v", err)
+	}
+	if product.BasePrice != 3500 || len(product.PricingTiers) != 2 {
+		t.Errorf("Expected base price 3500 with 2 tiers, got %d with %d", product.BasePrice, len(product.PricingTiers))
+	}
+
+	rows[1][4], rows[1][5] = "3700", ""
+	if result, err = svc.Import(ctx, rows, false); err != nil || result.Updated != 1 {
+		t.Fatalf("Expected the product updated by barcode, got %+v (%v)", result, err)
+	}
+	if product, err = productRepo.GetByBarcode(ctx, barcode); err != nil {
+		t.Fatalf("Failed to get imported product: %v", err)
+	}
+	if product.BasePrice != 3700 || len(product.PricingTiers) != 0 {
+		t.Errorf("Expected base price 3700 without tiers, got %d with %d", product.BasePrice, len(product.PricingTiers))
+	}
+
+	result, err = svc.Import(ctx, [][]string{{"barcode", "name"}}, true)
+	if err != nil || len(result.Errors) != 2 {
+		t.Errorf("Expected the missing unit and base_price columns reported, got %+v (%v)", result, err)
+	}
+}
+
+// TestProductImport_ReimportsExportWithoutBarcode exports a product that only has a SKU and a
+// formula-like name, and checks that importing the export updates it instead of adding a copy
+func TestProductImport_ReimportsExportWithoutBarcode(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	productRepo := repository.NewProductRepository(db)
+	svc := service.NewProductImportService(db, productRepo, repository.NewCategoryRepository(db))
+	ctx := context.Background()
+
+	sku := "SKU-" + uuid.New().String()[:8]
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM products WHERE sku = $1", sku); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+
+	header := []string{"sku", "name", "unit", "base_price"}
+	name := "=HYPERLINK(\"x\") " + sku
+	if result, err := svc.Import(ctx, [][]string{header, {sku, name, "pcs", "2000"}}, false); err != nil || result.Created != 1 {
+		t.Fatalf("Expected one product created, got %+v (%v)", result, err)
+	}
+
+	exported, err := svc.Export(ctx)
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	var row []string
+	for _, cells := range exported[1:] {
+		if cells[1] == sku {
+			for _, c := range cells {
+				if c == nil {
+					c = ""
+				}
+				row = append(row, fmt.Sprint(c))
+			}
+		}
+	}
+	if row == nil {
+		t.Fatal("Expected the product in the export")
+	}
+	if row[2] != "'"+name {
+		t.Errorf("Expected the name escaped as a text cell, got %q", row[2])
+	}
+
+	header = make([]string, len(exported[0]))
+	for i, h := range exported[0] {
+		header[i] = fmt.Sprint(h)
+	}
+	row[5] = "2500" // base_price
+	result, err := svc.Import(ctx, [][]string{header, row}, false)
+	if err != nil || result.Created != 0 || result.Updated != 1 {
+		t.Fatalf("Expected the product updated by SKU, got %+v (%v)", result, err)
+	}
+
+	var count int
+	var basePrice int64
+	var storedName string
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*), MAX(base_price), MAX(name) FROM products WHERE sku = $1", sku).Scan(&count, &basePrice, &storedName); err != nil {
+		t.Fatalf("Failed to count products: %v", err)
+	}
+	if count != 1 || basePrice != 2500 || storedName != name {
+		t.Errorf("Expected one product %q at 2500, got %d at %d named %q", name, count, basePrice, storedName)
+	}
+}
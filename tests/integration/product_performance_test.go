@@ -0,0 +1,85 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// TestProductPerformance_SellThroughAndMargin sells 4 of 10 units today and checks the
+// product's sales, margin and stock ratios
+func TestProductPerformance_SellThroughAndMargin(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	reportSvc := service.NewReportService(repository.NewTransactionRepository(db), repository.NewCashFlowRepository(db),
+		repository.NewKasbonRepository(db), repository.NewInventoryRepository(db), repository.NewReportRepository(db))
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	if _, err := svc.CreateTransaction(ctx, cashSale(
+		domain.TransactionItemInput{ProductID: product.ID, Quantity: 4},
+	)); err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	report, err := reportSvc.ProductPerformance(ctx, today, today, 30)
+	if err != nil {
+		t.Fatalf("Failed to get product performance: %v", err)
+	}
+
+	var found *domain.ProductPerformance
+	for i := range report.Products {
+		if report.Products[i].ProductID == product.ID {
+			found = &report.Products[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("Expected the product in the report")
+	}
+	if found.QuantitySold != 4 || found.Revenue != 4000 || found.Profit != 800 {
+		t.Errorf("Unexpected sales: %+v", found)
+	}
+	if found.Margin == nil || *found.Margin != 20 {
+		t.Errorf("Expected a 20%% margin, got %v", found.Margin)
+	}
+	if found.StockSold != 4 || found.CurrentStock != 6 {
+		t.Errorf("Unexpected stock flow: %+v", found)
+	}
+	if found.SellThrough == nil || *found.SellThrough != 40 {
+		t.Errorf("Expected 40%% sell-through, got %v", found.SellThrough)
+	}
+	if found.DaysOfCover == nil || *found.DaysOfCover != 1.5 {
+		t.Errorf("Expected 1.5 days of cover, got %v", found.DaysOfCover)
+	}
+
+	ranked := false
+	for _, m := range report.MarginRanking {
+		ranked = ranked || m.ProductID == product.ID
+	}
+	if !ranked {
+		t.Errorf("Expected the product in the margin ranking")
+	}
+	for _, d := range report.DeadStock {
+		if d.ProductID == product.ID {
+			t.Errorf("A product created today should not be dead stock")
+		}
+	}
+}
+
+// TestProductPerformance_RejectsDeadStockDays checks the dead stock window bounds
+func TestProductPerformance_RejectsDeadStockDays(t *testing.T) {
+	reportSvc := service.NewReportService(nil, nil, nil, nil, nil)
+	today := time.Now()
+	for _, days := range []int{0, 366} {
+		if _, err := reportSvc.ProductPerformance(context.Background(), today, today, days); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("Expected invalid input for dead_stock_days=%d, got %v", days, err)
+		}
+	}
+}
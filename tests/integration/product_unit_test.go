@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// TestProductUnits_SellByDus scans a carton barcode and sells one dus out of stock kept in pcs
+func TestProductUnits_SellByDus(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	product := createStockedProduct(t, db, productRepo, 50)
+	ctx := context.Background()
+
+	barcode := "DUS-" + uuid.New().String()[:8]
+	price := int64(20000)
+	unit, err := productRepo.CreateUnit(ctx, product.ID, domain.ProductUnitInput{
+		Name:             "dus",
+		ConversionFactor: 24,
+		Barcode:          &barcode,
+		Price:            &price,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create unit: %v", err)
+	}
+
+	scanned, err := productRepo.GetByBarcode(ctx, barcode)
+	if err != nil {
+		t.Fatalf("Failed to look up unit barcode: %v", err)
+	}
+	if scanned.ID != product.ID || scanned.ScannedUnit == nil || scanned.ScannedUnit.ID != unit.ID {
+		t.Fatalf("Expected the carton barcode to resolve to the dus unit, got %+v", scanned.ScannedUnit)
+	}
+
+	transaction, err := svc.CreateTransaction(ctx, cashSale(
+		domain.TransactionItemInput{ProductID: product.ID, UnitID: &unit.ID, Quantity: 2},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	item := transaction.Items[0]
+	if item.Quantity != 48 || item.UnitQuantity != 2 || item.Unit != "dus" || item.Subtotal != 2*price {
+		t.Errorf("Expected 2 dus = 48 pcs at %d, got %d %s (%d base) subtotal %d",
+			price, item.UnitQuantity, item.Unit, item.Quantity, item.Subtotal)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 2 {
+		t.Errorf("Expected stock 2, got %d", stock)
+	}
+
+	_, err = svc.CreateTransaction(ctx, cashSale(
+		domain.TransactionItemInput{ProductID: product.ID, UnitID: &unit.ID, Quantity: 1},
+	))
+	if err == nil {
+		t.Error("Expected a dus sale with 2 pcs left to fail")
+	}
+}
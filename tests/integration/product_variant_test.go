@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// TestProductVariants creates a variant, lists it collapsed under its parent and checks the one-level rule
+func TestProductVariants(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	_, productRepo := newTestTransactionService(db)
+	parent := createStockedProduct(t, db, productRepo, 0)
+	ctx := context.Background()
+
+	imageURL := "https://example.com/indomie.jpg"
+	if _, err := productRepo.Update(ctx, nil, parent.ID, domain.ProductUpdateInput{ImageURL: &imageURL}); err != nil {
+		t.Fatalf("Failed to update parent: %v", err)
+	}
+
+	variantName := "Soto"
+	variant, err := productRepo.Create(ctx, domain.ProductCreateInput{
+		BasePrice:   3500,
+		CostPrice:   2900,
+		ParentID:    &parent.ID,
+		VariantName: &variantName,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create variant: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM products WHERE id = $1", variant.ID); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+
+	if variant.Name != parent.Name+" Soto" || variant.Unit != parent.Unit {
+		t.Errorf("Expected name and unit from the parent, got %q in %q", variant.Name, variant.Unit)
+	}
+	if variant.ImageURL == nil || *variant.ImageURL != imageURL {
+		t.Errorf("Expected the parent's image, got %v", variant.ImageURL)
+	}
+
+	search := parent.Name
+	products, _, err := productRepo.List(ctx, domain.ProductFilter{Search: &search, CollapseVariants: true})
+	if err != nil {
+		t.Fatalf("Failed to list products: %v", err)
+	}
+	if len(products) != 1 || len(products[0].Variants) != 1 || products[0].Variants[0].ID != variant.ID {
+		t.Errorf("Expected the parent with its variant nested, got %+v", products)
+	}
+
+	nested := "Soto Pedas"
+	if _, err := productRepo.Create(ctx, domain.ProductCreateInput{
+		BasePrice:   3500,
+		ParentID:    &variant.ID,
+		VariantName: &nested,
+	}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected a variant of a variant to be rejected, got %v", err)
+	}
+}
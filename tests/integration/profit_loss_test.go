@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"cmp"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// TestProfitLoss_NetsRefundsAndCancellations checks how today's profit and loss moves after a sale,
+// a partial refund of it and a cancelled sale
+func TestProfitLoss_NetsRefundsAndCancellations(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	cashFlowRepo := repository.NewCashFlowRepository(db)
+	kasbonRepo := repository.NewKasbonRepository(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	posSvc := service.NewPOSService(db, repository.NewPOSRepository(db), productRepo, transactionRepo,
+		inventoryRepo, kasbonRepo, cashFlowRepo, service.NewOutboxService(db, repository.NewOutboxRepository(db), nil, nil))
+	reportSvc := service.NewReportService(transactionRepo, cashFlowRepo, kasbonRepo, inventoryRepo, repository.NewReportRepository(db))
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	today := time.Now()
+	snapshot := func() (*domain.ProfitLossReport, *domain.SalesByMethodReport) {
+		pl, err := reportSvc.ProfitLoss(ctx, today, today)
+		if err != nil {
+			t.Fatalf("Failed to get profit and loss: %v", err)
+		}
+		byMethod, err := reportSvc.SalesByMethod(ctx, today, today)
+		if err != nil {
+			t.Fatalf("Failed to get sales by method: %v", err)
+		}
+		return pl, byMethod
+	}
+	beforePL, beforeMethods := snapshot()
+
+	sale, err := svc.CreateTransaction(ctx, cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 4}))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	cancelled, err := svc.CreateTransaction(ctx, cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 1}))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if err := svc.CancelTransaction(ctx, cancelled.ID, domain.TransactionCancelInput{Reason: "P&L test"}); err != nil {
+		t.Fatalf("Failed to cancel transaction: %v", err)
+	}
+
+	refund, err := posSvc.CreateRefund(ctx, domain.CreateRefundInput{
+		TransactionID: sale.ID,
+		RefundMethod:  domain.RefundMethodCash,
+		Reason:        "P&L test",
+		Items:         []domain.RefundItemInput{{TransactionItemID: sale.Items[0].ID, Quantity: 1, Restock: true}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create refund: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, query := range []string{
+			"DELETE FROM cash_flow_records WHERE reference_id = $1",
+			"DELETE FROM refund_records WHERE id = $1",
+		} {
+			if _, err := db.ExecContext(ctx, query, refund.ID); err != nil {
+				t.Logf("Cleanup failed: %v", err)
+			}
+		}
+	})
+	if _, err := posSvc.ApproveRefund(ctx, refund.ID, "tester"); err != nil {
+		t.Fatalf("Failed to approve refund: %v", err)
+	}
+	if _, err := posSvc.CompleteRefund(ctx, refund.ID, "tester"); err != nil {
+		t.Fatalf("Failed to complete refund: %v", err)
+	}
+
+	afterPL, afterMethods := snapshot()
+
+	// 4 sold at 1000 (cost 800), one returned to stock
+	if got := afterPL.TotalRevenue - beforePL.TotalRevenue; got != 3000 {
+		t.Errorf("Expected revenue to grow by 3000, got %d", got)
+	}
+	if got := afterPL.TotalCOGS - beforePL.TotalCOGS; got != 2400 {
+		t.Errorf("Expected COGS to grow by 2400, got %d", got)
+	}
+	// The cash payout for the refund is not an operating expense
+	if afterPL.TotalExpenses != beforePL.TotalExpenses {
+		t.Errorf("Expected expenses to stay %d, got %d", beforePL.TotalExpenses, afterPL.TotalExpenses)
+	}
+	if got := afterMethods.TotalSales - beforeMethods.TotalSales; got != 3000 {
+		t.Errorf("Expected sales by method to grow by 3000, got %d", got)
+	}
+}
+
+// TestProfitLoss_ExcludesTax checks that tax added on top of the prices is reported on its own
+// line instead of as revenue
+func TestProfitLoss_ExcludesTax(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	useTaxSettings(t, db, "10", "false")
+	svc, productRepo := newTestTransactionService(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	reportSvc := service.NewReportService(transactionRepo, repository.NewCashFlowRepository(db),
+		repository.NewKasbonRepository(db), repository.NewInventoryRepository(db), repository.NewReportRepository(db))
+
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+	today := time.Now()
+
+	before, err := reportSvc.ProfitLoss(ctx, today, today)
+	if err != nil {
+		t.Fatalf("Failed to get profit and loss: %v", err)
+	}
+
+	sale, err := svc.CreateTransaction(ctx, cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 4}))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if sale.TaxAmount != 400 || sale.TotalAmount != 4400 {
+		t.Fatalf("Expected tax 400 on a total of 4400, got %d on %d", sale.TaxAmount, sale.TotalAmount)
+	}
+
+	after, err := reportSvc.ProfitLoss(ctx, today, today)
+	if err != nil {
+		t.Fatalf("Failed to get profit and loss: %v", err)
+	}
+	if got := after.TotalRevenue - before.TotalRevenue; got != 4000 {
+		t.Errorf("Expected revenue to grow by 4000, got %d", got)
+	}
+	if got := after.TotalTax - before.TotalTax; got != 400 {
+		t.Errorf("Expected tax to grow by 400, got %d", got)
+	}
+	if got := after.GrossProfit - before.GrossProfit; got != 800 {
+		t.Errorf("Expected gross profit to grow by 800, got %d", got)
+	}
+}
+
+// useTaxSettings sets the tax rate and pricing mode for one test and puts the old values back
+// afterwards. Services built after the call read the new values.
+func useTaxSettings(t *testing.T, db *database.PostgresDB, rate, inclusive string) {
+	t.Helper()
+	ctx := context.Background()
+	settingsSvc := service.NewSettingsService(db, repository.NewSettingsRepository(db))
+
+	old, err := repository.NewSettingsRepository(db).GetAll(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	if _, err := settingsSvc.Update(ctx, map[string]string{
+		domain.SettingTaxRate:      rate,
+		domain.SettingTaxInclusive: inclusive,
+	}); err != nil {
+		t.Fatalf("Failed to set tax settings: %v", err)
+	}
+	t.Cleanup(func() {
+		restore := map[string]string{
+			domain.SettingTaxRate:      cmp.Or(old[domain.SettingTaxRate], "0"),
+			domain.SettingTaxInclusive: cmp.Or(old[domain.SettingTaxInclusive], "false"),
+		}
+		if _, err := settingsSvc.Update(ctx, restore); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// TestPromotion_Discount checks each promotion type on a line at 1000 a unit
+func TestPromotion_Discount(t *testing.T) {
+	percent := func(p float64) domain.Promotion {
+		return domain.Promotion{Type: domain.PromotionTypePercentage, DiscountPercent: &p}
+	}
+	bundle := func(qty int, price int64) domain.Promotion {
+		return domain.Promotion{Type: domain.PromotionTypeBundle, BundleQuantity: &qty, BundlePrice: &price}
+	}
+	buyGet := func(buy, get int) domain.Promotion {
+		return domain.Promotion{Type: domain.PromotionTypeBuyXGetY, BuyQuantity: &buy, GetQuantity: &get}
+	}
+
+	tests := []struct {
+		name     string
+		promo    domain.Promotion
+		quantity int
+		want     int64
+	}{
+		{"percentage", percent(10), 3, 300},
+		{"percentage rounds", percent(12.5), 1, 125},
+		{"percentage capped at subtotal", percent(150), 2, 2000},
+		{"bundle", bundle(5, 4000), 5, 1000},
+		{"bundle leftover pays full price", bundle(5, 4000), 12, 2000},
+		{"bundle short of a group", bundle(5, 4000), 4, 0},
+		{"bundle dearer than units", bundle(2, 2500), 4, 0},
+		{"buy 2 get 1", buyGet(2, 1), 7, 2000},
+		{"buy 2 get 1 short of a group", buyGet(2, 1), 2, 0},
+		{"no quantity", percent(10), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.promo.Discount(tt.quantity, 1000); got != tt.want {
+				t.Errorf("Expected a discount of %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestPromotion_IsActiveAt checks the period, weekday and daily window of a promotion
+func TestPromotion_IsActiveAt(t *testing.T) {
+	// Wednesday 15 May 2024, 14:30
+	at := time.Date(2024, 5, 15, 14, 30, 0, 0, time.Local)
+	before, after := at.Add(-time.Hour), at.Add(time.Hour)
+	clock := func(s string) *string { return &s }
+
+	tests := []struct {
+		name     string
+		promo    domain.Promotion
+		inactive bool
+		want     bool
+	}{
+		{"always", domain.Promotion{}, false, true},
+		{"inactive", domain.Promotion{}, true, false},
+		{"inside period", domain.Promotion{StartsAt: &before, EndsAt: &after}, false, true},
+		{"not started", domain.Promotion{StartsAt: &after}, false, false},
+		{"ends exactly now", domain.Promotion{EndsAt: &at}, false, false},
+		{"allowed weekday", domain.Promotion{DaysOfWeek: []int{1, 3, 5}}, false, true},
+		{"other weekday", domain.Promotion{DaysOfWeek: []int{0, 6}}, false, false},
+		{"inside daily window", domain.Promotion{StartTime: clock("14:00:00"), EndTime: clock("15:00:00")}, false, true},
+		{"window starts now", domain.Promotion{StartTime: clock("14:30")}, false, true},
+		{"window ends now", domain.Promotion{EndTime: clock("14:30")}, false, false},
+		{"before daily window", domain.Promotion{StartTime: clock("17:00")}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promo := tt.promo
+			promo.IsActive = !tt.inactive
+			if got := promo.IsActiveAt(at); got != tt.want {
+				t.Errorf("Expected active %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestBestPromotion checks that the largest discount in scope wins and ties go to the higher priority
+func TestBestPromotion(t *testing.T) {
+	categoryID := uuid.New()
+	product := &domain.Product{ID: uuid.New(), CategoryID: &categoryID}
+	percent := func(name string, p float64, priority int, productID, catID *uuid.UUID) domain.Promotion {
+		return domain.Promotion{
+			Name: name, Type: domain.PromotionTypePercentage, DiscountPercent: &p,
+			Priority: priority, ProductID: productID, CategoryID: catID,
+		}
+	}
+	otherID, otherCategory := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name       string
+		promotions []domain.Promotion
+		wantName   string
+		want       int64
+	}{
+		{"none", nil, "", 0},
+		{"out of scope", []domain.Promotion{
+			percent("other product", 50, 0, &otherID, nil),
+			percent("other category", 50, 0, nil, &otherCategory),
+			percent("unscoped", 50, 0, nil, nil),
+		}, "", 0},
+		{"largest discount", []domain.Promotion{
+			percent("product", 10, 5, &product.ID, nil),
+			percent("category", 20, 0, nil, &categoryID),
+		}, "category", 1000},
+		{"tie goes to priority", []domain.Promotion{
+			percent("low", 10, 1, &product.ID, nil),
+			percent("high", 10, 2, nil, &categoryID),
+		}, "high", 500},
+		{"zero discount ignored", []domain.Promotion{
+			percent("zero", 0, 9, &product.ID, nil),
+		}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best, discount := domain.BestPromotion(tt.promotions, product, 5, 1000)
+			name := ""
+			if best != nil {
+				name = best.Name
+			}
+			if name != tt.wantName || discount != tt.want {
+				t.Errorf("Expected %q with %d off, got %q with %d", tt.wantName, tt.want, name, discount)
+			}
+		})
+	}
+}
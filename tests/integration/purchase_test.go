@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+func newTestPurchaseService(db *database.PostgresDB, productRepo *repository.ProductRepository) *service.PurchaseService {
+	return service.NewPurchaseService(db, repository.NewPurchaseRepository(db), repository.NewSupplierRepository(db),
+		productRepo, repository.NewInventoryRepository(db), repository.NewCashFlowRepository(db),
+		service.NewSettingsService(db, repository.NewSettingsRepository(db)))
+}
+
+// TestPurchase_ReceiveAndCancel receives one purchase order into stock and cancels another
+func TestPurchase_ReceiveAndCancel(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	_, productRepo := newTestTransactionService(db)
+	purchaseSvc := newTestPurchaseService(db, productRepo)
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	create := func() *domain.Purchase {
+		t.Helper()
+		purchase, err := purchaseSvc.CreatePurchase(ctx, domain.PurchaseCreateInput{
+			Items:     []domain.PurchaseItemInput{{ProductID: product.ID, Quantity: 5, CostPerUnit: 1000}},
+			CreatedBy: "tester",
+		})
+		if err != nil {
+			t.Fatalf("Failed to create purchase: %v", err)
+		}
+		t.Cleanup(func() {
+			for _, query := range []string{
+				"DELETE FROM cash_flow_records WHERE reference_type = 'purchase' AND reference_id = $1",
+				"DELETE FROM purchases WHERE id = $1",
+			} {
+				if _, err := db.ExecContext(ctx, query, purchase.ID); err != nil {
+					t.Logf("Cleanup failed: %v", err)
+				}
+			}
+		})
+		return purchase
+	}
+	expensed := func(purchase *domain.Purchase) int64 {
+		t.Helper()
+		var amount int64
+		if err := db.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(amount), 0) FROM cash_flow_records
+			WHERE type = 'expense' AND reference_type = 'purchase' AND reference_id = $1
+		`, purchase.ID).Scan(&amount); err != nil {
+			t.Fatalf("Failed to read cash flow: %v", err)
+		}
+		return amount
+	}
+
+	cancelled := create()
+	if cancelled.Status != domain.PurchaseStatusDraft || cancelled.TotalAmount != 5000 {
+		t.Fatalf("Expected a draft of 5000, got %s of %d", cancelled.Status, cancelled.TotalAmount)
+	}
+	if _, err := purchaseSvc.MarkOrdered(ctx, cancelled.ID); err != nil {
+		t.Fatalf("Failed to mark purchase ordered: %v", err)
+	}
+	reloaded, err := purchaseSvc.CancelPurchase(ctx, cancelled.ID)
+	if err != nil {
+		t.Fatalf("Failed to cancel purchase: %v", err)
+	}
+	if reloaded.Status != domain.PurchaseStatusCancelled {
+		t.Errorf("Expected status cancelled, got %s", reloaded.Status)
+	}
+	if _, err := purchaseSvc.ReceivePurchase(ctx, cancelled.ID, "tester"); !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Errorf("Expected receiving a cancelled purchase to be rejected, got %v", err)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 10 || expensed(cancelled) != 0 {
+		t.Errorf("Expected a cancelled purchase to leave stock at 10 with no expense, got %d and %d", stock, expensed(cancelled))
+	}
+
+	received := create()
+	reloaded, err = purchaseSvc.ReceivePurchase(ctx, received.ID, "tester")
+	if err != nil {
+		t.Fatalf("Failed to receive purchase: %v", err)
+	}
+	if reloaded.Status != domain.PurchaseStatusReceived || reloaded.ReceivedAt == nil {
+		t.Errorf("Expected status received with a receipt time, got %s", reloaded.Status)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 15 {
+		t.Errorf("Expected stock 15, got %d", stock)
+	}
+	if amount := expensed(received); amount != 5000 {
+		t.Errorf("Expected a purchase expense of 5000, got %d", amount)
+	}
+
+	// 10 at 800 and 5 at 1000 average to 866.67
+	costed, err := productRepo.GetByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload product: %v", err)
+	}
+	if costed.CostPrice != 867 {
+		t.Errorf("Expected average cost 867, got %d", costed.CostPrice)
+	}
+
+	if _, err := purchaseSvc.ReceivePurchase(ctx, received.ID, "tester"); !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Errorf("Expected receiving twice to be rejected, got %v", err)
+	}
+	if _, err := purchaseSvc.CancelPurchase(ctx, received.ID); !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Errorf("Expected cancelling a received purchase to be rejected, got %v", err)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 15 {
+		t.Errorf("Expected stock to stay 15, got %d", stock)
+	}
+}
@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eveeze/warung-backend/internal/pkg/receipt"
+)
+
+// TestReceipt_Money checks Rupiah amounts are grouped with dots
+func TestReceipt_Money(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{0, "0"},
+		{500, "500"},
+		{1000, "1.000"},
+		{12500, "12.500"},
+		{100000, "100.000"},
+		{1234567, "1.234.567"},
+		{-2500, "-2.500"},
+		{-999, "-999"},
+	}
+
+	for _, tt := range tests {
+		if got := receipt.Money(tt.amount); got != tt.want {
+			t.Errorf("Money(%d): expected %q, got %q", tt.amount, tt.want, got)
+		}
+	}
+}
+
+// TestReceipt_Layout lays out a sale on both paper widths and checks the line widths and the printed rows
+func TestReceipt_Layout(t *testing.T) {
+	data := receipt.Data{
+		StoreName:     "Warung Bu Sri",
+		StoreAddress:  "Jl. Melati No. 5",
+		InvoiceNumber: "INV-20240515-0001",
+		Date:          time.Date(2024, 5, 15, 14, 30, 0, 0, time.Local),
+		Cashier:       "Andi",
+		Reprint:       2,
+		Items: []receipt.Item{
+			{Name: "Kopi Susu Gula Aren Ukuran Besar Sekali", Quantity: 2, Unit: "pcs", UnitPrice: 5000, Subtotal: 10000, Total: 10000},
+			{Name: "Roti", Quantity: 5, Unit: "pcs", UnitPrice: 1000, Subtotal: 5000,
+				PromotionName: "Paket 5", PromotionDiscount: 500, Total: 4500},
+		},
+		Subtotal:     14500,
+		Discount:     500,
+		Tax:          1386,
+		TaxInclusive: true,
+		Total:        14000,
+		Tenders:      []receipt.Tender{{Label: "Tunai", Amount: 10000}, {Label: "QRIS", Amount: 5000}},
+		Change:       1000,
+	}
+
+	for _, width := range []int{receipt.Width58, receipt.Width80} {
+		cols := receipt.Columns(width)
+		lines := receipt.Layout(data, cols)
+
+		var text []string
+		for _, line := range lines {
+			if n := utf8.RuneCountInString(line.Text); n != cols {
+				t.Errorf("%dmm: expected every line %d wide, got %d in %q", width, cols, n, line.Text)
+			}
+			text = append(text, strings.TrimSpace(line.Text))
+		}
+		printed := strings.Join(text, "\n")
+
+		// label and value are at opposite ends of one line
+		row := func(label, value string) string {
+			return label + strings.Repeat(" ", cols-len(label)-len(value)) + value
+		}
+		for _, want := range []string{
+			"Warung Bu Sri",
+			"CETAK ULANG #2",
+			row("No", "INV-20240515-0001"),
+			row("Tanggal", "15/05/2024 14:30"),
+			row("  2 pcs x 5.000", "10.000"),
+			row("  Paket 5", "-500"),
+			row("Subtotal", "14.500"),
+			row("Diskon", "-500"),
+			row("Pajak (termasuk)", "1.386"),
+			row("TOTAL", "Rp 14.000"),
+			row("Tunai", "10.000"),
+			row("QRIS", "5.000"),
+			row("Kembali", "1.000"),
+		} {
+			if !strings.Contains(printed, strings.TrimSpace(want)) {
+				t.Errorf("%dmm: expected the receipt to contain %q, got:\n%s", width, want, printed)
+			}
+		}
+
+		var total receipt.Line
+		for _, line := range lines {
+			if strings.HasPrefix(line.Text, "TOTAL") {
+				total = line
+			}
+		}
+		if !total.Bold {
+			t.Errorf("%dmm: expected the total printed in bold", width)
+		}
+		if !lines[0].Tall || !lines[0].Bold {
+			t.Errorf("%dmm: expected the store name printed tall and bold", width)
+		}
+	}
+
+	data.TaxInclusive = false
+	for _, line := range receipt.Layout(data, receipt.Columns(receipt.Width58)) {
+		if strings.Contains(line.Text, "termasuk") {
+			t.Errorf("Expected exclusive tax printed without the inclusive note, got %q", line.Text)
+		}
+	}
+}
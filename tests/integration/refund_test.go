@@ -0,0 +1,296 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+func newTestPOSService(db *database.PostgresDB, productRepo *repository.ProductRepository) *service.POSService {
+	return service.NewPOSService(db, repository.NewPOSRepository(db), productRepo, repository.NewTransactionRepository(db),
+		repository.NewInventoryRepository(db), repository.NewKasbonRepository(db), repository.NewCashFlowRepository(db),
+		service.NewOutboxService(db, repository.NewOutboxRepository(db), nil, nil))
+}
+
+// requestRefund requests a refund of a sale and removes it, with its payout, after the test
+func requestRefund(t *testing.T, db *database.PostgresDB, posSvc *service.POSService, transactionID uuid.UUID, items ...domain.RefundItemInput) *domain.RefundRecord {
+	t.Helper()
+	ctx := context.Background()
+	refund, err := posSvc.CreateRefund(ctx, domain.CreateRefundInput{
+		TransactionID: transactionID,
+		RefundMethod:  domain.RefundMethodCash,
+		Reason:        "Refund test",
+		Items:         items,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create refund: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, query := range []string{
+			"DELETE FROM cash_flow_records WHERE reference_type = 'refund' AND reference_id = $1",
+			"DELETE FROM refund_records WHERE id = $1",
+		} {
+			if _, err := db.ExecContext(ctx, query, refund.ID); err != nil {
+				t.Logf("Cleanup failed: %v", err)
+			}
+		}
+	})
+	return refund
+}
+
+// saleLines maps a sale's product IDs to its lines
+func saleLines(sale *domain.Transaction) map[uuid.UUID]domain.TransactionItem {
+	lines := make(map[uuid.UUID]domain.TransactionItem, len(sale.Items))
+	for _, item := range sale.Items {
+		lines[item.ProductID] = item
+	}
+	return lines
+}
+
+// TestRefund_ProratesOrderDiscount refunds a sale with an order discount in two parts and checks
+// that together they pay back what the customer paid, not the undiscounted line
+func TestRefund_ProratesOrderDiscount(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	posSvc := newTestPOSService(db, productRepo)
+	product := createStockedProduct(t, db, productRepo, 10)
+	other := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	// 3000 + 1000 less a 400 order discount: the customer paid 3600
+	input := cashSale(
+		domain.TransactionItemInput{ProductID: product.ID, Quantity: 3},
+		domain.TransactionItemInput{ProductID: other.ID, Quantity: 1},
+	)
+	discount := int64(400)
+	input.DiscountAmount = &discount
+	sale, err := svc.CreateTransaction(ctx, input)
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if sale.TotalAmount != 3600 {
+		t.Fatalf("Expected a total of 3600, got %d", sale.TotalAmount)
+	}
+
+	lines := saleLines(sale)
+	refund := func(items ...domain.RefundItemInput) int64 {
+		t.Helper()
+		return requestRefund(t, db, posSvc, sale.ID, items...).TotalRefundAmount
+	}
+
+	first := refund(domain.RefundItemInput{TransactionItemID: lines[product.ID].ID, Quantity: 1})
+	if first != 900 {
+		t.Errorf("Expected one unit refunded at 900 (1000 less its share of the discount), got %d", first)
+	}
+	rest := refund(
+		domain.RefundItemInput{TransactionItemID: lines[product.ID].ID, Quantity: 2},
+		domain.RefundItemInput{TransactionItemID: lines[other.ID].ID, Quantity: 1},
+	)
+	if first+rest != sale.TotalAmount {
+		t.Errorf("Expected the refunds to add up to the %d paid, got %d", sale.TotalAmount, first+rest)
+	}
+}
+
+// TestCancelTransaction_AfterRefund checks that a sale with an open or completed partial refund
+// cannot be cancelled, so its stock and kasbon are not returned twice
+func TestCancelTransaction_AfterRefund(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	posSvc := newTestPOSService(db, productRepo)
+	customer := createKasbonCustomer(t, db, 0)
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	sale, err := svc.CreateTransaction(ctx, domain.TransactionCreateInput{
+		Items:         []domain.TransactionItemInput{{ProductID: product.ID, Quantity: 3}},
+		PaymentMethod: domain.PaymentMethodKasbon,
+		CustomerID:    &customer.ID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	cancel := func() error {
+		return svc.CancelTransaction(ctx, sale.ID, domain.TransactionCancelInput{Reason: "Cancel test", CancelledBy: "tester"})
+	}
+
+	line := saleLines(sale)[product.ID]
+	refund := requestRefund(t, db, posSvc, sale.ID, domain.RefundItemInput{TransactionItemID: line.ID, Quantity: 1, Restock: true})
+	if err := cancel(); !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Fatalf("Expected a sale with a pending refund to stay, got %v", err)
+	}
+
+	if _, err := posSvc.ApproveRefund(ctx, refund.ID, "tester"); err != nil {
+		t.Fatalf("Failed to approve refund: %v", err)
+	}
+	if _, err := posSvc.CompleteRefund(ctx, refund.ID, "tester"); err != nil {
+		t.Fatalf("Failed to complete refund: %v", err)
+	}
+	if err := cancel(); !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Fatalf("Expected a sale with a completed refund to stay, got %v", err)
+	}
+
+	if stock := currentStock(t, productRepo, product.ID); stock != 8 {
+		t.Errorf("Expected stock 8 (sold 3, 1 restocked), got %d", stock)
+	}
+	reloaded, err := repository.NewCustomerRepository(db).GetByID(ctx, customer.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload customer: %v", err)
+	}
+	if reloaded.CurrentDebt != 2000 {
+		t.Errorf("Expected debt 2000 (3000 less the 1000 refunded), got %d", reloaded.CurrentDebt)
+	}
+
+	// Without refunds the sale can be cancelled
+	other, err := svc.CreateTransaction(ctx, cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 2}))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	rejected := requestRefund(t, db, posSvc, other.ID, domain.RefundItemInput{TransactionItemID: other.Items[0].ID, Quantity: 1})
+	if _, err := posSvc.RejectRefund(ctx, rejected.ID, "tester"); err != nil {
+		t.Fatalf("Failed to reject refund: %v", err)
+	}
+	if err := svc.CancelTransaction(ctx, other.ID, domain.TransactionCancelInput{Reason: "Cancel test"}); err != nil {
+		t.Fatalf("Expected a sale whose only refund was rejected to be cancelled, got %v", err)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 8 {
+		t.Errorf("Expected the cancelled sale's stock back at 8, got %d", stock)
+	}
+}
+
+// TestCompleteRefund_SplitKasbonSale refunds a sale paid half in cash and half on kasbon in three parts
+// and checks the restock, that the kasbon credit never exceeds the kasbon part of the sale, the cash
+// paid out for the rest and that the sale flips to refunded once everything is returned
+func TestCompleteRefund_SplitKasbonSale(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	posSvc := newTestPOSService(db, productRepo)
+	customerRepo := repository.NewCustomerRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	customer := createKasbonCustomer(t, db, 0)
+	product := createStockedProduct(t, db, productRepo, 100)
+	ctx := context.Background()
+
+	// An unrelated kasbon sale the refunds must not touch
+	if _, err := svc.CreateTransaction(ctx, domain.TransactionCreateInput{
+		Items:         []domain.TransactionItemInput{{ProductID: product.ID, Quantity: 3}},
+		PaymentMethod: domain.PaymentMethodKasbon,
+		CustomerID:    &customer.ID,
+	}); err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	sale, err := svc.CreateTransaction(ctx, domain.TransactionCreateInput{
+		Items:         []domain.TransactionItemInput{{ProductID: product.ID, Quantity: 10}},
+		PaymentMethod: domain.PaymentMethodMixed,
+		Payments: []domain.TenderInput{
+			{Method: domain.PaymentMethodCash, Amount: 5000},
+			{Method: domain.PaymentMethodKasbon, Amount: 5000},
+		},
+		CustomerID: &customer.ID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	line := saleLines(sale)[product.ID]
+
+	complete := func(quantity int, restock bool) *domain.RefundRecord {
+		t.Helper()
+		refund := requestRefund(t, db, posSvc, sale.ID, domain.RefundItemInput{TransactionItemID: line.ID, Quantity: quantity, Restock: restock})
+		if _, err := posSvc.ApproveRefund(ctx, refund.ID, "tester"); err != nil {
+			t.Fatalf("Failed to approve refund: %v", err)
+		}
+		completed, err := posSvc.CompleteRefund(ctx, refund.ID, "tester")
+		if err != nil {
+			t.Fatalf("Failed to complete refund: %v", err)
+		}
+		if completed.Status != domain.RefundStatusCompleted {
+			t.Errorf("Expected the refund completed, got %s", completed.Status)
+		}
+		return refund
+	}
+	debt := func() int64 {
+		t.Helper()
+		c, err := customerRepo.GetByID(ctx, customer.ID)
+		if err != nil {
+			t.Fatalf("Failed to reload customer: %v", err)
+		}
+		return c.CurrentDebt
+	}
+	paidOut := func(refund *domain.RefundRecord) int64 {
+		t.Helper()
+		var amount int64
+		if err := db.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(amount), 0) FROM cash_flow_records
+			WHERE type = 'expense' AND reference_type = 'refund' AND reference_id = $1
+		`, refund.ID).Scan(&amount); err != nil {
+			t.Fatalf("Failed to read cash flow: %v", err)
+		}
+		return amount
+	}
+	status := func() domain.TransactionStatus {
+		t.Helper()
+		reloaded, err := transactionRepo.GetByID(ctx, sale.ID)
+		if err != nil {
+			t.Fatalf("Failed to reload transaction: %v", err)
+		}
+		return reloaded.Status
+	}
+
+	if got := debt(); got != 8000 {
+		t.Fatalf("Expected debt 8000 before refunds, got %d", got)
+	}
+
+	// 4000 back: all of it comes off the kasbon part, the units go back on the shelf
+	first := complete(4, true)
+	if got := debt(); got != 4000 {
+		t.Errorf("Expected debt 4000 after the first refund, got %d", got)
+	}
+	if got := paidOut(first); got != 0 {
+		t.Errorf("Expected no cash paid out for the first refund, got %d", got)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 91 {
+		t.Errorf("Expected stock 91 (87 + 4 restocked), got %d", stock)
+	}
+	var restocked int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(quantity), 0) FROM stock_movements
+		WHERE product_id = $1 AND type = 'return' AND reference_type = 'refund' AND reference_id = $2
+	`, product.ID, first.ID).Scan(&restocked); err != nil {
+		t.Fatalf("Failed to read stock movements: %v", err)
+	}
+	if restocked != 4 {
+		t.Errorf("Expected a return movement of 4 for the refund, got %d", restocked)
+	}
+
+	// 4000 back again: only 1000 of kasbon is left on this sale, the rest is cash
+	second := complete(4, false)
+	if got := debt(); got != 3000 {
+		t.Errorf("Expected debt 3000, the other sale's, after the second refund, got %d", got)
+	}
+	if got := paidOut(second); got != 3000 {
+		t.Errorf("Expected 3000 paid out in cash for the second refund, got %d", got)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 91 {
+		t.Errorf("Expected stock to stay 91 without restock, got %d", stock)
+	}
+	if got := status(); got != domain.TransactionStatusCompleted {
+		t.Errorf("Expected a partly refunded sale to stay completed, got %s", got)
+	}
+
+	last := complete(2, false)
+	if got := debt(); got != 3000 {
+		t.Errorf("Expected the other sale's debt of 3000 untouched, got %d", got)
+	}
+	if got := paidOut(last); got != 2000 {
+		t.Errorf("Expected 2000 paid out in cash for the last refund, got %d", got)
+	}
+	if got := status(); got != domain.TransactionStatusRefunded {
+		t.Errorf("Expected the sale refunded once every unit is returned, got %s", got)
+	}
+}
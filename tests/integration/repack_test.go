@@ -0,0 +1,114 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// TestRepack_CarriesCost breaks one sack into packs and checks stock and cost on both sides
+func TestRepack_CarriesCost(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	_, productRepo := newTestTransactionService(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	sack := createStockedProduct(t, db, productRepo, 0)
+	pack := createStockedProduct(t, db, productRepo, 0)
+	ctx := context.Background()
+
+	if _, err := inventoryRepo.Restock(ctx, domain.RestockInput{
+		ProductID:   sack.ID,
+		Quantity:    2,
+		CostPerUnit: 250000,
+	}); err != nil {
+		t.Fatalf("Failed to restock: %v", err)
+	}
+
+	result, err := inventoryRepo.Repack(ctx, domain.RepackInput{
+		SourceProductID: sack.ID,
+		SourceQuantity:  1,
+		TargetProductID: pack.ID,
+		TargetQuantity:  25,
+	}, domain.CostingFIFO)
+	if err != nil {
+		t.Fatalf("Failed to repack: %v", err)
+	}
+	if result.TotalCost != 250000 || result.TargetUnitCost != 10000 {
+		t.Errorf("Expected total cost 250000 at 10000 per pack, got %d at %d", result.TotalCost, result.TargetUnitCost)
+	}
+
+	if stock := currentStock(t, productRepo, sack.ID); stock != 1 {
+		t.Errorf("Expected 1 sack left, got %d", stock)
+	}
+	reloaded, err := productRepo.GetByID(ctx, pack.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload product: %v", err)
+	}
+	if reloaded.CurrentStock != 25 || reloaded.CostPrice != 10000 {
+		t.Errorf("Expected 25 packs at 10000, got %d at %d", reloaded.CurrentStock, reloaded.CostPrice)
+	}
+
+	if _, err := inventoryRepo.Repack(ctx, domain.RepackInput{
+		SourceProductID: sack.ID,
+		SourceQuantity:  2,
+		TargetProductID: pack.ID,
+		TargetQuantity:  50,
+	}, domain.CostingFIFO); err == nil {
+		t.Error("Expected repacking more than the stock on hand to fail")
+	}
+}
+
+// TestRepack_KeepsExactValueUnderFIFO repacks units from layers at different costs into a
+// quantity the total does not divide into, and checks that no value is lost or made up
+func TestRepack_KeepsExactValueUnderFIFO(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	_, productRepo := newTestTransactionService(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	sack := createStockedProduct(t, db, productRepo, 0)
+	pack := createStockedProduct(t, db, productRepo, 0)
+	ctx := context.Background()
+
+	for _, cost := range []int64{100000, 100001, 100002} {
+		if _, err := inventoryRepo.Restock(ctx, domain.RestockInput{
+			ProductID:   sack.ID,
+			Quantity:    1,
+			CostPerUnit: cost,
+		}); err != nil {
+			t.Fatalf("Failed to restock: %v", err)
+		}
+	}
+
+	result, err := inventoryRepo.Repack(ctx, domain.RepackInput{
+		SourceProductID: sack.ID,
+		SourceQuantity:  2,
+		TargetProductID: pack.ID,
+		TargetQuantity:  7,
+	}, domain.CostingFIFO)
+	if err != nil {
+		t.Fatalf("Failed to repack: %v", err)
+	}
+	if result.TotalCost != 200001 {
+		t.Errorf("Expected the two oldest sacks to carry 200001, got %d", result.TotalCost)
+	}
+
+	layerValue := func(productID uuid.UUID) (value int64, units int) {
+		layers, err := inventoryRepo.GetCostLayers(ctx, productID)
+		if err != nil {
+			t.Fatalf("Failed to get cost layers: %v", err)
+		}
+		for _, l := range layers {
+			value += l.UnitCost * int64(l.RemainingQuantity)
+			units += l.RemainingQuantity
+		}
+		return value, units
+	}
+	if value, units := layerValue(sack.ID); value != 100002 || units != 1 {
+		t.Errorf("Expected 1 sack worth 100002 left, got %d worth %d", units, value)
+	}
+	if value, units := layerValue(pack.ID); value != 200001 || units != 7 {
+		t.Errorf("Expected 7 packs worth 200001, got %d worth %d", units, value)
+	}
+}
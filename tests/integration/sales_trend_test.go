@@ -0,0 +1,143 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// TestSalesTrend_CountsSaleAndCashier checks today's trend after one sale by a new cashier,
+// and that weekly buckets are contiguous ISO weeks
+func TestSalesTrend_CountsSaleAndCashier(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	reportSvc := service.NewReportService(repository.NewTransactionRepository(db), repository.NewCashFlowRepository(db),
+		repository.NewKasbonRepository(db), repository.NewInventoryRepository(db), repository.NewReportRepository(db))
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	before, err := reportSvc.SalesTrend(ctx, today, today, domain.TrendIntervalDay)
+	if err != nil {
+		t.Fatalf("Failed to get sales trend: %v", err)
+	}
+
+	cashier := "Trend " + uuid.New().String()[:8]
+	input := cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 3})
+	input.CashierName = &cashier
+	if _, err := svc.CreateTransaction(ctx, input); err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	after, err := reportSvc.SalesTrend(ctx, today, today, domain.TrendIntervalDay)
+	if err != nil {
+		t.Fatalf("Failed to get sales trend: %v", err)
+	}
+	if got := after.Current.Sales - before.Current.Sales; got != 3000 {
+		t.Errorf("Expected sales to grow by 3000, got %d", got)
+	}
+	if len(after.Buckets) != 1 || after.Buckets[0].Sales != after.Current.Sales {
+		t.Errorf("Expected one bucket holding the day's sales, got %+v", after.Buckets)
+	}
+	if !after.Previous.DateTo.Equal(today.AddDate(0, 0, -1)) || !after.LastYear.DateFrom.Equal(today.AddDate(-1, 0, 0)) {
+		t.Errorf("Unexpected comparison periods: previous %v, last year %v", after.Previous, after.LastYear)
+	}
+
+	found := false
+	for _, c := range after.ByCashier {
+		if c.CashierName == cashier {
+			found = true
+			if c.Sales != 3000 || c.Transactions != 1 || c.Growth != nil {
+				t.Errorf("Unexpected cashier totals: %+v", c)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected cashier %q in the breakdown", cashier)
+	}
+
+	weekly, err := reportSvc.SalesTrend(ctx, today.AddDate(0, 0, -20), today, domain.TrendIntervalWeek)
+	if err != nil {
+		t.Fatalf("Failed to get weekly trend: %v", err)
+	}
+	for i, b := range weekly.Buckets {
+		if b.Start.Weekday() != time.Monday {
+			t.Errorf("Expected bucket %d to start on a Monday, got %s", i, b.Start.Weekday())
+		}
+		if i > 0 && !b.Start.Equal(weekly.Buckets[i-1].Start.AddDate(0, 0, 7)) {
+			t.Errorf("Expected bucket %d to follow the previous week, got %s", i, b.Start)
+		}
+	}
+	if n := len(weekly.Buckets); n < 3 || n > 4 {
+		t.Errorf("Expected 3 or 4 weekly buckets over 21 days, got %d", n)
+	}
+}
+
+// TestSalesTrend_CategoryExcludesInclusiveTax checks that a tax-inclusive sale counts towards its
+// category without the tax, as it does towards the period's sales
+func TestSalesTrend_CategoryExcludesInclusiveTax(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	useTaxSettings(t, db, "10", "true")
+	svc, productRepo := newTestTransactionService(db)
+	reportSvc := service.NewReportService(repository.NewTransactionRepository(db), repository.NewCashFlowRepository(db),
+		repository.NewKasbonRepository(db), repository.NewInventoryRepository(db), repository.NewReportRepository(db))
+	ctx := context.Background()
+
+	var categoryID uuid.UUID
+	if err := db.QueryRowContext(ctx, "INSERT INTO categories (name) VALUES ($1) RETURNING id",
+		"Trend "+uuid.New().String()[:8]).Scan(&categoryID); err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", categoryID); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+	product := createStockedProduct(t, db, productRepo, 20)
+	if _, err := db.ExecContext(ctx, "UPDATE products SET category_id = $1 WHERE id = $2", categoryID, product.ID); err != nil {
+		t.Fatalf("Failed to assign category: %v", err)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	before, err := reportSvc.SalesTrend(ctx, today, today, domain.TrendIntervalDay)
+	if err != nil {
+		t.Fatalf("Failed to get sales trend: %v", err)
+	}
+
+	// 11000 paid, of which 1000 is tax
+	sale, err := svc.CreateTransaction(ctx, cashSale(domain.TransactionItemInput{ProductID: product.ID, Quantity: 11}))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if sale.TaxAmount != 1000 || sale.TotalAmount != 11000 {
+		t.Fatalf("Expected tax 1000 within a total of 11000, got %d within %d", sale.TaxAmount, sale.TotalAmount)
+	}
+
+	after, err := reportSvc.SalesTrend(ctx, today, today, domain.TrendIntervalDay)
+	if err != nil {
+		t.Fatalf("Failed to get sales trend: %v", err)
+	}
+	if got := after.Current.Sales - before.Current.Sales; got != 10000 {
+		t.Errorf("Expected sales to grow by 10000, got %d", got)
+	}
+	found := false
+	for _, c := range after.ByCategory {
+		if c.CategoryID != nil && *c.CategoryID == categoryID {
+			found = true
+			if c.Sales != 10000 || c.Quantity != 11 {
+				t.Errorf("Expected 11 sold in the category for 10000, got %d for %d", c.Quantity, c.Sales)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected the category in the breakdown")
+	}
+}
@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/eveeze/warung-backend/internal/pkg/spreadsheet"
+)
+
+// TestSpreadsheet_Rupiah checks that Rupiah cells stay numbers, shown as Rupiah in a workbook
+// and written plain to CSV
+func TestSpreadsheet_Rupiah(t *testing.T) {
+	rows := [][]any{
+		{"Item", "Amount"},
+		{"Sales", spreadsheet.Rupiah(1_234_500)},
+		{"Refunds", spreadsheet.Rupiah(-2_500)},
+		{"Count", 7},
+	}
+
+	var buf bytes.Buffer
+	if err := spreadsheet.WriteWorkbook(&buf, []spreadsheet.Sheet{{Name: "Report", Rows: rows}}); err != nil {
+		t.Fatalf("Failed to write workbook: %v", err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("Failed to open workbook: %v", err)
+	}
+	defer f.Close()
+
+	for _, tt := range []struct {
+		cell, raw, shown string
+	}{
+		{"B2", "1234500", "Rp 1,234,500"},
+		{"B3", "-2500", "-Rp 2,500"},
+		{"B4", "7", "7"},
+	} {
+		raw, err := f.GetCellValue("Report", tt.cell, excelize.Options{RawCellValue: true})
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", tt.cell, err)
+		}
+		shown, err := f.GetCellValue("Report", tt.cell)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", tt.cell, err)
+		}
+		if raw != tt.raw || shown != tt.shown {
+			t.Errorf("Expected %s stored as %s and shown as %q, got %s shown as %q", tt.cell, tt.raw, tt.shown, raw, shown)
+		}
+		if cellType, _ := f.GetCellType("Report", tt.cell); cellType == excelize.CellTypeSharedString || cellType == excelize.CellTypeInlineString {
+			t.Errorf("Expected %s stored as a number, got a string", tt.cell)
+		}
+	}
+
+	buf.Reset()
+	if err := spreadsheet.Write(&buf, spreadsheet.FormatCSV, "Report", rows); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	want := "Item,Amount\nSales,1234500\nRefunds,-2500\nCount,7\n"
+	if buf.String() != want {
+		t.Errorf("Expected CSV %q, got %q", want, buf.String())
+	}
+}
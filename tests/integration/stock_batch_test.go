@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+)
+
+// TestStockBatches_FEFO sells from two lots received in the wrong order and cancels the sale
+func TestStockBatches_FEFO(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	product := createStockedProduct(t, db, productRepo, 0)
+	ctx := context.Background()
+
+	later := time.Now().AddDate(0, 2, 0).Format("2006-01-02")
+	sooner := time.Now().AddDate(0, 1, 0).Format("2006-01-02")
+	laterBatch, soonerBatch := "LATE", "SOON"
+	for _, lot := range []domain.LotInput{
+		{BatchNumber: &laterBatch, ExpiryDate: &later},
+		{BatchNumber: &soonerBatch, ExpiryDate: &sooner},
+	} {
+		if _, err := inventoryRepo.Restock(ctx, domain.RestockInput{
+			ProductID:   product.ID,
+			Quantity:    5,
+			CostPerUnit: 800,
+			LotInput:    lot,
+		}); err != nil {
+			t.Fatalf("Failed to restock: %v", err)
+		}
+	}
+
+	transaction, err := svc.CreateTransaction(ctx, cashSale(
+		domain.TransactionItemInput{ProductID: product.ID, Quantity: 7},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	remaining := func() map[string]int {
+		batches, err := inventoryRepo.ListBatches(ctx, product.ID, true)
+		if err != nil {
+			t.Fatalf("Failed to list batches: %v", err)
+		}
+		left := make(map[string]int)
+		for _, b := range batches {
+			left[*b.BatchNumber] = b.RemainingQuantity
+		}
+		return left
+	}
+
+	if left := remaining(); left[soonerBatch] != 0 || left[laterBatch] != 3 {
+		t.Errorf("Expected the sooner lot used up and 3 left in the later one, got %v", left)
+	}
+
+	if err := svc.CancelTransaction(ctx, transaction.ID, domain.TransactionCancelInput{Reason: "FEFO test"}); err != nil {
+		t.Fatalf("Failed to cancel transaction: %v", err)
+	}
+
+	if left := remaining(); left[soonerBatch] != 5 || left[laterBatch] != 5 {
+		t.Errorf("Expected both lots restored to 5, got %v", left)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 10 {
+		t.Errorf("Expected stock 10, got %d", stock)
+	}
+}
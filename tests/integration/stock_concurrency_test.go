@@ -0,0 +1,377 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/eveeze/warung-backend/internal/config"
+	"github.com/eveeze/warung-backend/internal/database"
+	"github.com/eveeze/warung-backend/internal/domain"
+	"github.com/eveeze/warung-backend/internal/repository"
+	"github.com/eveeze/warung-backend/internal/service"
+)
+
+// These tests need a migrated PostgreSQL database (TEST_DB_* env vars) and are skipped without one.
+
+func setupConcurrencyDB(t *testing.T) *database.PostgresDB {
+	getEnv := func(key, defaultVal string) string {
+		if val := os.Getenv(key); val != "" {
+			return val
+		}
+		return defaultVal
+	}
+
+	db, err := database.NewPostgres(&config.DatabaseConfig{
+		Host:            getEnv("TEST_DB_HOST", "localhost"),
+		Port:            getEnv("TEST_DB_PORT", "5432"),
+		User:            getEnv("TEST_DB_USER", "postgres"),
+		Password:        getEnv("TEST_DB_PASSWORD", "postgres"),
+		DBName:          getEnv("TEST_DB_NAME", "warung_db"),
+		SSLMode:         "disable",
+		MaxOpenConns:    30,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+	})
+	if err != nil {
+		t.Skipf("Skipping test: cannot connect to test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestTransactionService(db *database.PostgresDB) (*service.TransactionService, *repository.ProductRepository) {
+	productRepo := repository.NewProductRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	settingsSvc := service.NewSettingsService(db, repository.NewSettingsRepository(db))
+
+	svc := service.NewTransactionService(
+		db,
+		repository.NewTransactionRepository(db),
+		productRepo,
+		repository.NewCustomerRepository(db),
+		repository.NewKasbonRepository(db),
+		repository.NewInventoryRepository(db),
+		repository.NewRefillableRepository(db),
+		service.NewOutboxService(db, repository.NewOutboxRepository(db), nil, nil),
+		service.NewPromotionService(repository.NewPromotionRepository(db), productRepo, categoryRepo),
+		service.NewTaxService(settingsSvc, categoryRepo),
+		settingsSvc,
+	)
+	return svc, productRepo
+}
+
+// createStockedProduct creates a stock-tracked product and removes it, with its sales, after the test
+func createStockedProduct(t *testing.T, db *database.PostgresDB, productRepo *repository.ProductRepository, stock int) *domain.Product {
+	ctx := context.Background()
+	tracked := true
+	product, err := productRepo.Create(ctx, domain.ProductCreateInput{
+		Name:          "Concurrency Test " + uuid.New().String()[:8],
+		Unit:          "pcs",
+		BasePrice:     1000,
+		CostPrice:     800,
+		IsStockActive: &tracked,
+		CurrentStock:  &stock,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	t.Cleanup(func() {
+		var txIDs pq.StringArray
+		if err := db.QueryRowContext(ctx,
+			"SELECT COALESCE(array_agg(DISTINCT transaction_id::text), '{}') FROM transaction_items WHERE product_id = $1",
+			product.ID,
+		).Scan(&txIDs); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+			return
+		}
+
+		for _, query := range []string{
+			"DELETE FROM outbox_events WHERE payload->>'transaction_id' = ANY($1::text[])",
+			"DELETE FROM transaction_payments WHERE transaction_id = ANY($1::uuid[])",
+			"DELETE FROM transaction_items WHERE transaction_id = ANY($1::uuid[])",
+			"DELETE FROM transactions WHERE id = ANY($1::uuid[])",
+		} {
+			if _, err := db.ExecContext(ctx, query, txIDs); err != nil {
+				t.Logf("Cleanup failed: %v", err)
+			}
+		}
+		for _, query := range []string{
+			"DELETE FROM outbox_events WHERE payload->>'product_id' = $1::text",
+			"DELETE FROM stock_movements WHERE product_id = $1",
+			"DELETE FROM products WHERE id = $1",
+		} {
+			if _, err := db.ExecContext(ctx, query, product.ID.String()); err != nil {
+				t.Logf("Cleanup failed: %v", err)
+			}
+		}
+	})
+
+	return product
+}
+
+// createKasbonCustomer creates a customer with a credit limit and removes it, with its kasbon
+// records, after the test. Create it before the products it buys so it outlives their sales.
+func createKasbonCustomer(t *testing.T, db *database.PostgresDB, creditLimit int64) *domain.Customer {
+	ctx := context.Background()
+	customer, err := repository.NewCustomerRepository(db).Create(ctx, domain.CustomerCreateInput{
+		Name:        "Kasbon Test " + uuid.New().String()[:8],
+		CreditLimit: &creditLimit,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create customer: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM customers WHERE id = $1", customer.ID); err != nil {
+			t.Logf("Cleanup failed: %v", err)
+		}
+	})
+	return customer
+}
+
+func cashSale(items ...domain.TransactionItemInput) domain.TransactionCreateInput {
+	return domain.TransactionCreateInput{
+		Items:         items,
+		PaymentMethod: domain.PaymentMethodCash,
+		AmountPaid:    1_000_000,
+	}
+}
+
+func currentStock(t *testing.T, productRepo *repository.ProductRepository, id uuid.UUID) int {
+	product, err := productRepo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to reload product: %v", err)
+	}
+	return product.CurrentStock
+}
+
+// TestCreateTransaction_NoOversell races more buyers than there is stock
+func TestCreateTransaction_NoOversell(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	product := createStockedProduct(t, db, productRepo, 5)
+
+	const buyers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, buyers)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.CreateTransaction(context.Background(), cashSale(
+				domain.TransactionItemInput{ProductID: product.ID, Quantity: 1},
+			))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	sold := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			sold++
+		case errors.Is(err, domain.ErrInsufficientStock):
+		default:
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	if sold != 5 {
+		t.Errorf("Expected exactly 5 sales, got %d", sold)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 0 {
+		t.Errorf("Expected stock 0, got %d", stock)
+	}
+
+	var movements int
+	err := db.QueryRowContext(context.Background(),
+		"SELECT COUNT(*) FROM stock_movements WHERE product_id = $1 AND type = 'sale'", product.ID,
+	).Scan(&movements)
+	if err != nil {
+		t.Fatalf("Failed to count movements: %v", err)
+	}
+	if movements != sold {
+		t.Errorf("Expected %d sale movements, got %d", sold, movements)
+	}
+}
+
+// TestCreateTransaction_RepeatedLinesShareStock lists the same product twice in one cart
+func TestCreateTransaction_RepeatedLinesShareStock(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	product := createStockedProduct(t, db, productRepo, 3)
+
+	_, err := svc.CreateTransaction(context.Background(), cashSale(
+		domain.TransactionItemInput{ProductID: product.ID, Quantity: 2},
+		domain.TransactionItemInput{ProductID: product.ID, Quantity: 2},
+	))
+	if !errors.Is(err, domain.ErrInsufficientStock) {
+		t.Fatalf("Expected insufficient stock, got %v", err)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 3 {
+		t.Errorf("Expected stock to stay 3, got %d", stock)
+	}
+}
+
+// TestCreateTransaction_OppositeCartOrder sells two products listed in opposite orders;
+// without ordered locking these carts deadlock each other
+func TestCreateTransaction_OppositeCartOrder(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	a := createStockedProduct(t, db, productRepo, 50)
+	b := createStockedProduct(t, db, productRepo, 50)
+
+	const buyers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, buyers)
+	for i := 0; i < buyers; i++ {
+		first, second := a, b
+		if i%2 == 1 {
+			first, second = b, a
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.CreateTransaction(context.Background(), cashSale(
+				domain.TransactionItemInput{ProductID: first.ID, Quantity: 1},
+				domain.TransactionItemInput{ProductID: second.ID, Quantity: 1},
+			))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	for _, p := range []*domain.Product{a, b} {
+		if stock := currentStock(t, productRepo, p.ID); stock != 50-buyers {
+			t.Errorf("Expected stock %d for %s, got %d", 50-buyers, p.Name, stock)
+		}
+	}
+}
+
+// TestCancelTransaction_Concurrent cancels the same sale from several cashiers at once
+func TestCancelTransaction_Concurrent(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	product := createStockedProduct(t, db, productRepo, 10)
+
+	transaction, err := svc.CreateTransaction(context.Background(), cashSale(
+		domain.TransactionItemInput{ProductID: product.ID, Quantity: 2},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	const cashiers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, cashiers)
+	for i := 0; i < cashiers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- svc.CancelTransaction(context.Background(), transaction.ID, domain.TransactionCancelInput{
+				Reason:      "Concurrency test",
+				CancelledBy: "tester",
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	cancelled := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			cancelled++
+		case errors.Is(err, domain.ErrTransactionCancelled):
+		default:
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	if cancelled != 1 {
+		t.Errorf("Expected exactly one cancellation, got %d", cancelled)
+	}
+	if stock := currentStock(t, productRepo, product.ID); stock != 10 {
+		t.Errorf("Expected stock back at 10, got %d", stock)
+	}
+
+	var returns int
+	err = db.QueryRowContext(context.Background(),
+		"SELECT COUNT(*) FROM stock_movements WHERE product_id = $1 AND type = 'return' AND reference_id = $2",
+		product.ID, transaction.ID,
+	).Scan(&returns)
+	if err != nil {
+		t.Fatalf("Failed to count movements: %v", err)
+	}
+	if returns != 1 {
+		t.Errorf("Expected one return movement, got %d", returns)
+	}
+}
+
+// TestCreateTransaction_ConcurrentKasbonRespectsCreditLimit runs kasbon sales for one customer
+// at the same time; only as many as fit the credit limit may go through
+func TestCreateTransaction_ConcurrentKasbonRespectsCreditLimit(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	ctx := context.Background()
+
+	customer := createKasbonCustomer(t, db, 5000)
+	product := createStockedProduct(t, db, productRepo, 50)
+
+	const buyers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, buyers)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.CreateTransaction(ctx, domain.TransactionCreateInput{
+				Items:         []domain.TransactionItemInput{{ProductID: product.ID, Quantity: 2}},
+				PaymentMethod: domain.PaymentMethodKasbon,
+				CustomerID:    &customer.ID,
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	sold := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			sold++
+		case errors.Is(err, domain.ErrCreditLimitExceeded):
+		default:
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	// 2000 per sale against a limit of 5000
+	if sold != 2 {
+		t.Errorf("Expected exactly 2 kasbon sales, got %d", sold)
+	}
+	var debt int64
+	if err := db.QueryRowContext(ctx, "SELECT current_debt FROM customers WHERE id = $1", customer.ID).Scan(&debt); err != nil {
+		t.Fatalf("Failed to read debt: %v", err)
+	}
+	if debt != 4000 {
+		t.Errorf("Expected a debt of 4000, got %d", debt)
+	}
+}
@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eveeze/warung-backend/internal/domain"
+)
+
+// TestTaxConfig_Calculate checks the tax on a cart across inclusive, exclusive and rounding modes
+func TestTaxConfig_Calculate(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    domain.TaxConfig
+		subtotal  int64
+		taxable   int64
+		discount  int64
+		wantTax   int64
+		wantTotal int64
+	}{
+		{"exclusive", domain.TaxConfig{Rate: 11}, 10000, 10000, 0, 1100, 11100},
+		{"inclusive", domain.TaxConfig{Rate: 11, Inclusive: true}, 11100, 11100, 0, 1100, 11100},
+		{"no rate", domain.TaxConfig{}, 10000, 10000, 0, 0, 10000},
+		{"exempt lines", domain.TaxConfig{Rate: 11}, 10000, 0, 0, 0, 10000},
+		{"discount lowers the base", domain.TaxConfig{Rate: 10}, 10000, 10000, 2000, 800, 8800},
+		{"discount spread over exempt lines", domain.TaxConfig{Rate: 10}, 10000, 5000, 2000, 400, 8400},
+		{"inclusive with discount", domain.TaxConfig{Rate: 10, Inclusive: true}, 11000, 11000, 1100, 900, 9900},
+		{"discount of everything", domain.TaxConfig{Rate: 11}, 10000, 10000, 10000, 0, 0},
+		// 1234 * 11% = 135.74
+		{"rounds to nearest", domain.TaxConfig{Rate: 11}, 1234, 1234, 0, 136, 1370},
+		{"rounds up", domain.TaxConfig{Rate: 11, Rounding: domain.TaxRoundingUp}, 1234, 1234, 0, 136, 1370},
+		{"rounds down", domain.TaxConfig{Rate: 11, Rounding: domain.TaxRoundingDown}, 1234, 1234, 0, 135, 1369},
+		// 1001 * 11 / 111 = 99.19
+		{"inclusive rounds to nearest", domain.TaxConfig{Rate: 11, Inclusive: true}, 1001, 1001, 0, 99, 1001},
+		{"inclusive rounds up", domain.TaxConfig{Rate: 11, Inclusive: true, Rounding: domain.TaxRoundingUp}, 1001, 1001, 0, 100, 1001},
+		{"inclusive rounds down", domain.TaxConfig{Rate: 11, Inclusive: true, Rounding: domain.TaxRoundingDown}, 1001, 1001, 0, 99, 1001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tax := tt.config.Calculate(tt.subtotal, tt.taxable, tt.discount)
+			total := tt.config.Total(tt.subtotal, tt.discount, tax)
+			if tax != tt.wantTax || total != tt.wantTotal {
+				t.Errorf("Expected tax %d and total %d, got %d and %d", tt.wantTax, tt.wantTotal, tax, total)
+			}
+		})
+	}
+}
+
+// TestCalculateCart_RejectsInvalidDiscount checks that the cart preview validates the order discount
+// the same way checkout does
+func TestCalculateCart_RejectsInvalidDiscount(t *testing.T) {
+	db := setupConcurrencyDB(t)
+	svc, productRepo := newTestTransactionService(db)
+	product := createStockedProduct(t, db, productRepo, 10)
+	ctx := context.Background()
+
+	for _, discount := range []int64{-1, 2001} {
+		_, err := svc.CalculateCart(ctx, domain.CartCalculateInput{
+			Items:          []domain.CartItem{{ProductID: product.ID, Quantity: 2}},
+			DiscountAmount: &discount,
+		})
+		if !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("Expected a discount of %d on 2000 to be rejected, got %v", discount, err)
+		}
+	}
+}